@@ -3,14 +3,23 @@ package main
 
 import (
 	"flag"
+	"log"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/KOU050223/go-card/internal/auth"
+	"github.com/KOU050223/go-card/internal/game"
 	"github.com/KOU050223/go-card/internal/server"
+	"github.com/KOU050223/go-card/internal/ws"
 	"github.com/joho/godotenv"
 )
 
+// defaultAllowOrigin は ALLOW_ORIGINS が未設定のときに許可するOrigin (開発用のフロントエンド) です
+const defaultAllowOrigin = "http://localhost:3000"
+
 // LoadConfig は環境変数とコマンドラインフラグから設定を読み込みます
 func LoadConfig() *server.Config {
 	// .env ファイルがあれば読み込み
@@ -18,15 +27,30 @@ func LoadConfig() *server.Config {
 
 	// デフォルト値の設定
 	cfg := &server.Config{
-		Port:            8080,
-		AllowOrigins:    []string{"*"},
-		FirebaseProject: os.Getenv("FIREBASE_PROJECT_ID"),
+		Port:         8080,
+		AllowOrigins: []string{defaultAllowOrigin},
+		Auth: auth.Config{
+			Mode:            auth.ModeFirebase,
+			FirebaseProject: os.Getenv("FIREBASE_PROJECT_ID"),
+			CredentialsFile: os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"),
+			DevUserID:       os.Getenv("AUTH_DEV_USER_ID"),
+			JWT: auth.JWTConfig{
+				Secret:         os.Getenv("JWT_SECRET"),
+				PrivateKeyFile: os.Getenv("JWT_PRIVATE_KEY_FILE"),
+				Issuer:         os.Getenv("JWT_ISSUER"),
+			},
+		},
 		DB: server.DBConfig{
 			User:                   os.Getenv("DB_USER"),
 			Password:               os.Getenv("DB_PASS"),
 			Name:                   os.Getenv("DB_NAME"),
 			InstanceConnectionName: os.Getenv("INSTANCE_CONNECTION_NAME"),
 		},
+		RedisURL:             os.Getenv("REDIS_URL"),
+		WSMessageLimits:      ws.DefaultMessageLimits(),
+		MatchmakingStore:     "memory",
+		MatchAcceptTimeout:   game.DefaultAcceptTimeout,
+		MatchDeclineCooldown: game.DefaultDeclineCooldown,
 	}
 
 	// 環境変数から読み込み (PORT はGCP App Engineで使用)
@@ -40,6 +64,124 @@ func LoadConfig() *server.Config {
 		cfg.AllowOrigins = strings.Split(origins, ",")
 	}
 
+	// X-Forwarded-For を付けるリバースプロキシのアドレス範囲 (カンマ区切りのCIDR)。
+	// 未設定ならIPごとの制限には接続元のアドレスを使う
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		for _, cidr := range strings.Split(proxies, ",") {
+			_, ipRange, err := net.ParseCIDR(strings.TrimSpace(cidr))
+			if err != nil {
+				log.Fatalf("TRUSTED_PROXIES の設定エラー: %v", err)
+			}
+			cfg.TrustedProxies = append(cfg.TrustedProxies, ipRange)
+		}
+	}
+
+	// 認証モード (未設定なら firebase)。開発用のユーザーIDは local-dev・disabled を明示した場合のみ使う
+	if os.Getenv("AUTH_DEV_MODE") != "" {
+		log.Fatalf("AUTH_DEV_MODE は廃止されました。AUTH_MODE=local-dev を指定してください")
+	}
+	if mode := os.Getenv("AUTH_MODE"); mode != "" {
+		if !auth.ValidMode(mode) {
+			log.Fatalf("AUTH_MODE は firebase, jwt, local-dev, disabled のいずれかを指定してください: %q", mode)
+		}
+		cfg.Auth.Mode = mode
+	}
+	// "*" はどのサイトからもCORS・WebSocket接続を受け付けてしまうため、トークンを検証するモードでは使えない
+	for _, origin := range cfg.AllowOrigins {
+		if strings.TrimSpace(origin) != "*" {
+			continue
+		}
+		if cfg.Auth.Mode == auth.ModeFirebase || cfg.Auth.Mode == auth.ModeJWT {
+			log.Fatalf("ALLOW_ORIGINS に * は指定できません (AUTH_MODE=%s)。フロントエンドのOriginを指定してください", cfg.Auth.Mode)
+		}
+		log.Printf("警告: ALLOW_ORIGINS に * が指定されています。すべてのOriginからの接続を受け付けるため本番環境では使用しないでください")
+	}
+	if ttl := os.Getenv("JWT_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("JWT_TTL の設定エラー: %v", err)
+		}
+		cfg.Auth.JWT.TTL = d
+	}
+	// DBの権限にかかわらず管理者として扱うユーザーID (カンマ区切り。最初の管理者を作るため)
+	if ids := os.Getenv("ADMIN_USER_IDS"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				cfg.Auth.AdminUserIDs = append(cfg.Auth.AdminUserIDs, id)
+			}
+		}
+	}
+	if ttl := os.Getenv("JWT_GUEST_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("JWT_GUEST_TTL の設定エラー: %v", err)
+		}
+		cfg.Auth.JWT.GuestTTL = d
+	}
+
+	// 複数インスタンスで待機キューを共有する場合は mysql を指定
+	if store := os.Getenv("MATCHMAKING_STORE"); store != "" {
+		if store != "memory" && store != "mysql" {
+			log.Fatalf("MATCHMAKING_STORE は memory または mysql を指定してください: %q", store)
+		}
+		cfg.MatchmakingStore = store
+	}
+
+	// マッチ成立後の承認ステップ (Goの時間表記: "10s" など)
+	if timeout := os.Getenv("MATCH_ACCEPT_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			log.Fatalf("MATCH_ACCEPT_TIMEOUT の設定エラー: %v", err)
+		}
+		cfg.MatchAcceptTimeout = d
+	}
+	if cooldown := os.Getenv("MATCH_DECLINE_COOLDOWN"); cooldown != "" {
+		d, err := time.ParseDuration(cooldown)
+		if err != nil {
+			log.Fatalf("MATCH_DECLINE_COOLDOWN の設定エラー: %v", err)
+		}
+		cfg.MatchDeclineCooldown = d
+	}
+
+	// 待ち時間が長いプレイヤーをボットと組ませる (未設定なら無効)
+	if after := os.Getenv("MATCH_BOT_AFTER"); after != "" {
+		d, err := time.ParseDuration(after)
+		if err != nil {
+			log.Fatalf("MATCH_BOT_AFTER の設定エラー: %v", err)
+		}
+		cfg.MatchBotAfter = d
+	}
+
+	// WebSocket接続チケットの有効期間 (未設定なら ws.DefaultTicketTTL)
+	if ttl := os.Getenv("WS_TICKET_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatalf("WS_TICKET_TTL の設定エラー: %v", err)
+		}
+		cfg.WSTicketTTL = d
+	}
+
+	// WebSocketメッセージのサイズ上限
+	if size := os.Getenv("WS_MAX_MESSAGE_SIZE"); size != "" {
+		s, err := strconv.Atoi(size)
+		if err != nil {
+			log.Fatalf("WS_MAX_MESSAGE_SIZE の設定エラー: %v", err)
+		}
+		if s <= 0 {
+			log.Fatalf("WS_MAX_MESSAGE_SIZE は正の整数を指定してください: %d", s)
+		}
+		cfg.WSMessageLimits.Default = s
+	}
+	if spec := os.Getenv("WS_MESSAGE_SIZE_LIMITS"); spec != "" {
+		limits, err := ws.ParseMessageLimits(spec)
+		if err != nil {
+			log.Fatalf("WS_MESSAGE_SIZE_LIMITS の設定エラー: %v", err)
+		}
+		for msgType, size := range limits {
+			cfg.WSMessageLimits.PerType[msgType] = size
+		}
+	}
+
 	// コマンドラインフラグの処理
 	flag.IntVar(&cfg.Port, "port", cfg.Port, "Server port")
 	flag.Parse()
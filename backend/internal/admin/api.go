@@ -0,0 +1,240 @@
+// backend/internal/admin/api.go
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KOU050223/go-card/internal/auth"
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/KOU050223/go-card/internal/game"
+	"github.com/KOU050223/go-card/internal/model"
+	"github.com/KOU050223/go-card/internal/user"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxCardNameLength はカード名の最大文字数です
+	maxCardNameLength = 255
+	// maxCardPoints はカードの攻撃力・防御力の上限です
+	maxCardPoints = 99
+	// maxReasonLength は制裁の理由の最大文字数です
+	maxReasonLength = 255
+	// maxAuditDetailsLength は管理操作の記録の詳細の最大文字数です
+	maxAuditDetailsLength = 512
+)
+
+// 管理操作の記録に使う操作名
+const (
+	actionEndDuel       = "duel.end"
+	actionUpdateCard    = "card.update"
+	actionSetRole       = "user.role"
+	actionApplySanction = "sanction.apply"
+	actionLiftSanction  = "sanction.lift"
+)
+
+// Sessions は対戦とWebSocket接続・カードプールを操作します
+//
+// 別インスタンスの対戦・接続は担当インスタンスに転送し、一覧とカードの変更はすべてのインスタンスに問い合わせます。
+type Sessions interface {
+	LiveDuels() ([]*game.Duel, error)
+	GetDuel(duelID string) (*game.Duel, error)
+	ForceEndDuel(duelID, winnerID string, unrated bool) (*game.Duel, error)
+	DisconnectUser(userID, reason string) error
+	UpdateCard(card game.Card) error
+}
+
+// RoleResolver はDBに保存された権限から、設定の管理者を反映した実際の権限を返します
+type RoleResolver func(userID, role string) string
+
+// API は /api/admin の管理操作をRESTで公開します
+//
+// 対戦の監視・強制終了と制裁 (BAN・利用停止・チャット禁止) はモデレーター以上、
+// カードの編集・権限の変更と管理操作の記録の閲覧は管理者のみが行えます
+// (権限の確認はルーターで auth.RequireRole を使って行います)。変更を伴う操作はすべて記録します。
+type API struct {
+	Sessions  Sessions
+	Cards     *db.CardRepository
+	Users     *db.UserRepository
+	Sanctions *db.SanctionRepository
+	Audit     *db.AuditRepository
+	Access    *user.AccessCache
+	Roles     RoleResolver
+}
+
+// NewAPI はAPIを作成します
+func NewAPI(sessions Sessions, cards *db.CardRepository, users *db.UserRepository,
+	sanctions *db.SanctionRepository, audit *db.AuditRepository, access *user.AccessCache, roles RoleResolver) *API {
+	return &API{Sessions: sessions, Cards: cards, Users: users, Sanctions: sanctions, Audit: audit, Access: access, Roles: roles}
+}
+
+// ListDuels はすべてのインスタンスで進行中の対戦を返します
+// GET /api/admin/duels
+func (api *API) ListDuels(c echo.Context) error {
+	duels, err := api.Sessions.LiveDuels()
+	if err != nil {
+		log.Printf("進行中の対戦の取得エラー: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "対戦一覧の取得に失敗しました")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"duels": duels})
+}
+
+// GetDuel は対戦の状態 (両プレイヤーの手札を含む) を返します
+// GET /api/admin/duels/:id
+func (api *API) GetDuel(c echo.Context) error {
+	duel, err := api.Sessions.GetDuel(c.Param("id"))
+	if errors.Is(err, game.ErrDuelNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "対戦が見つかりません")
+	}
+	if err != nil {
+		log.Printf("対戦の取得エラー (対戦: %s): %v", c.Param("id"), err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "対戦の取得に失敗しました")
+	}
+	return c.JSON(http.StatusOK, duel)
+}
+
+// EndDuel は進行中の対戦を強制終了します
+// POST /api/admin/duels/:id/end
+func (api *API) EndDuel(c echo.Context) error {
+	var req struct {
+		WinnerID string `json:"winnerId"` // 空なら引き分け
+		Unrated  bool   `json:"unrated"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+
+	duelID := c.Param("id")
+	duel, err := api.Sessions.ForceEndDuel(duelID, req.WinnerID, req.Unrated)
+	switch {
+	case errors.Is(err, game.ErrDuelNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "対戦が見つかりません")
+	case errors.Is(err, game.ErrDuelNotActive):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, game.ErrInvalidWinner):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("対戦の強制終了エラー (対戦: %s): %v", duelID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "対戦の終了に失敗しました")
+	}
+
+	details := "引き分け"
+	if req.WinnerID != "" {
+		details = "勝者: " + req.WinnerID
+	}
+	if req.Unrated {
+		details += " (レーティング対象外)"
+	}
+	api.audit(c, actionEndDuel, "duel", duelID, details)
+
+	// 別インスタンスに転送した場合は結果を duelUpdate で通知する
+	if duel == nil {
+		return c.NoContent(http.StatusAccepted)
+	}
+	return c.JSON(http.StatusOK, duel)
+}
+
+// UpdateCard はカードの名前・攻撃力・防御力を変更します
+//
+// すべてのインスタンスのカードプールに反映し、変更後に作成される対戦から使われます。
+// 一部のインスタンスに反映できなかった場合も変更は保存済みのため、同じ内容で再実行できます。
+// PUT /api/admin/cards/:id
+func (api *API) UpdateCard(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "カードIDが不正です")
+	}
+	var req struct {
+		Name       string `json:"name"`
+		AttackPts  *int   `json:"attackPts"`
+		DefensePts *int   `json:"defensePts"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+	card := model.Card{ID: id, Name: strings.TrimSpace(req.Name)}
+	if card.Name == "" || utf8.RuneCountInString(card.Name) > maxCardNameLength {
+		return echo.NewHTTPError(http.StatusBadRequest, "nameは1〜255文字で指定してください")
+	}
+	if req.AttackPts == nil || req.DefensePts == nil ||
+		*req.AttackPts < 0 || *req.AttackPts > maxCardPoints || *req.DefensePts < 0 || *req.DefensePts > maxCardPoints {
+		return echo.NewHTTPError(http.StatusBadRequest, "attackPts・defensePtsは0〜99で指定してください")
+	}
+	card.AttackPts = *req.AttackPts
+	card.DefensePts = *req.DefensePts
+
+	err = api.Cards.Update(c.Request().Context(), card)
+	if errors.Is(err, db.ErrCardNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "カードが見つかりません")
+	}
+	if err != nil {
+		log.Printf("カード更新エラー (カード: %d): %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "カードの更新に失敗しました")
+	}
+	syncErr := api.Sessions.UpdateCard(game.Card(card))
+
+	api.audit(c, actionUpdateCard, "card", strconv.Itoa(id),
+		fmt.Sprintf("%s (攻撃力: %d, 防御力: %d)", card.Name, card.AttackPts, card.DefensePts))
+	if syncErr != nil {
+		log.Printf("カードの反映エラー (カード: %d): %v", id, syncErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, "カードは保存しましたが、一部のサーバーへの反映に失敗しました。再度更新してください")
+	}
+	return c.JSON(http.StatusOK, card)
+}
+
+// GetUser はユーザーの情報と有効な制裁を返します
+// GET /api/admin/users/:id
+func (api *API) GetUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	target, err := api.Users.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "ユーザー情報の取得に失敗しました")
+	}
+	if target == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "ユーザーが見つかりません")
+	}
+	sanctions, err := api.Sanctions.ListActive(ctx, target.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "制裁の取得に失敗しました")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"user": target, "sanctions": sanctions})
+}
+
+// SetRole はユーザーの権限を変更します
+// PUT /api/admin/users/:id/role
+func (api *API) SetRole(c echo.Context) error {
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+	if !auth.ValidRole(req.Role) {
+		return echo.NewHTTPError(http.StatusBadRequest, "roleは player, moderator, admin のいずれかを指定してください")
+	}
+
+	targetID := c.Param("id")
+	actorID := c.Get("uid").(string)
+	// 管理者が自分の権限を外して管理者がいなくなるのを防ぐ
+	if targetID == actorID {
+		return echo.NewHTTPError(http.StatusBadRequest, "自分の権限は変更できません")
+	}
+
+	err := api.Users.SetRole(c.Request().Context(), targetID, req.Role)
+	if errors.Is(err, db.ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "ユーザーが見つかりません")
+	}
+	if err != nil {
+		log.Printf("権限変更エラー (ユーザー: %s): %v", targetID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "権限の変更に失敗しました")
+	}
+	api.Access.Invalidate(targetID)
+
+	api.audit(c, actionSetRole, "user", targetID, req.Role)
+	return c.JSON(http.StatusOK, map[string]string{"userId": targetID, "role": req.Role})
+}
@@ -0,0 +1,52 @@
+// backend/internal/admin/audit.go
+package admin
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// ListAudit は管理操作の記録を新しい順に返します
+//
+// ?actorId= ?targetType= ?targetId= で絞り込み、?limit= で件数 (最大500件) を指定できます。
+// GET /api/admin/audit
+func (api *API) ListAudit(c echo.Context) error {
+	filter := db.AuditFilter{
+		ActorID:    c.QueryParam("actorId"),
+		TargetType: c.QueryParam("targetType"),
+		TargetID:   c.QueryParam("targetId"),
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limitは正の整数で指定してください")
+		}
+		filter.Limit = n
+	}
+
+	entries, err := api.Audit.List(c.Request().Context(), filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "管理操作の記録の取得に失敗しました")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+// audit は管理操作を記録します
+//
+// 操作自体は完了しているため、記録に失敗してもエラーは返さずログに残します。
+func (api *API) audit(c echo.Context, action, targetType, targetID, details string) {
+	actorID := c.Get("uid").(string)
+	log.Printf("管理操作: %s が %s を実行しました (%s: %s) %s", actorID, action, targetType, targetID, details)
+
+	if runes := []rune(details); len(runes) > maxAuditDetailsLength {
+		details = string(runes[:maxAuditDetailsLength])
+	}
+	entry := &db.AuditEntry{ActorID: actorID, Action: action, TargetType: targetType, TargetID: targetID, Details: details}
+	if err := api.Audit.Record(c.Request().Context(), entry); err != nil {
+		log.Printf("管理操作の記録エラー (%s %s: %s): %v", action, targetType, targetID, err)
+	}
+}
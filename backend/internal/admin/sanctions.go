@@ -0,0 +1,172 @@
+// backend/internal/admin/sanctions.go
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KOU050223/go-card/internal/auth"
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// maxSanctionDuration は期限付きの制裁に指定できる最長の期間です
+const maxSanctionDuration = 365 * 24 * time.Hour
+
+// sanctionRequest は制裁を科すリクエストです
+type sanctionRequest struct {
+	Kind     string `json:"kind"`     // "ban", "suspend", "mute"
+	Reason   string `json:"reason"`   // 対象のユーザーにも表示されます
+	Duration string `json:"duration"` // "72h" など。省略すると期限なし (suspend では必須)
+}
+
+// ListSanctions は全ユーザーの有効な制裁を返します
+// GET /api/admin/sanctions
+func (api *API) ListSanctions(c echo.Context) error {
+	sanctions, err := api.Sanctions.ListActive(c.Request().Context(), "")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "制裁の取得に失敗しました")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"sanctions": sanctions})
+}
+
+// ListUserSanctions はユーザーの制裁の履歴 (解除・期限切れを含む) を返します
+// GET /api/admin/users/:id/sanctions
+func (api *API) ListUserSanctions(c echo.Context) error {
+	sanctions, err := api.Sanctions.ListByUser(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "制裁の取得に失敗しました")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"sanctions": sanctions})
+}
+
+// ApplySanction はユーザーにBAN・利用停止・チャット禁止を科します
+//
+// 自分より弱い権限のユーザーだけが対象です。BAN・利用停止ではユーザーのWebSocket接続も切断します。
+// POST /api/admin/users/:id/sanctions
+func (api *API) ApplySanction(c echo.Context) error {
+	var req sanctionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+	sanction, err := req.sanction()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	target, err := api.manageableUser(c, c.Param("id"))
+	if err != nil {
+		return err
+	}
+	sanction.UserID = target.ID
+	sanction.CreatedBy = c.Get("uid").(string)
+
+	if err := api.Sanctions.Insert(c.Request().Context(), sanction); err != nil {
+		log.Printf("制裁の記録エラー (ユーザー: %s): %v", target.ID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "制裁に失敗しました")
+	}
+	api.Access.Invalidate(target.ID)
+
+	if sanction.Blocks() {
+		blocked := &auth.BlockedError{Sanction: sanction}
+		if err := api.Sessions.DisconnectUser(target.ID, blocked.Error()); err != nil {
+			log.Printf("利用停止したユーザーの切断エラー (ユーザー: %s): %v", target.ID, err)
+		}
+	}
+
+	api.audit(c, actionApplySanction, "user", target.ID, describeSanction(sanction))
+	return c.JSON(http.StatusCreated, sanction)
+}
+
+// LiftSanction は有効な制裁を解除します
+// DELETE /api/admin/sanctions/:id
+func (api *API) LiftSanction(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "制裁IDが不正です")
+	}
+
+	ctx := c.Request().Context()
+	sanction, err := api.Sanctions.GetByID(ctx, id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "制裁の取得に失敗しました")
+	}
+	if sanction == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "制裁が見つかりません")
+	}
+	if _, err := api.manageableUser(c, sanction.UserID); err != nil {
+		return err
+	}
+
+	err = api.Sanctions.Revoke(ctx, id, c.Get("uid").(string))
+	if errors.Is(err, db.ErrSanctionNotFound) {
+		return echo.NewHTTPError(http.StatusConflict, "この制裁は解除済みか期限切れです")
+	}
+	if err != nil {
+		log.Printf("制裁の解除エラー (制裁: %d): %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "制裁の解除に失敗しました")
+	}
+	api.Access.Invalidate(sanction.UserID)
+
+	api.audit(c, actionLiftSanction, "user", sanction.UserID, fmt.Sprintf("#%d %s", sanction.ID, sanction.Kind))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// manageableUser はユーザーを取得し、操作する権限があるかを確認します
+//
+// 自分自身や、自分と同じかより強い権限のユーザー (設定で指定した管理者を含む) は操作できません。
+func (api *API) manageableUser(c echo.Context, userID string) (*db.User, error) {
+	target, err := api.Users.GetByID(c.Request().Context(), userID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "ユーザー情報の取得に失敗しました")
+	}
+	if target == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "ユーザーが見つかりません")
+	}
+	if target.ID == c.Get("uid").(string) || auth.HasRole(api.Roles(target.ID, target.Role), auth.Role(c)) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "このユーザーは操作できません")
+	}
+	return target, nil
+}
+
+// sanction はリクエストを検証し、記録する制裁を返します
+func (req sanctionRequest) sanction() (*db.Sanction, error) {
+	s := &db.Sanction{Kind: req.Kind, Reason: strings.TrimSpace(req.Reason)}
+	switch s.Kind {
+	case db.SanctionBan, db.SanctionSuspend, db.SanctionMute:
+	default:
+		return nil, errors.New("kindは ban, suspend, mute のいずれかを指定してください")
+	}
+	if utf8.RuneCountInString(s.Reason) > maxReasonLength {
+		return nil, fmt.Errorf("reasonは%d文字以内で指定してください", maxReasonLength)
+	}
+
+	if req.Duration == "" {
+		if s.Kind == db.SanctionSuspend {
+			return nil, errors.New("suspendにはdurationが必要です")
+		}
+		return s, nil
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d <= 0 || d > maxSanctionDuration {
+		return nil, errors.New("durationは \"72h\" の形式で、1年以内の期間を指定してください")
+	}
+	expiresAt := time.Now().Add(d)
+	s.ExpiresAt = &expiresAt
+	return s, nil
+}
+
+// describeSanction は管理操作の記録に残す制裁の説明を返します
+func describeSanction(s *db.Sanction) string {
+	until := "期限なし"
+	if s.ExpiresAt != nil {
+		until = s.ExpiresAt.Format(time.RFC3339) + " まで"
+	}
+	return fmt.Sprintf("#%d %s (%s) %s", s.ID, s.Kind, until, s.Reason)
+}
@@ -0,0 +1,53 @@
+// backend/internal/admin/sanctions_test.go
+package admin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+)
+
+func TestSanctionRequestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     sanctionRequest
+		wantErr bool
+		wantFor time.Duration // 期限までの期間 (0 なら期限なし)
+	}{
+		{"期限なしのBAN", sanctionRequest{Kind: db.SanctionBan, Reason: "  迷惑行為  "}, false, 0},
+		{"期限付きのチャット禁止", sanctionRequest{Kind: db.SanctionMute, Duration: "72h"}, false, 72 * time.Hour},
+		{"期限付きの利用停止", sanctionRequest{Kind: db.SanctionSuspend, Duration: "24h"}, false, 24 * time.Hour},
+		{"最長の期間", sanctionRequest{Kind: db.SanctionBan, Duration: maxSanctionDuration.String()}, false, maxSanctionDuration},
+		{"利用停止は期限が必要", sanctionRequest{Kind: db.SanctionSuspend}, true, 0},
+		{"未知の種類", sanctionRequest{Kind: "warn"}, true, 0},
+		{"種類なし", sanctionRequest{}, true, 0},
+		{"期間の形式が不正", sanctionRequest{Kind: db.SanctionMute, Duration: "3 days"}, true, 0},
+		{"期間が0", sanctionRequest{Kind: db.SanctionMute, Duration: "0s"}, true, 0},
+		{"期間が負", sanctionRequest{Kind: db.SanctionMute, Duration: "-1h"}, true, 0},
+		{"期間が1年を超える", sanctionRequest{Kind: db.SanctionBan, Duration: (maxSanctionDuration + time.Hour).String()}, true, 0},
+		{"理由が長すぎる", sanctionRequest{Kind: db.SanctionBan, Reason: strings.Repeat("あ", maxReasonLength+1)}, true, 0},
+		{"理由が上限ちょうど", sanctionRequest{Kind: db.SanctionBan, Reason: strings.Repeat("あ", maxReasonLength)}, false, 0},
+	}
+	for _, tt := range tests {
+		before := time.Now()
+		s, err := tt.req.sanction()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: sanction() のエラー = %v; want エラー %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if s.Kind != tt.req.Kind || s.Reason != strings.TrimSpace(tt.req.Reason) {
+			t.Errorf("%s: 制裁 = %s %q; want %s %q", tt.name, s.Kind, s.Reason, tt.req.Kind, strings.TrimSpace(tt.req.Reason))
+		}
+		switch {
+		case tt.wantFor == 0 && s.ExpiresAt != nil:
+			t.Errorf("%s: 期限 = %v; want 期限なし", tt.name, s.ExpiresAt)
+		case tt.wantFor != 0 && (s.ExpiresAt == nil || s.ExpiresAt.Before(before.Add(tt.wantFor)) || s.ExpiresAt.After(time.Now().Add(tt.wantFor))):
+			t.Errorf("%s: 期限 = %v; want 現在から %s 後", tt.name, s.ExpiresAt, tt.wantFor)
+		}
+	}
+}
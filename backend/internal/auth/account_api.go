@@ -0,0 +1,159 @@
+// backend/internal/auth/account_api.go
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/KOU050223/go-card/internal/user"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// minPasswordLength はパスワードの最小バイト数です
+	minPasswordLength = 8
+	// maxPasswordLength はパスワードの最大バイト数です (bcryptは72バイトまでしか使わない)
+	maxPasswordLength = 72
+)
+
+// loginNamePattern はログイン名に使える文字列です (3〜32文字の英数字と . _ -)
+var loginNamePattern = regexp.MustCompile(`^[a-z0-9._-]{3,32}$`)
+
+// dummyPasswordHash は存在しないログイン名でもパスワード照合と同じ時間をかけるためのハッシュです
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("go-card-dummy-password"), bcrypt.DefaultCost)
+
+// AccountAPI は jwt モードのサインアップ・ログインをRESTで公開します
+//
+// パスワードはbcryptでハッシュ化して保存し、成功すると JWTIssuer で発行したトークンを返します。
+// ログインの失敗が続いたログイン名は、しばらくログインを受け付けません。
+type AccountAPI struct {
+	Credentials *db.CredentialRepository
+	Issuer      *JWTIssuer
+	backoff     *loginBackoff
+}
+
+func NewAccountAPI(credentials *db.CredentialRepository, issuer *JWTIssuer) *AccountAPI {
+	return &AccountAPI{Credentials: credentials, Issuer: issuer, backoff: newLoginBackoff()}
+}
+
+// accountRequest はサインアップ・ログインのリクエストです
+type accountRequest struct {
+	LoginName string `json:"loginName"`
+	Password  string `json:"password"`
+	Username  string `json:"username"` // 表示名 (サインアップのみ。省略時はログイン名)
+}
+
+// tokenResponse はサインアップ・ログインのレスポンスです
+type tokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	UserID    string    `json:"userId"`
+}
+
+// POST /auth/signup
+func (api *AccountAPI) Signup(c echo.Context) error {
+	var req accountRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+	loginName, hash, err := hashCredentials(req)
+	if err != nil {
+		return err
+	}
+
+	username := user.NormalizeUsername(req.Username)
+	if username == "" {
+		username = loginName
+	}
+	if err := user.ValidateChosenUsername(username); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	newUser := &db.User{ID: uuid.New().String(), Username: username}
+	err = api.Credentials.CreateUser(c.Request().Context(), newUser, loginName, hash)
+	if errors.Is(err, db.ErrLoginNameTaken) {
+		return echo.NewHTTPError(http.StatusConflict, "ログイン名は既に使われています")
+	}
+	if errors.Is(err, db.ErrUsernameTaken) {
+		return echo.NewHTTPError(http.StatusConflict, "ユーザー名は既に使われています")
+	}
+	if err != nil {
+		log.Printf("サインアップエラー: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "サインアップに失敗しました")
+	}
+
+	return api.respondWithToken(c, http.StatusCreated, newUser.ID)
+}
+
+// POST /auth/login
+func (api *AccountAPI) Login(c echo.Context) error {
+	var req accountRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+
+	loginName := normalizeLoginName(req.LoginName)
+	if wait := api.backoff.wait(loginName, time.Now()); wait > 0 {
+		seconds := int((wait + time.Second - 1) / time.Second)
+		c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+		return echo.NewHTTPError(http.StatusTooManyRequests, "ログインの失敗が続いたため、しばらくしてから再度お試しください")
+	}
+
+	cred, err := api.Credentials.GetByLoginName(c.Request().Context(), loginName)
+	if err != nil {
+		log.Printf("ログインエラー: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "ログインに失敗しました")
+	}
+
+	// ログイン名の有無が応答時間から分からないように、存在しない場合もハッシュを照合する
+	hash := dummyPasswordHash
+	if cred != nil {
+		hash = []byte(cred.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || cred == nil {
+		api.backoff.fail(loginName, time.Now())
+		return echo.NewHTTPError(http.StatusUnauthorized, "ログイン名またはパスワードが違います")
+	}
+	api.backoff.succeed(loginName)
+
+	return api.respondWithToken(c, http.StatusOK, cred.UserID)
+}
+
+// respondWithToken はユーザーのトークンを発行して返します
+func (api *AccountAPI) respondWithToken(c echo.Context, status int, userID string) error {
+	token, expiresAt, err := api.Issuer.Issue(userID)
+	if err != nil {
+		log.Printf("トークン発行エラー: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "トークンの発行に失敗しました")
+	}
+	return c.JSON(status, tokenResponse{Token: token, ExpiresAt: expiresAt, UserID: userID})
+}
+
+// hashCredentials はログイン名とパスワードを検証し、正規化したログイン名とパスワードのハッシュを返します
+func hashCredentials(req accountRequest) (string, string, error) {
+	loginName := normalizeLoginName(req.LoginName)
+	if !loginNamePattern.MatchString(loginName) {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "loginNameは3〜32文字の英数字と . _ - で指定してください")
+	}
+	if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "passwordは8〜72バイトで指定してください")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", echo.NewHTTPError(http.StatusInternalServerError, "パスワードの処理に失敗しました")
+	}
+	return loginName, string(hash), nil
+}
+
+// normalizeLoginName はログイン名を正規化します (大文字小文字・前後の空白を無視)
+func normalizeLoginName(loginName string) string {
+	return strings.ToLower(strings.TrimSpace(loginName))
+}
@@ -0,0 +1,79 @@
+// backend/internal/auth/authenticator.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"google.golang.org/api/option"
+)
+
+// Identity はトークンを検証できたユーザーです
+type Identity struct {
+	UserID string
+	// Name はトークンに含まれる表示名です (含まれない場合は空)
+	Name string
+	// Guest はサーバーが発行したゲストアカウントであることを表します
+	Guest bool
+}
+
+// Authenticator はトークンを検証してユーザーを返します
+//
+// HTTPの Verify ミドルウェアで使われます。
+// 検証できないトークンには ErrMissingToken または ErrInvalidToken を返してください。
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// FirebaseAuthenticator はFirebaseのIDトークンを検証します
+type FirebaseAuthenticator struct {
+	client *auth.Client
+}
+
+// NewFirebaseAuthenticator はFirebase Authのクライアントを初期化して FirebaseAuthenticator を作成します
+func NewFirebaseAuthenticator(projectID, credentialsFile string) (*FirebaseAuthenticator, error) {
+	client, err := newFirebaseClient(projectID, credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	return &FirebaseAuthenticator{client: client}, nil
+}
+
+// Authenticate はIDトークンを検証してFirebaseのユーザーIDと表示名 (name クレーム) を返します
+func (a *FirebaseAuthenticator) Authenticate(ctx context.Context, idToken string) (*Identity, error) {
+	if idToken == "" {
+		return nil, ErrMissingToken
+	}
+	token, err := a.client.VerifyIDToken(ctx, idToken)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	name, _ := token.Claims["name"].(string)
+	return &Identity{UserID: token.UID, Name: name}, nil
+}
+
+// newFirebaseClient はFirebase Authのクライアントを初期化します
+func newFirebaseClient(projectID, credentialsFile string) (*auth.Client, error) {
+	if projectID == "" {
+		return nil, errors.New("firebase モードには FIREBASE_PROJECT_ID が必要です")
+	}
+
+	ctx := context.Background()
+	var opts []option.ClientOption
+	if credentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(credentialsFile))
+	}
+
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: projectID}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Firebase初期化エラー: %w", err)
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Firebase Auth初期化エラー: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,172 @@
+// backend/internal/auth/guest_api.go
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/KOU050223/go-card/internal/user"
+	"github.com/labstack/echo/v4"
+)
+
+// GuestAPI はゲストアカウントの作成と、通常のアカウントへの連携をRESTで公開します
+//
+// ゲストはサーバーが発行したJWTで認証し、users に自分の行を持ちます。連携すると
+// 所持カード・対戦履歴・レーティングを引き継いだまま通常のアカウントになります。
+//   - firebase モード: FirebaseのIDトークンを渡すと、ゲストの行をFirebaseのユーザーIDに移します
+//   - jwt モード: ログイン名とパスワードを渡すと、ゲストの行にログイン情報を追加します
+type GuestAPI struct {
+	Users       *db.UserRepository
+	Credentials *db.CredentialRepository
+	Provisioner *user.Provisioner
+	Access      *user.AccessCache
+	Guests      *JWTIssuer    // ゲストのトークン発行
+	Issuer      *JWTIssuer    // jwt モードの通常のトークン発行 (firebase モードでは nil)
+	Firebase    Authenticator // firebase モードのIDトークン検証 (jwt モードでは nil)
+	// InMatch はユーザーがマッチング中・シリーズや大会への参加中かを返します (その間はユーザーIDを変えられない)
+	InMatch func(userID string) (bool, error)
+	// Forfeit はユーザーの進行中の対戦を相手の勝ちとして終了させます (ユーザーIDを変えた後に呼び出す)
+	Forfeit func(userID string) error
+}
+
+// NewGuestAPI は認証モードに応じた GuestAPI を作成します
+func NewGuestAPI(m *Middleware, users *db.UserRepository, credentials *db.CredentialRepository, provisioner *user.Provisioner, access *user.AccessCache, inMatch func(userID string) (bool, error), forfeit func(userID string) error) *GuestAPI {
+	api := &GuestAPI{
+		Users:       users,
+		Credentials: credentials,
+		Provisioner: provisioner,
+		Access:      access,
+		Guests:      m.GuestIssuer(),
+		Issuer:      m.Issuer(),
+		InMatch:     inMatch,
+		Forfeit:     forfeit,
+	}
+	if m.Mode() == ModeFirebase {
+		api.Firebase = m.Authenticator()
+	}
+	return api
+}
+
+// linkRequest はアカウント連携のリクエストです
+type linkRequest struct {
+	// IDToken は連携先のFirebaseのIDトークンです (firebase モード)
+	IDToken string `json:"idToken"`
+	// LoginName, Password は新しく作るログイン情報です (jwt モード)
+	LoginName string `json:"loginName"`
+	Password  string `json:"password"`
+}
+
+// POST /auth/guest
+func (api *GuestAPI) Create(c echo.Context) error {
+	userID, err := api.Provisioner.CreateGuest(c.Request().Context())
+	if err != nil {
+		log.Printf("ゲスト作成エラー: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "ゲストアカウントの作成に失敗しました")
+	}
+
+	token, expiresAt, err := api.Guests.IssueGuest(userID)
+	if err != nil {
+		log.Printf("トークン発行エラー: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "トークンの発行に失敗しました")
+	}
+	return c.JSON(http.StatusCreated, tokenResponse{Token: token, ExpiresAt: expiresAt, UserID: userID})
+}
+
+// POST /api/account/link
+func (api *GuestAPI) Link(c echo.Context) error {
+	if !IsGuest(c) {
+		return echo.NewHTTPError(http.StatusBadRequest, "ゲストアカウントではありません")
+	}
+	guestID := c.Get("uid").(string)
+
+	var req linkRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+
+	switch {
+	case api.Firebase != nil:
+		return api.linkFirebase(c, guestID, req)
+	case api.Issuer != nil:
+		return api.linkCredentials(c, guestID, req)
+	default:
+		return echo.NewHTTPError(http.StatusNotImplemented, "この認証モードではアカウントを連携できません")
+	}
+}
+
+// linkFirebase はゲストの行をFirebaseのユーザーIDに移します
+func (api *GuestAPI) linkFirebase(c echo.Context, guestID string, req linkRequest) error {
+	ctx := c.Request().Context()
+	identity, err := api.Firebase.Authenticate(ctx, req.IDToken)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+
+	// マッチングのキューやシリーズ・大会はゲストのユーザーIDで動いているため、終わるまで待ってもらう
+	if api.InMatch != nil {
+		inMatch, err := api.InMatch(guestID)
+		if err != nil {
+			log.Printf("参加状況の確認エラー (ユーザー: %s): %v", guestID, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "アカウントの連携に失敗しました")
+		}
+		if inMatch {
+			return echo.NewHTTPError(http.StatusConflict, "マッチング中・シリーズや大会への参加中はアカウントを連携できません")
+		}
+	}
+
+	err = api.Users.TransferGuest(ctx, guestID, identity.UserID)
+	switch {
+	case errors.Is(err, db.ErrUserExists):
+		return echo.NewHTTPError(http.StatusConflict, "このアカウントは既に使われています")
+	case errors.Is(err, db.ErrNotGuest):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case err != nil:
+		log.Printf("アカウント連携エラー (ゲスト: %s): %v", guestID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "アカウントの連携に失敗しました")
+	}
+
+	// ゲストの行は削除されたため、古いゲストのトークンは以後拒否される
+	api.Provisioner.Forget(guestID)
+	api.Access.Invalidate(guestID)
+	// 連携先のユーザーの行はゲストの行で置き換えたため、権限を読み直す
+	api.Access.Invalidate(identity.UserID)
+	// ゲストのユーザーIDで進行中の対戦は続けられないため、ゲストの負けとして終わらせる
+	if api.Forfeit != nil {
+		if err := api.Forfeit(guestID); err != nil {
+			log.Printf("進行中の対戦の終了エラー (ユーザー: %s): %v", guestID, err)
+		}
+	}
+	return c.JSON(http.StatusOK, map[string]string{"userId": identity.UserID})
+}
+
+// linkCredentials はゲストの行にログイン情報を追加し、通常のトークンを返します
+func (api *GuestAPI) linkCredentials(c echo.Context, guestID string, req linkRequest) error {
+	loginName, hash, err := hashCredentials(accountRequest{LoginName: req.LoginName, Password: req.Password})
+	if err != nil {
+		return err
+	}
+
+	err = api.Credentials.AttachToGuest(c.Request().Context(), guestID, loginName, hash)
+	switch {
+	case errors.Is(err, db.ErrLoginNameTaken):
+		return echo.NewHTTPError(http.StatusConflict, "ログイン名は既に使われています")
+	case errors.Is(err, db.ErrNotGuest):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case err != nil:
+		log.Printf("アカウント連携エラー (ゲスト: %s): %v", guestID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "アカウントの連携に失敗しました")
+	}
+
+	// ゲストではなくなったため、古いゲストのトークンは以後拒否される
+	// (他のインスタンスでは権限のキャッシュが切れるまで受け付けることがある)
+	api.Access.Invalidate(guestID)
+
+	token, expiresAt, err := api.Issuer.Issue(guestID)
+	if err != nil {
+		log.Printf("トークン発行エラー: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "トークンの発行に失敗しました")
+	}
+	return c.JSON(http.StatusOK, tokenResponse{Token: token, ExpiresAt: expiresAt, UserID: guestID})
+}
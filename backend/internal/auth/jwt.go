@@ -0,0 +1,164 @@
+// backend/internal/auth/jwt.go
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultJWTIssuer は自前で発行するJWTの標準の発行者 (iss) です
+	DefaultJWTIssuer = "go-card"
+	// DefaultJWTTTL は自前で発行するJWTの標準の有効期間です
+	DefaultJWTTTL = 24 * time.Hour
+	// DefaultGuestTTL はゲストアカウントのJWTの標準の有効期間です
+	// (失効するとゲストのデータに戻れなくなるため、通常より長くしています)
+	DefaultGuestTTL = 90 * 24 * time.Hour
+
+	// minHS256SecretLength はHS256の署名鍵の最小バイト数です
+	minHS256SecretLength = 32
+)
+
+// JWTConfig は自前で発行するJWTの設定です
+type JWTConfig struct {
+	// Secret はHS256の署名鍵です (32バイト以上)
+	Secret string
+	// PrivateKeyFile はRS256の秘密鍵 (PEM) のパスです。指定した場合は Secret より優先します
+	PrivateKeyFile string
+	// Issuer はJWTの発行者 (iss) です (空なら DefaultJWTIssuer)
+	Issuer string
+	// TTL はJWTの有効期間です (0以下なら DefaultJWTTTL)
+	TTL time.Duration
+	// GuestTTL はゲストアカウントのJWTの有効期間です (0以下なら DefaultGuestTTL)
+	GuestTTL time.Duration
+}
+
+// Configured は署名鍵が設定されているかを返します
+func (c JWTConfig) Configured() bool {
+	return c.Secret != "" || c.PrivateKeyFile != ""
+}
+
+// jwtClaims は自前で発行するJWTのクレームです
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	// Guest はゲストアカウントのトークンであることを表します
+	Guest bool `json:"guest,omitempty"`
+}
+
+// JWTIssuer はGoogleのサービスを使わずにJWTを発行・検証します
+//
+// 署名はHS256 (共有鍵) またはRS256 (RSA秘密鍵) で、発行したトークンの
+// sub にユーザーIDを入れます。
+type JWTIssuer struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	issuer    string
+	ttl       time.Duration
+	guestTTL  time.Duration
+}
+
+// NewJWTIssuer は設定から JWTIssuer を作成します
+//
+// 署名鍵がない場合や短すぎる場合はエラーを返します。
+func NewJWTIssuer(cfg JWTConfig) (*JWTIssuer, error) {
+	issuer := &JWTIssuer{issuer: cfg.Issuer, ttl: cfg.TTL, guestTTL: cfg.GuestTTL}
+	if issuer.issuer == "" {
+		issuer.issuer = DefaultJWTIssuer
+	}
+	if issuer.ttl <= 0 {
+		issuer.ttl = DefaultJWTTTL
+	}
+	if issuer.guestTTL <= 0 {
+		issuer.guestTTL = DefaultGuestTTL
+	}
+
+	switch {
+	case cfg.PrivateKeyFile != "":
+		key, err := loadRSAPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		issuer.method = jwt.SigningMethodRS256
+		issuer.signKey = key
+		issuer.verifyKey = &key.PublicKey
+	case cfg.Secret != "":
+		if len(cfg.Secret) < minHS256SecretLength {
+			return nil, fmt.Errorf("JWT_SECRET は%dバイト以上にしてください", minHS256SecretLength)
+		}
+		issuer.method = jwt.SigningMethodHS256
+		issuer.signKey = []byte(cfg.Secret)
+		issuer.verifyKey = []byte(cfg.Secret)
+	default:
+		return nil, errors.New("jwt モードには JWT_SECRET または JWT_PRIVATE_KEY_FILE が必要です")
+	}
+	return issuer, nil
+}
+
+// loadRSAPrivateKey はPEM形式のRSA秘密鍵を読み込みます
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("JWT秘密鍵の読み込みエラー: %w", err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("JWT秘密鍵の解析エラー: %w", err)
+	}
+	return key, nil
+}
+
+// Issue は userID のJWTを発行し、トークンと有効期限を返します
+func (i *JWTIssuer) Issue(userID string) (string, time.Time, error) {
+	return i.issue(userID, false, i.ttl)
+}
+
+// IssueGuest はゲストアカウント userID のJWTを発行し、トークンと有効期限を返します
+func (i *JWTIssuer) IssueGuest(userID string) (string, time.Time, error) {
+	return i.issue(userID, true, i.guestTTL)
+}
+
+func (i *JWTIssuer) issue(userID string, guest bool, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    i.issuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Guest: guest,
+	}
+	token, err := jwt.NewWithClaims(i.method, claims).SignedString(i.signKey)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("JWT署名エラー: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// Authenticate はJWTの署名・有効期限・発行者を検証してユーザーIDを返します
+//
+// ユーザーはサインアップ時に作成されるため、表示名はトークンに含めません。
+func (i *JWTIssuer) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	var claims jwtClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return i.verifyKey, nil
+	}, jwt.WithValidMethods([]string{i.method.Alg()}))
+	if err != nil || !claims.VerifyIssuer(i.issuer, true) || claims.Subject == "" || claims.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+	return &Identity{UserID: claims.Subject, Guest: claims.Guest}, nil
+}
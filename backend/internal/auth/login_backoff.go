@@ -0,0 +1,102 @@
+// backend/internal/auth/login_backoff.go
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// loginFreeAttempts はログイン名ごとに待たずに失敗できる回数です
+	loginFreeAttempts = 5
+	// loginBaseDelay は失敗が loginFreeAttempts 回を超えたときの最初の待ち時間です (以後失敗ごとに倍)
+	loginBaseDelay = time.Second
+	// loginMaxDelay は待ち時間の上限です
+	loginMaxDelay = 15 * time.Minute
+	// loginFailureTTL は最後の失敗からこの時間が過ぎると失敗回数を忘れます
+	loginFailureTTL = time.Hour
+
+	// loginBackoffMaxEntries は記録するログイン名の上限です。超えると最後の失敗が最も古いものから忘れます
+	loginBackoffMaxEntries = 10000
+)
+
+// loginBackoff はログイン名ごとの連続したログインの失敗を数え、失敗が続くほど長く待たせます
+//
+// IPごとの制限だけでは多数のIPから1つのログイン名のパスワードを試されるのを防げないため、
+// ログイン名ごとにも制限します。記録はインスタンスごとです。
+type loginBackoff struct {
+	mu       sync.Mutex
+	failures map[string]loginFailures
+}
+
+// loginFailures はログイン名の連続した失敗の記録です
+type loginFailures struct {
+	count int
+	last  time.Time
+	until time.Time // この時刻まではログインを試せない
+}
+
+func newLoginBackoff() *loginBackoff {
+	return &loginBackoff{failures: make(map[string]loginFailures)}
+}
+
+// wait はログイン名 loginName でログインを試せるまでの時間を返します (試せる場合は0)
+func (b *loginBackoff) wait(loginName string, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f, ok := b.failures[loginName]
+	if !ok || !now.Before(f.until) {
+		return 0
+	}
+	return f.until.Sub(now)
+}
+
+// fail はログインの失敗を記録します
+func (b *loginBackoff) fail(loginName string, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f, ok := b.failures[loginName]
+	if !ok && len(b.failures) >= loginBackoffMaxEntries {
+		b.evict(now)
+	}
+	if now.Sub(f.last) > loginFailureTTL {
+		f = loginFailures{}
+	}
+	f.count++
+	f.last = now
+	if over := f.count - loginFreeAttempts; over > 0 {
+		delay := loginMaxDelay
+		if over <= 20 {
+			delay = min(loginBaseDelay<<(over-1), loginMaxDelay)
+		}
+		f.until = now.Add(delay)
+	}
+	b.failures[loginName] = f
+}
+
+// evict は期限切れの記録を掃除し、それでも上限に達している場合は最後の失敗が最も古い記録を忘れます
+func (b *loginBackoff) evict(now time.Time) {
+	var oldestName string
+	var oldest time.Time
+	for name, f := range b.failures {
+		if now.Sub(f.last) > loginFailureTTL {
+			delete(b.failures, name)
+			continue
+		}
+		if oldestName == "" || f.last.Before(oldest) {
+			oldestName, oldest = name, f.last
+		}
+	}
+	if len(b.failures) >= loginBackoffMaxEntries {
+		delete(b.failures, oldestName)
+	}
+}
+
+// succeed はログインの成功を記録し、失敗回数を忘れます
+func (b *loginBackoff) succeed(loginName string) {
+	b.mu.Lock()
+	delete(b.failures, loginName)
+	b.mu.Unlock()
+}
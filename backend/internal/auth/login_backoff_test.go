@@ -0,0 +1,86 @@
+// backend/internal/auth/login_backoff_test.go
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	b := newLoginBackoff()
+	now := time.Now()
+
+	// loginFreeAttempts 回までは待たずに試せる
+	for i := 0; i < loginFreeAttempts-1; i++ {
+		b.fail("alice", now)
+	}
+	if wait := b.wait("alice", now); wait != 0 {
+		t.Fatalf("%d回失敗後の待ち時間 = %v, want 0", loginFreeAttempts-1, wait)
+	}
+	b.fail("alice", now)
+	if wait := b.wait("alice", now); wait != 0 {
+		t.Fatalf("%d回失敗後の待ち時間 = %v, want 0", loginFreeAttempts, wait)
+	}
+
+	// 以後は失敗ごとに待ち時間が倍になる
+	b.fail("alice", now)
+	if wait := b.wait("alice", now); wait != loginBaseDelay {
+		t.Errorf("%d回失敗後の待ち時間 = %v, want %v", loginFreeAttempts+1, wait, loginBaseDelay)
+	}
+	b.fail("alice", now)
+	if wait := b.wait("alice", now); wait != 2*loginBaseDelay {
+		t.Errorf("%d回失敗後の待ち時間 = %v, want %v", loginFreeAttempts+2, wait, 2*loginBaseDelay)
+	}
+	if wait := b.wait("alice", now.Add(2*loginBaseDelay)); wait != 0 {
+		t.Errorf("待ち時間の経過後 = %v, want 0", wait)
+	}
+
+	// 他のログイン名には影響しない
+	if wait := b.wait("bob", now); wait != 0 {
+		t.Errorf("別のログイン名の待ち時間 = %v, want 0", wait)
+	}
+
+	// 成功すると失敗回数を忘れる
+	b.succeed("alice")
+	b.fail("alice", now)
+	if wait := b.wait("alice", now); wait != 0 {
+		t.Errorf("成功後の1回の失敗の待ち時間 = %v, want 0", wait)
+	}
+}
+
+func TestLoginBackoffLimits(t *testing.T) {
+	b := newLoginBackoff()
+	now := time.Now()
+	for i := 0; i < loginFreeAttempts+100; i++ {
+		b.fail("alice", now)
+	}
+	if wait := b.wait("alice", now); wait != loginMaxDelay {
+		t.Errorf("失敗が続いたときの待ち時間 = %v, want %v", wait, loginMaxDelay)
+	}
+
+	// 最後の失敗から loginFailureTTL が過ぎると失敗回数を忘れる
+	later := now.Add(loginFailureTTL + time.Second)
+	b.fail("alice", later)
+	if wait := b.wait("alice", later); wait != 0 {
+		t.Errorf("期限切れ後の1回の失敗の待ち時間 = %v, want 0", wait)
+	}
+}
+
+func TestLoginBackoffMaxEntries(t *testing.T) {
+	b := newLoginBackoff()
+	now := time.Now()
+	for i := 0; i < loginBackoffMaxEntries+10; i++ {
+		b.fail("user"+strconv.Itoa(i), now.Add(time.Duration(i)*time.Millisecond))
+	}
+	if n := len(b.failures); n != loginBackoffMaxEntries {
+		t.Fatalf("記録の件数 = %d, want %d", n, loginBackoffMaxEntries)
+	}
+	// 最後の失敗が最も古いものから忘れる
+	if _, ok := b.failures["user0"]; ok {
+		t.Error("最も古い記録が残っている")
+	}
+	if _, ok := b.failures["user"+strconv.Itoa(loginBackoffMaxEntries+9)]; !ok {
+		t.Error("最新の記録が忘れられている")
+	}
+}
@@ -3,153 +3,306 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
 	"strings"
 
-	firebase "firebase.google.com/go/v4"
-	"firebase.google.com/go/v4/auth"
+	"github.com/KOU050223/go-card/internal/db"
 	"github.com/labstack/echo/v4"
-	"google.golang.org/api/option"
 )
 
-// FirebaseMiddleware はFirebase認証ミドルウェアを提供します
-type FirebaseMiddleware struct {
-	client     *auth.Client
-	devMode    bool
-	testUserID string
-}
-
-// NewFirebaseMiddleware は新しいFirebaseMiddlewareインスタンスを作成します
-func NewFirebaseMiddleware(projectID string) (*FirebaseMiddleware, error) {
-	// 開発モードの確認
-	if os.Getenv("AUTH_DEV_MODE") == "true" {
-		fmt.Printf("Firebase Middleware: AUTH_DEV_MODE is enabled, using dev mode\n")
-		return &FirebaseMiddleware{
-			client:     nil,
-			devMode:    true,
-			testUserID: os.Getenv("AUTH_TEST_USER_ID"),
-		}, nil
+// 認証モード
+const (
+	// ModeFirebase はFirebaseのIDトークンを検証します (本番用)。設定に不備があれば起動しません
+	ModeFirebase = "firebase"
+	// ModeJWT はサーバー自身が発行したJWTを検証します。Googleのサービスなしで動かす場合に使います
+	ModeJWT = "jwt"
+	// ModeLocalDev はローカル開発用です。トークンを検証せず、クライアントが指定したユーザーIDを信用します
+	ModeLocalDev = "local-dev"
+	// ModeDisabled は認証を無効にし、すべてのリクエストを DevUserID として扱います
+	ModeDisabled = "disabled"
+)
+
+const (
+	// DefaultDevUserID は開発用モードでユーザーIDが指定されなかったときのユーザーIDです
+	DefaultDevUserID = "dev-user-123"
+
+	// DevUserHeader は local-dev モードでユーザーIDを指定するHTTPヘッダーです
+	DevUserHeader = "X-Dev-User-ID"
+)
+
+var (
+	// ErrMissingToken は認証トークンがないことを表します
+	ErrMissingToken = errors.New("認証トークンがありません")
+	// ErrInvalidToken は認証トークンを検証できなかったことを表します
+	ErrInvalidToken = errors.New("無効なトークンです")
+	// ErrGuestLinked は通常のアカウントに連携済みのゲストの古いトークンであることを表します
+	ErrGuestLinked = errors.New("ゲストアカウントは連携済みです。連携したアカウントでログインしてください")
+)
+
+// Config は認証の設定です
+type Config struct {
+	// Mode は "firebase", "jwt", "local-dev", "disabled" のいずれかです
+	Mode string
+	// FirebaseProject は firebase モードで必須のプロジェクトIDです
+	FirebaseProject string
+	// CredentialsFile はサービスアカウントキーのパスです (空ならデフォルトの認証情報)
+	CredentialsFile string
+	// JWT は jwt モードで発行・検証するJWTの設定です
+	JWT JWTConfig
+	// DevUserID は local-dev・disabled モードで使うユーザーIDです (空なら DefaultDevUserID)
+	DevUserID string
+	// AdminUserIDs はDBの権限にかかわらず管理者として扱うユーザーIDです (最初の管理者を作るため)
+	AdminUserIDs []string
+}
+
+// ValidMode は認証モードとして使える値かを返します
+func ValidMode(mode string) bool {
+	return mode == ModeFirebase || mode == ModeJWT || mode == ModeLocalDev || mode == ModeDisabled
+}
+
+// Middleware はHTTPリクエストとWebSocket接続のユーザーを認証します
+//
+// firebase・jwt モードでは Authenticator で検証できたトークンのユーザーだけを受け付け、
+// 開発用のユーザーIDは使いません。開発用のユーザーIDは local-dev・disabled モードを
+// 明示的に指定した場合にのみ使われます。
+type Middleware struct {
+	mode          string
+	authenticator Authenticator // firebase・jwt モードのトークン検証
+	issuer        *JWTIssuer    // jwt モードのトークン発行
+	guests        *JWTIssuer    // ゲストアカウントのトークン発行・検証 (署名鍵がなければ nil)
+	redeemTicket  TicketRedeemer
+	provisionUser UserProvisioner
+	lookupAccess  AccessLookup
+	admins        map[string]bool
+	devUserID     string
+}
+
+// New は認証モードに応じた Middleware を作成します
+//
+// firebase モードでプロジェクトIDがない場合やFirebaseを初期化できない場合、
+// jwt モードで署名鍵がない場合はエラーを返します。firebase モードでも JWT の署名鍵が
+// 設定されていれば、ゲストアカウントのトークンを発行・検証します。
+func New(cfg Config) (*Middleware, error) {
+	m, err := newMiddleware(cfg)
+	if err != nil {
+		return nil, err
 	}
+	m.admins = make(map[string]bool, len(cfg.AdminUserIDs))
+	for _, id := range cfg.AdminUserIDs {
+		m.admins[id] = true
+	}
+	return m, nil
+}
 
-	fmt.Printf("Firebase Middleware: Initializing with projectID: %s\n", projectID)
-	ctx := context.Background()
-
-	// Firebase初期化 - 開発環境用の簡略化された設定
-	var app *firebase.App
-	var err error
-
-	// サービスアカウントキーパスがあればそれを使用
-	if keyPath := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); keyPath != "" {
-		fmt.Printf("Firebase Middleware: Using service account credentials from: %s\n", keyPath)
-		opt := option.WithCredentialsFile(keyPath)
-		app, err = firebase.NewApp(ctx, &firebase.Config{ProjectID: projectID}, opt)
-	} else {
-		// 開発環境では認証を無効にしてFirebaseアプリを初期化
-		fmt.Printf("Firebase Middleware: Using default credentials\n")
-		app, err = firebase.NewApp(ctx, &firebase.Config{ProjectID: projectID})
+func newMiddleware(cfg Config) (*Middleware, error) {
+	switch cfg.Mode {
+	case ModeFirebase:
+		authenticator, err := NewFirebaseAuthenticator(cfg.FirebaseProject, cfg.CredentialsFile)
 		if err != nil {
-			// Firebase初期化に失敗した場合は開発モードにフォールバック
-			fmt.Printf("Firebase Middleware: Failed to initialize Firebase app, falling back to dev mode: %v\n", err)
-			return &FirebaseMiddleware{
-				client:     nil,
-				devMode:    true,
-				testUserID: "fallback-user-123",
-			}, nil
+			return nil, err
 		}
-	}
+		m := &Middleware{mode: ModeFirebase, authenticator: authenticator}
+		if cfg.JWT.Configured() {
+			if m.guests, err = NewJWTIssuer(cfg.JWT); err != nil {
+				return nil, err
+			}
+		}
+		log.Printf("認証モード: firebase (プロジェクト: %s, ゲスト: %t)", cfg.FirebaseProject, m.guests != nil)
+		return m, nil
 
-	if err != nil {
-		fmt.Printf("Firebase Middleware: Failed to initialize Firebase app: %v\n", err)
-		return nil, fmt.Errorf("Firebase初期化エラー: %w", err)
-	}
+	case ModeJWT:
+		issuer, err := NewJWTIssuer(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("認証モード: jwt (署名: %s)", issuer.method.Alg())
+		return &Middleware{mode: ModeJWT, authenticator: issuer, issuer: issuer, guests: issuer}, nil
 
-	client, err := app.Auth(ctx)
-	if err != nil {
-		// Firebase Auth初期化に失敗した場合も開発モードにフォールバック
-		fmt.Printf("Firebase Middleware: Failed to initialize Firebase Auth client, falling back to dev mode: %v\n", err)
-		return &FirebaseMiddleware{
-			client:     nil,
-			devMode:    true,
-			testUserID: "fallback-user-123",
-		}, nil
+	case ModeLocalDev, ModeDisabled:
+		devUserID := cfg.DevUserID
+		if devUserID == "" {
+			devUserID = DefaultDevUserID
+		}
+		log.Printf("警告: 認証モード %s で起動します。IDトークンを検証しないため本番環境では使用しないでください", cfg.Mode)
+		return &Middleware{mode: cfg.Mode, devUserID: devUserID}, nil
+
+	default:
+		return nil, fmt.Errorf("不明な認証モードです: %q (firebase, jwt, local-dev, disabled のいずれかを指定してください)", cfg.Mode)
 	}
+}
 
-	fmt.Printf("Firebase Middleware: Successfully initialized Firebase Auth client\n")
-	return &FirebaseMiddleware{
-		client:     client,
-		devMode:    false,
-		testUserID: "",
-	}, nil
+// Mode は認証モードを返します
+func (m *Middleware) Mode() string {
+	return m.mode
 }
 
-// Verify はHTTPリクエストのIDトークンを検証します
-func (m *FirebaseMiddleware) Verify(next echo.HandlerFunc) echo.HandlerFunc {
+// Issuer は jwt モードでトークンを発行する JWTIssuer を返します。他のモードでは nil を返します
+func (m *Middleware) Issuer() *JWTIssuer {
+	return m.issuer
+}
+
+// GuestIssuer はゲストアカウントのトークンを発行する JWTIssuer を返します
+//
+// jwt モード、または JWT の署名鍵を設定した firebase モードでのみ nil 以外を返します。
+func (m *Middleware) GuestIssuer() *JWTIssuer {
+	return m.guests
+}
+
+// Authenticator は firebase・jwt モードでトークンを検証する Authenticator を返します
+func (m *Middleware) Authenticator() Authenticator {
+	return m.authenticator
+}
+
+// UserProvisioner は認証できたユーザーの users 行を用意します
+//
+// 初めて見るユーザーの場合は name (空ならサーバーが生成した名前) で作成します。
+// ゲストの行はゲスト作成時に作られるため、見つからない場合は db.ErrNotGuest を返してください。
+type UserProvisioner func(ctx context.Context, userID, name string, guest bool) error
+
+// SetUserProvisioner は認証できたユーザーの users 行を用意する処理を設定します
+func (m *Middleware) SetUserProvisioner(provisioner UserProvisioner) {
+	m.provisionUser = provisioner
+}
+
+// Verify はHTTPリクエストのユーザーを認証し、ユーザーIDをコンテキストの "uid" に設定します
+//
+// UserProvisioner が設定されている場合、初めて見るユーザーの users 行を作成してから
+// 次のハンドラーを呼び出します。AccessLookup が設定されている場合はBAN・利用停止中の
+// ユーザーを 403 で、通常のアカウントに連携済みのゲストの古いトークンを 401 で拒否し、
+// 権限をコンテキストの "role" に設定します。
+func (m *Middleware) Verify(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		// 開発モードの場合は認証をスキップ
-		if m.devMode {
-			// 開発用のユーザーIDを設定
-			uid := m.testUserID
-			if uid == "" {
-				uid = "dev-user-123"
-			}
-			c.Set("uid", uid)
-			return next(c)
+		identity, err := m.authenticateRequest(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
 		}
 
-		// WebSocket接続の場合はクエリパラメータからトークンを取得
-		var idToken string
-		if c.Request().Header.Get("Upgrade") == "websocket" {
-			idToken = c.QueryParam("token")
-		} else {
-			// 通常のHTTPリクエストの場合はAuthorizationヘッダーからトークンを取得
-			authHeader := c.Request().Header.Get("Authorization")
-			idToken = strings.TrimPrefix(authHeader, "Bearer ")
+		if m.provisionUser != nil {
+			err := m.provisionUser(c.Request().Context(), identity.UserID, identity.Name, identity.Guest)
+			if errors.Is(err, db.ErrNotGuest) {
+				// 連携済み・削除済みのゲストの古いトークン
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+			}
+			if err != nil {
+				log.Printf("ユーザー作成エラー (ユーザー: %s): %v", identity.UserID, err)
+				return echo.NewHTTPError(http.StatusInternalServerError, "ユーザー情報の作成に失敗しました")
+			}
 		}
 
-		if idToken == "" {
-			return echo.NewHTTPError(http.StatusUnauthorized, "認証トークンがありません")
+		role, err := m.checkAccess(c.Request().Context(), identity.UserID, identity.Guest)
+		var blocked *BlockedError
+		if errors.As(err, &blocked) {
+			return echo.NewHTTPError(http.StatusForbidden, blocked.Error())
+		}
+		if errors.Is(err, ErrGuestLinked) {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
 		}
-
-		// トークン検証
-		token, err := m.client.VerifyIDToken(c.Request().Context(), idToken)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusUnauthorized, "無効なトークンです")
+			log.Printf("権限取得エラー (ユーザー: %s): %v", identity.UserID, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "ユーザー情報の取得に失敗しました")
 		}
 
-		// ユーザーIDをコンテキストに設定
-		c.Set("uid", token.UID)
+		// ユーザーID・ゲストかどうか・権限をコンテキストに設定
+		c.Set("uid", identity.UserID)
+		c.Set("guest", identity.Guest)
+		c.Set("role", role)
+		return next(c)
+	}
+}
 
+// RequireFullAccount はゲストアカウントのリクエストを拒否します (Verify の後に使います)
+func RequireFullAccount(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if IsGuest(c) {
+			return echo.NewHTTPError(http.StatusForbidden, "ゲストアカウントでは利用できません。アカウントを連携してください")
+		}
 		return next(c)
 	}
 }
 
-// VerifyWebSocketToken はWebSocket接続用のトークン検証を行います
-func (m *FirebaseMiddleware) VerifyWebSocketToken(token string) (string, error) {
-	if token == "" {
-		// トークンがない場合のみ開発モードを確認
-		if m.devMode || m.client == nil {
-			uid := m.testUserID
-			if uid == "" {
-				uid = "dev-user-123"
-			}
-			return uid, nil
+// IsGuest はリクエストのユーザーがゲストアカウントかを返します
+func IsGuest(c echo.Context) bool {
+	guest, _ := c.Get("guest").(bool)
+	return guest
+}
+
+// authenticateRequest はHTTPリクエストのユーザーを返します
+func (m *Middleware) authenticateRequest(c echo.Context) (*Identity, error) {
+	switch m.mode {
+	case ModeDisabled:
+		return &Identity{UserID: m.devUserID}, nil
+	case ModeLocalDev:
+		if uid := c.Request().Header.Get(DevUserHeader); uid != "" {
+			return &Identity{UserID: uid}, nil
 		}
-		return "", fmt.Errorf("認証トークンがありません")
+		return &Identity{UserID: m.devUserID}, nil
 	}
 
-	// トークンが提供されている場合は、可能な限り実際のFirebase検証を試みる
-	if m.client != nil {
-		// Firebase クライアントが利用可能な場合は実際の検証を行う
-		firebaseToken, err := m.client.VerifyIDToken(context.Background(), token)
-		if err != nil {
-			return "", fmt.Errorf("無効なトークンです: %w", err)
+	// Authorizationヘッダーからトークンを取得
+	// (WebSocket接続ではトークンをクエリパラメータに載せず、接続チケットを使う)
+	idToken := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+
+	// firebase モードのゲストはサーバーが発行したJWTを使う
+	if m.guests != nil && m.guests != m.authenticator {
+		if identity, err := m.guests.Authenticate(c.Request().Context(), idToken); err == nil && identity.Guest {
+			return identity, nil
 		}
-		return firebaseToken.UID, nil
 	}
+	return m.authenticator.Authenticate(c.Request().Context(), idToken)
+}
+
+// TicketRedeemer はWebSocket接続チケットを使用済みにし、チケットのユーザーIDを返します
+//
+// duelID は接続先のDuelのIDです。Duelに紐づくチケットは、そのDuelへの接続にしか使えません。
+type TicketRedeemer func(ctx context.Context, ticket, duelID string) (string, error)
+
+// SetTicketRedeemer はWebSocket接続チケットの検証処理を設定します
+func (m *Middleware) SetTicketRedeemer(redeemer TicketRedeemer) {
+	m.redeemTicket = redeemer
+}
 
-	// Firebaseクライアントが利用できない場合は開発モードとして処理
-	// 本来はここに到達すべきではないが、安全のためのフォールバック
-	return "fallback-user-" + fmt.Sprintf("%d", len(token)), nil
+// WebSocketUser はWebSocket接続要求のユーザーIDを返します
+//
+// firebase・jwt モードでは POST /api/ws-ticket で発行した ?ticket= の接続チケットを
+// 検証します。IDトークンはアクセスログに残らないよう、クエリパラメータでは受け付けません。
+// local-dev モードでは ?uid= を信用し、指定がなければ DevUserID を使います。
+// チケット発行後にBAN・利用停止されたユーザーには *BlockedError を返します。
+func (m *Middleware) WebSocketUser(c echo.Context) (string, error) {
+	userID, err := m.webSocketUserID(c)
+	if err != nil {
+		return "", err
+	}
+	if _, err := m.checkAccess(c.Request().Context(), userID, false); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// WebSocketError は WebSocketUser のエラーを接続要求へのHTTPエラーに変換します
+func WebSocketError(err error) error {
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return echo.NewHTTPError(http.StatusForbidden, blocked.Error())
+	}
+	return echo.NewHTTPError(http.StatusUnauthorized, "接続チケットの検証に失敗しました")
+}
+
+// webSocketUserID は認証モードに応じてWebSocket接続要求のユーザーIDを返します
+func (m *Middleware) webSocketUserID(c echo.Context) (string, error) {
+	switch m.mode {
+	case ModeDisabled:
+		return m.devUserID, nil
+	case ModeLocalDev:
+		if uid := c.QueryParam("uid"); uid != "" {
+			return uid, nil
+		}
+		return m.devUserID, nil
+	}
+	if m.redeemTicket == nil {
+		return "", ErrInvalidToken
+	}
+	return m.redeemTicket(c.Request().Context(), c.QueryParam("ticket"), c.QueryParam("duelId"))
 }
@@ -0,0 +1,90 @@
+// backend/internal/auth/role_test.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KOU050223/go-card/internal/db"
+)
+
+func TestCheckAccess(t *testing.T) {
+	ban := &db.Sanction{Kind: db.SanctionBan}
+	accesses := map[string]*db.UserAccess{
+		"player":       {Role: RolePlayer},
+		"moderator":    {Role: RoleModerator},
+		"banned":       {Role: RolePlayer, Block: ban},
+		"bannedAdmin":  {Role: RolePlayer, Block: ban},
+		"configured":   {Role: RolePlayer},
+		"bannedModRow": {Role: RoleModerator, Block: ban},
+	}
+	m := &Middleware{
+		admins: map[string]bool{"configured": true, "bannedAdmin": true},
+		lookupAccess: func(ctx context.Context, userID string) (*db.UserAccess, error) {
+			return accesses[userID], nil
+		},
+	}
+
+	tests := []struct {
+		userID  string
+		want    string
+		blocked bool
+	}{
+		{"player", RolePlayer, false},
+		{"moderator", RoleModerator, false},
+		{"unknown", RolePlayer, false},
+		{"configured", RoleAdmin, false},
+		// 設定の管理者はBANされていても締め出されない
+		{"bannedAdmin", RoleAdmin, false},
+		{"banned", "", true},
+		{"bannedModRow", "", true},
+	}
+	for _, tt := range tests {
+		role, err := m.checkAccess(context.Background(), tt.userID, false)
+		var blocked *BlockedError
+		if got := errors.As(err, &blocked); got != tt.blocked {
+			t.Errorf("checkAccess(%q) のエラー = %v; blocked want %v", tt.userID, err, tt.blocked)
+		}
+		if role != tt.want {
+			t.Errorf("checkAccess(%q) = %q; want %q", tt.userID, role, tt.want)
+		}
+	}
+}
+
+func TestCheckAccessLinkedGuest(t *testing.T) {
+	accesses := map[string]*db.UserAccess{
+		"guest":  {Role: RolePlayer, IsGuest: true},
+		"linked": {Role: RolePlayer},
+	}
+	m := &Middleware{
+		lookupAccess: func(ctx context.Context, userID string) (*db.UserAccess, error) {
+			return accesses[userID], nil
+		},
+	}
+
+	if _, err := m.checkAccess(context.Background(), "guest", true); err != nil {
+		t.Errorf("ゲストのトークンのエラー = %v; want nil", err)
+	}
+	// 連携済みのゲストの古いトークンは拒否し、連携後の通常のトークンは受け付ける
+	if _, err := m.checkAccess(context.Background(), "linked", true); !errors.Is(err, ErrGuestLinked) {
+		t.Errorf("連携済みのゲストのトークンのエラー = %v; want ErrGuestLinked", err)
+	}
+	if _, err := m.checkAccess(context.Background(), "linked", false); err != nil {
+		t.Errorf("連携後のトークンのエラー = %v; want nil", err)
+	}
+	// Firebaseとの連携で削除されたゲストの行
+	if _, err := m.checkAccess(context.Background(), "deleted", true); !errors.Is(err, ErrGuestLinked) {
+		t.Errorf("削除済みのゲストのトークンのエラー = %v; want ErrGuestLinked", err)
+	}
+}
+
+func TestEffectiveRole(t *testing.T) {
+	m := &Middleware{admins: map[string]bool{"configured": true}}
+	if got := m.EffectiveRole("configured", RolePlayer); got != RoleAdmin {
+		t.Errorf("設定の管理者の権限 = %q; want %q", got, RoleAdmin)
+	}
+	if got := m.EffectiveRole("other", RoleModerator); got != RoleModerator {
+		t.Errorf("他のユーザーの権限 = %q; want %q", got, RoleModerator)
+	}
+}
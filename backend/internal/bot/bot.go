@@ -0,0 +1,21 @@
+// backend/internal/bot/bot.go
+package bot
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// IDPrefix はボットのユーザーIDの接頭辞です
+const IDPrefix = "bot-"
+
+// NewID は新しいボットのユーザーIDを生成します
+func NewID() string {
+	return IDPrefix + uuid.New().String()
+}
+
+// IsBot はユーザーIDがサーバー側のボットのものかを返します
+func IsBot(userID string) bool {
+	return strings.HasPrefix(userID, IDPrefix)
+}
@@ -0,0 +1,163 @@
+// backend/internal/bot/driver.go
+package bot
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+const (
+	// actionDelay はボットが1手ごとに待つ時間です (相手が状態の変化を追えるように)
+	actionDelay = 700 * time.Millisecond
+
+	// maxActionsPerTurn は1ターンに送る行動の上限です。超えた場合はターンを終えます
+	maxActionsPerTurn = 20
+
+	// assignmentTTL は対戦に使われていない思考ルーチンの割り当てを保持する期間です
+	// (対戦終了後も再戦に備えて保持する)
+	assignmentTTL = 10 * time.Minute
+)
+
+// assignment はボットに割り当てた思考ルーチンです
+type assignment struct {
+	strategy Strategy
+	at       time.Time
+}
+
+// seat は対戦中のボット1体の状態です
+type seat struct {
+	strategy    Strategy
+	lastVersion int // 最後に行動を決めた対戦状態のバージョン
+	turn        int // actions を数えているターン数
+	actions     int // このターンに送った行動の数
+}
+
+// Driver は対戦状態の更新を受け取り、ボットの手番であれば次の1手を送信します
+//
+// ボットは伏せられた対戦状態 (View) だけを見て Strategy で行動を決め、
+// 人間のプレイヤーと同じく DuelService.SubmitAction で処理されます。
+type Driver struct {
+	duels *game.DuelService
+
+	mu       sync.Mutex
+	assigned map[string]assignment       // botID -> 対戦に使われていない思考ルーチン
+	seats    map[string]map[string]*seat // duelID -> botID -> 状態
+}
+
+// NewDriver は新しいボットのドライバーを作成します
+func NewDriver(duels *game.DuelService) *Driver {
+	return &Driver{
+		duels:    duels,
+		assigned: make(map[string]assignment),
+		seats:    make(map[string]map[string]*seat),
+	}
+}
+
+// Assign は botID のボットが使う思考ルーチンを設定します。対戦を作成する前に呼び出してください
+//
+// 設定しなかったボットは DefaultDifficulty の思考ルーチンを使います。
+func (d *Driver) Assign(botID string, strategy Strategy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.assigned[botID] = assignment{strategy: strategy, at: time.Now()}
+}
+
+// OnRematch は再戦の申し込みを受け取り、相手がボットであれば承諾します
+//
+// 再戦でもボットは同じIDのまま、元の対戦と同じ思考ルーチンを使います。
+func (d *Driver) OnRematch(event *game.RematchEvent) {
+	if event.Status != "offered" {
+		return
+	}
+	for _, userID := range event.Players {
+		if userID != event.UserID && IsBot(userID) {
+			go d.submit(game.GameAction{DuelID: event.DuelID, PlayerID: userID, ActionType: game.ActionRematch})
+		}
+	}
+}
+
+// OnDuelUpdate は対戦の作成・更新時に呼び出し、ボットの手番であれば次の1手を送信します
+//
+// 同じバージョンの状態で何度呼ばれても行動は1回だけ送信します。
+func (d *Driver) OnDuelUpdate(duel *game.Duel) {
+	if duel.Status != "active" {
+		d.release(duel)
+		return
+	}
+
+	botID := duel.Players[duel.ActiveIdx].UserID
+	if !IsBot(botID) {
+		return
+	}
+
+	d.mu.Lock()
+	s := d.seatLocked(duel.ID, botID)
+	if duel.Version <= s.lastVersion {
+		d.mu.Unlock()
+		return
+	}
+	s.lastVersion = duel.Version
+	if s.turn != duel.TurnCount {
+		s.turn = duel.TurnCount
+		s.actions = 0
+	}
+
+	view := NewView(duel, duel.ActiveIdx)
+	action := view.Pass()
+	if s.actions < maxActionsPerTurn {
+		action = s.strategy.NextAction(view)
+	}
+	s.actions++
+	d.mu.Unlock()
+
+	go d.submit(action)
+}
+
+// submit は少し待ってからボットの行動を送信します
+func (d *Driver) submit(action game.GameAction) {
+	time.Sleep(actionDelay)
+	if err := d.duels.SubmitAction(action); err != nil {
+		log.Printf("ボットの行動送信エラー (対戦: %s): %v", action.DuelID, err)
+	}
+}
+
+// seatLocked は対戦中のボットの状態を返します。初めての場合は作成します (d.mu取得済み)
+func (d *Driver) seatLocked(duelID, botID string) *seat {
+	seats, ok := d.seats[duelID]
+	if !ok {
+		seats = make(map[string]*seat)
+		d.seats[duelID] = seats
+	}
+	s, ok := seats[botID]
+	if !ok {
+		strategy := d.assigned[botID].strategy
+		if strategy == nil {
+			strategy, _ = StrategyFor(DefaultDifficulty)
+		}
+		delete(d.assigned, botID)
+		s = &seat{strategy: strategy}
+		seats[botID] = s
+	}
+	return s
+}
+
+// release は終了した対戦のボットの状態を破棄し、思考ルーチンを再戦に備えて残します
+func (d *Driver) release(duel *game.Duel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for botID, s := range d.seats[duel.ID] {
+		d.assigned[botID] = assignment{strategy: s.strategy, at: now}
+	}
+	delete(d.seats, duel.ID)
+
+	for botID, a := range d.assigned {
+		if now.Sub(a.at) > assignmentTTL {
+			delete(d.assigned, botID)
+		}
+	}
+}
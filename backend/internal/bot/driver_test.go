@@ -0,0 +1,56 @@
+// backend/internal/bot/driver_test.go
+package bot
+
+import (
+	"testing"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+// seatActions はボットがこのターンに送った行動の数を返します
+func seatActions(d *Driver, duelID, botID string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.seats[duelID][botID].actions
+}
+
+func TestDriverActsOncePerVersion(t *testing.T) {
+	d := NewDriver(game.NewDuelService([]game.Card{medium}))
+	duel := testDuel([]game.Card{weak}, nil, nil)
+
+	// 同じバージョンの更新が重複して届いても、行動は1回だけ
+	d.OnDuelUpdate(duel)
+	d.OnDuelUpdate(duel.Clone())
+	if got := seatActions(d, duel.ID, "bot-1"); got != 1 {
+		t.Errorf("同じバージョンでの行動数 = %d; want 1", got)
+	}
+
+	next := duel.Clone()
+	next.Version++
+	d.OnDuelUpdate(next)
+	if got := seatActions(d, duel.ID, "bot-1"); got != 2 {
+		t.Errorf("次のバージョンでの行動数 = %d; want 2", got)
+	}
+
+	// 新しいターンでは数え直す
+	turn := next.Clone()
+	turn.Version++
+	turn.TurnCount += 2
+	d.OnDuelUpdate(turn)
+	if got := seatActions(d, duel.ID, "bot-1"); got != 1 {
+		t.Errorf("新しいターンでの行動数 = %d; want 1", got)
+	}
+}
+
+func TestDriverIgnoresHumanTurns(t *testing.T) {
+	d := NewDriver(game.NewDuelService([]game.Card{medium}))
+	duel := testDuel(nil, nil, nil)
+	duel.ActiveIdx = 1
+
+	d.OnDuelUpdate(duel)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.seats) != 0 {
+		t.Errorf("人間の手番で作成された状態 = %v; want なし", d.seats)
+	}
+}
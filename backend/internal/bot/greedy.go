@@ -0,0 +1,54 @@
+// backend/internal/bot/greedy.go
+package bot
+
+import "github.com/KOU050223/go-card/internal/game"
+
+// GreedyStrategy は相手プレイヤーへのダメージが最も大きくなる手を選びます
+//
+// 手札は攻撃力の高い順に場に出し、場のカードはすべて相手プレイヤーを直接攻撃してから
+// ターンを終えます。
+type GreedyStrategy struct{}
+
+// NewGreedyStrategy は新しい貪欲法の思考ルーチンを作成します
+func NewGreedyStrategy() *GreedyStrategy {
+	return &GreedyStrategy{}
+}
+
+func (s *GreedyStrategy) NextAction(view View) game.GameAction {
+	best := view.Pass()
+	bestScore := 0
+	for _, move := range view.LegalMoves() {
+		if score := s.score(view, move); score > bestScore {
+			best, bestScore = move, score
+		}
+	}
+	return best
+}
+
+// score は行動の評価値です
+//
+// 直接攻撃は今すぐ与えるダメージ、カードを出すのは次の攻撃で与えられるダメージで評価し、
+// 同じダメージなら直接攻撃を優先します。カード同士の戦闘は相手のHPを減らさないため評価しません。
+func (s *GreedyStrategy) score(view View, move game.GameAction) int {
+	switch move.ActionType {
+	case "attack":
+		if move.TargetID != 0 {
+			return 0
+		}
+		return attackOf(view.Self.PlayArea, move.CardID)*2 + 1
+	case "play_card":
+		return attackOf(view.Self.Hand, move.CardID) * 2
+	default:
+		return 0
+	}
+}
+
+// attackOf はカード一覧から cardID のカードの攻撃力を返します
+func attackOf(cards []game.Card, cardID int) int {
+	for _, card := range cards {
+		if card.ID == cardID {
+			return card.AttackPts
+		}
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+// backend/internal/bot/greedy_test.go
+package bot
+
+import (
+	"testing"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+func TestGreedyStrategy(t *testing.T) {
+	tests := []struct {
+		name             string
+		hand, playArea   []game.Card
+		opponentPlayArea []game.Card
+		want             moveKey
+	}{
+		// 強いカードを出せば次から5ダメージ (評価10) > 中のカードで今3ダメージ (評価7)
+		{"強いカードを出す", []game.Card{weak, strong}, []game.Card{medium}, nil, moveKey{"play_card", strong.ID, 0}},
+		// 弱いカードを出す (評価2) より中のカードで直接攻撃する (評価7)
+		{"直接攻撃する", []game.Card{weak}, []game.Card{medium}, nil, moveKey{"attack", medium.ID, 0}},
+		// 同じダメージならカードを出すより直接攻撃を優先する
+		{"同じダメージなら攻撃", []game.Card{medium}, []game.Card{medium}, nil, moveKey{"attack", medium.ID, 0}},
+		// カード同士の戦闘は相手のHPを減らさないため選ばない
+		{"カードを狙わない", nil, []game.Card{medium}, []game.Card{weak}, moveKey{"attack", medium.ID, 0}},
+		{"何もできなければ終了", nil, nil, []game.Card{strong}, moveKey{"pass", 0, 0}},
+	}
+	for _, tt := range tests {
+		view := NewView(testDuel(tt.hand, tt.playArea, tt.opponentPlayArea), 0)
+		move := NewGreedyStrategy().NextAction(view)
+		if got := (moveKey{move.ActionType, move.CardID, move.TargetID}); got != tt.want {
+			t.Errorf("%s: NextAction = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRandomStrategyChoosesLegalMoves(t *testing.T) {
+	view := NewView(testDuel([]game.Card{weak, strong}, []game.Card{medium}, []game.Card{weak}), 0)
+	legal := map[moveKey]bool{}
+	for _, key := range moveKeys(view.LegalMoves()) {
+		legal[key] = true
+	}
+
+	strategy := NewRandomStrategy()
+	chosen := map[moveKey]bool{}
+	for i := 0; i < 200; i++ {
+		move := strategy.NextAction(view)
+		key := moveKey{move.ActionType, move.CardID, move.TargetID}
+		if !legal[key] || move.DuelID != "d1" || move.PlayerID != "bot-1" {
+			t.Fatalf("NextAction = %+v; want 合法手", move)
+		}
+		chosen[key] = true
+	}
+	// 一様に選ぶため、200回あればすべての合法手が一度は選ばれる
+	if len(chosen) != len(legal) {
+		t.Errorf("選ばれた合法手 = %d 種類; want %d 種類", len(chosen), len(legal))
+	}
+}
@@ -0,0 +1,97 @@
+// backend/internal/bot/practice_api.go
+package bot
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/KOU050223/go-card/internal/game"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// PracticeAPI はボットとの練習対戦をRESTで公開します
+//
+// 練習対戦はマッチメイキングを通さずに作成され、レーティングには反映されません。
+// 対戦には時間切れがないため、練習対戦はユーザーごとに1つまでとし、新しく始めると
+// 進行中の前の練習対戦はボットの勝ちとして終了します (記録はインスタンスごとで、対戦の終了時に消します)。
+type PracticeAPI struct {
+	Duels  *game.DuelService
+	Driver *Driver
+
+	mu     sync.Mutex // Start をユーザーをまたいで直列にする
+	active sync.Map   // ユーザーID -> 進行中の練習対戦のID
+}
+
+// NewPracticeAPI は PracticeAPI を作成し、練習対戦の終了時に記録を消すコールバックを登録します
+func NewPracticeAPI(duels *game.DuelService, driver *Driver) *PracticeAPI {
+	api := &PracticeAPI{Duels: duels, Driver: driver}
+	duels.AddFinishCallback(api.onDuelFinished)
+	return api
+}
+
+// POST /api/practice
+func (api *PracticeAPI) Start(c echo.Context) error {
+	userID := c.Get("uid").(string)
+
+	req := struct {
+		Difficulty string `json:"difficulty"`
+	}{Difficulty: DefaultDifficulty}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "不正なリクエストです")
+	}
+
+	strategy, err := StrategyFor(req.Difficulty)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "difficulty は easy または normal を指定してください")
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.endPrevious(userID)
+
+	// プレイヤーを先手にする
+	botID := NewID()
+	duelID := uuid.New().String()
+	api.Driver.Assign(botID, strategy)
+	if err := api.Duels.CreateDuelWithOptions(duelID, userID, botID, game.DuelOptions{Unrated: true}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "対戦作成エラー")
+	}
+	api.active.Store(userID, duelID)
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"duelId":     duelID,
+		"botId":      botID,
+		"difficulty": req.Difficulty,
+	})
+}
+
+// endPrevious はユーザーの進行中の練習対戦をボットの勝ちとして終了させます (api.mu取得済み)
+func (api *PracticeAPI) endPrevious(userID string) {
+	value, ok := api.active.LoadAndDelete(userID)
+	if !ok {
+		return
+	}
+	duelID := value.(string)
+
+	duel, err := api.Duels.GetDuel(duelID)
+	if err != nil || duel.Status != "active" {
+		return
+	}
+	botID := duel.Players[1].UserID
+	if _, err := api.Duels.ForceEnd(duelID, botID, true); err != nil && !errors.Is(err, game.ErrDuelNotActive) {
+		log.Printf("前の練習対戦の終了エラー (対戦: %s): %v", duelID, err)
+	}
+}
+
+// onDuelFinished は終了した練習対戦の記録を消します
+//
+// endPrevious の ForceEnd から api.mu を持ったまま呼ばれるため、ロックを取らずに消します。
+func (api *PracticeAPI) onDuelFinished(duel *game.Duel) {
+	if !IsBot(duel.Players[1].UserID) {
+		return
+	}
+	api.active.CompareAndDelete(duel.Players[0].UserID, duel.ID)
+}
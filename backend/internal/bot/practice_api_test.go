@@ -0,0 +1,66 @@
+// backend/internal/bot/practice_api_test.go
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KOU050223/go-card/internal/game"
+	"github.com/labstack/echo/v4"
+)
+
+// startPractice は userID として練習対戦を開始し、作成された対戦のIDを返します
+func startPractice(t *testing.T, api *PracticeAPI, userID string) string {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/practice", strings.NewReader(`{"difficulty":"easy"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("uid", userID)
+
+	if err := api.Start(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Start のステータス = %d; want %d", rec.Code, http.StatusCreated)
+	}
+	var res struct {
+		DuelID string `json:"duelId"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res.DuelID
+}
+
+func TestPracticeStartEndsPreviousDuel(t *testing.T) {
+	duels := game.NewDuelService([]game.Card{medium})
+	api := NewPracticeAPI(duels, NewDriver(duels))
+
+	first := startPractice(t, api, "alice")
+	second := startPractice(t, api, "alice")
+
+	// 前の練習対戦はボットの勝ちとして終了する
+	duel, err := duels.GetDuel(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duel.Status != "finished" || duel.WinnerID != duel.Players[1].UserID {
+		t.Errorf("前の練習対戦 = %s (勝者: %q); want ボットの勝ちで終了", duel.Status, duel.WinnerID)
+	}
+	if value, _ := api.active.Load("alice"); value != second {
+		t.Errorf("進行中の練習対戦 = %v; want %s", value, second)
+	}
+
+	// 終了した練習対戦の記録は消える
+	if _, err := duels.ForceEnd(second, "alice", true); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := api.active.Load("alice"); ok {
+		t.Errorf("終了後の練習対戦の記録 = %v; want なし", value)
+	}
+}
@@ -0,0 +1,28 @@
+// backend/internal/bot/random.go
+package bot
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+// RandomStrategy は合法手から一様にランダムに選びます
+type RandomStrategy struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewRandomStrategy は新しいランダムな思考ルーチンを作成します
+func NewRandomStrategy() *RandomStrategy {
+	return &RandomStrategy{rng: rand.New(rand.NewSource(rand.Int63()))}
+}
+
+func (s *RandomStrategy) NextAction(view View) game.GameAction {
+	moves := view.LegalMoves()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return moves[s.rng.Intn(len(moves))]
+}
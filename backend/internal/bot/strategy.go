@@ -0,0 +1,40 @@
+// backend/internal/bot/strategy.go
+package bot
+
+import (
+	"fmt"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+// Strategy はボットの思考ルーチンです
+//
+// 自分の手番で状態が更新されるたびに呼ばれ、次の1手を返します。
+// 返す行動は view.LegalMoves() のいずれかである必要があります。
+type Strategy interface {
+	NextAction(view View) game.GameAction
+}
+
+// 難易度
+const (
+	DifficultyEasy   = "easy"   // ランダムに合法手を選ぶ
+	DifficultyNormal = "normal" // 相手へのダメージを優先する貪欲法
+)
+
+// DefaultDifficulty はマッチメイキングの補充などで使う標準の難易度です
+const DefaultDifficulty = DifficultyNormal
+
+// strategies は難易度ごとの思考ルーチンの生成関数です
+var strategies = map[string]func() Strategy{
+	DifficultyEasy:   func() Strategy { return NewRandomStrategy() },
+	DifficultyNormal: func() Strategy { return NewGreedyStrategy() },
+}
+
+// StrategyFor は難易度に対応する思考ルーチンを返します
+func StrategyFor(difficulty string) (Strategy, error) {
+	newStrategy, ok := strategies[difficulty]
+	if !ok {
+		return nil, fmt.Errorf("未知の難易度です: %q", difficulty)
+	}
+	return newStrategy(), nil
+}
@@ -0,0 +1,102 @@
+// backend/internal/bot/view.go
+package bot
+
+import "github.com/KOU050223/go-card/internal/game"
+
+// View はボットから見た対戦状態です
+//
+// 相手の手札は伏せられ、枚数だけが分かります。
+type View struct {
+	DuelID           string
+	Version          int
+	TurnCount        int
+	Self             game.Player
+	Opponent         game.Player // Hand は常に空
+	OpponentHandSize int
+
+	// canPlay はこのターンにまだ手札のカードを場に出せるかです
+	canPlay bool
+	// attacked はこのターンに攻撃済みのカードIDごとの回数です
+	attacked map[int]int
+}
+
+// NewView は playerIdx のプレイヤーから見た対戦状態を作成します
+func NewView(duel *game.Duel, playerIdx int) View {
+	opponent := duel.Players[(playerIdx+1)%2]
+	handSize := len(opponent.Hand)
+	opponent.Hand = []game.Card{}
+
+	// 1ターンの行動の回数は DuelService が数えている
+	attacked := map[int]int{}
+	for _, card := range duel.Players[playerIdx].PlayArea {
+		attacked[card.ID] = duel.AttacksMade(card.ID)
+	}
+	return View{
+		DuelID:           duel.ID,
+		Version:          duel.Version,
+		TurnCount:        duel.TurnCount,
+		Self:             duel.Players[playerIdx],
+		Opponent:         opponent,
+		OpponentHandSize: handSize,
+		canPlay:          duel.CanPlayCard(),
+		attacked:         attacked,
+	}
+}
+
+// LegalMoves は現在の状態で選べる行動の一覧を返します。ターン終了 (pass) は常に含まれます
+//
+// 手札のカードは1ターンに1枚まで場に出せ、場のカードは1ターンに1回ずつ、相手プレイヤー
+// または相手の場のカードを攻撃できます (DuelService と同じ制限です)。
+func (v View) LegalMoves() []game.GameAction {
+	moves := make([]game.GameAction, 0)
+
+	played := map[int]bool{}
+	for _, card := range v.Self.Hand {
+		if !v.canPlay || played[card.ID] {
+			continue
+		}
+		played[card.ID] = true
+		moves = append(moves, v.action("play_card", card.ID, 0))
+	}
+
+	for _, card := range v.Attackers() {
+		moves = append(moves, v.action("attack", card.ID, 0))
+		for _, target := range v.Opponent.PlayArea {
+			moves = append(moves, v.action("attack", card.ID, target.ID))
+		}
+	}
+
+	return append(moves, v.Pass())
+}
+
+// Attackers はこのターンにまだ攻撃していない場のカードを返します (同じIDのカードは1枚にまとめる)
+func (v View) Attackers() []game.Card {
+	available := map[int]int{}
+	for _, card := range v.Self.PlayArea {
+		available[card.ID]++
+	}
+
+	attackers := make([]game.Card, 0, len(v.Self.PlayArea))
+	for _, card := range v.Self.PlayArea {
+		if available[card.ID] > v.attacked[card.ID] {
+			attackers = append(attackers, card)
+			available[card.ID] = 0
+		}
+	}
+	return attackers
+}
+
+// Pass はターン終了の行動を返します
+func (v View) Pass() game.GameAction {
+	return v.action("pass", 0, 0)
+}
+
+func (v View) action(actionType string, cardID, targetID int) game.GameAction {
+	return game.GameAction{
+		DuelID:     v.DuelID,
+		PlayerID:   v.Self.UserID,
+		ActionType: actionType,
+		CardID:     cardID,
+		TargetID:   targetID,
+	}
+}
@@ -0,0 +1,96 @@
+// backend/internal/bot/view_test.go
+package bot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+var (
+	weak   = game.Card{ID: 1, Name: "弱", AttackPts: 1, DefensePts: 1}
+	medium = game.Card{ID: 2, Name: "中", AttackPts: 3, DefensePts: 2}
+	strong = game.Card{ID: 3, Name: "強", AttackPts: 5, DefensePts: 1}
+)
+
+// testDuel は bot (先手) と alice の進行中の対戦を作成します
+func testDuel(hand, playArea, opponentPlayArea []game.Card) *game.Duel {
+	return &game.Duel{
+		ID:      "d1",
+		Version: 3,
+		Status:  "active",
+		Players: [2]game.Player{
+			{UserID: "bot-1", HP: 30, Hand: hand, PlayArea: playArea},
+			{UserID: "alice", HP: 30, Hand: []game.Card{weak, weak}, PlayArea: opponentPlayArea},
+		},
+		TurnCount: 1,
+	}
+}
+
+// moveKey は比較しやすい行動の表記です
+type moveKey struct {
+	actionType       string
+	cardID, targetID int
+}
+
+func moveKeys(moves []game.GameAction) []moveKey {
+	keys := make([]moveKey, 0, len(moves))
+	for _, move := range moves {
+		keys = append(keys, moveKey{move.ActionType, move.CardID, move.TargetID})
+	}
+	return keys
+}
+
+func TestNewViewHidesOpponentHand(t *testing.T) {
+	view := NewView(testDuel(nil, nil, nil), 0)
+	if len(view.Opponent.Hand) != 0 || view.OpponentHandSize != 2 {
+		t.Errorf("相手の手札 = %v (%d 枚); want 伏せて 2 枚", view.Opponent.Hand, view.OpponentHandSize)
+	}
+}
+
+func TestLegalMoves(t *testing.T) {
+	duel := testDuel([]game.Card{weak, weak, strong}, []game.Card{medium}, []game.Card{weak})
+	got := moveKeys(NewView(duel, 0).LegalMoves())
+
+	// 同じカードは1回ずつ、攻撃は相手プレイヤーと相手の場のカード、最後にターン終了
+	want := []moveKey{
+		{"play_card", weak.ID, 0},
+		{"play_card", strong.ID, 0},
+		{"attack", medium.ID, 0},
+		{"attack", medium.ID, weak.ID},
+		{"pass", 0, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LegalMoves = %v; want %v", got, want)
+	}
+}
+
+func TestLegalMovesFollowTurnLimits(t *testing.T) {
+	ds := game.NewDuelService([]game.Card{medium})
+	updates := make(chan *game.Duel, 8)
+	ds.SetUpdateCallback(func(duel *game.Duel) { updates <- duel })
+	duelID, err := ds.CreateDuel("bot-1", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var duel *game.Duel
+	for _, actionType := range []string{"play_card", "attack"} {
+		if err := ds.SubmitAction(game.GameAction{DuelID: duelID, PlayerID: "bot-1", ActionType: actionType, CardID: medium.ID}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case duel = <-updates:
+		case <-time.After(time.Second):
+			t.Fatalf("%s が処理されませんでした", actionType)
+		}
+	}
+
+	// カードを1枚出して攻撃した後は、ターン終了しか選べない
+	got := moveKeys(NewView(duel, 0).LegalMoves())
+	if want := []moveKey{{"pass", 0, 0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("出して攻撃した後の LegalMoves = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+// backend/internal/db/audit_repo.go
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// defaultAuditLimit は管理操作の記録を取得するときの標準の件数です
+	defaultAuditLimit = 50
+	// maxAuditLimit は一度に取得できる管理操作の記録の最大件数です
+	maxAuditLimit = 500
+)
+
+// AuditEntry は管理操作の記録です
+type AuditEntry struct {
+	ID         int64     `db:"id" json:"id"`
+	ActorID    string    `db:"actor_id" json:"actorId"`
+	Action     string    `db:"action" json:"action"`          // "sanction.apply" など
+	TargetType string    `db:"target_type" json:"targetType"` // "user", "duel", "card", "sanction"
+	TargetID   string    `db:"target_id" json:"targetId"`
+	Details    string    `db:"details" json:"details"`
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
+
+// AuditFilter は管理操作の記録の絞り込み条件です (空の項目は条件にしません)
+type AuditFilter struct {
+	ActorID    string
+	TargetType string
+	TargetID   string
+	Limit      int // 0以下なら50件、最大500件
+}
+
+// AuditRepository は管理操作の記録を保存します
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+// NewAuditRepository は新しいAuditRepositoryを作成します
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Record は管理操作を記録し、entry の ID と CreatedAt を設定します
+func (r *AuditRepository) Record(ctx context.Context, entry *AuditEntry) error {
+	entry.CreatedAt = time.Now()
+	res, err := r.db.ExecContext(ctx, `
+        INSERT INTO moderation_audit_log (actor_id, action, target_type, target_id, details, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, entry.Details, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("管理操作記録エラー: %w", err)
+	}
+	if entry.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("管理操作記録エラー: %w", err)
+	}
+	return nil
+}
+
+// List は条件に合う管理操作の記録を新しい順に返します
+func (r *AuditRepository) List(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	query := `SELECT id, actor_id, action, target_type, target_id, details, created_at
+        FROM moderation_audit_log WHERE 1 = 1`
+	var args []interface{}
+	if filter.ActorID != "" {
+		query += ` AND actor_id = ?`
+		args = append(args, filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		query += ` AND target_type = ?`
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query += ` AND target_id = ?`
+		args = append(args, filter.TargetID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	entries := []AuditEntry{}
+	if err := r.db.SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, fmt.Errorf("管理操作記録取得エラー: %w", err)
+	}
+	return entries, nil
+}
@@ -3,11 +3,16 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/KOU050223/go-card/internal/model"
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrCardNotFound はカードが存在しないことを表します
+var ErrCardNotFound = errors.New("カードが見つかりません")
+
 type CardRepository struct {
 	db *sqlx.DB
 }
@@ -33,3 +38,24 @@ func (r *CardRepository) GetByIDs(ctx context.Context, ids []int) ([]model.Card,
 	}
 	return cards, nil
 }
+
+// Update はカードの名前・攻撃力・防御力を更新します。カードが存在しない場合は ErrCardNotFound を返します
+func (r *CardRepository) Update(ctx context.Context, card model.Card) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE cards SET name = ?, attack_pts = ?, defense_pts = ? WHERE id = ?`,
+		card.Name, card.AttackPts, card.DefensePts, card.ID)
+	if err != nil {
+		return fmt.Errorf("カード更新エラー: %w", err)
+	}
+	// 値が変わらない場合も0件になるため、存在を確認し直す
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		var exists bool
+		if err := r.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM cards WHERE id = ?)`, card.ID); err != nil {
+			return fmt.Errorf("カード取得エラー: %w", err)
+		}
+		if !exists {
+			return ErrCardNotFound
+		}
+	}
+	return nil
+}
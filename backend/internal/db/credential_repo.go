@@ -0,0 +1,128 @@
+// backend/internal/db/credential_repo.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrLoginNameTaken はログイン名が既に使われていることを表します
+var ErrLoginNameTaken = errors.New("ログイン名は既に使われています")
+
+// Credential は自前認証のログイン情報です
+type Credential struct {
+	UserID       string    `db:"user_id"`
+	LoginName    string    `db:"login_name"`
+	PasswordHash string    `db:"password_hash"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+// CredentialRepository は自前認証のログイン情報を保存します
+type CredentialRepository struct {
+	db *sqlx.DB
+}
+
+func NewCredentialRepository(db *sqlx.DB) *CredentialRepository {
+	return &CredentialRepository{db: db}
+}
+
+// CreateUser はユーザーとログイン情報を1トランザクションで作成します
+//
+// ログイン名が使われている場合は ErrLoginNameTaken を、ユーザー名が使われている場合は
+// ErrUsernameTaken を返します。
+func (r *CredentialRepository) CreateUser(ctx context.Context, user *User, loginName, passwordHash string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("トランザクション開始エラー: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO users (id, username, points, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?)
+    `, user.ID, user.Username, user.Points, user.CreatedAt, user.UpdatedAt)
+	if err := userInsertError(err); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO user_credentials (user_id, login_name, password_hash)
+        VALUES (?, ?, ?)
+    `, user.ID, loginName, passwordHash)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return ErrLoginNameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("ログイン情報作成エラー: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("トランザクション確定エラー: %w", err)
+	}
+	return nil
+}
+
+// AttachToGuest はゲストアカウントにログイン情報を追加し、通常のアカウントにします
+//
+// ユーザーIDは変わらないため、所持カード・対戦履歴・レーティングはそのまま残ります。
+// ゲストが見つからない場合は ErrNotGuest を、ログイン名が使われている場合は
+// ErrLoginNameTaken を返します。
+func (r *CredentialRepository) AttachToGuest(ctx context.Context, userID, loginName, passwordHash string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("トランザクション開始エラー: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET is_guest = FALSE, updated_at = ? WHERE id = ? AND is_guest`, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("ユーザー更新エラー: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotGuest
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO user_credentials (user_id, login_name, password_hash)
+        VALUES (?, ?, ?)
+    `, userID, loginName, passwordHash)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return ErrLoginNameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("ログイン情報作成エラー: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("トランザクション確定エラー: %w", err)
+	}
+	return nil
+}
+
+// GetByLoginName はログイン名のログイン情報を取得します。存在しない場合は nil を返します
+func (r *CredentialRepository) GetByLoginName(ctx context.Context, loginName string) (*Credential, error) {
+	var cred Credential
+	err := r.db.GetContext(ctx, &cred, `
+        SELECT user_id, login_name, password_hash, created_at, updated_at
+        FROM user_credentials WHERE login_name = ?
+    `, loginName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ログイン情報取得エラー: %w", err)
+	}
+	return &cred, nil
+}
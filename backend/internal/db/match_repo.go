@@ -0,0 +1,127 @@
+// backend/internal/db/match_repo.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MatchEntry は matches テーブルの1行です
+type MatchEntry struct {
+	ID          string         `db:"id"`
+	Player1ID   string         `db:"player1_id"`
+	Player2ID   string         `db:"player2_id"`
+	BestOf      int            `db:"best_of"`
+	Player1Wins int            `db:"player1_wins"`
+	Player2Wins int            `db:"player2_wins"`
+	Draws       int            `db:"draws"`
+	Status      string         `db:"status"`
+	WinnerID    sql.NullString `db:"winner_id"`
+	FinishedAt  sql.NullTime   `db:"finished_at"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+// MatchGame はシリーズ内の1試合 (duels テーブルの1行) です
+type MatchGame struct {
+	DuelID     string         `db:"id"`
+	GameNumber int            `db:"game_number"`
+	Player1ID  string         `db:"player1_id"`
+	Player2ID  string         `db:"player2_id"`
+	WinnerID   sql.NullString `db:"winner_id"`
+	Status     string         `db:"status"`
+	TurnCount  int            `db:"turn_count"`
+}
+
+// MatchRepository はBest-of-Nのシリーズと各試合を保存します
+type MatchRepository struct {
+	db *sqlx.DB
+}
+
+func NewMatchRepository(db *sqlx.DB) *MatchRepository {
+	return &MatchRepository{db: db}
+}
+
+// Insert はシリーズを追加します
+func (r *MatchRepository) Insert(ctx context.Context, m *MatchEntry) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO matches (id, player1_id, player2_id, best_of, status)
+        VALUES (?, ?, ?, ?, ?)
+    `, m.ID, m.Player1ID, m.Player2ID, m.BestOf, m.Status)
+	if err != nil {
+		return fmt.Errorf("シリーズ作成エラー: %w", err)
+	}
+	return nil
+}
+
+// Update はシリーズのスコアと状態を更新します
+func (r *MatchRepository) Update(ctx context.Context, m *MatchEntry) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE matches
+        SET player1_wins = ?, player2_wins = ?, draws = ?, status = ?, winner_id = ?, finished_at = ?
+        WHERE id = ?
+    `, m.Player1Wins, m.Player2Wins, m.Draws, m.Status, m.WinnerID, m.FinishedAt, m.ID)
+	if err != nil {
+		return fmt.Errorf("シリーズ更新エラー: %w", err)
+	}
+	return nil
+}
+
+// GetByID はシリーズを取得します。存在しない場合は nil を返します
+func (r *MatchRepository) GetByID(ctx context.Context, id string) (*MatchEntry, error) {
+	var m MatchEntry
+	err := r.db.GetContext(ctx, &m, `
+        SELECT id, player1_id, player2_id, best_of, player1_wins, player2_wins, draws,
+               status, winner_id, finished_at, created_at, updated_at
+        FROM matches WHERE id = ?
+    `, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("シリーズ取得エラー: %w", err)
+	}
+	return &m, nil
+}
+
+// InsertGame はシリーズの gameNumber 試合目の対戦を duels に追加します
+func (r *MatchRepository) InsertGame(ctx context.Context, matchID string, gameNumber int, duelID, player1ID, player2ID string) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO duels (id, match_id, game_number, player1_id, player2_id, status, started_at)
+        VALUES (?, ?, ?, ?, ?, 'active', NOW())
+    `, duelID, matchID, gameNumber, player1ID, player2ID)
+	if err != nil {
+		return fmt.Errorf("試合作成エラー: %w", err)
+	}
+	return nil
+}
+
+// FinishGame は試合の結果を保存します。winnerID が空の場合は引き分けです
+func (r *MatchRepository) FinishGame(ctx context.Context, duelID, winnerID string, turnCount int) error {
+	winner := sql.NullString{String: winnerID, Valid: winnerID != ""}
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE duels SET status = 'finished', winner_id = ?, turn_count = ?, finished_at = NOW()
+        WHERE id = ?
+    `, winner, turnCount, duelID)
+	if err != nil {
+		return fmt.Errorf("試合結果保存エラー: %w", err)
+	}
+	return nil
+}
+
+// ListGames はシリーズの試合を順に返します
+func (r *MatchRepository) ListGames(ctx context.Context, matchID string) ([]MatchGame, error) {
+	var games []MatchGame
+	err := r.db.SelectContext(ctx, &games, `
+        SELECT id, game_number, player1_id, player2_id, winner_id, status, turn_count
+        FROM duels WHERE match_id = ? ORDER BY game_number ASC
+    `, matchID)
+	if err != nil {
+		return nil, fmt.Errorf("試合一覧取得エラー: %w", err)
+	}
+	return games, nil
+}
@@ -4,8 +4,12 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/KOU050223/go-card/internal/rating"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -17,11 +21,21 @@ const (
 	StatusCancelled MatchmakingStatus = "cancelled"
 )
 
+// ErrAlreadyWaiting はユーザーが既に待機エントリを持っていることを表します
+var ErrAlreadyWaiting = errors.New("既に待機中です")
+
+// mysqlErrDuplicateEntry は一意制約違反のエラー番号です
+const mysqlErrDuplicateEntry = 1062
+
+// matchmakingColumns は生成列 (active_user_id) を除いた取得列です
+const matchmakingColumns = `id, user_id, status, duel_id, rating, created_at, updated_at`
+
 type MatchmakingEntry struct {
 	ID        int64             `db:"id"`
 	UserID    string            `db:"user_id"`
 	Status    MatchmakingStatus `db:"status"`
 	DuelID    sql.NullString    `db:"duel_id"`
+	Rating    float64           `db:"rating"`
 	CreatedAt time.Time         `db:"created_at"`
 	UpdatedAt time.Time         `db:"updated_at"`
 }
@@ -34,35 +48,125 @@ func NewMatchmakingRepository(db *sqlx.DB) *MatchmakingRepository {
 	return &MatchmakingRepository{db: db}
 }
 
-func (r *MatchmakingRepository) InsertWaiting(ctx context.Context, userID string) error {
-	_, err := r.db.ExecContext(ctx, `INSERT INTO matchmaking (user_id, status) VALUES (?, ?)`, userID, StatusWaiting)
+// InsertWaiting は joinedAt に参加した待機エントリを追加します。待機中のエントリがあれば ErrAlreadyWaiting を返します
+//
+// 待機キューは created_at の古い順なので、過去の joinedAt を指定するとキューの先頭側に戻ります。
+func (r *MatchmakingRepository) InsertWaiting(ctx context.Context, userID string, rating float64, joinedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO matchmaking (user_id, status, rating, created_at) VALUES (?, ?, ?, ?)`,
+		userID, StatusWaiting, rating, joinedAt)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return ErrAlreadyWaiting
+	}
 	return err
 }
 
-func (r *MatchmakingRepository) FindWaitingExcept(ctx context.Context, exceptUserID string) (*MatchmakingEntry, error) {
-	var entry MatchmakingEntry
-	err := r.db.GetContext(ctx, &entry, `SELECT * FROM matchmaking WHERE status = ? AND user_id != ? ORDER BY created_at ASC LIMIT 1`, StatusWaiting, exceptUserID)
+// ClaimOpponent は userID の待機エントリと最も古い他の待機エントリを1トランザクションでマッチ済みにします
+//
+// 他のトランザクションが確保中の行は SKIP LOCKED で飛ばすため、同時に参加しても
+// 同じ相手を二重に取ることはありません。相手は window の許容差に収まるレーティングの
+// 待機者に限ります。相手がいない場合や userID が既に他のユーザーに確保されている場合は nil を返します。
+func (r *MatchmakingRepository) ClaimOpponent(ctx context.Context, userID, duelID string, window rating.Window) (*MatchmakingEntry, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("トランザクション開始エラー: %w", err)
+	}
+	defer tx.Rollback()
+
+	var self MatchmakingEntry
+	err = tx.GetContext(ctx, &self,
+		`SELECT `+matchmakingColumns+` FROM matchmaking WHERE user_id = ? AND status = ? FOR UPDATE SKIP LOCKED`,
+		userID, StatusWaiting)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	return &entry, err
-}
+	if err != nil {
+		return nil, fmt.Errorf("待機エントリ取得エラー: %w", err)
+	}
 
-func (r *MatchmakingRepository) UpdateMatched(ctx context.Context, userID1, userID2, duelID string) error {
-	_, err := r.db.ExecContext(ctx, `UPDATE matchmaking SET status = ?, duel_id = ? WHERE user_id IN (?, ?)`, StatusMatched, duelID, userID1, userID2)
-	return err
+	// 許容差は2人のうち長く待っている方の待ち時間で決まる
+	var opponent MatchmakingEntry
+	err = tx.GetContext(ctx, &opponent,
+		`SELECT `+matchmakingColumns+` FROM matchmaking
+		 WHERE status = ? AND user_id != ?
+		   AND ABS(rating - ?) <= LEAST(?, ? + ? * TIMESTAMPDIFF(SECOND, LEAST(created_at, ?), NOW()))
+		 ORDER BY created_at ASC, id ASC LIMIT 1
+		 FOR UPDATE SKIP LOCKED`,
+		StatusWaiting, userID,
+		self.Rating, window.Max, window.Base, window.GrowthPerSecond, self.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("対戦相手検索エラー: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE matchmaking SET status = ?, duel_id = ? WHERE id IN (?, ?)`,
+		StatusMatched, duelID, self.ID, opponent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("マッチング更新エラー: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("トランザクション確定エラー: %w", err)
+	}
+
+	opponent.Status = StatusMatched
+	opponent.DuelID = sql.NullString{String: duelID, Valid: true}
+	return &opponent, nil
 }
 
 func (r *MatchmakingRepository) GetByUserID(ctx context.Context, userID string) (*MatchmakingEntry, error) {
 	var entry MatchmakingEntry
-	err := r.db.GetContext(ctx, &entry, `SELECT * FROM matchmaking WHERE user_id = ? ORDER BY created_at DESC LIMIT 1`, userID)
+	err := r.db.GetContext(ctx, &entry, `SELECT `+matchmakingColumns+` FROM matchmaking WHERE user_id = ? ORDER BY created_at DESC, id DESC LIMIT 1`, userID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return &entry, err
 }
 
-func (r *MatchmakingRepository) Cancel(ctx context.Context, userID string) error {
-	_, err := r.db.ExecContext(ctx, `UPDATE matchmaking SET status = ? WHERE user_id = ?`, StatusCancelled, userID)
-	return err
+func (r *MatchmakingRepository) ListWaiting(ctx context.Context) ([]MatchmakingEntry, error) {
+	var entries []MatchmakingEntry
+	err := r.db.SelectContext(ctx, &entries, `SELECT `+matchmakingColumns+` FROM matchmaking WHERE status = ? ORDER BY created_at ASC, id ASC`, StatusWaiting)
+	return entries, err
+}
+
+// MarkMatched は待機中のエントリを相手なしで duelID のマッチ済みにし、更新したかどうかを返します
+//
+// 待機中の行だけを更新するため、他のインスタンスが先に確保した場合は false を返します。
+func (r *MatchmakingRepository) MarkMatched(ctx context.Context, userID, duelID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE matchmaking SET status = ?, duel_id = ? WHERE user_id = ? AND status = ?`,
+		StatusMatched, duelID, userID, StatusWaiting)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CountMatchedSince は since 以降のマッチ数 (対戦IDの数) と、マッチ済みになったエントリ数を返します
+//
+// ボットとの対戦は1件のエントリだけがマッチ済みになるため、エントリ数はマッチ数の2倍とは限りません。
+func (r *MatchmakingRepository) CountMatchedSince(ctx context.Context, since time.Time) (matches, entries int, err error) {
+	var count struct {
+		Matches int `db:"matches"`
+		Entries int `db:"entries"`
+	}
+	err = r.db.GetContext(ctx, &count,
+		`SELECT COUNT(DISTINCT duel_id) AS matches, COUNT(*) AS entries FROM matchmaking WHERE status = ? AND updated_at >= ?`,
+		StatusMatched, since)
+	return count.Matches, count.Entries, err
+}
+
+// Cancel は待機中のエントリをキャンセルし、キャンセルしたかどうかを返します
+func (r *MatchmakingRepository) Cancel(ctx context.Context, userID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE matchmaking SET status = ? WHERE user_id = ? AND status = ?`, StatusCancelled, userID, StatusWaiting)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
@@ -0,0 +1,148 @@
+// backend/internal/db/sanction_repo.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 制裁の種類
+const (
+	// SanctionBan はアカウントの利用を禁止します (期限なしも可)
+	SanctionBan = "ban"
+	// SanctionSuspend はアカウントの利用を期限付きで停止します
+	SanctionSuspend = "suspend"
+	// SanctionMute はチャットの送信を禁止します
+	SanctionMute = "mute"
+)
+
+// ErrSanctionNotFound は有効な制裁が見つからないことを表します
+var ErrSanctionNotFound = errors.New("有効な制裁が見つかりません")
+
+// Sanction はユーザーへの制裁 (BAN・利用停止・チャット禁止) の記録です
+type Sanction struct {
+	ID        int64      `db:"id" json:"id"`
+	UserID    string     `db:"user_id" json:"userId"`
+	Kind      string     `db:"kind" json:"kind"`
+	Reason    string     `db:"reason" json:"reason"`
+	CreatedBy string     `db:"created_by" json:"createdBy"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+	ExpiresAt *time.Time `db:"expires_at" json:"expiresAt,omitempty"` // nil なら期限なし
+	RevokedAt *time.Time `db:"revoked_at" json:"revokedAt,omitempty"`
+	RevokedBy *string    `db:"revoked_by" json:"revokedBy,omitempty"`
+}
+
+// Blocks はアカウントの利用を禁止する制裁 (BAN・利用停止) かを返します
+func (s *Sanction) Blocks() bool {
+	return s.Kind == SanctionBan || s.Kind == SanctionSuspend
+}
+
+// outlasts は s が other より長く続くかを返します (期限なしが最も長い)
+func (s *Sanction) outlasts(other *Sanction) bool {
+	if other == nil || other.ExpiresAt == nil {
+		return other == nil
+	}
+	return s.ExpiresAt == nil || s.ExpiresAt.After(*other.ExpiresAt)
+}
+
+const sanctionColumns = `id, user_id, kind, reason, created_by, created_at, expires_at, revoked_at, revoked_by`
+
+// SanctionRepository は制裁の記録を保存します
+type SanctionRepository struct {
+	db *sqlx.DB
+}
+
+// NewSanctionRepository は新しいSanctionRepositoryを作成します
+func NewSanctionRepository(db *sqlx.DB) *SanctionRepository {
+	return &SanctionRepository{db: db}
+}
+
+// Insert は制裁を記録し、s の ID と CreatedAt を設定します
+func (r *SanctionRepository) Insert(ctx context.Context, s *Sanction) error {
+	s.CreatedAt = time.Now()
+	res, err := r.db.ExecContext(ctx, `
+        INSERT INTO user_sanctions (user_id, kind, reason, created_by, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, s.UserID, s.Kind, s.Reason, s.CreatedBy, s.CreatedAt, s.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("制裁記録エラー: %w", err)
+	}
+	if s.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("制裁記録エラー: %w", err)
+	}
+	return nil
+}
+
+// GetByID は制裁を返します。見つからない場合は nil を返します
+func (r *SanctionRepository) GetByID(ctx context.Context, id int64) (*Sanction, error) {
+	var s Sanction
+	err := r.db.GetContext(ctx, &s, `SELECT `+sanctionColumns+` FROM user_sanctions WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("制裁取得エラー: %w", err)
+	}
+	return &s, nil
+}
+
+// Revoke は有効な制裁を解除します。解除済み・期限切れ・存在しない場合は ErrSanctionNotFound を返します
+func (r *SanctionRepository) Revoke(ctx context.Context, id int64, revokedBy string) error {
+	now := time.Now()
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE user_sanctions SET revoked_at = ?, revoked_by = ?
+        WHERE id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)
+    `, now, revokedBy, id, now)
+	if err != nil {
+		return fmt.Errorf("制裁解除エラー: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("制裁解除エラー: %w", err)
+	}
+	if n == 0 {
+		return ErrSanctionNotFound
+	}
+	return nil
+}
+
+// ListActive は有効な制裁を新しい順に返します。userID が空の場合は全ユーザーの制裁を返します
+func (r *SanctionRepository) ListActive(ctx context.Context, userID string) ([]Sanction, error) {
+	return activeSanctions(ctx, r.db, userID)
+}
+
+// ListByUser はユーザーの制裁の履歴 (解除・期限切れを含む) を新しい順に返します
+func (r *SanctionRepository) ListByUser(ctx context.Context, userID string) ([]Sanction, error) {
+	sanctions := []Sanction{}
+	err := r.db.SelectContext(ctx, &sanctions, `
+        SELECT `+sanctionColumns+` FROM user_sanctions
+        WHERE user_id = ? ORDER BY created_at DESC, id DESC
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("制裁履歴取得エラー: %w", err)
+	}
+	return sanctions, nil
+}
+
+// activeSanctions は解除されておらず期限切れでもない制裁を新しい順に返します
+func activeSanctions(ctx context.Context, q sqlx.QueryerContext, userID string) ([]Sanction, error) {
+	query := `SELECT ` + sanctionColumns + ` FROM user_sanctions
+        WHERE revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`
+	args := []interface{}{time.Now()}
+	if userID != "" {
+		query += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+
+	sanctions := []Sanction{}
+	if err := sqlx.SelectContext(ctx, q, &sanctions, query, args...); err != nil {
+		return nil, fmt.Errorf("制裁一覧取得エラー: %w", err)
+	}
+	return sanctions, nil
+}
@@ -0,0 +1,195 @@
+// backend/internal/db/user_data_repo.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// historyReferences はアカウント削除時に匿名のユーザーへ付け替える、対戦履歴の列です
+//
+// 相手の対戦履歴とレーティングの計算に必要なため、削除するユーザーの対戦・シリーズは残します。
+var historyReferences = []struct{ table, column string }{
+	{"duels", "player1_id"},
+	{"duels", "player2_id"},
+	{"duels", "winner_id"},
+	{"matches", "player1_id"},
+	{"matches", "player2_id"},
+	{"matches", "winner_id"},
+}
+
+// OwnedCard はユーザーが所持するカードです
+type OwnedCard struct {
+	CardID     int       `db:"card_id" json:"cardId"`
+	Name       string    `db:"name" json:"name"`
+	Quantity   int       `db:"quantity" json:"quantity"`
+	AcquiredAt time.Time `db:"created_at" json:"acquiredAt"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// DuelRecord は対戦履歴の1件です
+type DuelRecord struct {
+	ID         string     `db:"id" json:"id"`
+	Player1ID  string     `db:"player1_id" json:"player1Id"`
+	Player2ID  string     `db:"player2_id" json:"player2Id"`
+	WinnerID   *string    `db:"winner_id" json:"winnerId,omitempty"`
+	Status     string     `db:"status" json:"status"`
+	TurnCount  int        `db:"turn_count" json:"turnCount"`
+	MatchID    *string    `db:"match_id" json:"matchId,omitempty"`
+	GameNumber *int       `db:"game_number" json:"gameNumber,omitempty"`
+	StartedAt  *time.Time `db:"started_at" json:"startedAt,omitempty"`
+	FinishedAt *time.Time `db:"finished_at" json:"finishedAt,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// MatchRecord はBest-of-Nのシリーズの履歴の1件です
+type MatchRecord struct {
+	ID          string     `db:"id" json:"id"`
+	Player1ID   string     `db:"player1_id" json:"player1Id"`
+	Player2ID   string     `db:"player2_id" json:"player2Id"`
+	BestOf      int        `db:"best_of" json:"bestOf"`
+	Player1Wins int        `db:"player1_wins" json:"player1Wins"`
+	Player2Wins int        `db:"player2_wins" json:"player2Wins"`
+	Draws       int        `db:"draws" json:"draws"`
+	Status      string     `db:"status" json:"status"`
+	WinnerID    *string    `db:"winner_id" json:"winnerId,omitempty"`
+	FinishedAt  *time.Time `db:"finished_at" json:"finishedAt,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// UserData はユーザー本人に開示する、アカウントに保存している個人データです
+type UserData struct {
+	Profile    *User         `json:"profile"`
+	LoginName  string        `json:"loginName,omitempty"` // 自前認証のログイン名 (パスワードのハッシュは含めない)
+	Collection []OwnedCard   `json:"collection"`
+	Duels      []DuelRecord  `json:"duels"`
+	Matches    []MatchRecord `json:"matches"`
+	Sanctions  []Sanction    `json:"sanctions"`
+}
+
+// ExportData はユーザーの個人データをまとめて返します。ユーザーが存在しない場合は nil を返します
+func (r *UserRepository) ExportData(ctx context.Context, id string) (*UserData, error) {
+	profile, err := r.GetByID(ctx, id)
+	if err != nil || profile == nil {
+		return nil, err
+	}
+	data := &UserData{
+		Profile:    profile,
+		Collection: []OwnedCard{},
+		Duels:      []DuelRecord{},
+		Matches:    []MatchRecord{},
+		Sanctions:  []Sanction{},
+	}
+
+	err = r.db.GetContext(ctx, &data.LoginName, `SELECT login_name FROM user_credentials WHERE user_id = ?`, id)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("ログイン情報取得エラー: %w", err)
+	}
+
+	err = r.db.SelectContext(ctx, &data.Collection, `
+        SELECT uc.card_id, c.name, uc.quantity, uc.created_at, uc.updated_at
+        FROM user_cards uc JOIN cards c ON c.id = uc.card_id
+        WHERE uc.user_id = ? ORDER BY uc.card_id
+    `, id)
+	if err != nil {
+		return nil, fmt.Errorf("所持カード取得エラー: %w", err)
+	}
+
+	err = r.db.SelectContext(ctx, &data.Duels, `
+        SELECT id, player1_id, player2_id, winner_id, status, turn_count, match_id, game_number,
+               started_at, finished_at, created_at
+        FROM duels WHERE player1_id = ? OR player2_id = ?
+        ORDER BY created_at DESC
+    `, id, id)
+	if err != nil {
+		return nil, fmt.Errorf("対戦履歴取得エラー: %w", err)
+	}
+
+	err = r.db.SelectContext(ctx, &data.Matches, `
+        SELECT id, player1_id, player2_id, best_of, player1_wins, player2_wins, draws, status,
+               winner_id, finished_at, created_at
+        FROM matches WHERE player1_id = ? OR player2_id = ?
+        ORDER BY created_at DESC
+    `, id, id)
+	if err != nil {
+		return nil, fmt.Errorf("シリーズ履歴取得エラー: %w", err)
+	}
+
+	err = r.db.SelectContext(ctx, &data.Sanctions, `
+        SELECT `+sanctionColumns+` FROM user_sanctions
+        WHERE user_id = ? ORDER BY created_at DESC, id DESC
+    `, id)
+	if err != nil {
+		return nil, fmt.Errorf("制裁履歴取得エラー: %w", err)
+	}
+	return data, nil
+}
+
+// Delete はユーザーを削除し、対戦履歴を anonymousID の匿名のユーザーに付け替えます
+//
+// ユーザー名・所持カード・ログイン情報・マッチングの記録は削除します。
+// 対戦とシリーズは相手の履歴として、制裁の記録はモデレーションの記録として残し、
+// ユーザーを匿名のユーザーに置き換えます。
+// ユーザーが存在しない場合は ErrUserNotFound を、有効な制裁がある場合は
+// (削除して作り直すことで制裁を逃れられないよう) ErrUnderSanction を返します。
+func (r *UserRepository) Delete(ctx context.Context, id, anonymousID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("トランザクション開始エラー: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists string
+	err = tx.GetContext(ctx, &exists, `SELECT id FROM users WHERE id = ? FOR UPDATE`, id)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("ユーザー取得エラー: %w", err)
+	}
+
+	active, err := activeSanctions(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if len(active) > 0 {
+		return ErrUnderSanction
+	}
+
+	// 匿名のユーザーはユーザー名にもIDを使う (ユーザーが指定できない名前)
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO users (id, username, points, created_at, updated_at)
+        VALUES (?, ?, 0, ?, ?)
+    `, anonymousID, anonymousID, now, now)
+	if err := userInsertError(err); err != nil {
+		return err
+	}
+
+	for _, ref := range historyReferences {
+		query := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, ref.table, ref.column, ref.column)
+		if _, err := tx.ExecContext(ctx, query, anonymousID, id); err != nil {
+			return fmt.Errorf("%s.%s の匿名化エラー: %w", ref.table, ref.column, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE user_sanctions SET user_id = ? WHERE user_id = ?`, anonymousID, id)
+	if err != nil {
+		return fmt.Errorf("制裁の記録の匿名化エラー: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM matchmaking WHERE user_id = ?`, id); err != nil {
+		return fmt.Errorf("マッチング記録削除エラー: %w", err)
+	}
+	// 所持カード・ログイン情報は外部キーの ON DELETE CASCADE で削除される
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("ユーザー削除エラー: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("トランザクション確定エラー: %w", err)
+	}
+	return nil
+}
@@ -4,17 +4,37 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
+var (
+	// ErrUserExists は同じIDのユーザーが既に存在することを表します
+	ErrUserExists = errors.New("ユーザーは既に存在します")
+	// ErrUsernameTaken はユーザー名が既に使われていることを表します
+	ErrUsernameTaken = errors.New("ユーザー名は既に使われています")
+	// ErrUserNotFound はユーザーが存在しないことを表します
+	ErrUserNotFound = errors.New("ユーザーが見つかりません")
+	// ErrNotGuest はユーザーが存在しないか、既にゲストではないことを表します
+	ErrNotGuest = errors.New("ゲストアカウントが見つかりません")
+	// ErrUnderSanction は有効な制裁があるためアカウントを削除できないことを表します
+	ErrUnderSanction = errors.New("制裁期間中です")
+)
+
 // User はユーザー情報を表す構造体です
 type User struct {
 	ID        string    `db:"id" json:"id"`
 	Username  string    `db:"username" json:"username"`
 	Points    int       `db:"points" json:"points"`
+	Rating    float64   `db:"rating" json:"rating"`
+	RatingRD  float64   `db:"rating_rd" json:"ratingRd"`
+	IsGuest   bool      `db:"is_guest" json:"isGuest"`
+	Role      string    `db:"role" json:"role"`
 	CreatedAt time.Time `db:"created_at" json:"createdAt"`
 	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
 }
@@ -32,7 +52,7 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 // GetByID はユーザーIDに基づいてユーザーを取得します
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*User, error) {
 	var user User
-	query := `SELECT id, username, points, created_at, updated_at FROM users WHERE id = ?`
+	query := `SELECT id, username, points, rating, rating_rd, is_guest, role, created_at, updated_at FROM users WHERE id = ?`
 	err := r.db.GetContext(ctx, &user, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -46,21 +66,246 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*User, error)
 // Create は新しいユーザーを作成します
 func (r *UserRepository) Create(ctx context.Context, user *User) error {
 	query := `
-        INSERT INTO users (id, username, points, created_at, updated_at) 
-        VALUES (?, ?, ?, ?, ?)
+        INSERT INTO users (id, username, points, is_guest, created_at, updated_at) 
+        VALUES (?, ?, ?, ?, ?, ?)
     `
 	now := time.Now()
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
 	_, err := r.db.ExecContext(ctx, query,
-		user.ID, user.Username, user.Points, user.CreatedAt, user.UpdatedAt)
+		user.ID, user.Username, user.Points, user.IsGuest, user.CreatedAt, user.UpdatedAt)
+	if err := userInsertError(err); err != nil {
+		return err
+	}
+	return nil
+}
+
+// guestReferences はゲストアカウントを引き継ぐときに書き換える、ユーザーIDを持つ列です
+//
+// users を参照する列を追加した場合はここにも追加してください。
+var guestReferences = []struct{ table, column string }{
+	{"user_cards", "user_id"},
+	{"user_credentials", "user_id"},
+	{"user_sanctions", "user_id"},
+	{"duels", "player1_id"},
+	{"duels", "player2_id"},
+	{"duels", "winner_id"},
+	{"matches", "player1_id"},
+	{"matches", "player2_id"},
+	{"matches", "winner_id"},
+	{"matchmaking", "user_id"},
+}
+
+// TransferGuest はゲストアカウントを userID のアカウントとして引き継ぎます
+//
+// ユーザー名・レーティング・所持カード・対戦履歴を userID に移し、ゲストの行を削除します。
+// 認証ミドルウェアは連携先のユーザーの行を最初のリクエストで作成するため、userID のユーザーが
+// 作成されただけで一度も使われていない場合は、その行を削除して引き継ぎます。
+// userID のユーザーが使われている場合は ErrUserExists を、ゲストが見つからない場合は
+// ErrNotGuest を返します。
+func (r *UserRepository) TransferGuest(ctx context.Context, guestID, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("トランザクション開始エラー: %w", err)
+	}
+	defer tx.Rollback()
+
+	var guest struct {
+		Username string `db:"username"`
+		IsGuest  bool   `db:"is_guest"`
+	}
+	err = tx.GetContext(ctx, &guest, `SELECT username, is_guest FROM users WHERE id = ? FOR UPDATE`, guestID)
+	if err == sql.ErrNoRows || (err == nil && !guest.IsGuest) {
+		return ErrNotGuest
+	}
+	if err != nil {
+		return fmt.Errorf("ゲスト取得エラー: %w", err)
+	}
+
+	if err := deleteUnusedUser(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	// ユーザー名の一意制約を避けるため、ゲストの行の名前を退避してから新しい行を作る
+	_, err = tx.ExecContext(ctx, `UPDATE users SET username = ? WHERE id = ?`, "transferring-"+guestID, guestID)
+	if err != nil {
+		return fmt.Errorf("ゲスト更新エラー: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO users (id, username, points, rating, rating_rd, rating_volatility, is_guest, role, created_at, updated_at)
+        SELECT ?, ?, points, rating, rating_rd, rating_volatility, FALSE, role, created_at, ?
+        FROM users WHERE id = ?
+    `, userID, guest.Username, time.Now(), guestID)
+	if err := userInsertError(err); err != nil {
+		return err
+	}
+
+	for _, ref := range guestReferences {
+		query := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, ref.table, ref.column, ref.column)
+		if _, err := tx.ExecContext(ctx, query, userID, guestID); err != nil {
+			return fmt.Errorf("%s.%s の引き継ぎエラー: %w", ref.table, ref.column, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, guestID); err != nil {
+		return fmt.Errorf("ゲスト削除エラー: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("トランザクション確定エラー: %w", err)
+	}
+	return nil
+}
+
+// deleteUnusedUser は作成されただけで一度も使われていないユーザーの行を削除します
+//
+// ユーザーが存在しない場合は何もしません。所持カード・ログイン情報・対戦履歴などが
+// あるユーザーや、権限を変更したユーザーの場合は ErrUserExists を返します。
+func deleteUnusedUser(ctx context.Context, tx *sqlx.Tx, id string) error {
+	var user struct {
+		IsGuest bool   `db:"is_guest"`
+		Role    string `db:"role"`
+	}
+	err := tx.GetContext(ctx, &user, `SELECT is_guest, role FROM users WHERE id = ? FOR UPDATE`, id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("ユーザー作成エラー: %w", err)
+		return fmt.Errorf("ユーザー取得エラー: %w", err)
+	}
+	if user.IsGuest || user.Role != "player" {
+		return ErrUserExists
+	}
+
+	for _, ref := range guestReferences {
+		var used bool
+		query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE %s = ?)`, ref.table, ref.column)
+		if err := tx.GetContext(ctx, &used, query, id); err != nil {
+			return fmt.Errorf("%s.%s の確認エラー: %w", ref.table, ref.column, err)
+		}
+		if used {
+			return ErrUserExists
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("ユーザー削除エラー: %w", err)
 	}
 	return nil
 }
 
+// UserAccess はリクエストごとに確認するユーザーの権限と有効な制裁です
+type UserAccess struct {
+	Role string `db:"role"`
+	// IsGuest はゲストアカウントかです (連携済みのゲストの古いトークンを拒否するために使います)
+	IsGuest bool `db:"is_guest"`
+	// Block はアカウントの利用を禁止している制裁 (BAN・利用停止) のうち最も長く続くものです
+	Block *Sanction
+	// Mute はチャットを禁止している制裁のうち最も長く続くものです
+	Mute *Sanction
+}
+
+// Blocked はアカウントの利用が禁止されているかを返します
+func (a *UserAccess) Blocked() bool {
+	return a.Block != nil
+}
+
+// Muted はチャットが禁止されているかを返します
+func (a *UserAccess) Muted() bool {
+	return a.Mute != nil
+}
+
+// GetAccess はユーザーの権限と有効な制裁を取得します。ユーザーが存在しない場合は nil を返します
+func (r *UserRepository) GetAccess(ctx context.Context, id string) (*UserAccess, error) {
+	var access UserAccess
+	err := r.db.GetContext(ctx, &access, `SELECT role, is_guest FROM users WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("権限取得エラー: %w", err)
+	}
+
+	sanctions, err := activeSanctions(ctx, r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	access.applySanctions(sanctions)
+	return &access, nil
+}
+
+// applySanctions は有効な制裁のうち、利用の禁止 (BAN・利用停止) とチャット禁止それぞれで
+// 最も長く続くものを設定します
+func (a *UserAccess) applySanctions(sanctions []Sanction) {
+	for i := range sanctions {
+		s := &sanctions[i]
+		switch {
+		case s.Blocks() && s.outlasts(a.Block):
+			a.Block = s
+		case s.Kind == SanctionMute && s.outlasts(a.Mute):
+			a.Mute = s
+		}
+	}
+}
+
+// SetRole はユーザーの権限を変更します。ユーザーが存在しない場合は ErrUserNotFound を返します
+func (r *UserRepository) SetRole(ctx context.Context, id, role string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET role = ?, updated_at = ? WHERE id = ?`, role, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("権限更新エラー: %w", err)
+	}
+	// 値が変わらない場合も0件になるため、存在を確認し直す
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		user, err := r.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return ErrUserNotFound
+		}
+	}
+	return nil
+}
+
+// UpdateUsername はユーザー名を変更します
+//
+// ユーザー名が使われている場合は ErrUsernameTaken を返します。
+func (r *UserRepository) UpdateUsername(ctx context.Context, id, username string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET username = ?, updated_at = ? WHERE id = ?`, username, time.Now(), id)
+	if isDuplicateKey(err, "uq_users_username") {
+		return ErrUsernameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("ユーザー名更新エラー: %w", err)
+	}
+	return nil
+}
+
+// userInsertError は users へのINSERTのエラーを ErrUserExists・ErrUsernameTaken に変換します
+func userInsertError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if isDuplicateKey(err, "uq_users_username") {
+		return ErrUsernameTaken
+	}
+	if isDuplicateKey(err, "PRIMARY") {
+		return ErrUserExists
+	}
+	return fmt.Errorf("ユーザー作成エラー: %w", err)
+}
+
+// isDuplicateKey はerrがインデックスkeyの一意制約違反かを返します
+func isDuplicateKey(err error, key string) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlErrDuplicateEntry {
+		return false
+	}
+	// MySQL 8.0 のメッセージは "for key 'users.uq_users_username'"、5.7 は "for key 'uq_users_username'"
+	return strings.HasSuffix(mysqlErr.Message, "'"+key+"'") || strings.HasSuffix(mysqlErr.Message, "."+key+"'")
+}
+
 // Update はユーザー情報を更新します
 func (r *UserRepository) Update(ctx context.Context, user *User) error {
 	query := `
@@ -77,3 +322,68 @@ func (r *UserRepository) Update(ctx context.Context, user *User) error {
 	}
 	return nil
 }
+
+// UserRating はユーザーのGlicko-2レーティングです
+type UserRating struct {
+	ID         string  `db:"id"`
+	Rating     float64 `db:"rating"`
+	RD         float64 `db:"rating_rd"`
+	Volatility float64 `db:"rating_volatility"`
+}
+
+// GetRating はユーザーのレーティングを取得します
+func (r *UserRepository) GetRating(ctx context.Context, id string) (*UserRating, error) {
+	var rating UserRating
+	query := `SELECT id, rating, rating_rd, rating_volatility FROM users WHERE id = ?`
+	err := r.db.GetContext(ctx, &rating, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("レーティング取得エラー: %w", err)
+	}
+	return &rating, nil
+}
+
+// UpdateRatings は複数ユーザーのレーティングを行ロックを取って読み込み、update で変更した値を保存します
+//
+// 存在しないユーザーは update に渡すmapに含まれません。points は rating を丸めた値に更新します。
+func (r *UserRepository) UpdateRatings(ctx context.Context, ids []string, update func(ratings map[string]*UserRating)) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("トランザクション開始エラー: %w", err)
+	}
+	defer tx.Rollback()
+
+	query, args, err := sqlx.In(`SELECT id, rating, rating_rd, rating_volatility FROM users WHERE id IN (?) ORDER BY id FOR UPDATE`, ids)
+	if err != nil {
+		return fmt.Errorf("レーティング取得エラー: %w", err)
+	}
+	var rows []UserRating
+	if err := tx.SelectContext(ctx, &rows, tx.Rebind(query), args...); err != nil {
+		return fmt.Errorf("レーティング取得エラー: %w", err)
+	}
+
+	ratings := make(map[string]*UserRating, len(rows))
+	for i := range rows {
+		ratings[rows[i].ID] = &rows[i]
+	}
+	update(ratings)
+
+	now := time.Now()
+	for _, rating := range ratings {
+		_, err := tx.ExecContext(ctx, `
+        UPDATE users
+        SET rating = ?, rating_rd = ?, rating_volatility = ?, points = ROUND(?), updated_at = ?
+        WHERE id = ?
+    `, rating.Rating, rating.RD, rating.Volatility, rating.Rating, now, rating.ID)
+		if err != nil {
+			return fmt.Errorf("レーティング更新エラー: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("レーティング更新エラー: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// backend/internal/db/user_repo_test.go
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplySanctionsKeepsLongest(t *testing.T) {
+	now := time.Now()
+	hour, week := now.Add(time.Hour), now.Add(7*24*time.Hour)
+
+	tests := []struct {
+		name      string
+		sanctions []Sanction
+		wantBlock int64 // 0 なら nil
+		wantMute  int64
+	}{
+		{"制裁なし", nil, 0, 0},
+		{"期限の遅い利用停止", []Sanction{
+			{ID: 1, Kind: SanctionSuspend, ExpiresAt: &hour},
+			{ID: 2, Kind: SanctionSuspend, ExpiresAt: &week},
+		}, 2, 0},
+		{"期限なしのBANは期限付きより長い", []Sanction{
+			{ID: 1, Kind: SanctionSuspend, ExpiresAt: &week},
+			{ID: 2, Kind: SanctionBan},
+			{ID: 3, Kind: SanctionBan, ExpiresAt: &hour},
+		}, 2, 0},
+		{"期限なし同士は先のもの", []Sanction{
+			{ID: 1, Kind: SanctionBan},
+			{ID: 2, Kind: SanctionBan},
+		}, 1, 0},
+		{"チャット禁止は利用の禁止と別に選ぶ", []Sanction{
+			{ID: 1, Kind: SanctionMute},
+			{ID: 2, Kind: SanctionSuspend, ExpiresAt: &hour},
+			{ID: 3, Kind: SanctionMute, ExpiresAt: &week},
+		}, 2, 1},
+	}
+	for _, tt := range tests {
+		var access UserAccess
+		access.applySanctions(tt.sanctions)
+		if got := sanctionID(access.Block); got != tt.wantBlock {
+			t.Errorf("%s: Block = #%d; want #%d", tt.name, got, tt.wantBlock)
+		}
+		if got := sanctionID(access.Mute); got != tt.wantMute {
+			t.Errorf("%s: Mute = #%d; want #%d", tt.name, got, tt.wantMute)
+		}
+		if access.Blocked() != (tt.wantBlock != 0) {
+			t.Errorf("%s: Blocked = %v; want %v", tt.name, access.Blocked(), tt.wantBlock != 0)
+		}
+	}
+}
+
+// sanctionID は制裁のIDを返します。nil の場合は 0 を返します
+func sanctionID(s *Sanction) int64 {
+	if s == nil {
+		return 0
+	}
+	return s.ID
+}
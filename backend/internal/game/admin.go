@@ -0,0 +1,125 @@
+// backend/internal/game/admin.go
+package game
+
+import (
+	"errors"
+	"log"
+	"sort"
+)
+
+var (
+	// ErrDuelNotFound は対戦がこのインスタンスにないことを表します
+	ErrDuelNotFound = errors.New("対戦が見つかりません")
+	// ErrDuelNotActive は対戦が既に終了していることを表します
+	ErrDuelNotActive = errors.New("対戦は進行中ではありません")
+	// ErrInvalidWinner は勝者が対戦のプレイヤーではないことを表します
+	ErrInvalidWinner = errors.New("勝者は対戦のプレイヤーを指定してください")
+)
+
+// LiveDuels はこのインスタンスで進行中の対戦のスナップショットをID順に返します
+func (ds *DuelService) LiveDuels() []*Duel {
+	ds.mu.RLock()
+	duels := make([]*Duel, 0, len(ds.duels))
+	for _, duel := range ds.duels {
+		if duel.Status == "active" {
+			duels = append(duels, duel.Clone())
+		}
+	}
+	ds.mu.RUnlock()
+
+	sort.Slice(duels, func(i, j int) bool { return duels[i].ID < duels[j].ID })
+	return duels
+}
+
+// ForceEnd は進行中の対戦を管理者の操作で終了させます
+//
+// winnerID が空の場合は引き分けとして終了します。unrated が true の場合は
+// レーティングに反映しません。通常の終了と同じく更新・終了のコールバックを呼び出します。
+func (ds *DuelService) ForceEnd(duelID, winnerID string, unrated bool) (*Duel, error) {
+	ds.mu.Lock()
+	duel, exists := ds.duels[duelID]
+	if !exists {
+		ds.mu.Unlock()
+		return nil, ErrDuelNotFound
+	}
+	if duel.Status != "active" {
+		ds.mu.Unlock()
+		return nil, ErrDuelNotActive
+	}
+	if winnerID != "" && winnerID != duel.Players[0].UserID && winnerID != duel.Players[1].UserID {
+		ds.mu.Unlock()
+		return nil, ErrInvalidWinner
+	}
+
+	duel.Status = "finished"
+	duel.FinishedAt = finishedNow()
+	duel.WinnerID = winnerID
+	duel.Unrated = duel.Unrated || unrated
+	duel.Version++
+	snapshot := duel.Clone()
+	ds.mu.Unlock()
+
+	log.Printf("対戦 %s を強制終了しました (勝者: %q, レーティング対象外: %t)", duelID, winnerID, snapshot.Unrated)
+	if ds.onUpdate != nil {
+		ds.onUpdate(snapshot)
+	}
+	for _, callback := range ds.onFinish {
+		callback(snapshot)
+	}
+	return snapshot, nil
+}
+
+// Forfeit はユーザーがこのインスタンスで参加している進行中の対戦を、相手の勝ちとして終了させます
+//
+// 対戦には時間切れがないため、アカウントの削除・連携でユーザーIDが使えなくなるときに
+// 呼び出して終わらせます。レーティングには反映しません。
+func (ds *DuelService) Forfeit(userID string) {
+	type forfeit struct{ duelID, winnerID string }
+	var forfeits []forfeit
+	ds.mu.RLock()
+	for _, duel := range ds.duels {
+		if duel.Status != "active" {
+			continue
+		}
+		switch userID {
+		case duel.Players[0].UserID:
+			forfeits = append(forfeits, forfeit{duel.ID, duel.Players[1].UserID})
+		case duel.Players[1].UserID:
+			forfeits = append(forfeits, forfeit{duel.ID, duel.Players[0].UserID})
+		}
+	}
+	ds.mu.RUnlock()
+
+	for _, f := range forfeits {
+		// 確認してから終了させるまでに通常どおり終了した対戦は ErrDuelNotActive になる
+		if _, err := ds.ForceEnd(f.duelID, f.winnerID, true); err != nil && !errors.Is(err, ErrDuelNotActive) {
+			log.Printf("対戦 %s の終了エラー (ユーザー: %s): %v", f.duelID, userID, err)
+		}
+	}
+}
+
+// UpdateCard はカードプールのカードを置き換えます。これから作成する対戦に反映されます
+//
+// カードプールにないIDの場合は false を返します。
+func (ds *DuelService) UpdateCard(card Card) bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for i := range ds.cardPool {
+		if ds.cardPool[i].ID == card.ID {
+			// 作成中の対戦が古いスライスを読んでいても影響しないようにコピーしてから置き換える
+			pool := append([]Card(nil), ds.cardPool...)
+			pool[i] = card
+			ds.cardPool = pool
+			return true
+		}
+	}
+	return false
+}
+
+// cards は現在のカードプールを返します
+func (ds *DuelService) cards() []Card {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.cardPool
+}
@@ -0,0 +1,40 @@
+// backend/internal/game/admin_test.go
+package game
+
+import "testing"
+
+func TestForfeit(t *testing.T) {
+	ds := NewDuelService([]Card{{ID: 1, Name: "テスト", AttackPts: 1, DefensePts: 1}})
+	first, err := ds.CreateDuel("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ds.CreateDuel("carol", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ds.CreateDuel("carol", "dave")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// alice の進行中の対戦だけを相手の勝ちとして終了させる
+	ds.Forfeit("alice")
+	for duelID, winner := range map[string]string{first: "bob", second: "carol"} {
+		duel, err := ds.GetDuel(duelID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if duel.Status != "finished" || duel.WinnerID != winner || !duel.Unrated {
+			t.Errorf("対戦 %s = %s (勝者 %q, レーティング対象外 %t), want finished (勝者 %q, 対象外)",
+				duelID, duel.Status, duel.WinnerID, duel.Unrated, winner)
+		}
+	}
+	duel, err := ds.GetDuel(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duel.Status != "active" {
+		t.Errorf("他のユーザーの対戦 = %s, want active", duel.Status)
+	}
+}
@@ -0,0 +1,503 @@
+// backend/internal/game/match.go
+package game
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/google/uuid"
+)
+
+// シリーズのステータス
+const (
+	// MatchActive はシリーズの試合が進行中です
+	MatchActive = "active"
+	// MatchChoosing は前の試合の敗者が次の試合の先手・後手を選んでいます
+	MatchChoosing = "choosing"
+	// MatchFinished はどちらかが必要な勝利数に達してシリーズが終了しました
+	MatchFinished = "finished"
+)
+
+const (
+	// maxBestOf はシリーズの最大試合数です
+	maxBestOf = 7
+	// firstChoiceTimeout は先手・後手を選ぶ制限時間です。過ぎた場合は選ぶ側が先手になります
+	firstChoiceTimeout = 30 * time.Second
+	// finishedMatchRetention は終了したシリーズをメモリに保持する期間です
+	finishedMatchRetention = 10 * time.Minute
+	// matchSaveTimeout はシリーズの保存1回あたりのタイムアウトです
+	matchSaveTimeout = 5 * time.Second
+)
+
+var (
+	// ErrMatchNotFound はシリーズが見つからないことを表します
+	ErrMatchNotFound = errors.New("match not found")
+	// ErrInvalidBestOf はシリーズの試合数が不正であることを表します
+	ErrInvalidBestOf = errors.New("bestOf must be an odd number between 1 and 7")
+	// ErrNotChooser は先手・後手を選べるプレイヤーでないか、選ぶ段階でないことを表します
+	ErrNotChooser = errors.New("not allowed to choose the first player")
+)
+
+// Match は同じ2人のプレイヤーが続けて行う Best-of-N のシリーズです
+type Match struct {
+	ID            string     `json:"id"`
+	Players       [2]string  `json:"players"` // シリーズ開始時の順 (Wins の添字と対応)
+	BestOf        int        `json:"bestOf"`
+	Wins          [2]int     `json:"wins"`
+	Draws         int        `json:"draws"`
+	DuelIDs       []string   `json:"duelIds"` // 各試合の対戦ID (試合順)
+	CurrentDuelID string     `json:"currentDuelId,omitempty"`
+	Status        string     `json:"status"`              // "active", "choosing", "finished"
+	ChooserID     string     `json:"chooserId,omitempty"` // 次の試合の先手・後手を選ぶプレイヤー
+	ChooseBy      *time.Time `json:"chooseBy,omitempty"`  // 先手・後手を選ぶ期限
+	WinnerID      string     `json:"winnerId,omitempty"`  // 引き分けで終了した場合は空
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+}
+
+// WinsNeeded はシリーズの勝利に必要な勝利数です
+func (m *Match) WinsNeeded() int {
+	return m.BestOf/2 + 1
+}
+
+// ValidBestOf はシリーズの試合数として使える値かを返します
+func ValidBestOf(bestOf int) bool {
+	return bestOf >= 1 && bestOf <= maxBestOf && bestOf%2 == 1
+}
+
+// MatchService は Best-of-N のシリーズを管理します
+//
+// 各試合は通常の対戦として DuelService で作成し、対戦終了時にスコアを更新します。
+// 負けたプレイヤー (引き分けの場合は後手だったプレイヤー) が次の試合の先手・後手を選び、
+// どちらかが必要な勝利数に達するとシリーズを終了します。
+type MatchService struct {
+	duels *DuelService
+	repo  *db.MatchRepository // nil ならメモリのみ
+
+	mu          sync.Mutex
+	matches     map[string]*Match
+	stored      map[string]bool   // DBに保存できたシリーズ
+	duelToMatch map[string]string // duelID -> matchID
+	timers      map[string]*time.Timer
+	onUpdate    func(match *Match)
+	writes      *writeQueue // DBへの保存 (試合の追加・結果・スコアを順に書き込む)
+}
+
+// NewMatchService は新しいシリーズ管理サービスを作成します
+//
+// 対戦終了の通知を受け取るため DuelService に終了コールバックを登録します。
+func NewMatchService(duels *DuelService, repo *db.MatchRepository) *MatchService {
+	ms := &MatchService{
+		duels:       duels,
+		repo:        repo,
+		matches:     make(map[string]*Match),
+		stored:      make(map[string]bool),
+		duelToMatch: make(map[string]string),
+		timers:      make(map[string]*time.Timer),
+		writes:      newWriteQueue("シリーズ"),
+	}
+	duels.AddFinishCallback(ms.onDuelFinished)
+	return ms
+}
+
+// SetUpdateCallback はシリーズの状態が変わったときのコールバックを設定します
+func (ms *MatchService) SetUpdateCallback(callback func(match *Match)) {
+	ms.onUpdate = callback
+}
+
+// StartMatch はシリーズを作成し、duelID で1試合目を開始します (player1 が先手)
+func (ms *MatchService) StartMatch(duelID, player1ID, player2ID string, bestOf int) (*Match, error) {
+	if !ValidBestOf(bestOf) {
+		return nil, ErrInvalidBestOf
+	}
+
+	now := time.Now()
+	match := &Match{
+		ID:            uuid.New().String(),
+		Players:       [2]string{player1ID, player2ID},
+		BestOf:        bestOf,
+		DuelIDs:       []string{duelID},
+		CurrentDuelID: duelID,
+		Status:        MatchActive,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	ms.mu.Lock()
+	ms.matches[match.ID] = match
+	ms.duelToMatch[duelID] = match.ID
+	started := match.clone()
+	ms.mu.Unlock()
+
+	ms.writes.enqueue(func() {
+		ms.saveNew(started)
+		ms.saveGame(started, player1ID, player2ID)
+	})
+	if err := ms.duels.CreateDuelWithOptions(duelID, player1ID, player2ID, DuelOptions{MatchID: match.ID}); err != nil {
+		ms.mu.Lock()
+		delete(ms.matches, match.ID)
+		delete(ms.duelToMatch, duelID)
+		ms.mu.Unlock()
+		return nil, err
+	}
+
+	log.Printf("シリーズ %s を開始しました (Best-of-%d: %s vs %s)", match.ID, bestOf, player1ID, player2ID)
+	ms.notify(started)
+	return started, nil
+}
+
+// GetMatch はシリーズの現在の状態を返します
+func (ms *MatchService) GetMatch(matchID string) (*Match, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	match, ok := ms.matches[matchID]
+	if !ok {
+		return nil, ErrMatchNotFound
+	}
+	return match.clone(), nil
+}
+
+// HasMatch はこのインスタンスがシリーズを保持しているかを返します
+func (ms *MatchService) HasMatch(matchID string) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	_, ok := ms.matches[matchID]
+	return ok
+}
+
+// MatchIDs はこのインスタンスが保持している終了していないシリーズのID一覧を返します
+func (ms *MatchService) MatchIDs() []string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ids := make([]string, 0, len(ms.matches))
+	for id, match := range ms.matches {
+		if match.Status != MatchFinished {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// InSeries はユーザーがこのインスタンスで終了していないシリーズに参加しているかを返します
+//
+// 試合の合間 (先手・後手の選択中) も参加中として扱います。
+func (ms *MatchService) InSeries(userID string) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, match := range ms.matches {
+		if match.Status != MatchFinished && (match.Players[0] == userID || match.Players[1] == userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChooseFirst は前の試合の敗者が次の試合で先手 (goFirst) か後手かを選び、次の試合を開始します
+func (ms *MatchService) ChooseFirst(matchID, userID string, goFirst bool) (*Match, error) {
+	ms.mu.Lock()
+	match, ok := ms.matches[matchID]
+	if !ok {
+		ms.mu.Unlock()
+		return nil, ErrMatchNotFound
+	}
+	if match.Status != MatchChoosing || match.ChooserID != userID {
+		ms.mu.Unlock()
+		return nil, ErrNotChooser
+	}
+
+	first, second := userID, match.opponentOf(userID)
+	if !goFirst {
+		first, second = second, first
+	}
+
+	if timer, ok := ms.timers[matchID]; ok {
+		timer.Stop()
+		delete(ms.timers, matchID)
+	}
+	duelID := uuid.New().String()
+	match.DuelIDs = append(match.DuelIDs, duelID)
+	match.CurrentDuelID = duelID
+	match.Status = MatchActive
+	match.ChooserID = ""
+	match.ChooseBy = nil
+	match.UpdatedAt = time.Now()
+	ms.duelToMatch[duelID] = matchID
+	started := match.clone()
+	ms.mu.Unlock()
+
+	ms.writes.enqueue(func() {
+		ms.saveGame(started, first, second)
+		ms.saveScore(started)
+	})
+	if err := ms.duels.CreateDuelWithOptions(duelID, first, second, DuelOptions{MatchID: matchID}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("シリーズ %s の第%d試合を開始しました (先手: %s)", matchID, len(started.DuelIDs), first)
+	ms.notify(started)
+	return started, nil
+}
+
+// CleanupFinishedMatches は終了してから一定時間経ったシリーズをメモリから削除します
+func (ms *MatchService) CleanupFinishedMatches() {
+	now := time.Now()
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for id, match := range ms.matches {
+		if match.FinishedAt == nil || now.Sub(*match.FinishedAt) < finishedMatchRetention {
+			continue
+		}
+		for _, duelID := range match.DuelIDs {
+			delete(ms.duelToMatch, duelID)
+		}
+		delete(ms.matches, id)
+		delete(ms.stored, id)
+	}
+}
+
+// onDuelFinished は終了した対戦の結果でシリーズのスコアを更新します
+func (ms *MatchService) onDuelFinished(duel *Duel) {
+	if duel.MatchID == "" {
+		return
+	}
+
+	ms.mu.Lock()
+	match, ok := ms.matches[duel.MatchID]
+	if !ok || match.Status != MatchActive || match.CurrentDuelID != duel.ID {
+		ms.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	match.UpdatedAt = now
+	match.CurrentDuelID = ""
+	switch duel.WinnerID {
+	case match.Players[0]:
+		match.Wins[0]++
+	case match.Players[1]:
+		match.Wins[1]++
+	default:
+		match.Draws++
+	}
+
+	switch {
+	case match.Wins[0] >= match.WinsNeeded():
+		match.finish(match.Players[0], now)
+	case match.Wins[1] >= match.WinsNeeded():
+		match.finish(match.Players[1], now)
+	case len(match.DuelIDs) >= match.BestOf*2:
+		// 引き分けが続いた場合は試合数の上限で打ち切り、勝ち数の多い方を勝者とする
+		winnerID := ""
+		if match.Wins[0] > match.Wins[1] {
+			winnerID = match.Players[0]
+		} else if match.Wins[1] > match.Wins[0] {
+			winnerID = match.Players[1]
+		}
+		match.finish(winnerID, now)
+	default:
+		// 敗者が次の試合の先手・後手を選ぶ。引き分けの場合は後手だったプレイヤーが選ぶ
+		chooserID := duel.Players[1].UserID
+		if duel.WinnerID != "" {
+			chooserID = match.opponentOf(duel.WinnerID)
+		}
+		chooseBy := now.Add(firstChoiceTimeout)
+		match.Status = MatchChoosing
+		match.ChooserID = chooserID
+		match.ChooseBy = &chooseBy
+
+		matchID, gameCount := match.ID, len(match.DuelIDs)
+		ms.timers[match.ID] = time.AfterFunc(firstChoiceTimeout, func() {
+			ms.expireChoice(matchID, gameCount)
+		})
+	}
+	updated := match.clone()
+	ms.mu.Unlock()
+
+	// 対戦のgoroutineから呼ばれるため、DBへの保存は書き込みキューで行う
+	ms.writes.enqueue(func() {
+		ms.saveResult(updated, duel)
+		ms.saveScore(updated)
+	})
+	if updated.Status == MatchFinished {
+		log.Printf("シリーズ %s が終了しました (勝者: %s, スコア: %d-%d)", updated.ID, updated.WinnerID, updated.Wins[0], updated.Wins[1])
+	}
+	ms.notify(updated)
+}
+
+// expireChoice は先手・後手を選ばないまま期限が過ぎた場合に、選ぶ側を先手として次の試合を開始します
+func (ms *MatchService) expireChoice(matchID string, gameCount int) {
+	ms.mu.Lock()
+	match, ok := ms.matches[matchID]
+	if !ok || match.Status != MatchChoosing || len(match.DuelIDs) != gameCount {
+		ms.mu.Unlock()
+		return
+	}
+	delete(ms.timers, matchID)
+	chooserID := match.ChooserID
+	ms.mu.Unlock()
+
+	log.Printf("シリーズ %s の先手・後手の選択期限が切れました (選択者: %s)", matchID, chooserID)
+	if _, err := ms.ChooseFirst(matchID, chooserID, true); err != nil {
+		log.Printf("シリーズの次の試合の開始エラー (シリーズ: %s): %v", matchID, err)
+	}
+}
+
+// notify はシリーズの状態変化を通知します
+func (ms *MatchService) notify(match *Match) {
+	if ms.onUpdate != nil {
+		ms.onUpdate(match)
+	}
+}
+
+// saveNew はシリーズをDBに追加します
+//
+// 未登録ユーザーを含む場合など保存できなかったシリーズは、以降もメモリのみで管理します。
+func (ms *MatchService) saveNew(match *Match) {
+	if ms.repo == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), matchSaveTimeout)
+	defer cancel()
+
+	if err := ms.repo.Insert(ctx, match.entry()); err != nil {
+		log.Printf("シリーズの保存エラー (シリーズ: %s): %v", match.ID, err)
+		return
+	}
+	ms.mu.Lock()
+	ms.stored[match.ID] = true
+	ms.mu.Unlock()
+}
+
+// saveGame はシリーズの最新の試合をDBに追加します
+func (ms *MatchService) saveGame(match *Match, player1ID, player2ID string) {
+	if !ms.isStored(match.ID) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), matchSaveTimeout)
+	defer cancel()
+
+	duelID := match.DuelIDs[len(match.DuelIDs)-1]
+	if err := ms.repo.InsertGame(ctx, match.ID, len(match.DuelIDs), duelID, player1ID, player2ID); err != nil {
+		log.Printf("シリーズの試合の保存エラー (シリーズ: %s): %v", match.ID, err)
+	}
+}
+
+// saveResult は終了した試合の結果をDBに保存します
+func (ms *MatchService) saveResult(match *Match, duel *Duel) {
+	if !ms.isStored(match.ID) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), matchSaveTimeout)
+	defer cancel()
+
+	if err := ms.repo.FinishGame(ctx, duel.ID, duel.WinnerID, duel.TurnCount); err != nil {
+		log.Printf("シリーズの試合結果の保存エラー (対戦: %s): %v", duel.ID, err)
+	}
+}
+
+// saveScore はシリーズのスコアと状態をDBに保存します
+func (ms *MatchService) saveScore(match *Match) {
+	if !ms.isStored(match.ID) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), matchSaveTimeout)
+	defer cancel()
+
+	if err := ms.repo.Update(ctx, match.entry()); err != nil {
+		log.Printf("シリーズのスコア保存エラー (シリーズ: %s): %v", match.ID, err)
+	}
+}
+
+// isStored はシリーズがDBに保存されているかを返します
+func (ms *MatchService) isStored(matchID string) bool {
+	if ms.repo == nil {
+		return false
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.stored[matchID]
+}
+
+// finish はシリーズを終了状態にします
+func (m *Match) finish(winnerID string, now time.Time) {
+	m.Status = MatchFinished
+	m.WinnerID = winnerID
+	m.ChooserID = ""
+	m.ChooseBy = nil
+	m.FinishedAt = &now
+}
+
+// opponentOf はシリーズでの userID の相手を返します
+func (m *Match) opponentOf(userID string) string {
+	if m.Players[0] == userID {
+		return m.Players[1]
+	}
+	return m.Players[0]
+}
+
+// clone はシリーズのコピーを返します
+func (m *Match) clone() *Match {
+	copied := *m
+	copied.DuelIDs = append([]string(nil), m.DuelIDs...)
+	return &copied
+}
+
+// entry はシリーズをDBの行に変換します
+func (m *Match) entry() *db.MatchEntry {
+	entry := &db.MatchEntry{
+		ID:          m.ID,
+		Player1ID:   m.Players[0],
+		Player2ID:   m.Players[1],
+		BestOf:      m.BestOf,
+		Player1Wins: m.Wins[0],
+		Player2Wins: m.Wins[1],
+		Draws:       m.Draws,
+		Status:      m.Status,
+		WinnerID:    sql.NullString{String: m.WinnerID, Valid: m.WinnerID != ""},
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+	if m.FinishedAt != nil {
+		entry.FinishedAt = sql.NullTime{Time: *m.FinishedAt, Valid: true}
+	}
+	return entry
+}
+
+// MatchFromEntry はDBに保存されたシリーズと試合一覧から Match を組み立てます
+func MatchFromEntry(entry *db.MatchEntry, games []db.MatchGame) *Match {
+	match := &Match{
+		ID:        entry.ID,
+		Players:   [2]string{entry.Player1ID, entry.Player2ID},
+		BestOf:    entry.BestOf,
+		Wins:      [2]int{entry.Player1Wins, entry.Player2Wins},
+		Draws:     entry.Draws,
+		DuelIDs:   make([]string, 0, len(games)),
+		Status:    entry.Status,
+		WinnerID:  entry.WinnerID.String,
+		CreatedAt: entry.CreatedAt,
+		UpdatedAt: entry.UpdatedAt,
+	}
+	for _, game := range games {
+		match.DuelIDs = append(match.DuelIDs, game.DuelID)
+		if game.Status != "finished" {
+			match.CurrentDuelID = game.DuelID
+		}
+	}
+	if entry.FinishedAt.Valid {
+		finishedAt := entry.FinishedAt.Time
+		match.FinishedAt = &finishedAt
+	}
+	return match
+}
@@ -0,0 +1,80 @@
+// backend/internal/game/match_api.go
+package game
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// FirstChooser はシリーズの先手・後手の選択を担当インスタンスで処理します
+type FirstChooser interface {
+	ChooseFirst(matchID, userID string, goFirst bool) error
+}
+
+// MatchAPI はBest-of-NのシリーズをRESTで公開します
+type MatchAPI struct {
+	Service *MatchService
+	Repo    *db.MatchRepository // このインスタンスにないシリーズの参照先 (nil可)
+	Chooser FirstChooser
+}
+
+func NewMatchAPI(service *MatchService, repo *db.MatchRepository, chooser FirstChooser) *MatchAPI {
+	return &MatchAPI{Service: service, Repo: repo, Chooser: chooser}
+}
+
+// GET /api/matches/:id
+func (api *MatchAPI) Get(c echo.Context) error {
+	matchID := c.Param("id")
+	if match, err := api.Service.GetMatch(matchID); err == nil {
+		return c.JSON(http.StatusOK, match)
+	}
+	if api.Repo == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "シリーズが見つかりません")
+	}
+
+	ctx := c.Request().Context()
+	entry, err := api.Repo.GetByID(ctx, matchID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "シリーズの取得に失敗しました")
+	}
+	if entry == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "シリーズが見つかりません")
+	}
+	games, err := api.Repo.ListGames(ctx, matchID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "シリーズの取得に失敗しました")
+	}
+	return c.JSON(http.StatusOK, MatchFromEntry(entry, games))
+}
+
+// POST /api/matches/:id/first
+func (api *MatchAPI) ChooseFirst(c echo.Context) error {
+	userID := c.Get("uid").(string)
+	matchID := c.Param("id")
+	var req struct {
+		GoFirst *bool `json:"goFirst"`
+	}
+	if err := c.Bind(&req); err != nil || req.GoFirst == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "goFirstが必要です")
+	}
+
+	err := api.Chooser.ChooseFirst(matchID, userID, *req.GoFirst)
+	switch {
+	case errors.Is(err, ErrMatchNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "シリーズが見つかりません")
+	case errors.Is(err, ErrNotChooser):
+		return echo.NewHTTPError(http.StatusConflict, "先手・後手を選べる状態ではありません")
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, "次の試合の開始に失敗しました")
+	}
+
+	// 別インスタンスに転送した場合は結果を matchUpdate で通知する
+	match, err := api.Service.GetMatch(matchID)
+	if err != nil {
+		return c.NoContent(http.StatusAccepted)
+	}
+	return c.JSON(http.StatusOK, match)
+}
@@ -0,0 +1,220 @@
+// backend/internal/game/match_test.go
+package game
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+)
+
+// newTestMatch は alice (先手) と bob の Best-of-bestOf のシリーズを開始します
+func newTestMatch(t *testing.T, bestOf int) (*MatchService, *Match) {
+	t.Helper()
+	ds := NewDuelService([]Card{{ID: 1, Name: "テスト", AttackPts: 1, DefensePts: 1}})
+	ms := NewMatchService(ds, nil)
+	match, err := ms.StartMatch("d1", "alice", "bob", bestOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ms.mu.Lock()
+		defer ms.mu.Unlock()
+		for _, timer := range ms.timers {
+			timer.Stop()
+		}
+	})
+	return ms, match
+}
+
+// finishGame は現在の試合を winnerID の勝ち (空なら引き分け) で終了させ、シリーズの状態を返します
+func finishGame(t *testing.T, ms *MatchService, matchID, winnerID string) *Match {
+	t.Helper()
+	match, err := ms.GetMatch(matchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ms.duels.ForceEnd(match.CurrentDuelID, winnerID, true); err != nil {
+		t.Fatal(err)
+	}
+	if match, err = ms.GetMatch(matchID); err != nil {
+		t.Fatal(err)
+	}
+	return match
+}
+
+// firstPlayer は試合の先手のユーザーIDを返します
+func firstPlayer(t *testing.T, ms *MatchService, duelID string) string {
+	t.Helper()
+	duel, err := ms.duels.GetDuel(duelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return duel.Players[0].UserID
+}
+
+func TestStartMatchValidatesBestOf(t *testing.T) {
+	ms := NewMatchService(NewDuelService(nil), nil)
+	for _, bestOf := range []int{0, 2, 9, -1} {
+		if _, err := ms.StartMatch("d1", "alice", "bob", bestOf); !errors.Is(err, ErrInvalidBestOf) {
+			t.Errorf("StartMatch(bestOf=%d) = %v; want %v", bestOf, err, ErrInvalidBestOf)
+		}
+	}
+}
+
+func TestMatchLoserChoosesFirst(t *testing.T) {
+	ms, match := newTestMatch(t, 3)
+
+	updated := finishGame(t, ms, match.ID, "alice")
+	if updated.Wins != [2]int{1, 0} || updated.Status != MatchChoosing || updated.ChooserID != "bob" || updated.ChooseBy == nil {
+		t.Fatalf("1試合目の後 = %+v; want 1-0 で bob が選択中", updated)
+	}
+
+	// 勝者は選べない
+	if _, err := ms.ChooseFirst(match.ID, "alice", true); !errors.Is(err, ErrNotChooser) {
+		t.Errorf("勝者の ChooseFirst = %v; want %v", err, ErrNotChooser)
+	}
+
+	// 敗者が後手を選ぶと、勝者が先手になる
+	next, err := ms.ChooseFirst(match.ID, "bob", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Status != MatchActive || len(next.DuelIDs) != 2 || next.ChooserID != "" {
+		t.Errorf("2試合目の開始後 = %+v; want 2試合目が進行中", next)
+	}
+	if first := firstPlayer(t, ms, next.CurrentDuelID); first != "alice" {
+		t.Errorf("2試合目の先手 = %s; want alice", first)
+	}
+
+	// 必要な勝利数 (2) に達するとシリーズが終わる
+	updated = finishGame(t, ms, match.ID, "alice")
+	if updated.Status != MatchFinished || updated.WinnerID != "alice" || updated.Wins != [2]int{2, 0} || updated.FinishedAt == nil {
+		t.Errorf("2試合目の後 = %+v; want alice の勝ちで終了", updated)
+	}
+	if ms.InSeries("alice") {
+		t.Error("終了後も InSeries = true")
+	}
+}
+
+func TestMatchDrawSecondPlayerChooses(t *testing.T) {
+	ms, match := newTestMatch(t, 3)
+
+	// 引き分けの場合は後手だった bob が選ぶ
+	updated := finishGame(t, ms, match.ID, "")
+	if updated.Draws != 1 || updated.ChooserID != "bob" {
+		t.Fatalf("引き分けの後 = %+v; want 引き分け1で bob が選択中", updated)
+	}
+
+	// bob が後手を選んだ2試合目では、後手だった alice が選ぶ
+	if _, err := ms.ChooseFirst(match.ID, "bob", true); err != nil {
+		t.Fatal(err)
+	}
+	if updated = finishGame(t, ms, match.ID, ""); updated.Draws != 2 || updated.ChooserID != "alice" {
+		t.Errorf("2回目の引き分けの後 = %+v; want 引き分け2で alice が選択中", updated)
+	}
+}
+
+func TestMatchExpireChoiceStartsChooserFirst(t *testing.T) {
+	ms, match := newTestMatch(t, 3)
+	finishGame(t, ms, match.ID, "alice")
+
+	// 古い試合数のタイマーは何もしない
+	ms.expireChoice(match.ID, 0)
+	if current, _ := ms.GetMatch(match.ID); current.Status != MatchChoosing {
+		t.Fatalf("古いタイマーの後の状態 = %s; want %s", current.Status, MatchChoosing)
+	}
+
+	ms.expireChoice(match.ID, 1)
+	current, _ := ms.GetMatch(match.ID)
+	if current.Status != MatchActive || len(current.DuelIDs) != 2 {
+		t.Fatalf("期限切れの後 = %+v; want 2試合目が進行中", current)
+	}
+	if first := firstPlayer(t, ms, current.CurrentDuelID); first != "bob" {
+		t.Errorf("期限切れの後の先手 = %s; want 選ぶ側の bob", first)
+	}
+	ms.mu.Lock()
+	_, pending := ms.timers[match.ID]
+	ms.mu.Unlock()
+	if pending {
+		t.Error("期限切れの後もタイマーが残っています")
+	}
+}
+
+func TestMatchDrawCap(t *testing.T) {
+	tests := []struct {
+		name    string
+		bestOf  int
+		winners []string // 各試合の勝者 (空は引き分け)
+		want    string
+	}{
+		{"引き分けのみ", 1, []string{"", ""}, ""},
+		{"勝ち数の多い方", 3, []string{"bob", "", "", "", "", ""}, "bob"},
+		{"勝ち数が同じ", 3, []string{"alice", "bob", "", "", "", ""}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, match := newTestMatch(t, tt.bestOf)
+			var updated *Match
+			for i, winnerID := range tt.winners {
+				if i > 0 {
+					if _, err := ms.ChooseFirst(match.ID, updated.ChooserID, true); err != nil {
+						t.Fatal(err)
+					}
+				}
+				updated = finishGame(t, ms, match.ID, winnerID)
+				if last := i == len(tt.winners)-1; (updated.Status == MatchFinished) != last {
+					t.Fatalf("第%d試合の後の状態 = %s; want 最終試合でだけ終了", i+1, updated.Status)
+				}
+			}
+			if updated.WinnerID != tt.want {
+				t.Errorf("試合数の上限 (%d) での勝者 = %q; want %q", tt.bestOf*2, updated.WinnerID, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchFromEntry(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entry := &db.MatchEntry{
+		ID:          "m1",
+		Player1ID:   "alice",
+		Player2ID:   "bob",
+		BestOf:      5,
+		Player1Wins: 1,
+		Player2Wins: 1,
+		Draws:       1,
+		Status:      MatchActive,
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}
+	games := []db.MatchGame{
+		{DuelID: "d1", GameNumber: 1, Status: "finished"},
+		{DuelID: "d2", GameNumber: 2, Status: "finished"},
+		{DuelID: "d3", GameNumber: 3, Status: "finished"},
+		{DuelID: "d4", GameNumber: 4, Status: "active"},
+	}
+
+	match := MatchFromEntry(entry, games)
+	if match.Players != [2]string{"alice", "bob"} || match.Wins != [2]int{1, 1} || match.Draws != 1 || match.BestOf != 5 {
+		t.Errorf("MatchFromEntry = %+v; want 1-1 (引き分け1) の Best-of-5", match)
+	}
+	if len(match.DuelIDs) != 4 || match.DuelIDs[3] != "d4" || match.CurrentDuelID != "d4" {
+		t.Errorf("試合 = %v (進行中: %q); want d1..d4 (進行中: d4)", match.DuelIDs, match.CurrentDuelID)
+	}
+	if match.FinishedAt != nil || match.WinnerID != "" {
+		t.Errorf("進行中のシリーズの終了情報 = %v, %q; want なし", match.FinishedAt, match.WinnerID)
+	}
+
+	// 終了したシリーズは勝者と終了時刻を復元する
+	finished := created.Add(time.Hour)
+	entry.Status = MatchFinished
+	entry.WinnerID = sql.NullString{String: "bob", Valid: true}
+	entry.FinishedAt = sql.NullTime{Time: finished, Valid: true}
+	match = MatchFromEntry(entry, games[:3])
+	if match.WinnerID != "bob" || match.FinishedAt == nil || !match.FinishedAt.Equal(finished) || match.CurrentDuelID != "" {
+		t.Errorf("終了したシリーズ = %+v; want bob の勝ちで終了", match)
+	}
+}
@@ -2,18 +2,21 @@
 package game
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
+	"github.com/KOU050223/go-card/internal/rating"
 	"github.com/google/uuid"
 )
 
 // MatchmakingRequest はマッチメイキングリクエストを表します
 type MatchmakingRequest struct {
 	UserID    string    `json:"userId"`
+	Rating    float64   `json:"rating"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -21,115 +24,379 @@ type MatchmakingRequest struct {
 type Room struct {
 	ID        string               `json:"id"`
 	Players   []MatchmakingRequest `json:"players"`
-	Status    string               `json:"status"` // "waiting", "ready", "active"
+	Status    string               `json:"status"` // "waiting", "accepting", "lobby", "ready", "active"
+	DuelID    string               `json:"duelId,omitempty"`
+	Bot       bool                 `json:"bot,omitempty"`     // 待ち時間が長いためボットと組んだルーム
+	Private   bool                 `json:"private,omitempty"` // 招待コードで参加するプライベートルーム
+	Code      string               `json:"code,omitempty"`    // プライベートルームの招待コード
+	Ready     []string             `json:"ready,omitempty"`   // 準備完了・承認したプレイヤーのID
+	BestOf    int                  `json:"bestOf,omitempty"`  // プライベートルームで行うシリーズの試合数 (1より大きい場合)
 	CreatedAt time.Time            `json:"createdAt"`
 	UpdatedAt time.Time            `json:"updatedAt"`
+	ExpiresAt *time.Time           `json:"expiresAt,omitempty"` // プライベートルームの有効期限・マッチの承認期限
 }
 
+// MatchStatus はユーザーのマッチメイキング状態です
+type MatchStatus struct {
+	Status        string     `json:"status"` // "none", "waiting", "accepting", "matched", "cancelled", "cooldown"
+	DuelID        string     `json:"duelId,omitempty"`
+	RoomID        string     `json:"roomId,omitempty"`
+	CooldownUntil *time.Time `json:"cooldownUntil,omitempty"` // 辞退・承認切れでキューに参加できない期限
+	Queue         *QueueInfo `json:"queue,omitempty"`         // 待機中のキュー内の状況
+}
+
+// ErrEntryDenied はBAN・利用停止などでマッチメイキングに参加できないことを表します
+var ErrEntryDenied = errors.New("matchmaking entry denied")
+
 // MatchmakingService はマッチメイキングを管理します
+//
+// WebSocketの findMatch とRESTの /api/matchmaking/* の両方がこのサービスを使用し、
+// 待機キューは QueueStore (メモリまたはMySQL) に保存されます。
 type MatchmakingService struct {
-	waitingQueue []MatchmakingRequest
-	rooms        map[string]*Room
-	userToRoom   map[string]string // userID -> roomID のマッピング
-	mu           sync.RWMutex
-	onMatch      func(roomID string, players []MatchmakingRequest) // マッチング完了時のコールバック
+	store          QueueStore
+	rooms          map[string]*Room
+	userToRoom     map[string]string // userID -> roomID のマッピング
+	codeToRoom     map[string]string // 招待コード -> roomID のマッピング (プライベートルーム)
+	mu             sync.RWMutex
+	onMatch        func(room *Room) error                       // マッチング完了時のコールバック (対戦作成)
+	onRoomUpdate   func(userID string, room *Room, closed bool) // プライベートルーム・承認待ちルーム更新時のコールバック
+	onMatchAborted func(userID string, room *Room, requeued bool, cooldown time.Duration)
+
+	acceptTimeout   time.Duration          // マッチ成立から承認までの制限時間 (0以下で承認なし)
+	declineCooldown time.Duration          // 辞退・承認切れのプレイヤーのキュー参加禁止時間
+	acceptTimers    map[string]*time.Timer // roomID -> 承認期限タイマー
+	cooldowns       map[string]time.Time   // userID -> キュー参加禁止の期限
+
+	botAfter time.Duration // この時間待ったプレイヤーをボットと組ませる (0以下で無効)
+	newBotID func() string // ボットのユーザーIDを生成する
+	window   rating.Window // マッチングで許容するレーティング差
+	ratingOf func(ctx context.Context, userID string) (float64, error)
+	canEnter func(ctx context.Context, userID string) error // キュー・ルームへの参加可否 (nilなら全員可)
+	joining  map[string]bool                                // FindMatch を処理中のユーザー
 }
 
 // NewMatchmakingService は新しいマッチメイキングサービスを作成します
-func NewMatchmakingService() *MatchmakingService {
+//
+// store が nil の場合はメモリ上の待機キューを使用します。
+func NewMatchmakingService(store QueueStore) *MatchmakingService {
+	if store == nil {
+		store = NewMemoryQueueStore()
+	}
 	return &MatchmakingService{
-		waitingQueue: make([]MatchmakingRequest, 0),
-		rooms:        make(map[string]*Room),
-		userToRoom:   make(map[string]string),
+		store:           store,
+		rooms:           make(map[string]*Room),
+		userToRoom:      make(map[string]string),
+		codeToRoom:      make(map[string]string),
+		acceptTimeout:   DefaultAcceptTimeout,
+		declineCooldown: DefaultDeclineCooldown,
+		acceptTimers:    make(map[string]*time.Timer),
+		cooldowns:       make(map[string]time.Time),
+		joining:         make(map[string]bool),
+		window:          rating.DefaultWindow(),
+		ratingOf: func(ctx context.Context, userID string) (float64, error) {
+			return rating.DefaultRating, nil
+		},
+	}
+}
+
+// SetRatingLookup はキュー参加時にユーザーのレーティングを取得する関数を設定します
+//
+// 未設定の場合は全員を初期レーティングとして扱います。
+func (ms *MatchmakingService) SetRatingLookup(lookup func(ctx context.Context, userID string) (float64, error)) {
+	ms.ratingOf = lookup
+}
+
+// SetEntryCheck はキュー・プライベートルームへの参加時にユーザーを確認する関数を設定します
+//
+// 参加させない場合は ErrEntryDenied をラップしたエラーを返してください。
+func (ms *MatchmakingService) SetEntryCheck(check func(ctx context.Context, userID string) error) {
+	ms.canEnter = check
+}
+
+// checkEntry はユーザーがキュー・プライベートルームに参加できるかを確認します
+func (ms *MatchmakingService) checkEntry(ctx context.Context, userID string) error {
+	if ms.canEnter == nil {
+		return nil
 	}
+	return ms.canEnter(ctx, userID)
+}
+
+// SetRatingWindow はマッチングで許容するレーティング差を設定します
+func (ms *MatchmakingService) SetRatingWindow(window rating.Window) {
+	ms.window = window
 }
 
 // SetMatchCallback はマッチング完了時のコールバックを設定します
-func (ms *MatchmakingService) SetMatchCallback(callback func(roomID string, players []MatchmakingRequest)) {
+//
+// コールバックは room.DuelID で対戦を作成し、プレイヤーに通知します。
+func (ms *MatchmakingService) SetMatchCallback(callback func(room *Room) error) {
 	ms.onMatch = callback
 }
 
 // FindMatch はプレイヤーをマッチメイキングキューに追加します
-func (ms *MatchmakingService) FindMatch(userID string) (*Room, error) {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
+func (ms *MatchmakingService) FindMatch(ctx context.Context, userID string) (*Room, error) {
+	if err := ms.checkEntry(ctx, userID); err != nil {
+		return nil, err
+	}
 
-	// 既にキューやルームにいるかチェック
-	if roomID, exists := ms.userToRoom[userID]; exists {
-		if room, ok := ms.rooms[roomID]; ok {
-			return room, nil
-		}
-		// ルームが存在しない場合はマッピングを削除
-		delete(ms.userToRoom, userID)
+	// 同じユーザーの参加を同時に処理すると、マッチ済みのエントリの後に再び待機して二重にマッチする
+	if !ms.startJoining(userID) {
+		return nil, ErrAlreadyQueued
 	}
+	defer ms.finishJoining(userID)
 
-	// キューに既にいるかチェック
-	for _, req := range ms.waitingQueue {
-		if req.UserID == userID {
-			return nil, errors.New("already in matchmaking queue")
-		}
+	// 既に対戦中のルームがあればそれを返す
+	if room := ms.activeRoom(userID); room != nil {
+		return room, nil
+	}
+	// 待機中ならキューに入れ直さない (別インスタンスの参加で既にマッチしていても再び待機させない)
+	if room := ms.waitingRoom(userID); room != nil {
+		return room, nil
 	}
 
-	// 新しいリクエストを作成
-	request := MatchmakingRequest{
-		UserID:    userID,
-		Timestamp: time.Now(),
+	// 辞退・承認切れのペナルティ中は参加できない
+	if until := ms.cooldownUntil(userID); !until.IsZero() {
+		return nil, fmt.Errorf("%w: あと%d秒", ErrQueueCooldown, int(time.Until(until).Seconds())+1)
 	}
 
-	// キューに追加
-	ms.waitingQueue = append(ms.waitingQueue, request)
-	log.Printf("ユーザー %s をマッチメイキングキューに追加しました。キュー内人数: %d", userID, len(ms.waitingQueue))
+	userRating, err := ms.ratingOf(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("レーティング取得エラー: %w", err)
+	}
+
+	now := time.Now()
+	request := MatchmakingRequest{UserID: userID, Rating: userRating, Timestamp: now}
+	err = ms.store.Enqueue(ctx, userID, userRating, now)
+	if errors.Is(err, ErrAlreadyQueued) {
+		// 待機中なら既存の待機ルームを返す
+		if room, roomErr := ms.GetUserRoom(userID); roomErr == nil {
+			return room, nil
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("マッチング登録エラー: %w", err)
+	}
+	log.Printf("ユーザー %s をマッチメイキングキューに追加しました (レーティング: %.0f)", userID, userRating)
 
 	// マッチング処理
-	room := ms.tryMatch()
+	room, err := ms.tryMatch(ctx, request)
+	if err != nil {
+		return nil, err
+	}
 	if room != nil {
 		return room, nil
 	}
 
 	// まだマッチしていない場合は待機ルームを作成
-	waitingRoom := ms.createWaitingRoom(request)
-	return waitingRoom, nil
+	return ms.createWaitingRoom(request), nil
 }
 
-// tryMatch はマッチングを試行します
-func (ms *MatchmakingService) tryMatch() *Room {
-	if len(ms.waitingQueue) < 2 {
-		return nil
+// startJoining はユーザーの参加処理を開始します。処理中の場合は false を返します
+func (ms *MatchmakingService) startJoining(userID string) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.joining[userID] {
+		return false
 	}
+	ms.joining[userID] = true
+	return true
+}
 
-	// 最初の2人をマッチング
-	player1 := ms.waitingQueue[0]
-	player2 := ms.waitingQueue[1]
+// finishJoining はユーザーの参加処理を終了します
+func (ms *MatchmakingService) finishJoining(userID string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.joining, userID)
+}
 
-	// キューから削除
-	ms.waitingQueue = ms.waitingQueue[2:]
+// tryMatch は待機中の相手とのマッチングを試行します
+func (ms *MatchmakingService) tryMatch(ctx context.Context, request MatchmakingRequest) (*Room, error) {
+	duelID := uuid.New().String()
+	opponent, err := ms.store.Match(ctx, request.UserID, duelID, ms.window)
+	if err != nil {
+		return nil, fmt.Errorf("マッチング処理エラー: %w", err)
+	}
+	if opponent == nil {
+		return nil, nil
+	}
 
-	// ルーム作成
+	// 先に待っていたプレイヤーを先手にする
+	player1 := MatchmakingRequest{UserID: opponent.UserID, Rating: opponent.Rating, Timestamp: opponent.JoinedAt}
+	player2 := request
+
+	ms.mu.Lock()
 	roomID := uuid.New().String()
 	room := &Room{
 		ID:        roomID,
 		Players:   []MatchmakingRequest{player1, player2},
 		Status:    "ready",
+		DuelID:    duelID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
-
+	for _, player := range room.Players {
+		ms.removeRoomLocked(player.UserID)
+		ms.userToRoom[player.UserID] = roomID
+	}
 	ms.rooms[roomID] = room
-	ms.userToRoom[player1.UserID] = roomID
-	ms.userToRoom[player2.UserID] = roomID
+
+	// 承認ステップがある場合は両者の承認を待ってから対戦を作成する
+	accepting := ms.acceptTimeout > 0
+	if accepting {
+		ms.proposeLocked(room)
+	}
+	matched := room.clone()
+	ms.mu.Unlock()
 
 	log.Printf("マッチング成功: ルーム %s でプレイヤー %s と %s", roomID, player1.UserID, player2.UserID)
 
-	// コールバック実行
+	if accepting {
+		ms.notifyRoomUpdate(matched)
+		return matched, nil
+	}
+
+	// コールバック実行 (マッチ1件につき対戦を1つだけ作成)
 	if ms.onMatch != nil {
-		go ms.onMatch(roomID, []MatchmakingRequest{player1, player2})
+		if err := ms.onMatch(matched); err != nil {
+			// 両者ともマッチ済みのまま残らないよう、キューの先頭に戻す (再マッチは MatchWaiting で行う)
+			log.Printf("対戦作成エラーのため両者をキューに戻します (ルーム: %s): %v", roomID, err)
+			ms.restoreToQueue(ctx, matched, matched.Players...)
+			return ms.waitingRoom(request.UserID), nil
+		}
+	}
+
+	return ms.roomSnapshot(roomID, matched), nil
+}
+
+// restoreToQueue は対戦を作成できなかったルームを削除し、players をキューの先頭に戻します
+//
+// 対戦作成の失敗を繰り返さないよう、すぐには再マッチしません。
+func (ms *MatchmakingService) restoreToQueue(ctx context.Context, room *Room, players ...MatchmakingRequest) {
+	ms.mu.Lock()
+	if current, ok := ms.rooms[room.ID]; ok {
+		ms.deleteRoomLocked(current)
+	}
+	ms.mu.Unlock()
+
+	for _, player := range players {
+		err := ms.store.Enqueue(ctx, player.UserID, player.Rating, player.Timestamp)
+		if err != nil && !errors.Is(err, ErrAlreadyQueued) {
+			log.Printf("キューへの再登録エラー (ユーザー: %s): %v", player.UserID, err)
+			continue
+		}
+		ms.createWaitingRoom(player)
+	}
+}
+
+// MatchWaiting は待機中のユーザー同士のマッチングを再試行します
+//
+// 待ち時間とともにレーティングの許容差が広がるため定期的に呼び出します。
+// MySQLの待機キューで同時に参加した2人が互いの行をスキップして両方待機のまま
+// 残った場合の取りこぼしもここで解消されます。別インスタンスでマッチ・キャンセルされた
+// ユーザーの待機ルームもここで削除します。
+func (ms *MatchmakingService) MatchWaiting(ctx context.Context) {
+	fetchedAt := time.Now()
+	waiting, err := ms.store.Waiting(ctx)
+	if err != nil {
+		log.Printf("待機キュー取得エラー: %v", err)
+		return
+	}
+	ms.pruneWaitingRooms(waiting, fetchedAt)
+
+	// 既に相手として確保されたエントリは store.Match が nil を返すので読み飛ばされる
+	if len(waiting) >= 2 {
+		for _, entry := range waiting {
+			request := MatchmakingRequest{UserID: entry.UserID, Rating: entry.Rating, Timestamp: entry.JoinedAt}
+			if _, err := ms.tryMatch(ctx, request); err != nil {
+				log.Printf("待機者のマッチング再試行エラー (ユーザー: %s): %v", entry.UserID, err)
+			}
+		}
+	}
+
+	if ms.botAfter > 0 && ms.newBotID != nil {
+		ms.fillWithBots(ctx, waiting)
 	}
+}
 
-	return room
+// SetBotFallback は after 以上待っているプレイヤーをサーバー側のボットと組ませるよう設定します
+//
+// after が0以下の場合は無効です。ボットとの対戦はレーティングに反映されません。
+// CleanupExpiredRooms の期限より短い時間を指定してください。
+func (ms *MatchmakingService) SetBotFallback(after time.Duration, newBotID func() string) {
+	ms.botAfter = after
+	ms.newBotID = newBotID
+}
+
+// fillWithBots は待ち時間が botAfter を超えたプレイヤーをボットと組ませます
+func (ms *MatchmakingService) fillWithBots(ctx context.Context, waiting []QueueEntry) {
+	now := time.Now()
+	for _, entry := range waiting {
+		if now.Sub(entry.JoinedAt) < ms.botAfter {
+			continue
+		}
+		// 直前のマッチングで相手が見つかったエントリは MatchAlone が false を返すので読み飛ばされる
+		request := MatchmakingRequest{UserID: entry.UserID, Rating: entry.Rating, Timestamp: entry.JoinedAt}
+		if _, err := ms.matchWithBot(ctx, request); err != nil {
+			log.Printf("ボットとのマッチングエラー (ユーザー: %s): %v", entry.UserID, err)
+		}
+	}
+}
+
+// matchWithBot は待機中のプレイヤーをボットと組ませて対戦を作成します
+//
+// 相手を待たせることがないため承認ステップは省略し、プレイヤーを先手にします。
+func (ms *MatchmakingService) matchWithBot(ctx context.Context, request MatchmakingRequest) (*Room, error) {
+	duelID := uuid.New().String()
+	matched, err := ms.store.MatchAlone(ctx, request.UserID, duelID)
+	if err != nil {
+		return nil, fmt.Errorf("マッチング処理エラー: %w", err)
+	}
+	if !matched {
+		return nil, nil
+	}
+
+	botPlayer := MatchmakingRequest{UserID: ms.newBotID(), Rating: request.Rating, Timestamp: time.Now()}
+
+	ms.mu.Lock()
+	roomID := uuid.New().String()
+	room := &Room{
+		ID:        roomID,
+		Players:   []MatchmakingRequest{request, botPlayer},
+		Status:    "ready",
+		DuelID:    duelID,
+		Bot:       true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	ms.removeRoomLocked(request.UserID)
+	ms.userToRoom[request.UserID] = roomID
+	ms.rooms[roomID] = room
+	snapshot := room.clone()
+	ms.mu.Unlock()
+
+	log.Printf("待ち時間が長いためプレイヤー %s をボット %s と組ませました (ルーム: %s)", request.UserID, botPlayer.UserID, roomID)
+
+	if ms.onMatch != nil {
+		if err := ms.onMatch(snapshot); err != nil {
+			ms.restoreToQueue(ctx, snapshot, request)
+			return nil, fmt.Errorf("対戦作成エラー: %w", err)
+		}
+	}
+	return ms.roomSnapshot(roomID, snapshot), nil
 }
 
 // createWaitingRoom は待機ルームを作成します
 func (ms *MatchmakingService) createWaitingRoom(request MatchmakingRequest) *Room {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// 相手が見つからなかった直後に、相手側の参加でマッチしていればそのルームを返す
+	if room, ok := ms.rooms[ms.userToRoom[request.UserID]]; ok && room.Status != "waiting" {
+		return room.clone()
+	}
+
 	roomID := uuid.New().String()
 	room := &Room{
 		ID:        roomID,
@@ -139,40 +406,133 @@ func (ms *MatchmakingService) createWaitingRoom(request MatchmakingRequest) *Roo
 		UpdatedAt: time.Now(),
 	}
 
+	ms.removeRoomLocked(request.UserID)
 	ms.rooms[roomID] = room
 	ms.userToRoom[request.UserID] = roomID
 
 	log.Printf("待機ルーム %s を作成しました (プレイヤー: %s)", roomID, request.UserID)
-	return room
+	return room.clone()
 }
 
-// CancelMatch はマッチメイキングをキャンセルします
-func (ms *MatchmakingService) CancelMatch(userID string) error {
+// activeRoom はユーザーが参加している対戦前/対戦中のルームを返します
+func (ms *MatchmakingService) activeRoom(userID string) *Room {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	room, ok := ms.rooms[ms.userToRoom[userID]]
+	if !ok || room.Status == "waiting" {
+		return nil
+	}
+	return room.clone()
+}
+
+// waitingRoom はユーザーの待機ルームを返します
+func (ms *MatchmakingService) waitingRoom(userID string) *Room {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	room, ok := ms.rooms[ms.userToRoom[userID]]
+	if !ok || room.Status != "waiting" {
+		return nil
+	}
+	return room.clone()
+}
+
+// pruneWaitingRooms は待機キューにいない (別インスタンスでマッチ・キャンセルされた) ユーザーの待機ルームを削除します
+//
+// waiting は fetchedAt の時点の待機エントリです。その後に作成した待機ルームは残します。
+func (ms *MatchmakingService) pruneWaitingRooms(waiting []QueueEntry, fetchedAt time.Time) {
+	queued := make(map[string]bool, len(waiting))
+	for _, entry := range waiting {
+		queued[entry.UserID] = true
+	}
+
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
-
-	// キューから削除
-	for i, req := range ms.waitingQueue {
-		if req.UserID == userID {
-			ms.waitingQueue = append(ms.waitingQueue[:i], ms.waitingQueue[i+1:]...)
-			log.Printf("ユーザー %s をマッチメイキングキューから削除しました", userID)
-			return nil
+	for _, room := range ms.rooms {
+		if room.Status != "waiting" || !room.CreatedAt.Before(fetchedAt) || queued[room.Players[0].UserID] {
+			continue
 		}
+		ms.deleteRoomLocked(room)
 	}
+}
 
-	// ルームから削除
-	if roomID, exists := ms.userToRoom[userID]; exists {
-		room := ms.rooms[roomID]
-		if room != nil && room.Status == "waiting" {
-			delete(ms.rooms, roomID)
-			delete(ms.userToRoom, userID)
-			log.Printf("待機ルーム %s を削除しました (プレイヤー: %s)", roomID, userID)
-			return nil
+// removeRoomLocked はユーザーの待機ルームを削除します (ms.mu取得済み)
+func (ms *MatchmakingService) removeRoomLocked(userID string) {
+	roomID, exists := ms.userToRoom[userID]
+	if !exists {
+		return
+	}
+	if room, ok := ms.rooms[roomID]; ok && room.Status == "waiting" {
+		delete(ms.rooms, roomID)
+	}
+	delete(ms.userToRoom, userID)
+}
+
+// deleteRoomLocked はルームと招待コード、参加者の所属を削除します (ms.mu取得済み)
+func (ms *MatchmakingService) deleteRoomLocked(room *Room) {
+	for _, player := range room.Players {
+		if ms.userToRoom[player.UserID] == room.ID {
+			delete(ms.userToRoom, player.UserID)
 		}
+	}
+	delete(ms.rooms, room.ID)
+	if room.Code != "" && ms.codeToRoom[room.Code] == room.ID {
+		delete(ms.codeToRoom, room.Code)
+	}
+}
+
+// CancelMatch はマッチメイキングをキャンセルします
+func (ms *MatchmakingService) CancelMatch(ctx context.Context, userID string) error {
+	if ms.activeRoom(userID) != nil {
 		return errors.New("cannot cancel: game already started")
 	}
 
-	return errors.New("user not in queue or room")
+	cancelled, err := ms.store.Cancel(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("キャンセル処理エラー: %w", err)
+	}
+
+	ms.mu.Lock()
+	ms.removeRoomLocked(userID)
+	ms.mu.Unlock()
+
+	if !cancelled {
+		return errors.New("user not in queue or room")
+	}
+	log.Printf("ユーザー %s をマッチメイキングキューから削除しました", userID)
+	return nil
+}
+
+// Status はユーザーのマッチメイキング状態を返します
+func (ms *MatchmakingService) Status(ctx context.Context, userID string) (*MatchStatus, error) {
+	entry, err := ms.store.Entry(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("状態取得エラー: %w", err)
+	}
+	if until := ms.cooldownUntil(userID); !until.IsZero() {
+		return &MatchStatus{Status: "cooldown", CooldownUntil: &until}, nil
+	}
+	if entry == nil {
+		return &MatchStatus{Status: "none"}, nil
+	}
+
+	status := &MatchStatus{Status: entry.Status, DuelID: entry.DuelID}
+	ms.mu.RLock()
+	status.RoomID = ms.userToRoom[userID]
+	if room, ok := ms.rooms[status.RoomID]; ok && room.Status == RoomAccepting {
+		// 承認待ちの間は対戦がまだ作成されていない
+		status.Status = RoomAccepting
+		status.DuelID = ""
+	}
+	ms.mu.RUnlock()
+
+	if status.Status == QueueWaiting {
+		if status.Queue, err = ms.QueueInfo(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
 }
 
 // GetRoom はルーム情報を取得します
@@ -185,13 +545,13 @@ func (ms *MatchmakingService) GetRoom(roomID string) (*Room, error) {
 		return nil, errors.New("room not found")
 	}
 
-	return room, nil
+	return room.clone(), nil
 }
 
 // GetUserRoom はユーザーが所属するルームを取得します
 func (ms *MatchmakingService) GetUserRoom(userID string) (*Room, error) {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
 
 	roomID, exists := ms.userToRoom[userID]
 	if !exists {
@@ -205,7 +565,7 @@ func (ms *MatchmakingService) GetUserRoom(userID string) (*Room, error) {
 		return nil, errors.New("room not found")
 	}
 
-	return room, nil
+	return room.clone(), nil
 }
 
 // StartGame はルームのゲームを開始します
@@ -233,16 +593,49 @@ func (ms *MatchmakingService) StartGame(roomID string) error {
 	return nil
 }
 
-// CleanupExpiredRooms は期限切れのルームをクリーンアップします
-func (ms *MatchmakingService) CleanupExpiredRooms(maxAge time.Duration) {
+// EndGame は対戦が終了したルームを削除し、プレイヤーが再びキューに参加できるようにします
+func (ms *MatchmakingService) EndGame(duelID string) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
+	for roomID, room := range ms.rooms {
+		if room.DuelID != duelID {
+			continue
+		}
+		ms.deleteRoomLocked(room)
+		log.Printf("対戦 %s の終了によりルーム %s を削除しました", duelID, roomID)
+	}
+}
+
+// CleanupExpiredRooms は期限切れの待機をキャンセルし、待機ルームをクリーンアップします
+//
+// プライベートルームは対象外です (CleanupExpiredPrivateRooms で個別の期限により削除)。
+func (ms *MatchmakingService) CleanupExpiredRooms(maxAge time.Duration) {
+	ctx := context.Background()
 	now := time.Now()
-	toDelete := make([]string, 0)
 
+	waiting, err := ms.store.Waiting(ctx)
+	if err != nil {
+		log.Printf("待機キュー取得エラー: %v", err)
+		return
+	}
+	for _, entry := range waiting {
+		if now.Sub(entry.JoinedAt) <= maxAge {
+			continue
+		}
+		if _, err := ms.store.Cancel(ctx, entry.UserID); err != nil {
+			log.Printf("期限切れ待機のキャンセルエラー (ユーザー: %s): %v", entry.UserID, err)
+		}
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.pruneCooldownsLocked(now)
+
+	toDelete := make([]string, 0)
 	for roomID, room := range ms.rooms {
-		if now.Sub(room.CreatedAt) > maxAge && room.Status == "waiting" {
+		if now.Sub(room.CreatedAt) > maxAge && room.Status == "waiting" && !room.Private {
 			toDelete = append(toDelete, roomID)
 		}
 	}
@@ -259,14 +652,25 @@ func (ms *MatchmakingService) CleanupExpiredRooms(maxAge time.Duration) {
 
 // GetQueueStatus はキューの状態を取得します
 func (ms *MatchmakingService) GetQueueStatus() map[string]interface{} {
+	ctx := context.Background()
+	waiting, err := ms.store.Waiting(ctx)
+	if err != nil {
+		log.Printf("待機キュー取得エラー: %v", err)
+	}
+	rate, err := ms.recentMatchRate(ctx)
+	if err != nil {
+		log.Printf("マッチ数取得エラー: %v", err)
+	}
+
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
 	return map[string]interface{}{
-		"queueSize":    len(ms.waitingQueue),
-		"totalRooms":   len(ms.rooms),
-		"activeRooms":  ms.countRoomsByStatus("active"),
-		"waitingRooms": ms.countRoomsByStatus("waiting"),
+		"queueSize":        len(waiting),
+		"totalRooms":       len(ms.rooms),
+		"activeRooms":      ms.countRoomsByStatus("active"),
+		"waitingRooms":     ms.countRoomsByStatus("waiting"),
+		"matchesPerMinute": rate.matchesPerSecond * 60,
 	}
 }
 
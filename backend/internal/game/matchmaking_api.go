@@ -2,20 +2,36 @@
 package game
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
-	"github.com/KOU050223/go-card/internal/db"
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
+// RoomRouter はプライベートルームと承認待ちのマッチの操作をルームを保持しているインスタンスで処理します
+//
+// 別インスタンスに転送した場合は nil のルーム (退出・辞退は forwarded が true) を返し、
+// 結果は roomUpdate などのWebSocketメッセージで通知します。
+type RoomRouter interface {
+	AcceptMatch(ctx context.Context, userID string) (*Room, error)
+	DeclineMatch(ctx context.Context, userID string) (forwarded bool, err error)
+	CreatePrivateRoom(userID string, bestOf int) (*Room, error)
+	JoinPrivateRoom(userID, code string) (*Room, error)
+	SetReady(userID string, ready bool) (*Room, error)
+	LeavePrivateRoom(userID string) (forwarded bool, err error)
+}
+
+// MatchmakingAPI は MatchmakingService をRESTで公開します
+//
+// WebSocketの findMatch と同じサービスを使うため、どちらから参加しても同じキューに入ります。
 type MatchmakingAPI struct {
-	Repo        *db.MatchmakingRepository
-	DuelService *DuelService
+	Service *MatchmakingService
+	Rooms   RoomRouter
 }
 
-func NewMatchmakingAPI(repo *db.MatchmakingRepository, ds *DuelService) *MatchmakingAPI {
-	return &MatchmakingAPI{Repo: repo, DuelService: ds}
+func NewMatchmakingAPI(service *MatchmakingService, rooms RoomRouter) *MatchmakingAPI {
+	return &MatchmakingAPI{Service: service, Rooms: rooms}
 }
 
 // POST /api/matchmaking/join
@@ -23,56 +39,170 @@ func (api *MatchmakingAPI) Join(c echo.Context) error {
 	ctx := c.Request().Context()
 	userID := c.Get("uid").(string)
 
-	// 既にwaitingの他ユーザーがいればマッチング成立
-	other, err := api.Repo.FindWaitingExcept(ctx, userID)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "DB検索エラー")
-	}
-	if other != nil {
-		duelID := uuid.New().String()
-		err = api.Repo.UpdateMatched(ctx, userID, other.UserID, duelID)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "マッチング更新エラー")
-		}
-
-		// Create duel data in memory so /ws/duel can fetch it
-		if api.DuelService != nil {
-			if err := api.DuelService.CreateDuelWithID(duelID, userID, other.UserID); err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "対戦作成エラー")
-			}
-		}
-
-		return c.JSON(http.StatusOK, map[string]interface{}{"status": "matched", "duelId": duelID})
-	}
-	// 自分をwaitingで登録
-	err = api.Repo.InsertWaiting(ctx, userID)
+	room, err := api.Service.FindMatch(ctx, userID)
+	if errors.Is(err, ErrAlreadyQueued) {
+		return c.JSON(http.StatusOK, map[string]interface{}{"status": "waiting"})
+	}
+	if errors.Is(err, ErrEntryDenied) {
+		return echo.NewHTTPError(http.StatusForbidden, "このアカウントはマッチメイキングに参加できません")
+	}
+	if errors.Is(err, ErrQueueCooldown) {
+		return echo.NewHTTPError(http.StatusTooManyRequests, "マッチを承認しなかったため、しばらく参加できません")
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "マッチング登録エラー")
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"status": "waiting"})
+
+	if room.Status == RoomAccepting {
+		return c.JSON(http.StatusOK, map[string]interface{}{"status": RoomAccepting, "roomId": room.ID, "expiresAt": room.ExpiresAt})
+	}
+	if room.DuelID != "" {
+		return c.JSON(http.StatusOK, map[string]interface{}{"status": "matched", "duelId": room.DuelID, "roomId": room.ID})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"status": "waiting", "roomId": room.ID})
 }
 
 // POST /api/matchmaking/cancel
 func (api *MatchmakingAPI) Cancel(c echo.Context) error {
 	ctx := c.Request().Context()
 	userID := c.Get("uid").(string)
-	err := api.Repo.Cancel(ctx, userID)
+	err := api.Service.CancelMatch(ctx, userID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "キャンセル失敗")
+		return echo.NewHTTPError(http.StatusConflict, "キャンセル失敗")
 	}
 	return c.JSON(http.StatusOK, map[string]interface{}{"status": "cancelled"})
 }
 
+// POST /api/matchmaking/accept
+func (api *MatchmakingAPI) Accept(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := c.Get("uid").(string)
+	room, err := api.Rooms.AcceptMatch(ctx, userID)
+	if errors.Is(err, ErrNoPendingMatch) {
+		return echo.NewHTTPError(http.StatusNotFound, "承認待ちのマッチがありません")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "マッチ承認エラー")
+	}
+	if room == nil {
+		// 別インスタンスのマッチに転送した場合は結果を matchFound・gameStart で通知する
+		return c.NoContent(http.StatusAccepted)
+	}
+	return c.JSON(http.StatusOK, room)
+}
+
+// POST /api/matchmaking/decline
+func (api *MatchmakingAPI) Decline(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := c.Get("uid").(string)
+	forwarded, err := api.Rooms.DeclineMatch(ctx, userID)
+	if errors.Is(err, ErrNoPendingMatch) {
+		return echo.NewHTTPError(http.StatusNotFound, "承認待ちのマッチがありません")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "マッチ辞退エラー")
+	}
+	if forwarded {
+		// 別インスタンスのマッチに転送した場合は結果を matchDeclined で通知する
+		return c.NoContent(http.StatusAccepted)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"status": "declined"})
+}
+
 // GET /api/matchmaking/status
 func (api *MatchmakingAPI) Status(c echo.Context) error {
 	ctx := c.Request().Context()
 	userID := c.Get("uid").(string)
-	entry, err := api.Repo.GetByUserID(ctx, userID)
+	status, err := api.Service.Status(ctx, userID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "状態取得失敗")
 	}
-	if entry == nil {
-		return c.JSON(http.StatusOK, map[string]interface{}{"status": "none"})
+	return c.JSON(http.StatusOK, status)
+}
+
+// roomError はプライベートルーム操作のエラーをHTTPエラーに変換します
+func roomError(err error) error {
+	switch {
+	case errors.Is(err, ErrRoomNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "ルームが見つかりません")
+	case errors.Is(err, ErrRoomFull):
+		return echo.NewHTTPError(http.StatusConflict, "ルームは満員です")
+	case errors.Is(err, ErrAlreadyInRoom):
+		return echo.NewHTTPError(http.StatusConflict, "既に別のルームまたはキューに参加しています")
+	case errors.Is(err, ErrEntryDenied):
+		return echo.NewHTTPError(http.StatusForbidden, "このアカウントはルームに参加できません")
+	case errors.Is(err, ErrInvalidBestOf):
+		return echo.NewHTTPError(http.StatusBadRequest, "bestOfは1から7の奇数で指定してください")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "ルーム操作エラー")
+	}
+}
+
+// POST /api/rooms
+func (api *MatchmakingAPI) CreateRoom(c echo.Context) error {
+	userID := c.Get("uid").(string)
+	var req struct {
+		BestOf int `json:"bestOf"` // 省略時は1試合
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+	room, err := api.Rooms.CreatePrivateRoom(userID, req.BestOf)
+	if err != nil {
+		return roomError(err)
+	}
+	return c.JSON(http.StatusCreated, room)
+}
+
+// POST /api/rooms/join
+func (api *MatchmakingAPI) JoinRoom(c echo.Context) error {
+	userID := c.Get("uid").(string)
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "codeが必要です")
+	}
+	room, err := api.Rooms.JoinPrivateRoom(userID, req.Code)
+	if err != nil {
+		return roomError(err)
+	}
+	if room == nil {
+		// 別インスタンスのルームに転送した場合は結果を roomUpdate で通知する
+		return c.NoContent(http.StatusAccepted)
+	}
+	return c.JSON(http.StatusOK, room)
+}
+
+// POST /api/rooms/ready
+func (api *MatchmakingAPI) SetReady(c echo.Context) error {
+	userID := c.Get("uid").(string)
+	req := struct {
+		Ready bool `json:"ready"`
+	}{Ready: true}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "不正なリクエストです")
+	}
+	room, err := api.Rooms.SetReady(userID, req.Ready)
+	if err != nil {
+		return roomError(err)
+	}
+	if room == nil {
+		return c.NoContent(http.StatusAccepted)
+	}
+	return c.JSON(http.StatusOK, room)
+}
+
+// POST /api/rooms/leave
+func (api *MatchmakingAPI) LeaveRoom(c echo.Context) error {
+	userID := c.Get("uid").(string)
+	forwarded, err := api.Rooms.LeavePrivateRoom(userID)
+	if err != nil {
+		return roomError(err)
+	}
+	if forwarded {
+		// 別インスタンスのルームに転送した場合は結果を roomLeft で通知する
+		return c.NoContent(http.StatusAccepted)
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"status": entry.Status, "duelId": entry.DuelID.String})
+	return c.JSON(http.StatusOK, map[string]interface{}{"status": "left"})
 }
@@ -0,0 +1,321 @@
+// backend/internal/game/matchmaking_store.go
+package game
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/KOU050223/go-card/internal/rating"
+)
+
+// 待機キューのエントリ状態
+const (
+	QueueWaiting   = "waiting"
+	QueueMatched   = "matched"
+	QueueCancelled = "cancelled"
+)
+
+// ErrAlreadyQueued はユーザーが既に待機キューにいることを表します
+var ErrAlreadyQueued = errors.New("already in matchmaking queue")
+
+// QueueEntry は待機キューの1エントリです
+type QueueEntry struct {
+	UserID   string    `json:"userId"`
+	Status   string    `json:"status"`
+	DuelID   string    `json:"duelId,omitempty"`
+	Rating   float64   `json:"rating"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// QueueStore はマッチメイキングの待機キューを保存する先です
+//
+// メモリ実装は単一インスタンス用、MySQL実装は複数インスタンスでキューを共有する場合に使用します。
+type QueueStore interface {
+	// Enqueue は at に参加したものとしてユーザーを待機キューに追加します。待機中の場合は ErrAlreadyQueued を返します
+	Enqueue(ctx context.Context, userID string, rating float64, at time.Time) error
+
+	// Match は userID 以外で window の許容差に収まる最も古い待機者を対戦相手として取り出し、
+	// 両者を duelID でマッチ済みにします。相手がいない場合は nil を返します
+	Match(ctx context.Context, userID, duelID string, window rating.Window) (*QueueEntry, error)
+
+	// MatchAlone は待機中のユーザーを相手なしで duelID のマッチ済みにします (ボットとの対戦など)。
+	// 待機していなかった場合は false を返します
+	MatchAlone(ctx context.Context, userID, duelID string) (bool, error)
+
+	// Cancel は待機中のユーザーをキューから外します。待機していなかった場合は false を返します
+	Cancel(ctx context.Context, userID string) (bool, error)
+
+	// Entry はユーザーの最新のエントリを返します。存在しない場合は nil を返します
+	Entry(ctx context.Context, userID string) (*QueueEntry, error)
+
+	// Waiting は待機中のエントリを古い順に返します
+	Waiting(ctx context.Context) ([]QueueEntry, error)
+
+	// MatchedSince は since 以降のマッチ数と、マッチしてキューを抜けた人数を返します
+	MatchedSince(ctx context.Context, since time.Time) (MatchCount, error)
+}
+
+// MatchCount は期間内のマッチの集計です
+//
+// ボットとの対戦はキューから1人だけが抜けるため、マッチ数と人数は2倍の関係になりません。
+type MatchCount struct {
+	Matches int // 成立したマッチ数
+	Players int // マッチしてキューを抜けた人数
+}
+
+// memoryQueueStore はプロセス内で完結する QueueStore です
+//
+// マッチ済み・キャンセル済みのエントリは状態確認のため settledRetention の間だけ残します。
+type memoryQueueStore struct {
+	mu      sync.Mutex
+	entries map[string]*QueueEntry // userID -> 最新のエントリ
+	settled map[string]time.Time   // userID -> エントリがマッチ済み・キャンセル済みになった時刻
+	matched []matchRecord          // マッチの記録 (古い順, matchedRetention まで保持)
+}
+
+// matchRecord はメモリ実装で保持するマッチの時刻とキューを抜けた人数です
+type matchRecord struct {
+	at      time.Time
+	players int
+}
+
+const (
+	// matchedRetention はメモリ実装でマッチ時刻を保持する期間です
+	matchedRetention = time.Hour
+	// settledRetention はメモリ実装でマッチ済み・キャンセル済みのエントリを保持する期間です
+	settledRetention = 10 * time.Minute
+)
+
+// NewMemoryQueueStore はメモリ上の待機キューを作成します
+func NewMemoryQueueStore() QueueStore {
+	return &memoryQueueStore{
+		entries: make(map[string]*QueueEntry),
+		settled: make(map[string]time.Time),
+	}
+}
+
+func (s *memoryQueueStore) Enqueue(ctx context.Context, userID string, rating float64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[userID]; ok && entry.Status == QueueWaiting {
+		return ErrAlreadyQueued
+	}
+	s.pruneSettledLocked(time.Now().Add(-settledRetention))
+	s.entries[userID] = &QueueEntry{UserID: userID, Status: QueueWaiting, Rating: rating, JoinedAt: at}
+	delete(s.settled, userID)
+	return nil
+}
+
+func (s *memoryQueueStore) Match(ctx context.Context, userID, duelID string, window rating.Window) (*QueueEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	self, ok := s.entries[userID]
+	if !ok || self.Status != QueueWaiting {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var opponent *QueueEntry
+	for _, entry := range s.entries {
+		if entry.UserID == userID || entry.Status != QueueWaiting {
+			continue
+		}
+		// 許容差は2人のうち長く待っている方の待ち時間で決まる
+		since := self.JoinedAt
+		if entry.JoinedAt.Before(since) {
+			since = entry.JoinedAt
+		}
+		if !window.Accepts(self.Rating, entry.Rating, now.Sub(since)) {
+			continue
+		}
+		if opponent == nil || entry.JoinedAt.Before(opponent.JoinedAt) {
+			opponent = entry
+		}
+	}
+	if opponent == nil {
+		return nil, nil
+	}
+
+	for _, entry := range []*QueueEntry{self, opponent} {
+		entry.Status = QueueMatched
+		entry.DuelID = duelID
+		s.settled[entry.UserID] = now
+	}
+	s.matched = append(s.matched, matchRecord{at: now, players: 2})
+	s.pruneMatchedLocked(now.Add(-matchedRetention))
+	matched := *opponent
+	return &matched, nil
+}
+
+func (s *memoryQueueStore) MatchAlone(ctx context.Context, userID, duelID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[userID]
+	if !ok || entry.Status != QueueWaiting {
+		return false, nil
+	}
+	entry.Status = QueueMatched
+	entry.DuelID = duelID
+	now := time.Now()
+	s.settled[userID] = now
+	s.matched = append(s.matched, matchRecord{at: now, players: 1})
+	s.pruneMatchedLocked(now.Add(-matchedRetention))
+	return true, nil
+}
+
+func (s *memoryQueueStore) Cancel(ctx context.Context, userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[userID]
+	if !ok || entry.Status != QueueWaiting {
+		return false, nil
+	}
+	entry.Status = QueueCancelled
+	s.settled[userID] = time.Now()
+	return true, nil
+}
+
+func (s *memoryQueueStore) Entry(ctx context.Context, userID string) (*QueueEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[userID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *entry
+	return &copied, nil
+}
+
+func (s *memoryQueueStore) Waiting(ctx context.Context) ([]QueueEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	waiting := make([]QueueEntry, 0)
+	for _, entry := range s.entries {
+		if entry.Status == QueueWaiting {
+			waiting = append(waiting, *entry)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool {
+		return waiting[i].JoinedAt.Before(waiting[j].JoinedAt)
+	})
+	return waiting, nil
+}
+
+func (s *memoryQueueStore) MatchedSince(ctx context.Context, since time.Time) (MatchCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := sort.Search(len(s.matched), func(i int) bool {
+		return !s.matched[i].at.Before(since)
+	})
+	count := MatchCount{Matches: len(s.matched) - i}
+	for _, record := range s.matched[i:] {
+		count.Players += record.players
+	}
+	return count, nil
+}
+
+// pruneMatchedLocked は before より古いマッチ時刻を削除します (s.mu取得済み)
+func (s *memoryQueueStore) pruneMatchedLocked(before time.Time) {
+	i := sort.Search(len(s.matched), func(i int) bool {
+		return !s.matched[i].at.Before(before)
+	})
+	s.matched = append(s.matched[:0], s.matched[i:]...)
+}
+
+// pruneSettledLocked は before より前にマッチ済み・キャンセル済みになったエントリを削除します (s.mu取得済み)
+func (s *memoryQueueStore) pruneSettledLocked(before time.Time) {
+	for userID, at := range s.settled {
+		if at.Before(before) {
+			delete(s.entries, userID)
+			delete(s.settled, userID)
+		}
+	}
+}
+
+// mysqlQueueStore は matchmaking テーブルを使う QueueStore です
+//
+// 相手の確保は行ロック付きのトランザクションで行うため、複数インスタンスから
+// 同時に参加しても1人の待機者が二重にマッチすることはありません。
+type mysqlQueueStore struct {
+	repo *db.MatchmakingRepository
+}
+
+// NewMySQLQueueStore はMySQLの matchmaking テーブルを使う待機キューを作成します
+func NewMySQLQueueStore(repo *db.MatchmakingRepository) QueueStore {
+	return &mysqlQueueStore{repo: repo}
+}
+
+func (s *mysqlQueueStore) Enqueue(ctx context.Context, userID string, rating float64, at time.Time) error {
+	// 待機エントリの一意性はDBの一意制約で保証する
+	err := s.repo.InsertWaiting(ctx, userID, rating, at)
+	if errors.Is(err, db.ErrAlreadyWaiting) {
+		return ErrAlreadyQueued
+	}
+	return err
+}
+
+func (s *mysqlQueueStore) Match(ctx context.Context, userID, duelID string, window rating.Window) (*QueueEntry, error) {
+	other, err := s.repo.ClaimOpponent(ctx, userID, duelID, window)
+	if err != nil {
+		return nil, err
+	}
+	if other == nil {
+		return nil, nil
+	}
+	return toQueueEntry(other), nil
+}
+
+func (s *mysqlQueueStore) MatchAlone(ctx context.Context, userID, duelID string) (bool, error) {
+	return s.repo.MarkMatched(ctx, userID, duelID)
+}
+
+func (s *mysqlQueueStore) Cancel(ctx context.Context, userID string) (bool, error) {
+	return s.repo.Cancel(ctx, userID)
+}
+
+func (s *mysqlQueueStore) Entry(ctx context.Context, userID string) (*QueueEntry, error) {
+	entry, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	return toQueueEntry(entry), nil
+}
+
+func (s *mysqlQueueStore) Waiting(ctx context.Context) ([]QueueEntry, error) {
+	entries, err := s.repo.ListWaiting(ctx)
+	if err != nil {
+		return nil, err
+	}
+	waiting := make([]QueueEntry, 0, len(entries))
+	for i := range entries {
+		waiting = append(waiting, *toQueueEntry(&entries[i]))
+	}
+	return waiting, nil
+}
+
+func (s *mysqlQueueStore) MatchedSince(ctx context.Context, since time.Time) (MatchCount, error) {
+	matches, players, err := s.repo.CountMatchedSince(ctx, since)
+	return MatchCount{Matches: matches, Players: players}, err
+}
+
+// toQueueEntry はDBのエントリを QueueEntry に変換します
+func toQueueEntry(entry *db.MatchmakingEntry) *QueueEntry {
+	return &QueueEntry{
+		UserID:   entry.UserID,
+		Status:   string(entry.Status),
+		DuelID:   entry.DuelID.String,
+		Rating:   entry.Rating,
+		JoinedAt: entry.CreatedAt,
+	}
+}
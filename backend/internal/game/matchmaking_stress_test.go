@@ -0,0 +1,198 @@
+// backend/internal/game/matchmaking_stress_test.go
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+)
+
+const (
+	// stressUsers は同時に参加するユーザー数です (全員がペアになるよう偶数)
+	stressUsers = 200
+	// stressInstances は同じ待機キューを共有するサービスインスタンス数です
+	stressInstances = 4
+	// stressDupJoins はユーザーごとの同時参加回数です (重複参加の検証)
+	stressDupJoins = 3
+)
+
+// matchRecorder はマッチ成立のコールバックで作成された対戦を記録します
+type matchRecorder struct {
+	mu    sync.Mutex
+	rooms []*Room
+}
+
+func (r *matchRecorder) record(room *Room) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rooms = append(r.rooms, room)
+	return nil
+}
+
+// runMatchStress は store を共有する複数のサービスから同時に参加し、
+// 二重マッチがなく全員がちょうど1回ずつペアになることを確認します
+func runMatchStress(t *testing.T, store QueueStore, prefix string) *matchRecorder {
+	t.Helper()
+	ctx := context.Background()
+
+	recorder := &matchRecorder{}
+	services := make([]*MatchmakingService, stressInstances)
+	for i := range services {
+		services[i] = NewMatchmakingService(store)
+		// マッチ成立数を数えるため承認ステップは省略する
+		services[i].SetAcceptTimeout(0)
+		services[i].SetMatchCallback(recorder.record)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, stressUsers*stressDupJoins)
+	for u := 0; u < stressUsers; u++ {
+		userID := fmt.Sprintf("%s%d", prefix, u)
+		for d := 0; d < stressDupJoins; d++ {
+			wg.Add(1)
+			go func(svc *MatchmakingService) {
+				defer wg.Done()
+				if _, err := svc.FindMatch(ctx, userID); err != nil && !errors.Is(err, ErrAlreadyQueued) {
+					errs <- fmt.Errorf("参加エラー (ユーザー: %s): %w", userID, err)
+				}
+			}(services[u%len(services)])
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// 同時参加で互いをスキップした待機者を解消
+	for i := 0; i < 10; i++ {
+		waiting, err := store.Waiting(ctx)
+		if err != nil {
+			t.Fatalf("待機キュー取得エラー: %v", err)
+		}
+		if len(ownedBy(waiting, prefix)) == 0 {
+			break
+		}
+		services[i%len(services)].MatchWaiting(ctx)
+	}
+
+	assertPairedOnce(t, recorder.rooms, prefix)
+	return recorder
+}
+
+// ownedBy は prefix で始まるユーザーのエントリだけを返します
+func ownedBy(entries []QueueEntry, prefix string) []QueueEntry {
+	var owned []QueueEntry
+	for _, entry := range entries {
+		if len(entry.UserID) >= len(prefix) && entry.UserID[:len(prefix)] == prefix {
+			owned = append(owned, entry)
+		}
+	}
+	return owned
+}
+
+// assertPairedOnce は作成された対戦を検査します
+func assertPairedOnce(t *testing.T, rooms []*Room, prefix string) {
+	t.Helper()
+
+	duels := make(map[string]bool, len(rooms))
+	pairedIn := make(map[string]string, stressUsers)
+	for _, room := range rooms {
+		if duels[room.DuelID] {
+			t.Errorf("対戦 %s が二重に作成されました", room.DuelID)
+		}
+		duels[room.DuelID] = true
+
+		if len(room.Players) != 2 || room.Players[0].UserID == room.Players[1].UserID {
+			t.Errorf("対戦 %s のプレイヤーが不正です: %+v", room.DuelID, room.Players)
+			continue
+		}
+		for _, player := range room.Players {
+			if previous, ok := pairedIn[player.UserID]; ok {
+				t.Errorf("ユーザー %s が対戦 %s と %s で二重にマッチしました", player.UserID, previous, room.DuelID)
+			}
+			pairedIn[player.UserID] = room.DuelID
+		}
+	}
+
+	for u := 0; u < stressUsers; u++ {
+		if userID := fmt.Sprintf("%s%d", prefix, u); pairedIn[userID] == "" {
+			t.Errorf("ユーザー %s がマッチしていません", userID)
+		}
+	}
+	if len(rooms) != stressUsers/2 {
+		t.Errorf("対戦数 = %d; want %d", len(rooms), stressUsers/2)
+	}
+}
+
+func TestMatchmakingStressMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("-short のため省略します")
+	}
+	runMatchStress(t, NewMemoryQueueStore(), "stress-")
+}
+
+// TestMatchmakingStressMySQL はローカルのMySQLに対して同時参加を実行します
+//
+//	MATCHSTRESS_MYSQL=1 DB_USER=root DB_PASS=... DB_NAME=go_card_db go test ./internal/game -run MatchmakingStressMySQL
+func TestMatchmakingStressMySQL(t *testing.T) {
+	if os.Getenv("MATCHSTRESS_MYSQL") == "" {
+		t.Skip("MATCHSTRESS_MYSQL が未設定のため省略します")
+	}
+	if testing.Short() {
+		t.Skip("-short のため省略します")
+	}
+
+	t.Setenv("DB_LOCAL", "true")
+	conn, err := db.NewMySQL(os.Getenv("DB_USER"), os.Getenv("DB_PASS"), "", os.Getenv("DB_NAME"))
+	if err != nil {
+		t.Fatalf("データベース接続エラー: %v", err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	prefix := fmt.Sprintf("stress-%d-", time.Now().UnixNano())
+	t.Cleanup(func() {
+		if _, err := conn.ExecContext(ctx, `DELETE FROM matchmaking WHERE user_id LIKE ?`, prefix+"%"); err != nil {
+			t.Logf("テストデータ削除エラー: %v", err)
+		}
+	})
+
+	recorder := runMatchStress(t, NewMySQLQueueStore(db.NewMatchmakingRepository(conn)), prefix)
+
+	// DB上も1つの対戦IDには異なる2人がちょうど1回ずつ
+	var duelSizes []struct {
+		DuelID  string `db:"duel_id"`
+		Count   int    `db:"cnt"`
+		Players int    `db:"players"`
+	}
+	if err := conn.SelectContext(ctx, &duelSizes,
+		`SELECT duel_id, COUNT(*) AS cnt, COUNT(DISTINCT user_id) AS players FROM matchmaking
+		 WHERE user_id LIKE ? AND status = 'matched' GROUP BY duel_id`, prefix+"%"); err != nil {
+		t.Fatalf("検証クエリエラー: %v", err)
+	}
+	for _, d := range duelSizes {
+		if d.Count != 2 || d.Players != 2 {
+			t.Errorf("対戦 %s のエントリが %d 件 (ユーザー %d 人) です", d.DuelID, d.Count, d.Players)
+		}
+	}
+	if len(duelSizes) != len(recorder.rooms) {
+		t.Errorf("DB上のマッチ数 %d と作成された対戦数 %d が一致しません", len(duelSizes), len(recorder.rooms))
+	}
+
+	// 待機エントリが残っていない
+	var waiting int
+	if err := conn.GetContext(ctx, &waiting,
+		`SELECT COUNT(*) FROM matchmaking WHERE user_id LIKE ? AND status = 'waiting'`, prefix+"%"); err != nil {
+		t.Fatalf("検証クエリエラー: %v", err)
+	}
+	if waiting != 0 {
+		t.Errorf("%d 件の待機エントリが残っています", waiting)
+	}
+}
@@ -0,0 +1,226 @@
+// backend/internal/game/matchmaking_test.go
+package game
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/rating"
+)
+
+// testWindow は待ち時間0で100、1秒ごとに10広がる許容差です
+var testWindow = rating.Window{Base: 100, GrowthPerSecond: 10, Max: 1000}
+
+func TestMemoryQueueStoreWideningWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryQueueStore()
+	now := time.Now()
+
+	// レーティング差400は、待ち始めたばかりの2人では許容差100を超える
+	store.Enqueue(ctx, "alice", 1500, now)
+	store.Enqueue(ctx, "bob", 1900, now)
+	if opponent, err := store.Match(ctx, "bob", "d1", testWindow); err != nil || opponent != nil {
+		t.Fatalf("待ち始めの Match = %v, %v; want nil", opponent, err)
+	}
+
+	// 40秒待っている相手となら許容差は500に広がる
+	store.Enqueue(ctx, "carol", 1500, now.Add(-40*time.Second))
+	opponent, err := store.Match(ctx, "bob", "d1", testWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opponent == nil || opponent.UserID != "carol" {
+		t.Fatalf("Match = %+v; want carol", opponent)
+	}
+	for _, userID := range []string{"bob", "carol"} {
+		entry, _ := store.Entry(ctx, userID)
+		if entry.Status != QueueMatched || entry.DuelID != "d1" {
+			t.Errorf("%s のエントリ = %+v; want d1 でマッチ済み", userID, entry)
+		}
+	}
+	if entry, _ := store.Entry(ctx, "alice"); entry.Status != QueueWaiting {
+		t.Errorf("alice のエントリ = %+v; want 待機中", entry)
+	}
+}
+
+func TestMemoryQueueStorePrefersOldest(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryQueueStore()
+	now := time.Now()
+
+	store.Enqueue(ctx, "newer", 1500, now.Add(-time.Second))
+	store.Enqueue(ctx, "older", 1550, now.Add(-5*time.Second))
+	store.Enqueue(ctx, "self", 1500, now)
+
+	opponent, err := store.Match(ctx, "self", "d1", testWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opponent == nil || opponent.UserID != "older" {
+		t.Errorf("Match = %+v; want 最も長く待っている older", opponent)
+	}
+}
+
+func TestMemoryQueueStorePrunesSettledEntries(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryQueueStore().(*memoryQueueStore)
+	now := time.Now()
+
+	store.Enqueue(ctx, "cancelled", 1500, now)
+	store.Cancel(ctx, "cancelled")
+	store.Enqueue(ctx, "matched", 1500, now)
+	store.MatchAlone(ctx, "matched", "d1")
+	store.Enqueue(ctx, "recent", 1500, now)
+	store.Cancel(ctx, "recent")
+
+	// 保持期間を過ぎたものだけ、次の参加時に削除される
+	store.mu.Lock()
+	store.settled["cancelled"] = now.Add(-settledRetention - time.Second)
+	store.settled["matched"] = now.Add(-settledRetention - time.Second)
+	store.mu.Unlock()
+	store.Enqueue(ctx, "next", 1500, now)
+
+	for userID, want := range map[string]bool{"cancelled": false, "matched": false, "recent": true, "next": true} {
+		entry, _ := store.Entry(ctx, userID)
+		if got := entry != nil; got != want {
+			t.Errorf("%s のエントリが残っている = %v; want %v", userID, got, want)
+		}
+	}
+}
+
+func TestMemoryQueueStoreMatchedSinceCountsBotMatches(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryQueueStore()
+	now := time.Now()
+
+	store.Enqueue(ctx, "a", 1500, now)
+	store.Enqueue(ctx, "b", 1500, now)
+	if opponent, err := store.Match(ctx, "a", "d1", testWindow); err != nil || opponent == nil {
+		t.Fatalf("Match = %v, %v; want b", opponent, err)
+	}
+	// ボットとの対戦はキューから1人だけが抜ける
+	store.Enqueue(ctx, "c", 1500, now)
+	store.MatchAlone(ctx, "c", "d2")
+
+	count, err := store.MatchedSince(ctx, now.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (MatchCount{Matches: 2, Players: 3}); count != want {
+		t.Errorf("MatchedSince = %+v; want %+v", count, want)
+	}
+}
+
+// newTestMatchmaking は承認ステップなしのマッチメイキングを作成します
+func newTestMatchmaking(ratings map[string]float64) (*MatchmakingService, QueueStore) {
+	store := NewMemoryQueueStore()
+	ms := NewMatchmakingService(store)
+	ms.SetAcceptTimeout(0)
+	ms.SetRatingWindow(testWindow)
+	ms.SetRatingLookup(func(ctx context.Context, userID string) (float64, error) {
+		return ratings[userID], nil
+	})
+	return ms, store
+}
+
+func TestMatchWaitingWidensWindow(t *testing.T) {
+	ctx := context.Background()
+	ms, store := newTestMatchmaking(nil)
+	var created []*Room
+	ms.SetMatchCallback(func(room *Room) error {
+		created = append(created, room)
+		return nil
+	})
+
+	// 待ち始めはレーティング差が大きすぎてマッチしないが、待ち時間が延びると組まれる
+	now := time.Now()
+	store.Enqueue(ctx, "alice", 1500, now.Add(-60*time.Second))
+	store.Enqueue(ctx, "bob", 2100, now.Add(-10*time.Second))
+	ms.MatchWaiting(ctx)
+	if len(created) != 1 {
+		t.Fatalf("作成された対戦 = %d件; want 1", len(created))
+	}
+	if players := created[0].Players; len(players) != 2 || players[0].UserID == players[1].UserID {
+		t.Errorf("ルームのプレイヤー = %+v; want alice と bob", players)
+	}
+
+	// 許容差の上限 (1000) を超える差はいくら待ってもマッチしない
+	store.Enqueue(ctx, "carol", 1000, now.Add(-time.Hour))
+	store.Enqueue(ctx, "dave", 2100, now.Add(-time.Hour))
+	ms.MatchWaiting(ctx)
+	if len(created) != 1 {
+		t.Errorf("上限を超える差でマッチしました: %+v", created[1:])
+	}
+}
+
+func TestFindMatchRequeuesWhenDuelCreationFails(t *testing.T) {
+	ctx := context.Background()
+	ms, store := newTestMatchmaking(map[string]float64{"alice": 1500, "bob": 1500})
+	ms.SetMatchCallback(func(room *Room) error {
+		return errors.New("対戦を作成できません")
+	})
+
+	if _, err := ms.FindMatch(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	room, err := ms.FindMatch(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room == nil || room.Status != "waiting" {
+		t.Errorf("対戦作成に失敗したときのルーム = %+v; want 待機ルーム", room)
+	}
+
+	// 両者ともマッチ済みのまま残らず、待機中に戻る
+	for _, userID := range []string{"alice", "bob"} {
+		entry, _ := store.Entry(ctx, userID)
+		if entry == nil || entry.Status != QueueWaiting {
+			t.Errorf("%s のエントリ = %+v; want 待機中", userID, entry)
+		}
+		if r, err := ms.GetUserRoom(userID); err != nil || r.Status != "waiting" {
+			t.Errorf("%s のルーム = %+v, %v; want 待機ルーム", userID, r, err)
+		}
+	}
+}
+
+func TestReturnedRoomsAreSnapshots(t *testing.T) {
+	ctx := context.Background()
+	ms, _ := newTestMatchmaking(map[string]float64{"alice": 1500, "bob": 1500})
+	ms.SetMatchCallback(func(room *Room) error { return nil })
+
+	waiting, err := ms.FindMatch(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matched, err := ms.FindMatch(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 返したルームは呼び出し元がロックの外で読むため、その後の状態変化の影響を受けない
+	matchedStatus := matched.Status
+	if err := ms.StartGame(matched.ID); err != nil {
+		t.Fatal(err)
+	}
+	if waiting.Status != "waiting" || matched.Status != matchedStatus {
+		t.Errorf("返したルームの状態が変わりました: 待機 %q, マッチ %q", waiting.Status, matched.Status)
+	}
+
+	// 取得したルームを書き換えてもサービスのルームは変わらない
+	for _, get := range []func() (*Room, error){
+		func() (*Room, error) { return ms.GetRoom(matched.ID) },
+		func() (*Room, error) { return ms.GetUserRoom("alice") },
+	} {
+		room, err := get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		room.Status = "finished"
+		room.Players[0].UserID = "mallory"
+	}
+	room, _ := ms.GetRoom(matched.ID)
+	if room.Status != "active" || room.Players[0].UserID == "mallory" {
+		t.Errorf("サービスのルーム = %+v; want 書き換えの影響を受けない", room)
+	}
+}
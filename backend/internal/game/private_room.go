@@ -0,0 +1,354 @@
+// backend/internal/game/private_room.go
+package game
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// privateRoomCodeAlphabet は招待コードに使う文字です (読み間違えやすい 0/O/1/I/L を除く)
+	privateRoomCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	// privateRoomCodeLength は招待コードの長さです
+	privateRoomCodeLength = 6
+	// privateRoomTTL はプライベートルームの有効期限です (参加者が入るたびに延長)
+	privateRoomTTL = 10 * time.Minute
+)
+
+// ルームのステータスのうちプライベートルームでのみ使うもの
+const (
+	// RoomLobby は対戦開始前のプライベートルームです (両者の準備完了で "ready" になる)
+	RoomLobby = "lobby"
+)
+
+var (
+	// ErrRoomNotFound は招待コードに対応するルームがないことを表します
+	ErrRoomNotFound = errors.New("room not found")
+	// ErrRoomFull はプライベートルームが満員であることを表します
+	ErrRoomFull = errors.New("room is full")
+	// ErrAlreadyInRoom はユーザーが既に別のルームやキューにいることを表します
+	ErrAlreadyInRoom = errors.New("already in a room")
+)
+
+// CreatePrivateRoom は招待コード付きのプライベートルームを作成します
+//
+// プライベートルームはランダムマッチの待機キューには入らず、JoinPrivateRoom で
+// コードを指定した相手だけが参加できます。bestOf に1より大きい値を指定すると
+// 対戦は Best-of-N のシリーズとして行います。
+func (ms *MatchmakingService) CreatePrivateRoom(userID string, bestOf int) (*Room, error) {
+	if bestOf == 0 {
+		bestOf = 1
+	}
+	if !ValidBestOf(bestOf) {
+		return nil, ErrInvalidBestOf
+	}
+	if err := ms.checkEntry(context.Background(), userID); err != nil {
+		return nil, err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, exists := ms.userToRoom[userID]; exists {
+		return nil, ErrAlreadyInRoom
+	}
+
+	code, err := ms.newRoomCodeLocked()
+	if err != nil {
+		return nil, fmt.Errorf("招待コード生成エラー: %w", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(privateRoomTTL)
+	room := &Room{
+		ID:        uuid.New().String(),
+		Players:   []MatchmakingRequest{{UserID: userID, Timestamp: now}},
+		Status:    RoomLobby,
+		Private:   true,
+		Code:      code,
+		BestOf:    bestOf,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: &expiresAt,
+	}
+	ms.rooms[room.ID] = room
+	ms.userToRoom[userID] = room.ID
+	ms.codeToRoom[code] = room.ID
+
+	log.Printf("プライベートルーム %s を作成しました (コード: %s, プレイヤー: %s)", room.ID, code, userID)
+	return room.clone(), nil
+}
+
+// JoinPrivateRoom は招待コードでプライベートルームに参加します
+func (ms *MatchmakingService) JoinPrivateRoom(userID, code string) (*Room, error) {
+	if err := ms.checkEntry(context.Background(), userID); err != nil {
+		return nil, err
+	}
+
+	ms.mu.Lock()
+	room, ok := ms.rooms[ms.codeToRoom[NormalizeRoomCode(code)]]
+	if !ok || room.Status != RoomLobby {
+		ms.mu.Unlock()
+		return nil, ErrRoomNotFound
+	}
+
+	// 参加済みならそのまま返す
+	if room.hasPlayer(userID) {
+		joined := room.clone()
+		ms.mu.Unlock()
+		return joined, nil
+	}
+	if _, exists := ms.userToRoom[userID]; exists {
+		ms.mu.Unlock()
+		return nil, ErrAlreadyInRoom
+	}
+	if len(room.Players) >= 2 {
+		ms.mu.Unlock()
+		return nil, ErrRoomFull
+	}
+
+	now := time.Now()
+	room.Players = append(room.Players, MatchmakingRequest{UserID: userID, Timestamp: now})
+	room.UpdatedAt = now
+	expiresAt := now.Add(privateRoomTTL)
+	room.ExpiresAt = &expiresAt
+	ms.userToRoom[userID] = room.ID
+	joined := room.clone()
+	ms.mu.Unlock()
+
+	log.Printf("ユーザー %s がプライベートルーム %s に参加しました", userID, joined.ID)
+	ms.notifyRoomUpdate(joined)
+	return joined, nil
+}
+
+// SetReady はプライベートルームでの準備状態を設定します
+//
+// 2人が揃って両者が準備完了になると、マッチング完了コールバックで対戦を作成します。
+func (ms *MatchmakingService) SetReady(userID string, ready bool) (*Room, error) {
+	ms.mu.Lock()
+	room, ok := ms.rooms[ms.userToRoom[userID]]
+	if !ok || !room.Private || room.Status != RoomLobby {
+		ms.mu.Unlock()
+		return nil, ErrRoomNotFound
+	}
+
+	room.Ready = removeString(room.Ready, userID)
+	if ready {
+		room.Ready = append(room.Ready, userID)
+	}
+	room.UpdatedAt = time.Now()
+
+	start := len(room.Players) == 2 && len(room.Ready) == 2
+	if start {
+		room.Status = "ready"
+		room.DuelID = uuid.New().String()
+	}
+	updated := room.clone()
+	ms.mu.Unlock()
+
+	if !start {
+		ms.notifyRoomUpdate(updated)
+		return updated, nil
+	}
+
+	log.Printf("プライベートルーム %s の準備が完了しました", updated.ID)
+	if ms.onMatch != nil {
+		if err := ms.onMatch(updated); err != nil {
+			// 対戦を作成できなかった場合は準備前の状態に戻す
+			ms.mu.Lock()
+			room.Status = RoomLobby
+			room.DuelID = ""
+			room.Ready = nil
+			ms.mu.Unlock()
+			return nil, fmt.Errorf("対戦作成エラー: %w", err)
+		}
+	}
+	return ms.roomSnapshot(updated.ID, updated), nil
+}
+
+// LeavePrivateRoom は対戦開始前のプライベートルームから退出します
+//
+// 作成者が退出した場合や最後の1人が退出した場合はルームを削除します。
+func (ms *MatchmakingService) LeavePrivateRoom(userID string) error {
+	ms.mu.Lock()
+	room, ok := ms.rooms[ms.userToRoom[userID]]
+	if !ok || !room.Private || room.Status != RoomLobby {
+		ms.mu.Unlock()
+		return ErrRoomNotFound
+	}
+
+	if room.Players[0].UserID == userID || len(room.Players) == 1 {
+		ms.deleteRoomLocked(room)
+		closed := room.clone()
+		ms.mu.Unlock()
+		log.Printf("プライベートルーム %s を削除しました (退出: %s)", closed.ID, userID)
+		for _, player := range closed.Players {
+			if player.UserID != userID {
+				ms.notifyRoomClosed(closed, player.UserID)
+			}
+		}
+		return nil
+	}
+
+	delete(ms.userToRoom, userID)
+
+	players := make([]MatchmakingRequest, 0, 1)
+	for _, player := range room.Players {
+		if player.UserID != userID {
+			players = append(players, player)
+		}
+	}
+	room.Players = players
+	room.Ready = removeString(room.Ready, userID)
+	room.UpdatedAt = time.Now()
+	updated := room.clone()
+	ms.mu.Unlock()
+
+	log.Printf("ユーザー %s がプライベートルーム %s から退出しました", userID, updated.ID)
+	ms.notifyRoomUpdate(updated)
+	return nil
+}
+
+// CleanupExpiredPrivateRooms は有効期限を過ぎた対戦開始前のプライベートルームを削除します
+//
+// ランダムマッチの待機ルームとは別に、各ルームの ExpiresAt で期限を判定します。
+func (ms *MatchmakingService) CleanupExpiredPrivateRooms() {
+	now := time.Now()
+
+	ms.mu.Lock()
+	expired := make([]*Room, 0)
+	for _, room := range ms.rooms {
+		if room.Private && room.Status == RoomLobby && now.After(*room.ExpiresAt) {
+			ms.deleteRoomLocked(room)
+			expired = append(expired, room.clone())
+		}
+	}
+	ms.mu.Unlock()
+
+	for _, room := range expired {
+		log.Printf("期限切れのプライベートルーム %s を削除しました (コード: %s)", room.ID, room.Code)
+		for _, player := range room.Players {
+			ms.notifyRoomClosed(room, player.UserID)
+		}
+	}
+}
+
+// HasRoomCode は招待コードのプライベートルームがこのインスタンスにあるかを返します
+func (ms *MatchmakingService) HasRoomCode(code string) bool {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	_, ok := ms.codeToRoom[NormalizeRoomCode(code)]
+	return ok
+}
+
+// PrivateLobbies は対戦開始前のプライベートルームのコピーを返します
+func (ms *MatchmakingService) PrivateLobbies() []*Room {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	rooms := make([]*Room, 0)
+	for _, room := range ms.rooms {
+		if room.Private && room.Status == RoomLobby {
+			rooms = append(rooms, room.clone())
+		}
+	}
+	return rooms
+}
+
+// roomSnapshot はルームの現在の状態のコピーを返します。削除済みの場合は fallback を返します
+func (ms *MatchmakingService) roomSnapshot(roomID string, fallback *Room) *Room {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if room, ok := ms.rooms[roomID]; ok {
+		return room.clone()
+	}
+	return fallback
+}
+
+// newRoomCodeLocked は使用中のものと重複しない招待コードを生成します (ms.mu取得済み)
+func (ms *MatchmakingService) newRoomCodeLocked() (string, error) {
+	alphabetSize := big.NewInt(int64(len(privateRoomCodeAlphabet)))
+	for attempt := 0; attempt < 10; attempt++ {
+		var code strings.Builder
+		for i := 0; i < privateRoomCodeLength; i++ {
+			n, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return "", err
+			}
+			code.WriteByte(privateRoomCodeAlphabet[n.Int64()])
+		}
+		if _, used := ms.codeToRoom[code.String()]; !used {
+			return code.String(), nil
+		}
+	}
+	return "", errors.New("招待コードの空きがありません")
+}
+
+// SetRoomUpdateCallback はプライベートルームの参加者・準備状態や、ランダムマッチの
+// 承認状態 (Status が RoomAccepting) が変わったときのコールバックを設定します
+//
+// closed が true の場合、ルームは削除され userID はルームから外れています。
+func (ms *MatchmakingService) SetRoomUpdateCallback(callback func(userID string, room *Room, closed bool)) {
+	ms.onRoomUpdate = callback
+}
+
+// notifyRoomUpdate はルームの全参加者に状態変化を通知します
+func (ms *MatchmakingService) notifyRoomUpdate(room *Room) {
+	if ms.onRoomUpdate == nil {
+		return
+	}
+	for _, player := range room.Players {
+		ms.onRoomUpdate(player.UserID, room, false)
+	}
+}
+
+// notifyRoomClosed はルームが削除されたことを userID に通知します
+func (ms *MatchmakingService) notifyRoomClosed(room *Room, userID string) {
+	if ms.onRoomUpdate != nil {
+		ms.onRoomUpdate(userID, room, true)
+	}
+}
+
+// NormalizeRoomCode は入力された招待コードを正規化します (大文字小文字・空白を無視)
+func NormalizeRoomCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// hasPlayer はユーザーがルームに参加しているかを返します
+func (r *Room) hasPlayer(userID string) bool {
+	for _, player := range r.Players {
+		if player.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// clone はルームのコピーを返します
+func (r *Room) clone() *Room {
+	copied := *r
+	copied.Players = append([]MatchmakingRequest(nil), r.Players...)
+	copied.Ready = append([]string(nil), r.Ready...)
+	return &copied
+}
+
+// removeString は slice から value を取り除いたスライスを返します
+func removeString(slice []string, value string) []string {
+	result := slice[:0]
+	for _, s := range slice {
+		if s != value {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+// backend/internal/game/queue_status.go
+package game
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// matchRateWindow は待ち時間の見積もりに使う直近のマッチの集計期間です
+const matchRateWindow = 10 * time.Minute
+
+// QueueInfo は待機中のユーザーのキュー内の状況です
+type QueueInfo struct {
+	Position             int     `json:"position"` // 1始まりの待ち順
+	QueueSize            int     `json:"queueSize"`
+	ElapsedSeconds       int     `json:"elapsedSeconds"`
+	EstimatedWaitSeconds *int    `json:"estimatedWaitSeconds,omitempty"` // 直近のマッチがない場合は見積もれないため省略
+	MatchesPerMinute     float64 `json:"matchesPerMinute"`
+}
+
+// QueueInfo はユーザーのキュー内の状況を返します。待機中でない場合は nil を返します
+func (ms *MatchmakingService) QueueInfo(ctx context.Context, userID string) (*QueueInfo, error) {
+	infos, err := ms.QueueInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return infos[userID], nil
+}
+
+// QueueInfos は待機中の全ユーザーのキュー内の状況を返します
+//
+// 待ち時間は直近 matchRateWindow にマッチしてキューを抜けた人数から抜ける速さを求め、
+// 自分より前にいる人数と自分がその速さで消化されるまでの時間として見積もります。
+func (ms *MatchmakingService) QueueInfos(ctx context.Context) (map[string]*QueueInfo, error) {
+	waiting, err := ms.store.Waiting(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("待機キュー取得エラー: %w", err)
+	}
+
+	infos := make(map[string]*QueueInfo, len(waiting))
+	if len(waiting) == 0 {
+		return infos, nil
+	}
+
+	rate, err := ms.recentMatchRate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i, entry := range waiting {
+		info := &QueueInfo{
+			Position:         i + 1,
+			QueueSize:        len(waiting),
+			ElapsedSeconds:   int(now.Sub(entry.JoinedAt).Seconds()),
+			MatchesPerMinute: rate.matchesPerSecond * 60,
+		}
+		if rate.playersPerSecond > 0 {
+			estimate := int(math.Ceil(float64(info.Position) / rate.playersPerSecond))
+			info.EstimatedWaitSeconds = &estimate
+		}
+		infos[entry.UserID] = info
+	}
+	return infos, nil
+}
+
+// matchRate は直近の1秒あたりの平均のマッチ数と、マッチしてキューを抜けた人数です
+type matchRate struct {
+	matchesPerSecond float64
+	playersPerSecond float64
+}
+
+// recentMatchRate は直近 matchRateWindow のマッチ数とキューを抜けた人数から1秒あたりの平均を求めます
+func (ms *MatchmakingService) recentMatchRate(ctx context.Context) (matchRate, error) {
+	count, err := ms.store.MatchedSince(ctx, time.Now().Add(-matchRateWindow))
+	if err != nil {
+		return matchRate{}, fmt.Errorf("マッチ数取得エラー: %w", err)
+	}
+	return matchRate{
+		matchesPerSecond: float64(count.Matches) / matchRateWindow.Seconds(),
+		playersPerSecond: float64(count.Players) / matchRateWindow.Seconds(),
+	}, nil
+}
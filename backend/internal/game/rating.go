@@ -0,0 +1,109 @@
+// backend/internal/game/rating.go
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/KOU050223/go-card/internal/rating"
+)
+
+// ratingUpdateTimeout はレーティング更新1回あたりのタイムアウトです
+const ratingUpdateTimeout = 5 * time.Second
+
+// RatingService は対戦結果からユーザーのGlicko-2レーティングを更新します
+type RatingService struct {
+	users  *db.UserRepository
+	writes *writeQueue
+}
+
+// NewRatingService は新しいレーティングサービスを作成します
+func NewRatingService(users *db.UserRepository) *RatingService {
+	return &RatingService{users: users, writes: newWriteQueue("レーティング")}
+}
+
+// Rating はユーザーの現在のレーティングを返します。ユーザーが存在しない場合は初期値を返します
+func (rs *RatingService) Rating(ctx context.Context, userID string) (float64, error) {
+	userRating, err := rs.users.GetRating(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if userRating == nil {
+		return rating.DefaultRating, nil
+	}
+	return userRating.Rating, nil
+}
+
+// OnDuelFinished は終了した対戦の結果で両プレイヤーのレーティングを更新します
+//
+// DuelService.AddFinishCallback に登録して使用します。対戦の進行を止めないよう、
+// 更新は書き込みキューで対戦終了の順に行います。
+func (rs *RatingService) OnDuelFinished(duel *Duel) {
+	if len(duel.Players) != 2 || duel.Unrated {
+		return
+	}
+	rs.writes.enqueue(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ratingUpdateTimeout)
+		defer cancel()
+
+		if err := rs.apply(ctx, duel); err != nil {
+			log.Printf("レーティング更新エラー (対戦: %s): %v", duel.ID, err)
+		}
+	})
+}
+
+// apply は対戦の両プレイヤーのレーティングを同時に更新します
+func (rs *RatingService) apply(ctx context.Context, duel *Duel) error {
+	p0, p1 := duel.Players[0].UserID, duel.Players[1].UserID
+	if p0 == p1 {
+		return fmt.Errorf("同じユーザー同士の対戦です: %s", p0)
+	}
+
+	return rs.users.UpdateRatings(ctx, []string{p0, p1}, func(ratings map[string]*db.UserRating) {
+		r0, ok0 := ratings[p0]
+		r1, ok1 := ratings[p1]
+		if !ok0 || !ok1 {
+			// 未登録ユーザーとの対戦はレーティングに反映しない
+			log.Printf("未登録ユーザーを含む対戦のためレーティングを更新しません (対戦: %s)", duel.ID)
+			for id := range ratings {
+				delete(ratings, id)
+			}
+			return
+		}
+
+		score := scoreFor(duel, p0)
+		before0, before1 := toRating(r0), toRating(r1)
+		after0 := rating.Update(before0, []rating.Result{{Opponent: before1, Score: score}})
+		after1 := rating.Update(before1, []rating.Result{{Opponent: before0, Score: 1 - score}})
+		setRating(r0, after0)
+		setRating(r1, after1)
+
+		log.Printf("レーティング更新 (対戦: %s): %s %.0f -> %.0f, %s %.0f -> %.0f",
+			duel.ID, p0, before0.Rating, after0.Rating, p1, before1.Rating, after1.Rating)
+	})
+}
+
+// scoreFor は userID から見た対戦のスコアを返します
+func scoreFor(duel *Duel, userID string) float64 {
+	switch duel.WinnerID {
+	case "":
+		return rating.Draw
+	case userID:
+		return rating.Win
+	default:
+		return rating.Loss
+	}
+}
+
+func toRating(r *db.UserRating) rating.Rating {
+	return rating.Rating{Rating: r.Rating, RD: r.RD, Volatility: r.Volatility}
+}
+
+func setRating(r *db.UserRating, updated rating.Rating) {
+	r.Rating = updated.Rating
+	r.RD = updated.RD
+	r.Volatility = updated.Volatility
+}
@@ -0,0 +1,248 @@
+// backend/internal/game/ready_check.go
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// RoomAccepting はランダムマッチ成立後、両プレイヤーの承認を待っているルームです
+	RoomAccepting = "accepting"
+
+	// DefaultAcceptTimeout はマッチ成立から承認までの標準の制限時間です
+	DefaultAcceptTimeout = 10 * time.Second
+	// DefaultDeclineCooldown は辞退・承認切れのプレイヤーが再びキューに入れるまでの標準の時間です
+	DefaultDeclineCooldown = 30 * time.Second
+)
+
+var (
+	// ErrQueueCooldown は辞退・承認切れのペナルティでキューに参加できないことを表します
+	ErrQueueCooldown = errors.New("matchmaking is on cooldown")
+	// ErrNoPendingMatch は承認待ちのマッチがないことを表します
+	ErrNoPendingMatch = errors.New("no pending match")
+)
+
+// SetAcceptTimeout はマッチ成立から承認までの制限時間を設定します
+//
+// 0以下を指定すると承認ステップを省略し、マッチ成立と同時に対戦を作成します。
+func (ms *MatchmakingService) SetAcceptTimeout(timeout time.Duration) {
+	ms.acceptTimeout = timeout
+}
+
+// SetDeclineCooldown は辞退・承認切れのプレイヤーに課すキュー参加の待ち時間を設定します
+func (ms *MatchmakingService) SetDeclineCooldown(cooldown time.Duration) {
+	ms.declineCooldown = cooldown
+}
+
+// SetMatchAbortedCallback は承認待ちのマッチが辞退・期限切れで不成立になったときのコールバックを設定します
+//
+// requeued が true のプレイヤーはキューの先頭に戻され、false のプレイヤーには cooldown の間キュー参加を禁止します。
+func (ms *MatchmakingService) SetMatchAbortedCallback(callback func(userID string, room *Room, requeued bool, cooldown time.Duration)) {
+	ms.onMatchAborted = callback
+}
+
+// AcceptMatch は承認待ちのマッチを承認します
+//
+// 両プレイヤーが承認するとマッチング完了コールバックで対戦を作成します。
+func (ms *MatchmakingService) AcceptMatch(ctx context.Context, userID string) (*Room, error) {
+	ms.mu.Lock()
+	room, ok := ms.rooms[ms.userToRoom[userID]]
+	if !ok || room.Status != RoomAccepting {
+		ms.mu.Unlock()
+		return nil, ErrNoPendingMatch
+	}
+
+	room.Ready = removeString(room.Ready, userID)
+	room.Ready = append(room.Ready, userID)
+	room.UpdatedAt = time.Now()
+
+	if len(room.Ready) < len(room.Players) {
+		updated := room.clone()
+		ms.mu.Unlock()
+		log.Printf("ユーザー %s がマッチを承認しました (ルーム: %s)", userID, updated.ID)
+		ms.notifyRoomUpdate(updated)
+		return updated, nil
+	}
+
+	ms.stopAcceptTimerLocked(room.ID)
+	room.Status = "ready"
+	room.ExpiresAt = nil
+	accepted := room.clone()
+	ms.mu.Unlock()
+
+	log.Printf("マッチが両者に承認されました (ルーム: %s)", accepted.ID)
+	if ms.onMatch != nil {
+		if err := ms.onMatch(accepted); err != nil {
+			// 対戦を作成できなかった場合は両者をキューの先頭に戻す
+			ms.mu.Lock()
+			ms.deleteRoomLocked(room)
+			ms.mu.Unlock()
+			for _, player := range accepted.Players {
+				ms.requeue(ctx, player)
+			}
+			return nil, fmt.Errorf("対戦作成エラー: %w", err)
+		}
+	}
+	return ms.roomSnapshot(accepted.ID, accepted), nil
+}
+
+// DeclineMatch は承認待ちのマッチを辞退します
+//
+// 相手はキューの先頭に戻り、辞退したプレイヤーはしばらくキューに参加できなくなります。
+func (ms *MatchmakingService) DeclineMatch(ctx context.Context, userID string) error {
+	ms.mu.Lock()
+	room, ok := ms.rooms[ms.userToRoom[userID]]
+	if !ok || room.Status != RoomAccepting {
+		ms.mu.Unlock()
+		return ErrNoPendingMatch
+	}
+	ms.stopAcceptTimerLocked(room.ID)
+	ms.deleteRoomLocked(room)
+	aborted := room.clone()
+	ms.mu.Unlock()
+
+	log.Printf("ユーザー %s がマッチを辞退しました (ルーム: %s)", userID, aborted.ID)
+	ms.abortMatch(ctx, aborted, []string{userID})
+	return nil
+}
+
+// expireAcceptance は承認期限を過ぎたマッチを不成立にします
+//
+// 承認しなかったプレイヤーにペナルティを課し、承認済みのプレイヤーはキューの先頭に戻します。
+func (ms *MatchmakingService) expireAcceptance(roomID string) {
+	ms.mu.Lock()
+	room, ok := ms.rooms[roomID]
+	if !ok || room.Status != RoomAccepting {
+		ms.mu.Unlock()
+		return
+	}
+	delete(ms.acceptTimers, roomID)
+	ms.deleteRoomLocked(room)
+	aborted := room.clone()
+	ms.mu.Unlock()
+
+	penalized := make([]string, 0, len(aborted.Players))
+	for _, player := range aborted.Players {
+		if !containsString(aborted.Ready, player.UserID) {
+			penalized = append(penalized, player.UserID)
+		}
+	}
+
+	log.Printf("マッチの承認期限が切れました (ルーム: %s, 未承認: %v)", roomID, penalized)
+	ms.abortMatch(context.Background(), aborted, penalized)
+}
+
+// abortMatch は不成立になったマッチの後処理を行います (ルームは削除済み)
+func (ms *MatchmakingService) abortMatch(ctx context.Context, room *Room, penalized []string) {
+	until := time.Now().Add(ms.declineCooldown)
+	ms.mu.Lock()
+	for _, userID := range penalized {
+		ms.cooldowns[userID] = until
+	}
+	ms.mu.Unlock()
+
+	for _, player := range room.Players {
+		requeued := !containsString(penalized, player.UserID)
+		cooldown := ms.declineCooldown
+		if requeued {
+			cooldown = 0
+		}
+		if ms.onMatchAborted != nil {
+			ms.onMatchAborted(player.UserID, room, requeued, cooldown)
+		}
+		if requeued {
+			ms.requeue(ctx, player)
+		}
+	}
+}
+
+// requeue はプレイヤーを元の参加時刻で待機キューに戻します
+//
+// 待機キューは参加時刻の古い順なので、戻したプレイヤーは新しく参加した人より先にマッチします。
+func (ms *MatchmakingService) requeue(ctx context.Context, player MatchmakingRequest) {
+	err := ms.store.Enqueue(ctx, player.UserID, player.Rating, player.Timestamp)
+	if err != nil && !errors.Is(err, ErrAlreadyQueued) {
+		log.Printf("キューへの再登録エラー (ユーザー: %s): %v", player.UserID, err)
+		return
+	}
+	log.Printf("ユーザー %s をキューの先頭に戻しました", player.UserID)
+
+	room, err := ms.tryMatch(ctx, player)
+	if err != nil {
+		log.Printf("再登録後のマッチングエラー (ユーザー: %s): %v", player.UserID, err)
+	}
+	if room == nil {
+		ms.createWaitingRoom(player)
+	}
+}
+
+// proposeLocked はマッチしたルームを承認待ちにして期限タイマーを開始します (ms.mu取得済み)
+func (ms *MatchmakingService) proposeLocked(room *Room) {
+	expiresAt := time.Now().Add(ms.acceptTimeout)
+	room.Status = RoomAccepting
+	room.ExpiresAt = &expiresAt
+
+	roomID := room.ID
+	ms.acceptTimers[roomID] = time.AfterFunc(ms.acceptTimeout, func() {
+		ms.expireAcceptance(roomID)
+	})
+}
+
+// stopAcceptTimerLocked は承認期限タイマーを止めます (ms.mu取得済み)
+func (ms *MatchmakingService) stopAcceptTimerLocked(roomID string) {
+	if timer, ok := ms.acceptTimers[roomID]; ok {
+		timer.Stop()
+		delete(ms.acceptTimers, roomID)
+	}
+}
+
+// SetCooldown は別インスタンスで課されたキュー参加の禁止を記録します
+//
+// 承認待ちのマッチは担当インスタンスで不成立になるため、ペナルティを全インスタンスで共有するのに使います。
+func (ms *MatchmakingService) SetCooldown(userID string, until time.Time) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if until.After(ms.cooldowns[userID]) {
+		ms.cooldowns[userID] = until
+	}
+}
+
+// cooldownUntil はユーザーのキュー参加禁止の期限を返します。禁止されていない場合はゼロ値を返します
+func (ms *MatchmakingService) cooldownUntil(userID string) time.Time {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	until, ok := ms.cooldowns[userID]
+	if !ok {
+		return time.Time{}
+	}
+	if time.Now().After(until) {
+		delete(ms.cooldowns, userID)
+		return time.Time{}
+	}
+	return until
+}
+
+// pruneCooldownsLocked は期限切れのペナルティを削除します (ms.mu取得済み)
+func (ms *MatchmakingService) pruneCooldownsLocked(now time.Time) {
+	for userID, until := range ms.cooldowns {
+		if now.After(until) {
+			delete(ms.cooldowns, userID)
+		}
+	}
+}
+
+// containsString は slice に value が含まれるかを返します
+func containsString(slice []string, value string) bool {
+	for _, s := range slice {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
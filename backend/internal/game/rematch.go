@@ -0,0 +1,204 @@
+// backend/internal/game/rematch.go
+package game
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 再戦のアクション (終了した対戦に対して送る)
+const (
+	// ActionRematch は再戦の申し込みです。両プレイヤーが申し込むと再戦が成立します
+	ActionRematch = "rematch"
+	// ActionDeclineRematch は再戦の申し込みを断ります
+	ActionDeclineRematch = "decline_rematch"
+)
+
+// rematchWindow は対戦終了後に再戦を申し込める期間です
+const rematchWindow = 2 * time.Minute
+
+// RematchEvent は再戦の申し込み状況の変化です
+type RematchEvent struct {
+	DuelID    string   `json:"duelId"`              // 終了した対戦のID
+	NewDuelID string   `json:"newDuelId,omitempty"` // 成立した再戦の対戦ID
+	UserID    string   `json:"userId"`              // 申し込み・辞退したプレイヤー
+	Status    string   `json:"status"`              // "offered", "declined", "accepted", "expired"
+	Players   []string `json:"players"`
+}
+
+// rematchState は終了した対戦1件の再戦の申し込み状況です
+//
+// 申し込み期間が終わると expiry で削除します。
+type rematchState struct {
+	offers    map[string]bool
+	newDuelID string
+	expiry    *time.Timer
+}
+
+// SetRematchCallback は再戦の申し込み状況が変わったときのコールバックを設定します
+//
+// 再戦が成立した場合は、コールバックの前に新しい対戦が作成されています。
+func (ds *DuelService) SetRematchCallback(callback func(event *RematchEvent)) {
+	ds.onRematch = callback
+}
+
+// rematchLocked は再戦のアクションを処理し、通知するイベントを返します (ds.mu取得済み)
+//
+// 再戦が成立した場合は作成する対戦のオプションも返します。マッチメイキングは通さず、
+// 先手と後手を入れ替えて同じプレイヤー同士で対戦します。
+func (ds *DuelService) rematchLocked(duel *Duel, action GameAction) (*RematchEvent, *DuelOptions) {
+	if duel.Status != "finished" {
+		log.Printf("終了していない対戦には再戦を申し込めません: %s", duel.ID)
+		return nil, nil
+	}
+	if duel.MatchID != "" {
+		log.Printf("シリーズの試合には再戦を申し込めません: %s", duel.ID)
+		return nil, nil
+	}
+	if duel.Players[0].UserID != action.PlayerID && duel.Players[1].UserID != action.PlayerID {
+		log.Printf("プレイヤーが対戦に参加していません: %s", action.PlayerID)
+		return nil, nil
+	}
+
+	event := &RematchEvent{
+		DuelID:  duel.ID,
+		UserID:  action.PlayerID,
+		Players: []string{duel.Players[0].UserID, duel.Players[1].UserID},
+	}
+
+	state, ok := ds.rematches[duel.ID]
+
+	// 成立済みの場合は新しい対戦を再度通知する
+	if ok && state.newDuelID != "" {
+		event.Status = "accepted"
+		event.NewDuelID = state.newDuelID
+		return event, nil
+	}
+
+	remaining := rematchWindow
+	if duel.FinishedAt != nil {
+		remaining -= time.Since(*duel.FinishedAt)
+	}
+	if remaining <= 0 {
+		log.Printf("再戦の申し込み期限を過ぎています: %s", duel.ID)
+		return nil, nil
+	}
+
+	if action.ActionType == ActionDeclineRematch {
+		if ok {
+			state.expiry.Stop()
+			delete(ds.rematches, duel.ID)
+		}
+		event.Status = "declined"
+		return event, nil
+	}
+
+	if !ok {
+		duelID := duel.ID
+		state = &rematchState{offers: make(map[string]bool)}
+		state.expiry = time.AfterFunc(remaining, func() { ds.expireRematch(duelID) })
+		ds.rematches[duel.ID] = state
+	}
+
+	state.offers[action.PlayerID] = true
+	if len(state.offers) < 2 {
+		event.Status = "offered"
+		return event, nil
+	}
+
+	state.newDuelID = uuid.New().String()
+	event.Status = "accepted"
+	event.NewDuelID = state.newDuelID
+	return event, &DuelOptions{Unrated: duel.Unrated}
+}
+
+// rematchPendingLocked は終了した対戦で再戦の申し込みが期限内に残っているかを返します (ds.mu取得済み)
+func (ds *DuelService) rematchPendingLocked(duel *Duel) bool {
+	state, ok := ds.rematches[duel.ID]
+	if !ok || state.newDuelID != "" || len(state.offers) == 0 {
+		return false
+	}
+	return duel.FinishedAt == nil || time.Since(*duel.FinishedAt) <= rematchWindow
+}
+
+// expireRematch は申し込み期間の終わった再戦の申し込み状況を削除し、成立していなければ通知します
+func (ds *DuelService) expireRematch(duelID string) {
+	ds.mu.Lock()
+	state, ok := ds.rematches[duelID]
+	if !ok {
+		ds.mu.Unlock()
+		return
+	}
+	delete(ds.rematches, duelID)
+	var event *RematchEvent
+	if duel, exists := ds.duels[duelID]; exists && state.newDuelID == "" {
+		event = &RematchEvent{
+			DuelID:  duelID,
+			Status:  "expired",
+			Players: []string{duel.Players[0].UserID, duel.Players[1].UserID},
+		}
+	}
+	ds.mu.Unlock()
+
+	if event != nil && ds.onRematch != nil {
+		ds.onRematch(event)
+	}
+}
+
+// CancelRematches はユーザーが参加した対戦の、成立していない再戦の申し込みを取り消します
+//
+// 切断したユーザーへの申し込みが期限まで残らないように、接続が切れたときに呼び出します。
+// 取り消した申し込みは辞退として通知します。
+func (ds *DuelService) CancelRematches(userID string) {
+	ds.mu.Lock()
+	var events []*RematchEvent
+	for duelID, state := range ds.rematches {
+		duel, exists := ds.duels[duelID]
+		if state.newDuelID != "" || !exists ||
+			(duel.Players[0].UserID != userID && duel.Players[1].UserID != userID) {
+			continue
+		}
+		state.expiry.Stop()
+		delete(ds.rematches, duelID)
+		events = append(events, &RematchEvent{
+			DuelID:  duelID,
+			UserID:  userID,
+			Status:  "declined",
+			Players: []string{duel.Players[0].UserID, duel.Players[1].UserID},
+		})
+	}
+	ds.mu.Unlock()
+
+	for _, event := range events {
+		log.Printf("ユーザー %s の切断により再戦の申し込みを取り消しました: %s", userID, event.DuelID)
+		if ds.onRematch != nil {
+			ds.onRematch(event)
+		}
+	}
+}
+
+// finishRematch は成立した再戦の対戦を作成し、イベントを通知します
+func (ds *DuelService) finishRematch(event *RematchEvent, opts *DuelOptions) {
+	if event == nil {
+		return
+	}
+	if opts != nil {
+		// 先手と後手を入れ替える
+		if err := ds.CreateDuelWithOptions(event.NewDuelID, event.Players[1], event.Players[0], *opts); err != nil {
+			log.Printf("再戦の対戦作成エラー (対戦: %s): %v", event.DuelID, err)
+			return
+		}
+		log.Printf("再戦が成立しました: %s -> %s", event.DuelID, event.NewDuelID)
+	}
+	if ds.onRematch != nil {
+		ds.onRematch(event)
+	}
+}
+
+// finishedNow は対戦の終了時刻を返します
+func finishedNow() *time.Time {
+	now := time.Now()
+	return &now
+}
@@ -0,0 +1,108 @@
+// backend/internal/game/rematch_test.go
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// finishedDuel は終了した対戦を作成し、再戦のイベントを受け取るチャネルを返します
+func finishedDuel(t *testing.T) (*DuelService, string, chan *RematchEvent) {
+	t.Helper()
+	ds := NewDuelService([]Card{{ID: 1, Name: "テスト", AttackPts: 1, DefensePts: 1}})
+	events := make(chan *RematchEvent, 4)
+	ds.SetRematchCallback(func(event *RematchEvent) { events <- event })
+
+	duelID, err := ds.CreateDuel("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.ForceEnd(duelID, "alice", true); err != nil {
+		t.Fatal(err)
+	}
+	return ds, duelID, events
+}
+
+// nextRematchEvent は次の再戦のイベントを待ちます
+func nextRematchEvent(t *testing.T, events chan *RematchEvent) *RematchEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("再戦のイベントが通知されませんでした")
+		return nil
+	}
+}
+
+func TestRematchExpires(t *testing.T) {
+	ds, duelID, events := finishedDuel(t)
+
+	// 申し込み期間の終わる直前に申し込む
+	ds.mu.Lock()
+	finishedAt := time.Now().Add(-rematchWindow + 50*time.Millisecond)
+	ds.duels[duelID].FinishedAt = &finishedAt
+	ds.mu.Unlock()
+
+	if err := ds.SubmitAction(GameAction{DuelID: duelID, PlayerID: "alice", ActionType: ActionRematch}); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextRematchEvent(t, events); event.Status != "offered" {
+		t.Fatalf("申し込みのイベント = %q, want offered", event.Status)
+	}
+	if !ds.RematchPending("alice") {
+		t.Error("再戦の申し込み中になっていません")
+	}
+
+	if event := nextRematchEvent(t, events); event.Status != "expired" {
+		t.Fatalf("期限切れのイベント = %q, want expired", event.Status)
+	}
+	ds.mu.RLock()
+	remaining := len(ds.rematches)
+	ds.mu.RUnlock()
+	if remaining != 0 {
+		t.Errorf("期限切れ後の申し込み状況 = %d件, want 0", remaining)
+	}
+	if ds.RematchPending("alice") {
+		t.Error("期限切れ後も再戦の申し込み中になっています")
+	}
+}
+
+func TestCancelRematches(t *testing.T) {
+	ds, duelID, events := finishedDuel(t)
+
+	if err := ds.SubmitAction(GameAction{DuelID: duelID, PlayerID: "alice", ActionType: ActionRematch}); err != nil {
+		t.Fatal(err)
+	}
+	nextRematchEvent(t, events)
+
+	// 相手が切断すると申し込みは辞退として取り消される
+	ds.CancelRematches("bob")
+	event := nextRematchEvent(t, events)
+	if event.Status != "declined" || event.UserID != "bob" {
+		t.Errorf("切断時のイベント = %+v, want bob の辞退", event)
+	}
+	ds.mu.RLock()
+	remaining := len(ds.rematches)
+	ds.mu.RUnlock()
+	if remaining != 0 {
+		t.Errorf("取り消し後の申し込み状況 = %d件, want 0", remaining)
+	}
+}
+
+func TestDuelIDsSkipsFinishedAfterRematchWindow(t *testing.T) {
+	ds, duelID, _ := finishedDuel(t)
+
+	// 再戦を申し込める期間内は所有者キーを延長する
+	if ids := ds.DuelIDs(); len(ids) != 1 || ids[0] != duelID {
+		t.Fatalf("終了直後の DuelIDs = %v, want [%s]", ids, duelID)
+	}
+
+	ds.mu.Lock()
+	finishedAt := time.Now().Add(-rematchWindow - time.Second)
+	ds.duels[duelID].FinishedAt = &finishedAt
+	ds.mu.Unlock()
+	if ids := ds.DuelIDs(); len(ids) != 0 {
+		t.Errorf("再戦の申し込み期間後の DuelIDs = %v, want []", ids)
+	}
+}
@@ -2,7 +2,6 @@
 package game
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -35,12 +34,31 @@ type Player struct {
 
 // Duel は対戦情報を表します
 type Duel struct {
-	ID        string    `json:"id"`
-	Players   [2]Player `json:"players"`
-	TurnCount int       `json:"turnCount"`
-	ActiveIdx int       `json:"activeIdx"` // 手番プレイヤーのインデックス
-	Status    string    `json:"status"`    // "waiting", "active", "finished"
-	StartedAt time.Time `json:"startedAt"`
+	ID         string     `json:"id"`
+	Players    [2]Player  `json:"players"`
+	TurnCount  int        `json:"turnCount"`
+	ActiveIdx  int        `json:"activeIdx"` // 手番プレイヤーのインデックス
+	Status     string     `json:"status"`    // "waiting", "active", "finished"
+	StartedAt  time.Time  `json:"startedAt"`
+	Version    int        `json:"version"`            // アクションを処理するたびに増加する状態バージョン
+	WinnerID   string     `json:"winnerId,omitempty"` // 引き分けの場合は空
+	Unrated    bool       `json:"unrated,omitempty"`  // レーティングに反映しない対戦 (ボット戦など)
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	MatchID    string     `json:"matchId,omitempty"` // Best-of-N のシリーズに属する場合のシリーズID
+
+	acted [2]bool // 一度でもアクションを送ったプレイヤー (大会の不戦敗の判定に使用)
+
+	// 手番のプレイヤーのこのターンの行動 (ターン終了でリセット)
+	turnPlays   int         // 手札から場に出したカードの枚数
+	turnAttacks map[int]int // 攻撃した場のカードIDごとの回数
+}
+
+// DuelOptions は対戦作成時のオプションです
+type DuelOptions struct {
+	// Unrated が true の対戦は終了してもレーティングを更新しません
+	Unrated bool
+	// MatchID は対戦が属する Best-of-N のシリーズです
+	MatchID string
 }
 
 // GameAction はプレーヤーのアクションを表します
@@ -67,6 +85,12 @@ type DuelService struct {
 	actions  chan GameAction
 	cardPool []Card
 	mu       sync.RWMutex
+	onCreate func(duel *Duel)   // 対戦作成時のコールバック
+	onUpdate func(duel *Duel)   // 対戦状態更新時のコールバック
+	onFinish []func(duel *Duel) // 対戦終了時のコールバック
+
+	rematches map[string]*rematchState  // 終了した対戦ID -> 再戦の申し込み状況
+	onRematch func(event *RematchEvent) // 再戦の申し込み状況が変わったときのコールバック
 }
 
 const (
@@ -74,19 +98,65 @@ const (
 	startMana  = 1
 	startHand  = 3
 	maxManaCap = 10
+
+	// maxPlaysPerTurn は1ターンに手札から場に出せるカードの枚数です
+	// (場のカードはそれぞれ1ターンに1回だけ攻撃できます)
+	maxPlaysPerTurn = 1
 )
 
 // NewDuelService は新しい対戦サービスを作成します
 func NewDuelService(cards []Card) *DuelService {
 	ds := &DuelService{
-		duels:    make(map[string]*Duel),
-		actions:  make(chan GameAction, 100),
-		cardPool: cards,
+		duels:     make(map[string]*Duel),
+		actions:   make(chan GameAction, 100),
+		cardPool:  cards,
+		rematches: make(map[string]*rematchState),
 	}
 	go ds.processActions()
 	return ds
 }
 
+// SetCreateCallback は対戦作成時のコールバックを設定します
+func (ds *DuelService) SetCreateCallback(callback func(duel *Duel)) {
+	ds.onCreate = callback
+}
+
+// SetUpdateCallback はアクション処理後に対戦状態を受け取るコールバックを設定します
+func (ds *DuelService) SetUpdateCallback(callback func(duel *Duel)) {
+	ds.onUpdate = callback
+}
+
+// AddFinishCallback は対戦終了時に一度だけ呼ばれるコールバックを追加します
+func (ds *DuelService) AddFinishCallback(callback func(duel *Duel)) {
+	ds.onFinish = append(ds.onFinish, callback)
+}
+
+// Clone は対戦情報のディープコピーを返します
+func (d *Duel) Clone() *Duel {
+	c := *d
+	for i := range d.Players {
+		c.Players[i].Hand = append(make([]Card, 0, len(d.Players[i].Hand)), d.Players[i].Hand...)
+		c.Players[i].PlayArea = append(make([]Card, 0, len(d.Players[i].PlayArea)), d.Players[i].PlayArea...)
+	}
+	c.turnAttacks = make(map[int]int, len(d.turnAttacks))
+	for cardID, n := range d.turnAttacks {
+		c.turnAttacks[cardID] = n
+	}
+	return &c
+}
+
+// CanPlayCard は手番のプレイヤーがこのターンにまだ手札のカードを場に出せるかを返します
+func (d *Duel) CanPlayCard() bool {
+	return d.turnPlays < maxPlaysPerTurn
+}
+
+// AttacksMade は手番のプレイヤーがこのターンに cardID の場のカードで攻撃した回数を返します
+//
+// 同じIDのカードが場に複数ある場合は、その枚数まで攻撃できます。
+func (d *Duel) AttacksMade(cardID int) int {
+	return d.turnAttacks[cardID]
+}
+
 // processActions はゲームアクションを処理します
 func (ds *DuelService) processActions() {
 	for action := range ds.actions {
@@ -98,6 +168,21 @@ func (ds *DuelService) processActions() {
 			continue
 		}
 
+		// 再戦の申し込みは終了した対戦に対して行う
+		if action.ActionType == ActionRematch || action.ActionType == ActionDeclineRematch {
+			event, opts := ds.rematchLocked(duel, action)
+			ds.mu.Unlock()
+			ds.finishRematch(event, opts)
+			continue
+		}
+
+		// 終了した対戦へのアクションは無視
+		if duel.Status != "active" {
+			ds.mu.Unlock()
+			log.Printf("対戦は進行中ではありません: %s", action.DuelID)
+			continue
+		}
+
 		// プレイヤーが対戦相手かどうか確認
 		playerIdx := -1
 		for i, p := range duel.Players {
@@ -120,13 +205,13 @@ func (ds *DuelService) processActions() {
 			continue
 		}
 
+		duel.acted[playerIdx] = true
+
 		// アクションタイプに応じた処理
 		switch action.ActionType {
 		case "play_card":
-			// カードをプレイするロジック
-			// 1. プレイヤーの手札にカードがあるか確認
-			// 2. カードをプレイエリアに移動
-			// ...
+			// 手札のカードをプレイエリアに移動 (1ターンに maxPlaysPerTurn 枚まで)
+			ds.playCard(duel, playerIdx, action.CardID)
 
 		case "attack":
 			// 攻撃ロジック
@@ -134,20 +219,52 @@ func (ds *DuelService) processActions() {
 			// 2. ダメージ計算と適用
 			// ...
 
-			// 例：攻撃カードの効果を適用
+			// 例：攻撃カードの効果を適用 (場のカードはそれぞれ1ターンに1回まで)
 			ds.applyAttack(duel, playerIdx, action.CardID, action.TargetID)
 
 		case "pass":
 			// ターンを終了して次のプレイヤーへ
 			duel.ActiveIdx = (duel.ActiveIdx + 1) % 2
 			duel.TurnCount++
+			duel.turnPlays = 0
+			duel.turnAttacks = nil
 		}
 
 		// 勝敗確認
-		ds.checkGameEnd(duel)
+		finished := ds.checkGameEnd(duel)
+		duel.Version++
 
+		snapshot := duel.Clone()
 		ds.mu.Unlock()
+
+		if ds.onUpdate != nil {
+			ds.onUpdate(snapshot)
+		}
+		if finished {
+			for _, callback := range ds.onFinish {
+				callback(snapshot)
+			}
+		}
+	}
+}
+
+// playCard は手札のカードをプレイエリアに移動します
+func (ds *DuelService) playCard(duel *Duel, playerIdx, cardID int) {
+	player := &duel.Players[playerIdx]
+	if !duel.CanPlayCard() {
+		log.Printf("プレイヤー %s はこのターンにこれ以上カードを出せません", player.UserID)
+		return
 	}
+	for i, card := range player.Hand {
+		if card.ID == cardID {
+			player.Hand = append(player.Hand[:i], player.Hand[i+1:]...)
+			player.PlayArea = append(player.PlayArea, card)
+			duel.turnPlays++
+			log.Printf("プレイヤー %s がカード %s をプレイしました", player.UserID, card.Name)
+			return
+		}
+	}
+	log.Printf("手札にカードが見つかりません: %d", cardID)
 }
 
 // applyAttack は攻撃カードの効果を適用します
@@ -161,10 +278,13 @@ func (ds *DuelService) applyAttack(duel *Duel, attackerIdx, cardID, targetID int
 
 	// 攻撃カードを見つける
 	var attackCard *Card
+	copies := 0
 	for i := range attacker.PlayArea {
 		if attacker.PlayArea[i].ID == cardID {
-			attackCard = &attacker.PlayArea[i]
-			break
+			if attackCard == nil {
+				attackCard = &attacker.PlayArea[i]
+			}
+			copies++
 		}
 	}
 
@@ -172,6 +292,15 @@ func (ds *DuelService) applyAttack(duel *Duel, attackerIdx, cardID, targetID int
 		log.Printf("攻撃カードが見つかりません: %d", cardID)
 		return
 	}
+	// 同じIDのカードは場にある枚数まで攻撃できる
+	if duel.AttacksMade(cardID) >= copies {
+		log.Printf("カード %s はこのターンに攻撃済みです", attackCard.Name)
+		return
+	}
+	if duel.turnAttacks == nil {
+		duel.turnAttacks = make(map[int]int)
+	}
+	duel.turnAttacks[cardID]++
 
 	// 対象カードがある場合（カード対カードの攻撃）
 	if targetID > 0 {
@@ -201,21 +330,26 @@ func (ds *DuelService) applyAttack(duel *Duel, attackerIdx, cardID, targetID int
 	}
 }
 
-// checkGameEnd はゲーム終了条件をチェックします
-func (ds *DuelService) checkGameEnd(duel *Duel) {
+// checkGameEnd はゲーム終了条件をチェックし、終了した場合は true を返します
+func (ds *DuelService) checkGameEnd(duel *Duel) bool {
 	for i, player := range duel.Players {
 		if player.HP <= 0 {
 			duel.Status = "finished"
-			log.Printf("ゲーム終了: プレイヤー %s の勝利", duel.Players[(i+1)%2].UserID)
-			return
+			duel.FinishedAt = finishedNow()
+			duel.WinnerID = duel.Players[(i+1)%2].UserID
+			log.Printf("ゲーム終了: プレイヤー %s の勝利", duel.WinnerID)
+			return true
 		}
 	}
 
-	// ターン数が上限に達した場合も終了
+	// ターン数が上限に達した場合は引き分けで終了
 	if duel.TurnCount >= 30 {
 		duel.Status = "finished"
+		duel.FinishedAt = finishedNow()
 		log.Printf("ゲーム終了: ターン制限に達しました")
+		return true
 	}
+	return false
 }
 
 // CreateDuel は新しい対戦を作成します
@@ -240,39 +374,115 @@ func (s *DuelService) newPlayer(uid string) *Player {
 		PlayArea: make([]Card, 0),
 		DeckSize: 30,
 	}
-	for i := 0; i < startHand && len(s.cardPool) > 0; i++ {
-		idx := rand.Intn(len(s.cardPool))
-		p.Hand = append(p.Hand, s.cardPool[idx])
+	pool := s.cards()
+	for i := 0; i < startHand && len(pool) > 0; i++ {
+		idx := rand.Intn(len(pool))
+		p.Hand = append(p.Hand, pool[idx])
 	}
 	return p
 }
 
 // CreateDuelWithID creates a new duel using the provided ID.
 func (s *DuelService) CreateDuelWithID(id, p1, p2 string) error {
+	return s.CreateDuelWithOptions(id, p1, p2, DuelOptions{})
+}
+
+// CreateDuelWithOptions は指定したIDとオプションで対戦を作成します
+func (s *DuelService) CreateDuelWithOptions(id, p1, p2 string, opts DuelOptions) error {
 	duel := &Duel{
 		ID:        id,
 		Players:   [2]Player{*s.newPlayer(p1), *s.newPlayer(p2)},
 		TurnCount: 1,
 		ActiveIdx: 0,
 		Status:    "active",
+		Version:   1,
+		Unrated:   opts.Unrated,
+		MatchID:   opts.MatchID,
 	}
 	s.mu.Lock()
 	s.duels[id] = duel
+	snapshot := duel.Clone()
 	s.mu.Unlock()
+
+	if s.onCreate != nil {
+		s.onCreate(snapshot)
+	}
 	return nil
 }
 
-// GetDuel は対戦情報を取得します
+// GetDuel は対戦情報のスナップショットを取得します
 func (ds *DuelService) GetDuel(duelID string) (*Duel, error) {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
 
 	duel, exists := ds.duels[duelID]
 	if !exists {
-		return nil, errors.New("対戦が見つかりません")
+		return nil, ErrDuelNotFound
 	}
 
-	return duel, nil
+	return duel.Clone(), nil
+}
+
+// HasDuel はこのインスタンスが対戦を保持しているかを返します
+func (ds *DuelService) HasDuel(duelID string) bool {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	_, exists := ds.duels[duelID]
+	return exists
+}
+
+// DuelIDs はこのインスタンスが保持している対戦のうち、進行中か再戦を申し込める期間内の対戦IDの一覧を返します
+//
+// 終了した対戦は保持し続けるため、期間を過ぎたものは含めません。
+func (ds *DuelService) DuelIDs() []string {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	ids := make([]string, 0, len(ds.duels))
+	for id, duel := range ds.duels {
+		if duel.Status == "finished" && duel.FinishedAt != nil && time.Since(*duel.FinishedAt) > rematchWindow {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// RematchPending はユーザーがこのインスタンスで終了した対戦の再戦を申し込み中かを返します
+//
+// 申し込みは期限で消えるため、進行中の対戦と違っていつまでも続くことはありません。
+func (ds *DuelService) RematchPending(userID string) bool {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	for _, duel := range ds.duels {
+		if duel.Players[0].UserID != userID && duel.Players[1].UserID != userID {
+			continue
+		}
+		if duel.Status == "finished" && ds.rematchPendingLocked(duel) {
+			return true
+		}
+	}
+	return false
+}
+
+// AbsentPlayer は進行中の対戦で、手番なのに一度もアクションを送っていないプレイヤーを返します
+//
+// 該当するプレイヤーがいない場合は空文字列を返します。相手の手番を待っている
+// プレイヤーはアクションを送れないため、手番のプレイヤーだけを対象にします。
+func (ds *DuelService) AbsentPlayer(duelID string) (string, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	duel, exists := ds.duels[duelID]
+	if !exists {
+		return "", ErrDuelNotFound
+	}
+	if duel.Status != "active" || duel.acted[duel.ActiveIdx] {
+		return "", nil
+	}
+	return duel.Players[duel.ActiveIdx].UserID, nil
 }
 
 // SubmitAction はプレイヤーのアクションを処理します
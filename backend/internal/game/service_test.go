@@ -0,0 +1,99 @@
+// backend/internal/game/service_test.go
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// activeDuel は手札がすべて同じカードの対戦を作成し、更新を受け取るチャネルを返します
+func activeDuel(t *testing.T) (*DuelService, string, chan *Duel) {
+	t.Helper()
+	ds := NewDuelService([]Card{{ID: 1, Name: "テスト", AttackPts: 3, DefensePts: 1}})
+	updates := make(chan *Duel, 16)
+	ds.SetUpdateCallback(func(duel *Duel) { updates <- duel })
+
+	duelID, err := ds.CreateDuel("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds, duelID, updates
+}
+
+// act はアクションを送信し、処理後の対戦状態を返します
+func act(t *testing.T, ds *DuelService, updates chan *Duel, action GameAction) *Duel {
+	t.Helper()
+	if err := ds.SubmitAction(action); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case duel := <-updates:
+		return duel
+	case <-time.After(time.Second):
+		t.Fatalf("アクション %s が処理されませんでした", action.ActionType)
+		return nil
+	}
+}
+
+func TestPlayCardOncePerTurn(t *testing.T) {
+	ds, duelID, updates := activeDuel(t)
+	play := GameAction{DuelID: duelID, PlayerID: "alice", ActionType: "play_card", CardID: 1}
+
+	duel := act(t, ds, updates, play)
+	if len(duel.Players[0].Hand) != startHand-1 || len(duel.Players[0].PlayArea) != 1 {
+		t.Fatalf("1枚目: 手札 %d 枚・場 %d 枚; want %d 枚・1 枚", len(duel.Players[0].Hand), len(duel.Players[0].PlayArea), startHand-1)
+	}
+	if duel.CanPlayCard() {
+		t.Error("1枚出した後も CanPlayCard = true")
+	}
+
+	// 同じターンの2枚目は出せない
+	duel = act(t, ds, updates, play)
+	if len(duel.Players[0].PlayArea) != 1 {
+		t.Errorf("2枚目: 場 %d 枚; want 1 枚", len(duel.Players[0].PlayArea))
+	}
+
+	// ターンが回ってくるとまた出せる
+	act(t, ds, updates, GameAction{DuelID: duelID, PlayerID: "alice", ActionType: "pass"})
+	act(t, ds, updates, GameAction{DuelID: duelID, PlayerID: "bob", ActionType: "pass"})
+	duel = act(t, ds, updates, play)
+	if len(duel.Players[0].PlayArea) != 2 {
+		t.Errorf("次のターン: 場 %d 枚; want 2 枚", len(duel.Players[0].PlayArea))
+	}
+}
+
+func TestAttackOncePerCardPerTurn(t *testing.T) {
+	ds, duelID, updates := activeDuel(t)
+	attack := GameAction{DuelID: duelID, PlayerID: "alice", ActionType: "attack", CardID: 1}
+
+	// 場にカードがなければ攻撃できない
+	duel := act(t, ds, updates, attack)
+	if duel.Players[1].HP != startHP {
+		t.Fatalf("場にカードがないときの攻撃後のHP = %d; want %d", duel.Players[1].HP, startHP)
+	}
+
+	act(t, ds, updates, GameAction{DuelID: duelID, PlayerID: "alice", ActionType: "play_card", CardID: 1})
+	duel = act(t, ds, updates, attack)
+	if duel.Players[1].HP != startHP-3 || duel.AttacksMade(1) != 1 {
+		t.Fatalf("攻撃後のHP = %d (攻撃回数 %d); want %d (1回)", duel.Players[1].HP, duel.AttacksMade(1), startHP-3)
+	}
+
+	// 同じカードで何度攻撃しても、1ターンに1回しかダメージを与えない
+	for i := 0; i < 3; i++ {
+		duel = act(t, ds, updates, attack)
+	}
+	if duel.Players[1].HP != startHP-3 {
+		t.Errorf("攻撃を繰り返した後のHP = %d; want %d", duel.Players[1].HP, startHP-3)
+	}
+
+	// 次のターンには出した2枚で2回攻撃できる
+	act(t, ds, updates, GameAction{DuelID: duelID, PlayerID: "alice", ActionType: "pass"})
+	act(t, ds, updates, GameAction{DuelID: duelID, PlayerID: "bob", ActionType: "pass"})
+	act(t, ds, updates, GameAction{DuelID: duelID, PlayerID: "alice", ActionType: "play_card", CardID: 1})
+	for i := 0; i < 3; i++ {
+		duel = act(t, ds, updates, attack)
+	}
+	if duel.Players[1].HP != startHP-9 {
+		t.Errorf("2枚で攻撃した後のHP = %d; want %d", duel.Players[1].HP, startHP-9)
+	}
+}
@@ -0,0 +1,41 @@
+// backend/internal/game/write_queue.go
+package game
+
+import "log"
+
+// writeQueueSize は処理待ちにできる書き込みの数です
+const writeQueueSize = 256
+
+// writeQueue は対戦終了時のDB書き込みを専用のgoroutineで順に実行します
+//
+// 対戦終了コールバックは対戦のアクションを処理するgoroutineから呼ばれるため、
+// そこでDBを待つと他の対戦の進行まで止まります。書き込みは追加した順に1件ずつ実行します。
+type writeQueue struct {
+	name string
+	jobs chan func()
+}
+
+// newWriteQueue は書き込みキューを作成し、処理を開始します
+func newWriteQueue(name string) *writeQueue {
+	q := &writeQueue{name: name, jobs: make(chan func(), writeQueueSize)}
+	go q.run()
+	return q
+}
+
+// enqueue は書き込みを追加します
+//
+// キューが一杯の場合は空くまで待ちます (書き込みを捨てると結果が失われるため)。
+func (q *writeQueue) enqueue(job func()) {
+	select {
+	case q.jobs <- job:
+	default:
+		log.Printf("%sの書き込みキューが一杯です (%d件)。空くまで待ちます", q.name, writeQueueSize)
+		q.jobs <- job
+	}
+}
+
+func (q *writeQueue) run() {
+	for job := range q.jobs {
+		job()
+	}
+}
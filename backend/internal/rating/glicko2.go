@@ -0,0 +1,129 @@
+// backend/internal/rating/glicko2.go
+package rating
+
+import "math"
+
+const (
+	// DefaultRating は新規プレイヤーのレーティングです
+	DefaultRating = 1500.0
+	// DefaultRD は新規プレイヤーのレーティング偏差です
+	DefaultRD = 350.0
+	// DefaultVolatility は新規プレイヤーのボラティリティです
+	DefaultVolatility = 0.06
+
+	// glickoScale はGlicko尺度とGlicko-2尺度の変換係数です
+	glickoScale = 173.7178
+	// tau はボラティリティの変化量を制約する定数です (0.3〜1.2が推奨)
+	tau = 0.5
+	// epsilon はボラティリティ計算の収束判定値です
+	epsilon = 0.000001
+)
+
+// Rating はGlicko-2のレーティングです
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	RD         float64 `json:"rd"`
+	Volatility float64 `json:"volatility"`
+}
+
+// Default は新規プレイヤーの初期レーティングを返します
+func Default() Rating {
+	return Rating{Rating: DefaultRating, RD: DefaultRD, Volatility: DefaultVolatility}
+}
+
+// Result は1試合の結果です。Score は勝ち1、引き分け0.5、負け0です
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+// 試合結果のスコア
+const (
+	Win  = 1.0
+	Draw = 0.5
+	Loss = 0.0
+)
+
+// Update は評価期間内の試合結果からレーティングを更新します
+//
+// Glickman "Example of the Glicko-2 system" の手順に従います。
+func Update(player Rating, results []Result) Rating {
+	mu := (player.Rating - DefaultRating) / glickoScale
+	phi := player.RD / glickoScale
+	sigma := player.Volatility
+
+	// 試合がない場合は偏差だけが広がる
+	if len(results) == 0 {
+		phiStar := math.Sqrt(phi*phi + sigma*sigma)
+		return Rating{Rating: player.Rating, RD: phiStar * glickoScale, Volatility: sigma}
+	}
+
+	var vInv, deltaSum float64
+	for _, r := range results {
+		muJ := (r.Opponent.Rating - DefaultRating) / glickoScale
+		phiJ := r.Opponent.RD / glickoScale
+		gJ := g(phiJ)
+		e := expected(mu, muJ, gJ)
+		vInv += gJ * gJ * e * (1 - e)
+		deltaSum += gJ * (r.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * deltaSum
+
+	newSigma := volatility(phi, sigma, v, delta)
+	phiStar := math.Sqrt(phi*phi + newSigma*newSigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*deltaSum
+
+	return Rating{
+		Rating:     newMu*glickoScale + DefaultRating,
+		RD:         newPhi * glickoScale,
+		Volatility: newSigma,
+	}
+}
+
+// g は相手の偏差による重み付けです
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// expected は相手に対する期待スコアです
+func expected(mu, muJ, gJ float64) float64 {
+	return 1 / (1 + math.Exp(-gJ*(mu-muJ)))
+}
+
+// volatility は Illinois 法で新しいボラティリティを求めます
+func volatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
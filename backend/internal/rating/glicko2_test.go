@@ -0,0 +1,86 @@
+// backend/internal/rating/glicko2_test.go
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+// glickmanPlayer と glickmanResults は Glickman "Example of the Glicko-2 system" の例です
+var (
+	glickmanPlayer  = Rating{Rating: 1500, RD: 200, Volatility: 0.06}
+	glickmanResults = []Result{
+		{Opponent: Rating{Rating: 1400, RD: 30, Volatility: DefaultVolatility}, Score: Win},
+		{Opponent: Rating{Rating: 1550, RD: 100, Volatility: DefaultVolatility}, Score: Loss},
+		{Opponent: Rating{Rating: 1700, RD: 300, Volatility: DefaultVolatility}, Score: Loss},
+	}
+)
+
+func assertNear(t *testing.T, name string, got, want, tolerance float64) {
+	t.Helper()
+	if math.Abs(got-want) > tolerance {
+		t.Errorf("%s = %.6f; want %.6f (±%g)", name, got, want, tolerance)
+	}
+}
+
+func TestUpdateGlickmanExample(t *testing.T) {
+	got := Update(glickmanPlayer, glickmanResults)
+
+	// 論文の結果: r' = 1464.06, RD' = 151.52, σ' = 0.05999
+	assertNear(t, "Rating", got.Rating, 1464.06, 0.01)
+	assertNear(t, "RD", got.RD, 151.52, 0.01)
+	assertNear(t, "Volatility", got.Volatility, 0.05999, 0.00001)
+}
+
+func TestGlickmanIntermediateValues(t *testing.T) {
+	mu := (glickmanPlayer.Rating - DefaultRating) / glickoScale
+	phi := glickmanPlayer.RD / glickoScale
+
+	// 論文の Step 3 の表: g(φj) と E(μ, μj, φj)
+	wantG := []float64{0.9955, 0.9531, 0.7242}
+	wantE := []float64{0.639, 0.432, 0.303}
+	var vInv, deltaSum float64
+	for i, r := range glickmanResults {
+		muJ := (r.Opponent.Rating - DefaultRating) / glickoScale
+		gJ := g(r.Opponent.RD / glickoScale)
+		e := expected(mu, muJ, gJ)
+		assertNear(t, "g", gJ, wantG[i], 0.0001)
+		assertNear(t, "E", e, wantE[i], 0.001)
+		vInv += gJ * gJ * e * (1 - e)
+		deltaSum += gJ * (r.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * deltaSum
+
+	// Step 3, 4: v = 1.7785, Δ = -0.4834 (論文は丸めた g・E から計算しているため誤差を広めにとる)
+	assertNear(t, "v", v, 1.7785, 0.001)
+	assertNear(t, "Δ", delta, -0.4834, 0.001)
+	// Step 5: σ' = 0.05999
+	assertNear(t, "σ'", volatility(phi, glickmanPlayer.Volatility, v, delta), 0.05999, 0.00001)
+}
+
+func TestUpdateWithoutResults(t *testing.T) {
+	got := Update(glickmanPlayer, nil)
+
+	// 試合がない期間はレーティングとボラティリティは変わらず、偏差だけが広がる
+	if got.Rating != glickmanPlayer.Rating || got.Volatility != glickmanPlayer.Volatility {
+		t.Errorf("Update(nil) = %+v; レーティングとボラティリティは変わらないはずです", got)
+	}
+	phi := glickmanPlayer.RD / glickoScale
+	want := math.Sqrt(phi*phi+glickmanPlayer.Volatility*glickmanPlayer.Volatility) * glickoScale
+	assertNear(t, "RD", got.RD, want, 1e-9)
+}
+
+func TestUpdateIsSymmetric(t *testing.T) {
+	a, b := Default(), Default()
+	winner := Update(a, []Result{{Opponent: b, Score: Win}})
+	loser := Update(b, []Result{{Opponent: a, Score: Loss}})
+
+	// 同じ初期値同士の対戦では上がった分だけ下がる
+	assertNear(t, "変化量の和", (winner.Rating-DefaultRating)+(loser.Rating-DefaultRating), 0, 1e-9)
+	if winner.Rating <= DefaultRating {
+		t.Errorf("勝者のレーティング = %.2f; 初期値より上がるはずです", winner.Rating)
+	}
+	drawn := Update(a, []Result{{Opponent: b, Score: Draw}})
+	assertNear(t, "引き分け後のレーティング", drawn.Rating, DefaultRating, 1e-9)
+}
@@ -0,0 +1,34 @@
+// backend/internal/rating/window.go
+package rating
+
+import (
+	"math"
+	"time"
+)
+
+// Window はマッチングで許容するレーティング差です
+//
+// 待ち時間が長くなるほど許容差が広がり、Max で頭打ちになります。
+type Window struct {
+	Base            float64 // 待ち時間0での許容差
+	GrowthPerSecond float64 // 1秒待つごとに広がる許容差
+	Max             float64 // 許容差の上限
+}
+
+// DefaultWindow は標準の許容差を返します (約90秒でレーティング差1000まで広がる)
+func DefaultWindow() Window {
+	return Window{Base: 100, GrowthPerSecond: 10, Max: 1000}
+}
+
+// At は待ち時間 wait における許容差を返します
+func (w Window) At(wait time.Duration) float64 {
+	if wait < 0 {
+		wait = 0
+	}
+	return math.Min(w.Max, w.Base+w.GrowthPerSecond*wait.Seconds())
+}
+
+// Accepts は2人のレーティング差が、長く待っている方の待ち時間 wait で許容されるかを返します
+func (w Window) Accepts(a, b float64, wait time.Duration) bool {
+	return math.Abs(a-b) <= w.At(wait)
+}
@@ -3,27 +3,24 @@ package server
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/KOU050223/go-card/internal/admin"
 	"github.com/KOU050223/go-card/internal/auth"
+	"github.com/KOU050223/go-card/internal/bot"
 	"github.com/KOU050223/go-card/internal/db"
 	"github.com/KOU050223/go-card/internal/game"
+	"github.com/KOU050223/go-card/internal/tournament"
+	"github.com/KOU050223/go-card/internal/user"
 	"github.com/KOU050223/go-card/internal/ws"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
 )
 
-type MatchmakingAPI struct {
-	Repo           *db.MatchmakingRepository
-	DuelService    *game.DuelService
-	DuelRepository *db.DuelRepository
-}
-
-func NewMatchmakingAPI(r *db.MatchmakingRepository, ds *game.DuelService, dr *db.DuelRepository) *MatchmakingAPI {
-	return &MatchmakingAPI{Repo: r, DuelService: ds, DuelRepository: dr}
-}
-
 // setupRoutes はすべてのルートをEchoインスタンスに登録します
 func setupRoutes(e *echo.Echo, cfg *Config) {
 	// データベース初期化
@@ -35,11 +32,18 @@ func setupRoutes(e *echo.Echo, cfg *Config) {
 	// リポジトリ作成
 	userRepo := db.NewUserRepository(dbConn)
 
-	// Firebase認証ミドルウェア初期化
-	authMiddleware, err := auth.NewFirebaseMiddleware(cfg.FirebaseProject)
+	// 認証ミドルウェア初期化 (firebase モードで設定に不備があれば起動しない)
+	authMiddleware, err := auth.New(cfg.Auth)
 	if err != nil {
-		e.Logger.Fatalf("Firebase初期化エラー: %v", err)
+		e.Logger.Fatalf("認証の初期化エラー: %v", err)
 	}
+	// 初めて認証できたユーザーの users 行を作成する (対戦の外部キーのため)
+	provisioner := user.NewProvisioner(userRepo)
+	authMiddleware.SetUserProvisioner(provisioner.Ensure)
+	// リクエストごとに権限と制裁 (BAN・利用停止・チャット禁止) を確認する
+	accessCache := user.NewAccessCache(userRepo)
+	authMiddleware.SetAccessLookup(accessCache.Lookup)
+	credentialRepo := db.NewCredentialRepository(dbConn)
 
 	cardRepo := db.NewCardRepository(dbConn)
 	allCards, err := cardRepo.GetAll(context.Background())
@@ -53,10 +57,105 @@ func setupRoutes(e *echo.Echo, cfg *Config) {
 		gameCards = append(gameCards, game.Card(c))
 	}
 
+	// Best-of-N のシリーズは各試合とともにDBに保存する
+	matchRepo := db.NewMatchRepository(dbConn)
+
+	// インスタンス間中継 (未設定ならプロセス内のみ)
+	hubOpts := []ws.Option{
+		ws.WithMessageLimits(cfg.WSMessageLimits),
+		ws.WithMatchRepository(matchRepo),
+		ws.WithAccessLookup(accessCache.Lookup),
+		// 他サイトのページから認証済みのWebSocketを開かれないよう、CORSと同じOriginだけを受け付ける
+		ws.WithAllowedOrigins(cfg.AllowOrigins),
+	}
+
+	// マッチメイキングの待機キュー (RESTとWebSocketで共有)
+	if cfg.MatchmakingStore == "mysql" {
+		matchmakingRepo := db.NewMatchmakingRepository(dbConn)
+		hubOpts = append(hubOpts, ws.WithMatchmakingStore(game.NewMySQLQueueStore(matchmakingRepo)))
+	}
+	// WebSocket接続チケット (Redisがあればインスタンス間で共有する)
+	var ticketStore ws.TicketStore = ws.NewMemoryTicketStore()
+	if cfg.RedisURL != "" {
+		backplane, err := ws.NewRedisBackplane(cfg.RedisURL)
+		if err != nil {
+			e.Logger.Fatalf("Redis Backplane初期化エラー: %v", err)
+		}
+		hubOpts = append(hubOpts, ws.WithBackplane(backplane))
+		ticketStore = backplane.TicketStore()
+	}
+	tickets := ws.NewTickets(ticketStore, cfg.WSTicketTTL)
+	authMiddleware.SetTicketRedeemer(tickets.RedeemFor)
+
 	// WebSocketハブ初期化
-	hub := ws.NewHub(gameCards)
+	hub := ws.NewHub(gameCards, hubOpts...)
 	go hub.Run()
 
+	// 対戦終了時にレーティングを更新し、マッチングではレーティングの近い相手を選ぶ
+	ratingService := game.NewRatingService(userRepo)
+	hub.GetDuelService().AddFinishCallback(ratingService.OnDuelFinished)
+	hub.GetMatchmakingService().SetRatingLookup(ratingService.Rating)
+
+	// マッチ成立後の承認ステップ
+	hub.GetMatchmakingService().SetAcceptTimeout(cfg.MatchAcceptTimeout)
+	hub.GetMatchmakingService().SetDeclineCooldown(cfg.MatchDeclineCooldown)
+
+	// BAN・利用停止中のユーザーはキューにもプライベートルームにも参加できない
+	// (WebSocket接続中に利用停止された場合も、切断前のメッセージを受け付けないようにする)
+	hub.GetMatchmakingService().SetEntryCheck(func(ctx context.Context, userID string) error {
+		access, err := accessCache.Lookup(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if access != nil && access.Blocked() {
+			blocked := &auth.BlockedError{Sanction: access.Block}
+			return fmt.Errorf("%w: %s", game.ErrEntryDenied, blocked.Error())
+		}
+		return nil
+	})
+
+	// 待ち時間が長いプレイヤーはボットと対戦 (レーティングに反映しない)
+	hub.GetMatchmakingService().SetBotFallback(cfg.MatchBotAfter, bot.NewID)
+
+	// 大会 (スイス式・勝ち抜き戦)。各回戦の対戦は自動で作成し、組み合わせをWebSocketで通知する
+	// 大会は作成したインスタンスが保持し、他のインスタンスへのリクエストはそのインスタンスに転送する
+	tournaments := tournament.NewService(hub.GetDuelService())
+	tournaments.SetCluster(hub)
+	tournaments.SetRatingLookup(ratingService.Rating)
+	tournaments.SetRoundCallback(func(t *tournament.Tournament, round *tournament.Round) {
+		for _, pairing := range round.Pairings {
+			for _, userID := range []string{pairing.Player1ID, pairing.Player2ID} {
+				if userID == "" {
+					continue
+				}
+				msg := &ws.Message{Type: "tournamentRound", Content: map[string]interface{}{
+					"tournamentId": t.ID,
+					"round":        round.Number,
+					"pairing":      pairing,
+				}}
+				if err := hub.SendToUser(userID, msg); err != nil {
+					e.Logger.Warnf("大会の組み合わせ通知エラー (ユーザー: %s): %v", userID, err)
+				}
+			}
+		}
+	})
+	tournaments.SetFinishCallback(func(t *tournament.Tournament) {
+		for _, entrant := range t.Entrants {
+			msg := &ws.Message{Type: "tournamentFinished", Content: t}
+			if err := hub.SendToUser(entrant.UserID, msg); err != nil {
+				e.Logger.Warnf("大会終了通知エラー (ユーザー: %s): %v", entrant.UserID, err)
+			}
+		}
+	})
+
+	// jwt モードではサーバー自身がアカウントを管理し、トークンを発行する
+	if issuer := authMiddleware.Issuer(); issuer != nil {
+		accountAPI := auth.NewAccountAPI(credentialRepo, issuer)
+		// パスワードの総当たりとアカウントの大量作成を防ぐため、IPごとに回数を制限する
+		e.POST("/auth/signup", accountAPI.Signup, ipRateLimiter(rate.Every(time.Minute), 5))
+		e.POST("/auth/login", accountAPI.Login, ipRateLimiter(rate.Every(10*time.Second), 10))
+	}
+
 	// パブリックエンドポイント
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
@@ -65,38 +164,40 @@ func setupRoutes(e *echo.Echo, cfg *Config) {
 	// 認証が必要なエンドポイント
 	api := e.Group("/api", authMiddleware.Verify)
 
+	// メトリクス (WebSocketのレート制限拒否数など)。接続数や内部の状態が分かるため管理者のみ
+	api.GET("/debug/vars", echo.WrapHandler(expvar.Handler()), auth.RequireRole(auth.RoleAdmin))
+
+	// マッチング中・シリーズや大会への参加中 (ユーザーIDを変えたり削除したりできない間) かどうかは
+	// すべてのインスタンスに問い合わせる。大会は各インスタンスが保持している分を確認する
+	hub.AddInMatchCheck(tournaments.Participating)
+	provisioner.SetCluster(hub)
+
 	// ユーザー関連API
-	api.GET("/users/me", func(c echo.Context) error {
-		uid := c.Get("uid").(string)
-		user, err := userRepo.GetByID(c.Request().Context(), uid)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "ユーザー情報の取得に失敗しました")
-		}
-		if user == nil {
-			return echo.NewHTTPError(http.StatusNotFound, "ユーザーが見つかりません")
-		}
-		return c.JSON(http.StatusOK, user)
-	})
+	userAPI := user.NewAPI(userRepo, provisioner, accessCache, hub.InMatch, hub.Forfeit)
+	api.GET("/users/me", userAPI.GetMe)
+	api.PATCH("/users/me", userAPI.UpdateMe, auth.RequireFullAccount)
+	api.DELETE("/users/me", userAPI.DeleteMe)
+	api.GET("/users/me/export", userAPI.ExportMe)
+
+	// ゲストアカウント (jwt モード、または JWT の署名鍵を設定した firebase モード)
+	if authMiddleware.GuestIssuer() != nil {
+		guestAPI := auth.NewGuestAPI(authMiddleware, userRepo, credentialRepo, provisioner, accessCache, hub.InMatch, hub.Forfeit)
+		// ゲストは誰でも作れるため、IPごとに作成数を制限する
+		e.POST("/auth/guest", guestAPI.Create, ipRateLimiter(rate.Every(time.Minute), 5))
+		api.POST("/account/link", guestAPI.Link)
+	}
+
+	// WebSocket接続チケットの発行 (IDトークンをクエリパラメータに載せないため)
+	ticketAPI := ws.NewTicketAPI(tickets)
+	api.POST("/ws-ticket", ticketAPI.Issue)
+
 	// WebSocket接続エンドポイント
+	// 認証モードが firebase・jwt の場合は ?ticket= の接続チケットを検証する (検証できない接続は拒否)
+	// BAN・利用停止中のユーザーの接続は 403 で拒否する
 	e.GET("/ws", func(c echo.Context) error {
-		// WebSocketの場合はクエリパラメータからトークンとUIDを取得
-		token := c.QueryParam("token")
-		userID := c.QueryParam("uid")
-
-		var uid string
-		if token != "" {
-			// トークンが提供されている場合は検証
-			verifiedUID, err := authMiddleware.VerifyWebSocketToken(token)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, "トークン検証に失敗しました")
-			}
-			uid = verifiedUID
-		} else if userID != "" {
-			// UIDが直接提供されている場合はそれを使用（開発用）
-			uid = userID
-		} else {
-			// どちらもない場合はランダムなユーザーIDを生成（開発用）
-			uid = "anonymous-" + c.RealIP() + "-" + fmt.Sprintf("%d", time.Now().Unix())
+		uid, err := authMiddleware.WebSocketUser(c)
+		if err != nil {
+			return auth.WebSocketError(err)
 		}
 
 		return ws.ServeWS(c, hub, uid)
@@ -104,37 +205,79 @@ func setupRoutes(e *echo.Echo, cfg *Config) {
 
 	// Duel用WebSocket接続エンドポイント
 	e.GET("/ws/duel", func(c echo.Context) error {
-		token := c.QueryParam("token")
-		userID := c.QueryParam("uid")
-		duelID := c.QueryParam("duelId")
-
-		var uid string
-		if token != "" {
-			verifiedUID, err := authMiddleware.VerifyWebSocketToken(token)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, "トークン検証に失敗しました")
-			}
-			uid = verifiedUID
-		} else if userID != "" {
-			uid = userID
-		} else {
-			uid = "anonymous-" + c.RealIP() + "-" + fmt.Sprintf("%d", time.Now().Unix())
-		}
-
 		// duelIdが必須
-		if duelID == "" {
+		if c.QueryParam("duelId") == "" {
 			return echo.NewHTTPError(http.StatusBadRequest, "duelIdが必要です")
 		}
 
+		uid, err := authMiddleware.WebSocketUser(c)
+		if err != nil {
+			return auth.WebSocketError(err)
+		}
+
 		return ws.ServeDuelWS(c, hub, uid)
 	})
 
-	// マッチング用リポジトリとAPI
-	matchmakingRepo := db.NewMatchmakingRepository(dbConn)
-	matchmakingAPI := game.NewMatchmakingAPI(matchmakingRepo, hub.GetDuelService())
+	// マッチングAPI (WebSocketのfindMatchと同じサービス)
+	matchmakingAPI := game.NewMatchmakingAPI(hub.GetMatchmakingService(), hub)
 
 	// マッチングAPIエンドポイント
 	api.POST("/matchmaking/join", matchmakingAPI.Join)
 	api.POST("/matchmaking/cancel", matchmakingAPI.Cancel)
 	api.GET("/matchmaking/status", matchmakingAPI.Status)
+	api.POST("/matchmaking/accept", matchmakingAPI.Accept)
+	api.POST("/matchmaking/decline", matchmakingAPI.Decline)
+
+	// ボットとの練習対戦
+	practiceAPI := bot.NewPracticeAPI(hub.GetDuelService(), hub.GetBotDriver())
+	api.POST("/practice", practiceAPI.Start)
+
+	// プライベートルーム (招待コードで参加)
+	api.POST("/rooms", matchmakingAPI.CreateRoom)
+	api.POST("/rooms/join", matchmakingAPI.JoinRoom)
+	api.POST("/rooms/ready", matchmakingAPI.SetReady)
+	api.POST("/rooms/leave", matchmakingAPI.LeaveRoom)
+
+	// Best-of-N のシリーズ (負けた側が次の試合の先手・後手を選ぶ)
+	matchAPI := game.NewMatchAPI(hub.GetMatchService(), matchRepo, hub)
+	api.GET("/matches/:id", matchAPI.Get)
+	api.POST("/matches/:id/first", matchAPI.ChooseFirst)
+
+	// 大会API (ゲストアカウントは大会の作成・参加ができない)
+	tournamentAPI := tournament.NewAPI(tournaments)
+	api.POST("/tournaments", tournamentAPI.Create, auth.RequireFullAccount)
+	api.GET("/tournaments", tournamentAPI.List)
+	api.GET("/tournaments/:id", tournamentAPI.Get)
+	api.POST("/tournaments/:id/register", tournamentAPI.Register, auth.RequireFullAccount)
+	api.POST("/tournaments/:id/withdraw", tournamentAPI.Withdraw)
+	api.POST("/tournaments/:id/start", tournamentAPI.Start)
+	api.GET("/tournaments/:id/standings", tournamentAPI.Standings)
+	api.GET("/tournaments/:id/bracket", tournamentAPI.Bracket)
+
+	// 管理API (対戦の監視・強制終了と制裁はモデレーター以上、カード編集・権限変更・操作記録の閲覧は管理者のみ)
+	adminAPI := admin.NewAPI(hub, cardRepo, userRepo,
+		db.NewSanctionRepository(dbConn), db.NewAuditRepository(dbConn), accessCache, authMiddleware.EffectiveRole)
+	moderation := api.Group("/admin", auth.RequireRole(auth.RoleModerator))
+	moderation.GET("/duels", adminAPI.ListDuels)
+	moderation.GET("/duels/:id", adminAPI.GetDuel)
+	moderation.POST("/duels/:id/end", adminAPI.EndDuel)
+	moderation.GET("/users/:id", adminAPI.GetUser)
+	moderation.GET("/users/:id/sanctions", adminAPI.ListUserSanctions)
+	moderation.POST("/users/:id/sanctions", adminAPI.ApplySanction)
+	moderation.GET("/sanctions", adminAPI.ListSanctions)
+	moderation.DELETE("/sanctions/:id", adminAPI.LiftSanction)
+	moderation.PUT("/cards/:id", adminAPI.UpdateCard, auth.RequireRole(auth.RoleAdmin))
+	moderation.PUT("/users/:id/role", adminAPI.SetRole, auth.RequireRole(auth.RoleAdmin))
+	moderation.GET("/audit", adminAPI.ListAudit, auth.RequireRole(auth.RoleAdmin))
+}
+
+// ipRateLimiter はIPごとにリクエストの回数を制限するミドルウェアを返します
+func ipRateLimiter(every rate.Limit, burst int) echo.MiddlewareFunc {
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:      every,
+			Burst:     burst,
+			ExpiresIn: 10 * time.Minute,
+		}),
+	})
 }
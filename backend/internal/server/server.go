@@ -3,9 +3,13 @@ package server
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/KOU050223/go-card/internal/auth"
+	"github.com/KOU050223/go-card/internal/ws"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
@@ -18,10 +22,27 @@ type Server struct {
 
 // Config はサーバー設定を保持します
 type Config struct {
-	Port            int
-	AllowOrigins    []string
-	FirebaseProject string
-	DB              DBConfig
+	Port         int
+	AllowOrigins []string
+	// X-Forwarded-For を信頼するリバースプロキシのアドレス範囲 (空なら接続元のアドレスをそのまま使う)
+	TrustedProxies []*net.IPNet
+	DB             DBConfig
+	// 認証モード ("firebase", "jwt", "local-dev", "disabled") とFirebase・JWTの設定
+	Auth auth.Config
+	// RedisURL が設定されている場合、インスタンス間の中継にRedisを使用します
+	RedisURL string
+	// POST /api/ws-ticket で発行するWebSocket接続チケットの有効期間 (0以下で既定値)
+	WSTicketTTL time.Duration
+	// WebSocketで受信するメッセージのサイズ上限
+	WSMessageLimits ws.MessageLimits
+	// マッチメイキングの待機キューの保存先 ("memory" または "mysql")
+	MatchmakingStore string
+	// マッチ成立から両者の承認までの制限時間 (0以下で承認ステップなし)
+	MatchAcceptTimeout time.Duration
+	// マッチを辞退・承認しなかったプレイヤーがキューに参加できない時間
+	MatchDeclineCooldown time.Duration
+	// この時間待ったプレイヤーをボットと組ませる (0以下で無効)
+	MatchBotAfter time.Duration
 }
 
 // DBConfig はデータベース接続設定を保持します
@@ -35,14 +56,15 @@ type DBConfig struct {
 // New は設定に基づいて新しいサーバーインスタンスを作成します
 func New(cfg *Config) *Server {
 	e := echo.New()
+	e.IPExtractor = ipExtractor(cfg.TrustedProxies)
 
 	// ミドルウェア設定
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     cfg.AllowOrigins,
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
-		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, auth.DevUserHeader},
+		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
 		AllowCredentials: true, // 追加: Cookie/認証情報を許可
 	}))
 
@@ -55,6 +77,24 @@ func New(cfg *Config) *Server {
 	}
 }
 
+// ipExtractor はIPごとの制限に使うクライアントのIPの取り出し方を返します
+//
+// X-Forwarded-For はクライアントが自由に設定できるため、信頼するプロキシが設定されていない場合は使いません。
+func ipExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, ipRange := range trustedProxies {
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
 // Start はサーバーを指定ポートで起動します
 func (s *Server) Start(addr string) error {
 	port := ":" + strconv.Itoa(s.config.Port)
@@ -0,0 +1,142 @@
+// backend/internal/tournament/api.go
+package tournament
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxNameLength は大会名の最大文字数です
+const maxNameLength = 64
+
+// API は大会をRESTで公開します
+type API struct {
+	Service *Service
+}
+
+func NewAPI(service *Service) *API {
+	return &API{Service: service}
+}
+
+// tournamentError は大会操作のエラーをHTTPエラーに変換します
+func tournamentError(err error) error {
+	switch {
+	case errors.Is(err, ErrTournamentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "大会が見つかりません")
+	case errors.Is(err, ErrInvalidFormat):
+		return echo.NewHTTPError(http.StatusBadRequest, "formatはswissまたはsingle_eliminationを指定してください")
+	case errors.Is(err, ErrInvalidRounds):
+		return echo.NewHTTPError(http.StatusBadRequest, "roundsは0〜15で指定してください")
+	case errors.Is(err, ErrTooManyTournaments):
+		return echo.NewHTTPError(http.StatusConflict, "同時に開ける大会は3つまでです")
+	case errors.Is(err, ErrNotOrganizer):
+		return echo.NewHTTPError(http.StatusForbidden, "主催者のみ操作できます")
+	case errors.Is(err, ErrRegistrationClosed):
+		return echo.NewHTTPError(http.StatusConflict, "参加受付は終了しています")
+	case errors.Is(err, ErrAlreadyRegistered):
+		return echo.NewHTTPError(http.StatusConflict, "既に参加登録しています")
+	case errors.Is(err, ErrNotRegistered):
+		return echo.NewHTTPError(http.StatusConflict, "参加登録していません")
+	case errors.Is(err, ErrTournamentFull):
+		return echo.NewHTTPError(http.StatusConflict, "参加者数が上限に達しています")
+	case errors.Is(err, ErrNotEnoughPlayers):
+		return echo.NewHTTPError(http.StatusConflict, "参加者が2人以上必要です")
+	case errors.Is(err, ErrNoBracket):
+		return echo.NewHTTPError(http.StatusBadRequest, "スイス式の大会にはブラケットがありません")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "大会操作エラー")
+	}
+}
+
+// POST /api/tournaments
+func (api *API) Create(c echo.Context) error {
+	userID := c.Get("uid").(string)
+	var req struct {
+		Name   string `json:"name"`
+		Format string `json:"format"`
+		Rounds int    `json:"rounds"` // スイス式の回戦数 (省略時は参加者数から算出)
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxNameLength {
+		return echo.NewHTTPError(http.StatusBadRequest, "nameは1〜64文字で指定してください")
+	}
+
+	t, err := api.Service.Create(userID, req.Name, req.Format, req.Rounds)
+	if err != nil {
+		return tournamentError(err)
+	}
+	return c.JSON(http.StatusCreated, t)
+}
+
+// GET /api/tournaments
+func (api *API) List(c echo.Context) error {
+	list, err := api.Service.List()
+	if err != nil {
+		return tournamentError(err)
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+// GET /api/tournaments/:id
+func (api *API) Get(c echo.Context) error {
+	t, err := api.Service.Get(c.Param("id"))
+	if err != nil {
+		return tournamentError(err)
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+// POST /api/tournaments/:id/register
+func (api *API) Register(c echo.Context) error {
+	userID := c.Get("uid").(string)
+	t, err := api.Service.Register(c.Param("id"), userID)
+	if err != nil {
+		return tournamentError(err)
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+// POST /api/tournaments/:id/withdraw
+func (api *API) Withdraw(c echo.Context) error {
+	userID := c.Get("uid").(string)
+	t, err := api.Service.Withdraw(c.Param("id"), userID)
+	if err != nil {
+		return tournamentError(err)
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+// POST /api/tournaments/:id/start
+func (api *API) Start(c echo.Context) error {
+	userID := c.Get("uid").(string)
+	t, err := api.Service.Start(c.Request().Context(), c.Param("id"), userID)
+	if err != nil {
+		return tournamentError(err)
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+// GET /api/tournaments/:id/standings
+func (api *API) Standings(c echo.Context) error {
+	table, err := api.Service.Standings(c.Param("id"))
+	if err != nil {
+		return tournamentError(err)
+	}
+	return c.JSON(http.StatusOK, table)
+}
+
+// GET /api/tournaments/:id/bracket
+func (api *API) Bracket(c echo.Context) error {
+	b, err := api.Service.Bracket(c.Param("id"))
+	if err != nil {
+		return tournamentError(err)
+	}
+	return c.JSON(http.StatusOK, b)
+}
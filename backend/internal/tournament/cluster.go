@@ -0,0 +1,187 @@
+// backend/internal/tournament/cluster.go
+package tournament
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// 担当インスタンスで実行する大会の操作
+const (
+	methodGet       = "tournament.get"
+	methodRegister  = "tournament.register"
+	methodWithdraw  = "tournament.withdraw"
+	methodStart     = "tournament.start"
+	methodStandings = "tournament.standings"
+	methodBracket   = "tournament.bracket"
+	methodList      = "tournament.list"
+	methodOpen      = "tournament.open"
+)
+
+// Cluster は複数インスタンスで大会を共有するための連携です (ws.Hub が実装します)
+//
+// 大会は作成したインスタンスのメモリにあり、所有者キーでそのインスタンスを登録します。
+// 他のインスタンスへのリクエストは Call で担当インスタンスに転送し、一覧は Gather で集めます。
+type Cluster interface {
+	Owner(key string) (string, error)
+	Claim(key string)
+	Own(keys func() []string)
+	Handle(method string, handler func(args json.RawMessage) (interface{}, error))
+	Call(instanceID, method string, args, result interface{}) error
+	Gather(method string, args interface{}) ([]json.RawMessage, error)
+}
+
+// tournamentCall は担当インスタンスに転送する大会の操作の引数です
+type tournamentCall struct {
+	TournamentID string `json:"tournamentId,omitempty"`
+	UserID       string `json:"userId,omitempty"`
+}
+
+// tournamentKey は大会を保持しているインスタンスの所有者キーを返します
+func tournamentKey(tournamentID string) string {
+	return "tournament:" + tournamentID
+}
+
+// SetCluster は他のインスタンスとの連携を設定し、転送された操作を受け付けます
+//
+// 設定しない場合はこのインスタンスの大会だけを扱います。
+func (s *Service) SetCluster(cluster Cluster) {
+	s.cluster = cluster
+	cluster.Own(s.ownedKeys)
+
+	handle := func(method string, op func(call tournamentCall) (interface{}, error)) {
+		cluster.Handle(method, func(args json.RawMessage) (interface{}, error) {
+			var call tournamentCall
+			if err := json.Unmarshal(args, &call); err != nil {
+				return nil, err
+			}
+			return op(call)
+		})
+	}
+	handle(methodGet, func(call tournamentCall) (interface{}, error) {
+		return s.get(call.TournamentID)
+	})
+	handle(methodRegister, func(call tournamentCall) (interface{}, error) {
+		return s.register(call.TournamentID, call.UserID)
+	})
+	handle(methodWithdraw, func(call tournamentCall) (interface{}, error) {
+		return s.withdraw(call.TournamentID, call.UserID)
+	})
+	handle(methodStart, func(call tournamentCall) (interface{}, error) {
+		return s.start(context.Background(), call.TournamentID, call.UserID)
+	})
+	handle(methodStandings, func(call tournamentCall) (interface{}, error) {
+		return s.standingsOf(call.TournamentID)
+	})
+	handle(methodBracket, func(call tournamentCall) (interface{}, error) {
+		return s.bracketOf(call.TournamentID)
+	})
+	handle(methodList, func(tournamentCall) (interface{}, error) {
+		return s.list(), nil
+	})
+	handle(methodOpen, func(call tournamentCall) (interface{}, error) {
+		return s.openCount(call.UserID), nil
+	})
+}
+
+// ownedKeys はこのインスタンスが保持している大会の所有者キーを返します
+func (s *Service) ownedKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.tournaments))
+	for id := range s.tournaments {
+		keys = append(keys, tournamentKey(id))
+	}
+	return keys
+}
+
+// forward は大会を保持しているインスタンスで method を実行し、結果を result に読み込みます
+//
+// どのインスタンスも大会を保持していない場合は ErrTournamentNotFound を返します。
+func (s *Service) forward(method string, call tournamentCall, result interface{}) error {
+	if s.cluster == nil {
+		return ErrTournamentNotFound
+	}
+	owner, err := s.cluster.Owner(tournamentKey(call.TournamentID))
+	if err != nil {
+		return err
+	}
+	if owner == "" {
+		return ErrTournamentNotFound
+	}
+	return s.cluster.Call(owner, method, call, result)
+}
+
+// forwardTournament はこのインスタンスにない大会の操作を担当インスタンスに転送します
+func (s *Service) forwardTournament(method string, call tournamentCall) (*Tournament, error) {
+	var t Tournament
+	if err := s.forward(method, call, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// gatherList は他のインスタンスが保持している大会の一覧を集めます
+func (s *Service) gatherList() ([]*Tournament, error) {
+	if s.cluster == nil {
+		return nil, nil
+	}
+	replies, err := s.cluster.Gather(methodList, tournamentCall{})
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*Tournament
+	for _, reply := range replies {
+		var remote []*Tournament
+		if err := json.Unmarshal(reply, &remote); err != nil {
+			return nil, err
+		}
+		list = append(list, remote...)
+	}
+	return list, nil
+}
+
+// remoteOpenCount は他のインスタンスで organizerID が主催している終了していない大会の数を返します
+func (s *Service) remoteOpenCount(organizerID string) (int, error) {
+	if s.cluster == nil {
+		return 0, nil
+	}
+	replies, err := s.cluster.Gather(methodOpen, tournamentCall{UserID: organizerID})
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, reply := range replies {
+		var count int
+		if err := json.Unmarshal(reply, &count); err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// mergeList は大会の一覧を重複を除いて新しい順に並べます
+//
+// 所有者が移った直後などに同じ大会が複数のインスタンスから返る場合があります。
+func mergeList(lists ...[]*Tournament) []*Tournament {
+	seen := make(map[string]bool)
+	merged := []*Tournament{}
+	for _, list := range lists {
+		for _, t := range list {
+			if seen[t.ID] {
+				continue
+			}
+			seen[t.ID] = true
+			merged = append(merged, t)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].CreatedAt.After(merged[j].CreatedAt)
+	})
+	return merged
+}
@@ -0,0 +1,195 @@
+// backend/internal/tournament/cluster_test.go
+package tournament
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+// testCluster は同じプロセス内の Service をインスタンスとしてつなぐ Cluster です
+type testCluster struct {
+	mu       sync.Mutex
+	owners   map[string]string
+	handlers map[string]map[string]func(json.RawMessage) (interface{}, error) // インスタンス -> 処理名 -> 処理
+}
+
+// testNode は testCluster の1インスタンスです
+type testNode struct {
+	cluster *testCluster
+	id      string
+}
+
+func newTestCluster() *testCluster {
+	return &testCluster{
+		owners:   make(map[string]string),
+		handlers: make(map[string]map[string]func(json.RawMessage) (interface{}, error)),
+	}
+}
+
+// join はインスタンスを追加し、そのインスタンスの大会サービスを返します
+func (c *testCluster) join(id string) *Service {
+	c.mu.Lock()
+	c.handlers[id] = make(map[string]func(json.RawMessage) (interface{}, error))
+	c.mu.Unlock()
+
+	s := NewService(game.NewDuelService(testCards))
+	s.SetCluster(&testNode{cluster: c, id: id})
+	return s
+}
+
+func (n *testNode) Owner(key string) (string, error) {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+	return n.cluster.owners[key], nil
+}
+
+func (n *testNode) Claim(key string) {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+	n.cluster.owners[key] = n.id
+}
+
+func (n *testNode) Own(keys func() []string) {}
+
+func (n *testNode) Handle(method string, handler func(json.RawMessage) (interface{}, error)) {
+	n.cluster.mu.Lock()
+	defer n.cluster.mu.Unlock()
+	n.cluster.handlers[n.id][method] = handler
+}
+
+// Call は Backplane と同じくJSONを経由して instanceID の処理を実行します
+func (n *testNode) Call(instanceID, method string, args, result interface{}) error {
+	raw, err := n.cluster.invoke(instanceID, method, args)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, result)
+}
+
+func (n *testNode) Gather(method string, args interface{}) ([]json.RawMessage, error) {
+	n.cluster.mu.Lock()
+	var others []string
+	for id := range n.cluster.handlers {
+		if id != n.id {
+			others = append(others, id)
+		}
+	}
+	n.cluster.mu.Unlock()
+
+	var replies []json.RawMessage
+	for _, id := range others {
+		raw, err := n.cluster.invoke(id, method, args)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, raw)
+	}
+	return replies, nil
+}
+
+func (c *testCluster) invoke(instanceID, method string, args interface{}) (json.RawMessage, error) {
+	c.mu.Lock()
+	handler := c.handlers[instanceID][method]
+	c.mu.Unlock()
+
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := handler(raw)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+func TestRequestsAreForwardedToOwningInstance(t *testing.T) {
+	cluster := newTestCluster()
+	owner, other := cluster.join("a"), cluster.join("b")
+
+	created, err := owner.Create("alice", "テスト大会", FormatSingleElimination, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 別インスタンスへのリクエストは大会を保持しているインスタンスで処理される
+	if _, err := other.Register(created.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Register(created.ID, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Register(created.ID, "bob"); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Errorf("2回目の Register = %v; want %v", err, ErrAlreadyRegistered)
+	}
+	if got, err := owner.Get(created.ID); err != nil || len(got.Entrants) != 2 {
+		t.Fatalf("担当インスタンスの大会 = %+v, %v; want 参加者2人", got, err)
+	}
+
+	if _, err := other.Start(context.Background(), created.ID, "bob"); !errors.Is(err, ErrNotOrganizer) {
+		t.Errorf("主催者以外の Start = %v; want %v", err, ErrNotOrganizer)
+	}
+	started, err := other.Start(context.Background(), created.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.Status != StatusRunning || len(started.Rounds) != 1 {
+		t.Errorf("開始した大会 = %+v; want 1回戦が進行中", started)
+	}
+	// 対戦は大会を保持しているインスタンスで作成される
+	if duelID := started.Rounds[0].Pairings[0].DuelID; !owner.duels.HasDuel(duelID) {
+		t.Errorf("対戦 %s が担当インスタンスで作成されていません", duelID)
+	}
+
+	if table, err := other.Standings(created.ID); err != nil || len(table) != 2 {
+		t.Errorf("Standings = %+v, %v; want 2人の順位表", table, err)
+	}
+	if b, err := other.Bracket(created.ID); err != nil || b.TournamentID != created.ID {
+		t.Errorf("Bracket = %+v, %v; want %s のブラケット", b, err, created.ID)
+	}
+	if _, err := other.Get("missing"); !errors.Is(err, ErrTournamentNotFound) {
+		t.Errorf("存在しない大会の Get = %v; want %v", err, ErrTournamentNotFound)
+	}
+}
+
+func TestListMergesInstances(t *testing.T) {
+	cluster := newTestCluster()
+	a, b := cluster.join("a"), cluster.join("b")
+	first, _ := a.Create("alice", "1つ目", FormatSwiss, 0)
+	second, _ := b.Create("bob", "2つ目", FormatSwiss, 0)
+
+	for name, s := range map[string]*Service{"a": a, "b": b} {
+		list, err := s.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 || list[0].ID != second.ID || list[1].ID != first.ID {
+			t.Errorf("インスタンス %s の List = %d 件; want 新しい順に2件", name, len(list))
+		}
+	}
+}
+
+func TestCreateLimitsOpenTournaments(t *testing.T) {
+	cluster := newTestCluster()
+	a, b := cluster.join("a"), cluster.join("b")
+
+	// 上限はすべてのインスタンスの合計で数える
+	for i, s := range []*Service{a, a, b} {
+		if _, err := s.Create("alice", "大会", FormatSwiss, 0); err != nil {
+			t.Fatalf("%d つ目の Create = %v", i+1, err)
+		}
+	}
+	for _, s := range []*Service{a, b} {
+		if _, err := s.Create("alice", "大会", FormatSwiss, 0); !errors.Is(err, ErrTooManyTournaments) {
+			t.Errorf("上限を超える Create = %v; want %v", err, ErrTooManyTournaments)
+		}
+	}
+	if _, err := a.Create("bob", "大会", FormatSwiss, 0); err != nil {
+		t.Errorf("他の主催者の Create = %v; want nil", err)
+	}
+}
@@ -0,0 +1,116 @@
+// backend/internal/tournament/elimination.go
+package tournament
+
+import "fmt"
+
+// Bracket は勝ち抜き戦のブラケットです
+type Bracket struct {
+	TournamentID string         `json:"tournamentId"`
+	Size         int            `json:"size"` // 不戦勝を含む枠の数 (2の累乗)
+	Rounds       []BracketRound `json:"rounds"`
+	WinnerID     string         `json:"winnerId,omitempty"`
+}
+
+// BracketRound はブラケットの1回戦です。まだ始まっていない回戦も勝ち上がりが決まった分だけ埋めて返します
+type BracketRound struct {
+	Number   int       `json:"number"`
+	Name     string    `json:"name"` // "final", "semifinal", "quarterfinal", "round_of_16" など
+	Started  bool      `json:"started"`
+	Pairings []Pairing `json:"pairings"`
+}
+
+// pairElimination は勝ち抜き戦の次の回戦の組み合わせを作ります
+//
+// 1回戦はシード順で上位と下位を組み (1対8、4対5、2対7、3対6 …)、枠が余る分は
+// 上位シードを不戦勝にします。2回戦以降は隣り合う卓の勝者同士を組みます。
+func pairElimination(t *Tournament) []Pairing {
+	if len(t.Rounds) == 0 {
+		size := 1 << t.TotalRounds
+		bySeed := make(map[int]string, len(t.Entrants))
+		for _, entrant := range t.Entrants {
+			bySeed[entrant.Seed] = entrant.UserID
+		}
+
+		order := seedOrder(size)
+		pairings := make([]Pairing, 0, size/2)
+		for i := 0; i < size; i += 2 {
+			pairings = append(pairings, Pairing{
+				Table:     i/2 + 1,
+				Player1ID: bySeed[order[i]],
+				Player2ID: bySeed[order[i+1]],
+			})
+		}
+		return pairings
+	}
+
+	prev := t.Rounds[len(t.Rounds)-1].Pairings
+	pairings := make([]Pairing, 0, len(prev)/2)
+	for i := 0; i+1 < len(prev); i += 2 {
+		pairings = append(pairings, Pairing{
+			Table:     i/2 + 1,
+			Player1ID: prev[i].WinnerID,
+			Player2ID: prev[i+1].WinnerID,
+		})
+	}
+	return pairings
+}
+
+// bracket は大会のブラケットを作ります
+func bracket(t *Tournament) *Bracket {
+	b := &Bracket{TournamentID: t.ID, Rounds: []BracketRound{}, WinnerID: t.WinnerID}
+	if t.TotalRounds == 0 {
+		return b
+	}
+	b.Size = 1 << t.TotalRounds
+
+	var prev []Pairing
+	for number := 1; number <= t.TotalRounds; number++ {
+		round := BracketRound{Number: number, Name: roundName(b.Size >> (number - 1))}
+		if number <= len(t.Rounds) {
+			round.Started = true
+			round.Pairings = append([]Pairing(nil), t.Rounds[number-1].Pairings...)
+		} else {
+			// 未開始の回戦は前の回戦で決まった勝者だけを埋める
+			round.Pairings = make([]Pairing, 0, len(prev)/2)
+			for i := 0; i+1 < len(prev); i += 2 {
+				round.Pairings = append(round.Pairings, Pairing{
+					Table:     i/2 + 1,
+					Player1ID: prev[i].WinnerID,
+					Player2ID: prev[i+1].WinnerID,
+				})
+			}
+		}
+		b.Rounds = append(b.Rounds, round)
+		prev = round.Pairings
+	}
+	return b
+}
+
+// seedOrder は size 枠のブラケットの上から順のシード番号を返します
+//
+// 上位シード同士が決勝まで当たらないように並べます (8枠なら 1, 8, 4, 5, 2, 7, 3, 6)。
+func seedOrder(size int) []int {
+	order := []int{1}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, seed := range order {
+			next = append(next, seed, n+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// roundName は残り players 人で行う回戦の名前です
+func roundName(players int) string {
+	switch players {
+	case 2:
+		return "final"
+	case 4:
+		return "semifinal"
+	case 8:
+		return "quarterfinal"
+	default:
+		return fmt.Sprintf("round_of_%d", players)
+	}
+}
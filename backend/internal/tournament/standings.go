@@ -0,0 +1,116 @@
+// backend/internal/tournament/standings.go
+package tournament
+
+import "sort"
+
+// 得点 (勝ち1・引き分け0.5・負け0。不戦勝は勝ちと同じ)
+const (
+	winPoints  = 1.0
+	drawPoints = 0.5
+)
+
+// Standing は順位表の1行です
+type Standing struct {
+	Rank            int     `json:"rank"`
+	UserID          string  `json:"userId"`
+	Seed            int     `json:"seed"`
+	Points          float64 `json:"points"`
+	Wins            int     `json:"wins"`
+	Draws           int     `json:"draws"`
+	Losses          int     `json:"losses"`
+	Byes            int     `json:"byes"`
+	Buchholz        float64 `json:"buchholz"`             // 対戦相手の得点の合計
+	SonnebornBerger float64 `json:"sonnebornBerger"`      // 勝った相手の得点と引き分けた相手の得点の半分の合計
+	Eliminated      bool    `json:"eliminated,omitempty"` // 勝ち抜き戦で敗退済み
+
+	reached   int      // 勝ち抜き戦で勝ち残った回戦数
+	opponents []string // 対戦した相手 (不戦勝を除く)
+	beaten    []string // 勝った相手
+	drawn     []string // 引き分けた相手
+}
+
+// standings は終了した組み合わせから順位表を作ります
+//
+// スイス式は得点、ブッフホルツ、ゾネボルン・ベルガー、勝ち数、シードの順に比べます。
+// 勝ち抜き戦は勝ち残った回戦数、シードの順に比べます。
+func standings(t *Tournament) []Standing {
+	rows := make([]Standing, len(t.Entrants))
+	index := make(map[string]*Standing, len(t.Entrants))
+	for i, entrant := range t.Entrants {
+		rows[i] = Standing{UserID: entrant.UserID, Seed: entrant.Seed}
+		index[entrant.UserID] = &rows[i]
+	}
+
+	for _, round := range t.Rounds {
+		for _, pairing := range round.Pairings {
+			p1, p2 := index[pairing.Player1ID], index[pairing.Player2ID]
+			switch pairing.Result {
+			case ResultBye:
+				p1.Byes++
+				p1.Points += winPoints
+				p1.reached++
+			case ResultDraw:
+				p1.Draws++
+				p2.Draws++
+				p1.Points += drawPoints
+				p2.Points += drawPoints
+				p1.drawn = append(p1.drawn, p2.UserID)
+				p2.drawn = append(p2.drawn, p1.UserID)
+			case ResultPlayer1, ResultPlayer2:
+				winner, loser := p1, p2
+				if pairing.Result == ResultPlayer2 {
+					winner, loser = p2, p1
+				}
+				winner.Wins++
+				winner.Points += winPoints
+				winner.reached++
+				winner.beaten = append(winner.beaten, loser.UserID)
+				loser.Losses++
+				loser.Eliminated = t.Format == FormatSingleElimination
+			}
+			if pairing.Result != ResultPending && pairing.Result != ResultBye {
+				p1.opponents = append(p1.opponents, p2.UserID)
+				p2.opponents = append(p2.opponents, p1.UserID)
+			}
+		}
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		for _, opponent := range row.opponents {
+			row.Buchholz += index[opponent].Points
+		}
+		for _, opponent := range row.beaten {
+			row.SonnebornBerger += index[opponent].Points
+		}
+		for _, opponent := range row.drawn {
+			row.SonnebornBerger += index[opponent].Points / 2
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if t.Format == FormatSingleElimination {
+			if a.reached != b.reached {
+				return a.reached > b.reached
+			}
+			return a.Seed < b.Seed
+		}
+		switch {
+		case a.Points != b.Points:
+			return a.Points > b.Points
+		case a.Buchholz != b.Buchholz:
+			return a.Buchholz > b.Buchholz
+		case a.SonnebornBerger != b.SonnebornBerger:
+			return a.SonnebornBerger > b.SonnebornBerger
+		case a.Wins != b.Wins:
+			return a.Wins > b.Wins
+		default:
+			return a.Seed < b.Seed
+		}
+	})
+	for i := range rows {
+		rows[i].Rank = i + 1
+	}
+	return rows
+}
@@ -0,0 +1,102 @@
+// backend/internal/tournament/swiss.go
+package tournament
+
+// maxPairingSteps は再戦を避ける組み合わせの探索回数の上限です
+// (超えた場合は順位の隣同士で組み、再戦を許します)
+const maxPairingSteps = 100000
+
+// pairSwiss はスイス式の次の回戦の組み合わせを作ります
+//
+// 現在の順位の上から順に、まだ対戦していない相手のうち最も順位の近い相手と組みます。
+// 参加者が奇数の場合は、まだ不戦勝になっていない最下位のプレイヤーを不戦勝にします。
+// 先手は、これまで先手になった回数の少ない方にします。
+func pairSwiss(t *Tournament) []Pairing {
+	table := standings(t)
+	ids := make([]string, len(table))
+	for i, row := range table {
+		ids[i] = row.UserID
+	}
+
+	played := make(map[string]bool)
+	firsts := make(map[string]int)
+	hadBye := make(map[string]bool)
+	for _, round := range t.Rounds {
+		for _, pairing := range round.Pairings {
+			if pairing.Player2ID == "" {
+				hadBye[pairing.Player1ID] = true
+				continue
+			}
+			played[pairKey(pairing.Player1ID, pairing.Player2ID)] = true
+			firsts[pairing.Player1ID]++
+		}
+	}
+
+	bye := ""
+	if len(ids)%2 == 1 {
+		i := len(ids) - 1
+		for j := len(ids) - 1; j >= 0; j-- {
+			if !hadBye[ids[j]] {
+				i = j
+				break
+			}
+		}
+		bye = ids[i]
+		ids = append(ids[:i:i], ids[i+1:]...)
+	}
+
+	steps := 0
+	pairs, ok := pairAvoidingRematches(ids, played, &steps)
+	if !ok {
+		pairs = pairs[:0]
+		for i := 0; i+1 < len(ids); i += 2 {
+			pairs = append(pairs, [2]string{ids[i], ids[i+1]})
+		}
+	}
+
+	pairings := make([]Pairing, 0, len(pairs)+1)
+	for i, pair := range pairs {
+		first, second := pair[0], pair[1]
+		if firsts[second] < firsts[first] {
+			first, second = second, first
+		}
+		pairings = append(pairings, Pairing{Table: i + 1, Player1ID: first, Player2ID: second})
+	}
+	if bye != "" {
+		pairings = append(pairings, Pairing{Table: len(pairings) + 1, Player1ID: bye})
+	}
+	return pairings
+}
+
+// pairAvoidingRematches は ids を先頭から順に、対戦済みでない相手と組み合わせます
+//
+// 組めない場合は手前の組み合わせを変えてやり直します (探索は steps 回まで)。
+func pairAvoidingRematches(ids []string, played map[string]bool, steps *int) ([][2]string, bool) {
+	if len(ids) == 0 {
+		return nil, true
+	}
+	first := ids[0]
+	for i := 1; i < len(ids); i++ {
+		*steps++
+		if *steps > maxPairingSteps {
+			return nil, false
+		}
+		if played[pairKey(first, ids[i])] {
+			continue
+		}
+		rest := make([]string, 0, len(ids)-2)
+		rest = append(rest, ids[1:i]...)
+		rest = append(rest, ids[i+1:]...)
+		if pairs, ok := pairAvoidingRematches(rest, played, steps); ok {
+			return append([][2]string{{first, ids[i]}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+// pairKey は2人の組み合わせを順序によらず表すキーです
+func pairKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "\x00" + b
+}
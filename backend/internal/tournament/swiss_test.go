@@ -0,0 +1,104 @@
+// backend/internal/tournament/swiss_test.go
+package tournament
+
+import (
+	"fmt"
+	"testing"
+)
+
+// swissTournament は n 人の参加者 (シード順) のスイス式の大会を作ります
+func swissTournament(n, rounds int) *Tournament {
+	t := &Tournament{ID: "t", Format: FormatSwiss, Status: StatusRunning, TotalRounds: rounds}
+	for i := 0; i < n; i++ {
+		t.Entrants = append(t.Entrants, Entrant{UserID: fmt.Sprintf("p%d", i+1), Seed: i + 1})
+	}
+	return t
+}
+
+// playRound は組み合わせの結果を決めて回戦として追加します (Player1 の勝ち)
+func playRound(t *Tournament, pairings []Pairing) {
+	for i := range pairings {
+		if pairings[i].Player2ID == "" {
+			pairings[i].Result, pairings[i].WinnerID = ResultBye, pairings[i].Player1ID
+			continue
+		}
+		pairings[i].Result, pairings[i].WinnerID = ResultPlayer1, pairings[i].Player1ID
+	}
+	t.Rounds = append(t.Rounds, Round{Number: len(t.Rounds) + 1, Pairings: pairings})
+}
+
+func TestPairSwissAvoidsRematches(t *testing.T) {
+	tour := swissTournament(8, 3)
+	played := make(map[string]bool)
+	for round := 0; round < 3; round++ {
+		pairings := pairSwiss(tour)
+		if len(pairings) != 4 {
+			t.Fatalf("第%d回戦の卓数 = %d, want 4", round+1, len(pairings))
+		}
+		seen := make(map[string]bool)
+		for _, p := range pairings {
+			if p.Player2ID == "" {
+				t.Fatalf("第%d回戦: 偶数人数なのに不戦勝 %s", round+1, p.Player1ID)
+			}
+			if seen[p.Player1ID] || seen[p.Player2ID] {
+				t.Fatalf("第%d回戦: 同じプレイヤーが複数の卓にいます (%s, %s)", round+1, p.Player1ID, p.Player2ID)
+			}
+			seen[p.Player1ID], seen[p.Player2ID] = true, true
+			key := pairKey(p.Player1ID, p.Player2ID)
+			if played[key] {
+				t.Fatalf("第%d回戦: %s と %s が再戦しています", round+1, p.Player1ID, p.Player2ID)
+			}
+			played[key] = true
+		}
+		playRound(tour, pairings)
+	}
+}
+
+func TestPairSwissByeGoesToLowestWithoutBye(t *testing.T) {
+	tour := swissTournament(5, 3)
+	byes := make(map[string]int)
+	for round := 0; round < 3; round++ {
+		pairings := pairSwiss(tour)
+		last := pairings[len(pairings)-1]
+		if last.Player2ID != "" {
+			t.Fatalf("第%d回戦: 奇数人数なのに不戦勝がありません", round+1)
+		}
+		// 不戦勝は、まだ不戦勝になっていない中で最も順位の低いプレイヤー
+		table := standings(tour)
+		want := ""
+		for i := len(table) - 1; i >= 0; i-- {
+			if byes[table[i].UserID] == 0 {
+				want = table[i].UserID
+				break
+			}
+		}
+		if last.Player1ID != want {
+			t.Errorf("第%d回戦の不戦勝 = %s, want %s", round+1, last.Player1ID, want)
+		}
+		byes[last.Player1ID]++
+		playRound(tour, pairings)
+	}
+	for id, n := range byes {
+		if n > 1 {
+			t.Errorf("%s が %d 回不戦勝になっています", id, n)
+		}
+	}
+}
+
+func TestStandingsCountByeAsWin(t *testing.T) {
+	tour := swissTournament(3, 1)
+	playRound(tour, pairSwiss(tour))
+
+	var bye Standing
+	for _, row := range standings(tour) {
+		if row.Byes == 1 {
+			bye = row
+		}
+	}
+	if bye.UserID != "p3" {
+		t.Fatalf("不戦勝のプレイヤー = %q, want p3", bye.UserID)
+	}
+	if bye.Points != winPoints || bye.Wins != 0 || bye.Buchholz != 0 {
+		t.Errorf("不戦勝の順位表 = %+v, want 得点 %v・勝ち数0・ブッフホルツ0", bye, winPoints)
+	}
+}
@@ -0,0 +1,828 @@
+// backend/internal/tournament/tournament.go
+package tournament
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/game"
+	"github.com/google/uuid"
+)
+
+// 大会の形式
+const (
+	// FormatSwiss はスイス式です。同じ成績のプレイヤー同士を決まった回戦数だけ対戦させます
+	FormatSwiss = "swiss"
+	// FormatSingleElimination はシングルエリミネーション (勝ち抜き戦) です
+	FormatSingleElimination = "single_elimination"
+)
+
+// 大会のステータス
+const (
+	StatusRegistration = "registration" // 参加受付中
+	StatusRunning      = "running"      // 対戦中
+	StatusFinished     = "finished"     // 終了
+)
+
+// 組み合わせの結果
+const (
+	ResultPending = "pending" // 対戦中
+	ResultPlayer1 = "player1" // Player1 の勝利
+	ResultPlayer2 = "player2" // Player2 の勝利
+	ResultDraw    = "draw"    // 引き分け (スイス式のみ。勝ち抜き戦では再試合する)
+	ResultBye     = "bye"     // 不戦勝
+)
+
+const (
+	minPlayers     = 2
+	maxPlayers     = 256
+	maxSwissRounds = 15
+
+	// maxOpenTournaments は1人の主催者が同時に開ける (終了していない) 大会の数の上限です
+	maxOpenTournaments = 3
+
+	// ratingLookupTimeout はシード決定時のレーティング取得1回あたりのタイムアウトです
+	ratingLookupTimeout = 3 * time.Second
+
+	// DefaultNoShowTimeout は対戦が始まってから手番のプレイヤーが一度もアクションを
+	// 送らない場合に不戦敗とするまでの時間です
+	DefaultNoShowTimeout = 5 * time.Minute
+
+	// finishedRetention は終了した大会をメモリに保持する期間です (結果・順位表の閲覧用)
+	finishedRetention = time.Hour
+	// abandonedRetention は参加受付のまま開始されない大会をメモリに保持する期間です
+	abandonedRetention = 24 * time.Hour
+	// cleanupInterval は終了した大会・開始されない大会を削除する間隔です
+	cleanupInterval = 10 * time.Minute
+)
+
+var (
+	// ErrTournamentNotFound は大会が見つからないことを表します
+	ErrTournamentNotFound = errors.New("tournament not found")
+	// ErrInvalidFormat は大会の形式が不正であることを表します
+	ErrInvalidFormat = errors.New("format must be swiss or single_elimination")
+	// ErrInvalidRounds はスイス式の回戦数が不正であることを表します
+	ErrInvalidRounds = errors.New("rounds must be between 0 and 15")
+	// ErrTooManyTournaments は主催者が開いている大会の数が上限に達していることを表します
+	ErrTooManyTournaments = errors.New("too many open tournaments")
+	// ErrRegistrationClosed は参加受付が終了していることを表します
+	ErrRegistrationClosed = errors.New("registration is closed")
+	// ErrAlreadyRegistered はユーザーが既に参加登録していることを表します
+	ErrAlreadyRegistered = errors.New("already registered")
+	// ErrNotRegistered はユーザーが参加登録していないことを表します
+	ErrNotRegistered = errors.New("not registered")
+	// ErrTournamentFull は参加者数が上限に達していることを表します
+	ErrTournamentFull = errors.New("tournament is full")
+	// ErrNotEnoughPlayers は大会を開始するには参加者が足りないことを表します
+	ErrNotEnoughPlayers = errors.New("not enough players")
+	// ErrNotOrganizer は主催者以外が大会を操作しようとしたことを表します
+	ErrNotOrganizer = errors.New("only the organizer can do this")
+	// ErrNoBracket はスイス式の大会にブラケットを要求したことを表します
+	ErrNoBracket = errors.New("swiss tournaments have no bracket")
+)
+
+// Entrant は大会の参加者です
+type Entrant struct {
+	UserID       string    `json:"userId"`
+	Seed         int       `json:"seed,omitempty"` // 1始まり。開始時にレーティング順で決定
+	Rating       float64   `json:"rating,omitempty"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+// Pairing は1回戦の1卓の組み合わせです
+type Pairing struct {
+	Table     int    `json:"table"`
+	Player1ID string `json:"player1Id"`
+	Player2ID string `json:"player2Id,omitempty"` // 空の場合は Player1 の不戦勝
+	DuelID    string `json:"duelId,omitempty"`    // 進行中 (または最後) の対戦ID
+	Result    string `json:"result"`
+	WinnerID  string `json:"winnerId,omitempty"`
+	Games     int    `json:"games"` // 勝ち抜き戦で引き分けを再試合した場合を含む対戦数
+}
+
+// Round は大会の1回戦です
+type Round struct {
+	Number     int        `json:"number"`
+	Pairings   []Pairing  `json:"pairings"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Tournament は大会です
+type Tournament struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Format      string     `json:"format"` // "swiss", "single_elimination"
+	Status      string     `json:"status"` // "registration", "running", "finished"
+	OrganizerID string     `json:"organizerId"`
+	TotalRounds int        `json:"totalRounds,omitempty"` // 開始時に決定 (スイス式は指定値または参加者数から算出)
+	Entrants    []Entrant  `json:"entrants"`
+	Rounds      []Round    `json:"rounds"`
+	WinnerID    string     `json:"winnerId,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+
+	requestedRounds int // 作成時に指定されたスイス式の回戦数 (0なら自動)
+}
+
+// pairingRef は対戦IDから組み合わせを引くための参照です
+type pairingRef struct {
+	tournamentID string
+	round        int // Rounds の添字
+	table        int // Pairings の添字
+}
+
+// newDuel は作成する対戦です (ロックを外してから DuelService で作成する)
+type newDuel struct {
+	id      string
+	first   string
+	second  string
+	tableOf pairingRef
+}
+
+// Service は大会の参加受付・組み合わせ・結果集計を管理します
+//
+// 各回戦の対戦は DuelService で作成し、対戦終了時に結果を取り込みます。
+// 全卓の結果が揃うと自動的に次の回戦を組み合わせます。大会の状態は
+// 大会を作成したインスタンスのメモリに保持し、他のインスタンスへのリクエストは
+// Cluster で担当インスタンスに転送します。
+type Service struct {
+	duels    *game.DuelService
+	ratingOf func(ctx context.Context, userID string) (float64, error)
+	cluster  Cluster // nil ならこのインスタンスの大会のみ
+
+	mu            sync.Mutex
+	tournaments   map[string]*Tournament
+	duelToTable   map[string]pairingRef
+	noShowTimers  map[string]*time.Timer // duelID -> 不戦敗の判定タイマー
+	noShowTimeout time.Duration
+	onRound       func(t *Tournament, round *Round)
+	onFinish      func(t *Tournament)
+}
+
+// NewService は新しい大会サービスを作成します
+//
+// 対戦結果を取り込むため DuelService に終了コールバックを登録します。
+// 終了してから finishedRetention が過ぎた大会と、作成から abandonedRetention が過ぎても
+// 開始されない大会は定期的にメモリから削除します。
+func NewService(duels *game.DuelService) *Service {
+	s := &Service{
+		duels:         duels,
+		tournaments:   make(map[string]*Tournament),
+		duelToTable:   make(map[string]pairingRef),
+		noShowTimers:  make(map[string]*time.Timer),
+		noShowTimeout: DefaultNoShowTimeout,
+	}
+	duels.AddFinishCallback(s.onDuelFinished)
+	go s.cleanupLoop()
+	return s
+}
+
+// SetNoShowTimeout は対戦に現れないプレイヤーを不戦敗とするまでの時間を設定します (0以下で無効)
+func (s *Service) SetNoShowTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noShowTimeout = timeout
+}
+
+// SetRatingLookup はシード決定に使うレーティングの取得方法を設定します
+//
+// 設定しない場合は参加登録順でシードを決めます。
+func (s *Service) SetRatingLookup(lookup func(ctx context.Context, userID string) (float64, error)) {
+	s.ratingOf = lookup
+}
+
+// SetRoundCallback は回戦が始まったときのコールバックを設定します
+func (s *Service) SetRoundCallback(callback func(t *Tournament, round *Round)) {
+	s.onRound = callback
+}
+
+// SetFinishCallback は大会が終了したときのコールバックを設定します
+func (s *Service) SetFinishCallback(callback func(t *Tournament)) {
+	s.onFinish = callback
+}
+
+// Create は参加受付中の大会を作成します
+//
+// rounds はスイス式の回戦数です。0の場合は開始時の参加者数から決めます。
+// 主催者が開いている大会 (全インスタンスの合計) が maxOpenTournaments に達している場合は
+// ErrTooManyTournaments を返します。
+func (s *Service) Create(organizerID, name, format string, rounds int) (*Tournament, error) {
+	if format != FormatSwiss && format != FormatSingleElimination {
+		return nil, ErrInvalidFormat
+	}
+	if rounds < 0 || rounds > maxSwissRounds {
+		return nil, ErrInvalidRounds
+	}
+	remote, err := s.remoteOpenCount(organizerID)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tournament{
+		ID:              uuid.New().String(),
+		Name:            name,
+		Format:          format,
+		Status:          StatusRegistration,
+		OrganizerID:     organizerID,
+		Entrants:        []Entrant{},
+		Rounds:          []Round{},
+		CreatedAt:       time.Now(),
+		requestedRounds: rounds,
+	}
+
+	s.mu.Lock()
+	if remote+s.openCountLocked(organizerID) >= maxOpenTournaments {
+		s.mu.Unlock()
+		return nil, ErrTooManyTournaments
+	}
+	s.tournaments[t.ID] = t
+	created := t.clone()
+	s.mu.Unlock()
+
+	if s.cluster != nil {
+		s.cluster.Claim(tournamentKey(t.ID))
+	}
+	log.Printf("大会 %s を作成しました (形式: %s, 主催者: %s)", t.ID, format, organizerID)
+	return created, nil
+}
+
+// List は全インスタンスの大会の一覧を新しい順に返します
+func (s *Service) List() ([]*Tournament, error) {
+	remote, err := s.gatherList()
+	if err != nil {
+		return nil, err
+	}
+	return mergeList(s.list(), remote), nil
+}
+
+// list はこのインスタンスの大会の一覧を返します
+func (s *Service) list() []*Tournament {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]*Tournament, 0, len(s.tournaments))
+	for _, t := range s.tournaments {
+		list = append(list, t.clone())
+	}
+	return list
+}
+
+// openCount はこのインスタンスで organizerID が主催している終了していない大会の数を返します
+func (s *Service) openCount(organizerID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.openCountLocked(organizerID)
+}
+
+// openCountLocked は openCount と同じです (s.mu取得済み)
+func (s *Service) openCountLocked(organizerID string) int {
+	count := 0
+	for _, t := range s.tournaments {
+		if t.OrganizerID == organizerID && t.Status != StatusFinished {
+			count++
+		}
+	}
+	return count
+}
+
+// Get は大会の現在の状態を返します
+func (s *Service) Get(tournamentID string) (*Tournament, error) {
+	t, err := s.get(tournamentID)
+	if errors.Is(err, ErrTournamentNotFound) {
+		return s.forwardTournament(methodGet, tournamentCall{TournamentID: tournamentID})
+	}
+	return t, err
+}
+
+// get はこのインスタンスの大会の現在の状態を返します
+func (s *Service) get(tournamentID string) (*Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tournaments[tournamentID]
+	if !ok {
+		return nil, ErrTournamentNotFound
+	}
+	return t.clone(), nil
+}
+
+// Register は大会に参加登録します
+func (s *Service) Register(tournamentID, userID string) (*Tournament, error) {
+	t, err := s.register(tournamentID, userID)
+	if errors.Is(err, ErrTournamentNotFound) {
+		return s.forwardTournament(methodRegister, tournamentCall{TournamentID: tournamentID, UserID: userID})
+	}
+	return t, err
+}
+
+// register はこのインスタンスの大会に参加登録します
+func (s *Service) register(tournamentID, userID string) (*Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tournaments[tournamentID]
+	if !ok {
+		return nil, ErrTournamentNotFound
+	}
+	if t.Status != StatusRegistration {
+		return nil, ErrRegistrationClosed
+	}
+	if t.entrantIndex(userID) >= 0 {
+		return nil, ErrAlreadyRegistered
+	}
+	if len(t.Entrants) >= maxPlayers {
+		return nil, ErrTournamentFull
+	}
+
+	t.Entrants = append(t.Entrants, Entrant{UserID: userID, RegisteredAt: time.Now()})
+	log.Printf("ユーザー %s が大会 %s に参加登録しました", userID, t.ID)
+	return t.clone(), nil
+}
+
+// Withdraw は開始前の大会の参加登録を取り消します
+func (s *Service) Withdraw(tournamentID, userID string) (*Tournament, error) {
+	t, err := s.withdraw(tournamentID, userID)
+	if errors.Is(err, ErrTournamentNotFound) {
+		return s.forwardTournament(methodWithdraw, tournamentCall{TournamentID: tournamentID, UserID: userID})
+	}
+	return t, err
+}
+
+// withdraw はこのインスタンスの大会の参加登録を取り消します
+func (s *Service) withdraw(tournamentID, userID string) (*Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tournaments[tournamentID]
+	if !ok {
+		return nil, ErrTournamentNotFound
+	}
+	if t.Status != StatusRegistration {
+		return nil, ErrRegistrationClosed
+	}
+	i := t.entrantIndex(userID)
+	if i < 0 {
+		return nil, ErrNotRegistered
+	}
+
+	t.Entrants = append(t.Entrants[:i], t.Entrants[i+1:]...)
+	log.Printf("ユーザー %s が大会 %s の参加登録を取り消しました", userID, t.ID)
+	return t.clone(), nil
+}
+
+// Start は参加受付を締め切り、シードを決めて1回戦を開始します (主催者のみ)
+//
+// 各回戦の対戦は大会を保持しているインスタンスで作成します。
+func (s *Service) Start(ctx context.Context, tournamentID, userID string) (*Tournament, error) {
+	t, err := s.start(ctx, tournamentID, userID)
+	if errors.Is(err, ErrTournamentNotFound) {
+		return s.forwardTournament(methodStart, tournamentCall{TournamentID: tournamentID, UserID: userID})
+	}
+	return t, err
+}
+
+// start はこのインスタンスの大会を開始します
+func (s *Service) start(ctx context.Context, tournamentID, userID string) (*Tournament, error) {
+	s.mu.Lock()
+	t, ok := s.tournaments[tournamentID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrTournamentNotFound
+	}
+	if t.OrganizerID != userID {
+		s.mu.Unlock()
+		return nil, ErrNotOrganizer
+	}
+	if t.Status != StatusRegistration {
+		s.mu.Unlock()
+		return nil, ErrRegistrationClosed
+	}
+	if len(t.Entrants) < minPlayers {
+		s.mu.Unlock()
+		return nil, ErrNotEnoughPlayers
+	}
+	// レーティング取得中に参加登録が変わらないように先に締め切る
+	t.Status = StatusRunning
+	entrants := append([]Entrant(nil), t.Entrants...)
+	s.mu.Unlock()
+
+	entrants = s.seed(ctx, entrants)
+
+	s.mu.Lock()
+	now := time.Now()
+	t.Entrants = entrants
+	t.StartedAt = &now
+	t.TotalRounds = plannedRounds(t.Format, len(entrants), t.requestedRounds)
+	duels := s.startRoundLocked(t)
+	started := t.clone()
+	s.mu.Unlock()
+
+	log.Printf("大会 %s を開始しました (参加者: %d人, 回戦数: %d)", t.ID, len(entrants), started.TotalRounds)
+	s.createDuels(duels)
+	s.notifyRound(started)
+	return started, nil
+}
+
+// Participating はユーザーがこのインスタンスの参加受付中・対戦中の大会に参加登録しているかを返します
+func (s *Service) Participating(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tournaments {
+		if t.Status == StatusFinished {
+			continue
+		}
+		for _, entrant := range t.Entrants {
+			if entrant.UserID == userID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Standings は大会の順位表を返します
+func (s *Service) Standings(tournamentID string) ([]Standing, error) {
+	table, err := s.standingsOf(tournamentID)
+	if errors.Is(err, ErrTournamentNotFound) {
+		table = nil
+		err = s.forward(methodStandings, tournamentCall{TournamentID: tournamentID}, &table)
+	}
+	return table, err
+}
+
+// standingsOf はこのインスタンスの大会の順位表を返します
+func (s *Service) standingsOf(tournamentID string) ([]Standing, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tournaments[tournamentID]
+	if !ok {
+		return nil, ErrTournamentNotFound
+	}
+	return standings(t), nil
+}
+
+// Bracket は勝ち抜き戦のブラケット (各回戦の組み合わせ) を返します
+func (s *Service) Bracket(tournamentID string) (*Bracket, error) {
+	b, err := s.bracketOf(tournamentID)
+	if errors.Is(err, ErrTournamentNotFound) {
+		b = &Bracket{}
+		if err = s.forward(methodBracket, tournamentCall{TournamentID: tournamentID}, b); err != nil {
+			return nil, err
+		}
+	}
+	return b, err
+}
+
+// bracketOf はこのインスタンスの勝ち抜き戦のブラケットを返します
+func (s *Service) bracketOf(tournamentID string) (*Bracket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tournaments[tournamentID]
+	if !ok {
+		return nil, ErrTournamentNotFound
+	}
+	if t.Format != FormatSingleElimination {
+		return nil, ErrNoBracket
+	}
+	return bracket(t), nil
+}
+
+// onDuelFinished は終了した対戦の結果を組み合わせに取り込み、回戦が終わっていれば次の回戦を開始します
+func (s *Service) onDuelFinished(duel *game.Duel) {
+	s.mu.Lock()
+	ref, ok := s.duelToTable[duel.ID]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.duelToTable, duel.ID)
+	s.stopNoShowTimerLocked(duel.ID)
+
+	t := s.tournaments[ref.tournamentID]
+	pairing := &t.Rounds[ref.round].Pairings[ref.table]
+	if t.Status != StatusRunning || pairing.Result != ResultPending || pairing.DuelID != duel.ID {
+		s.mu.Unlock()
+		return
+	}
+
+	var duels []newDuel
+	switch duel.WinnerID {
+	case pairing.Player1ID:
+		pairing.Result, pairing.WinnerID = ResultPlayer1, pairing.Player1ID
+	case pairing.Player2ID:
+		pairing.Result, pairing.WinnerID = ResultPlayer2, pairing.Player2ID
+	default:
+		if t.Format == FormatSingleElimination {
+			// 勝ち抜き戦は勝者が決まるまで先手を入れ替えて再試合する
+			duels = append(duels, s.replayLocked(ref, pairing))
+		} else {
+			pairing.Result = ResultDraw
+		}
+	}
+	log.Printf("大会 %s 第%d回戦 %d卓の結果: %s", t.ID, ref.round+1, pairing.Table, pairing.Result)
+
+	roundStarted, finished := false, false
+	if len(duels) == 0 && t.Rounds[ref.round].complete() {
+		now := time.Now()
+		t.Rounds[ref.round].FinishedAt = &now
+		if t.isLastRound() {
+			t.finish(now)
+			finished = true
+		} else {
+			duels = s.startRoundLocked(t)
+			roundStarted = true
+		}
+	}
+	updated := t.clone()
+	s.mu.Unlock()
+
+	s.createDuels(duels)
+	if roundStarted {
+		s.notifyRound(updated)
+	} else if len(duels) > 0 {
+		// 再試合は同じ卓の2人にだけ通知する
+		s.notifyPairing(updated, ref)
+	}
+	if finished {
+		log.Printf("大会 %s が終了しました (優勝: %s)", updated.ID, updated.WinnerID)
+		if s.onFinish != nil {
+			s.onFinish(updated)
+		}
+	}
+}
+
+// startRoundLocked は次の回戦の組み合わせを作り、作成する対戦を返します (s.mu取得済み)
+func (s *Service) startRoundLocked(t *Tournament) []newDuel {
+	var pairings []Pairing
+	if t.Format == FormatSwiss {
+		pairings = pairSwiss(t)
+	} else {
+		pairings = pairElimination(t)
+	}
+
+	roundIdx := len(t.Rounds)
+	t.Rounds = append(t.Rounds, Round{Number: roundIdx + 1, Pairings: pairings, StartedAt: time.Now()})
+
+	duels := make([]newDuel, 0, len(pairings))
+	for i := range t.Rounds[roundIdx].Pairings {
+		pairing := &t.Rounds[roundIdx].Pairings[i]
+		if pairing.Player2ID == "" {
+			pairing.Result, pairing.WinnerID = ResultBye, pairing.Player1ID
+			continue
+		}
+		ref := pairingRef{tournamentID: t.ID, round: roundIdx, table: i}
+		duels = append(duels, s.assignDuelLocked(ref, pairing, pairing.Player1ID, pairing.Player2ID))
+	}
+	return duels
+}
+
+// replayLocked は引き分けた組み合わせの再試合を用意します (s.mu取得済み)
+func (s *Service) replayLocked(ref pairingRef, pairing *Pairing) newDuel {
+	first, second := pairing.Player1ID, pairing.Player2ID
+	if pairing.Games%2 == 1 {
+		first, second = second, first
+	}
+	return s.assignDuelLocked(ref, pairing, first, second)
+}
+
+// assignDuelLocked は組み合わせに新しい対戦IDを割り当てます (s.mu取得済み)
+func (s *Service) assignDuelLocked(ref pairingRef, pairing *Pairing, first, second string) newDuel {
+	duelID := uuid.New().String()
+	pairing.DuelID = duelID
+	pairing.Result = ResultPending
+	pairing.Games++
+	s.duelToTable[duelID] = ref
+	return newDuel{id: duelID, first: first, second: second, tableOf: ref}
+}
+
+// createDuels は組み合わせの対戦を作成し、不戦敗の判定タイマーを開始します
+func (s *Service) createDuels(duels []newDuel) {
+	for _, d := range duels {
+		if err := s.duels.CreateDuelWithID(d.id, d.first, d.second); err != nil {
+			log.Printf("大会の対戦作成エラー (大会: %s, 対戦: %s): %v", d.tableOf.tournamentID, d.id, err)
+			continue
+		}
+		s.startNoShowTimer(d.id)
+	}
+}
+
+// startNoShowTimer は対戦の不戦敗の判定タイマーを開始します
+func (s *Service) startNoShowTimer(duelID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 作成直後に終了した対戦にはタイマーを付けない
+	if _, ok := s.duelToTable[duelID]; !ok || s.noShowTimeout <= 0 {
+		return
+	}
+	s.noShowTimers[duelID] = time.AfterFunc(s.noShowTimeout, func() {
+		s.forfeitNoShow(duelID)
+	})
+}
+
+// stopNoShowTimerLocked は対戦の不戦敗の判定タイマーを止めます (s.mu取得済み)
+func (s *Service) stopNoShowTimerLocked(duelID string) {
+	if timer, ok := s.noShowTimers[duelID]; ok {
+		timer.Stop()
+		delete(s.noShowTimers, duelID)
+	}
+}
+
+// forfeitNoShow は手番なのに一度もアクションを送らないプレイヤーを不戦敗にします
+//
+// 不戦敗の対戦はレーティングに反映しません。結果は通常の対戦終了と同じく onDuelFinished で取り込みます。
+func (s *Service) forfeitNoShow(duelID string) {
+	s.mu.Lock()
+	delete(s.noShowTimers, duelID)
+	_, ok := s.duelToTable[duelID]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	absentID, err := s.duels.AbsentPlayer(duelID)
+	if err != nil || absentID == "" {
+		return
+	}
+	duel, err := s.duels.GetDuel(duelID)
+	if err != nil {
+		return
+	}
+	winnerID := duel.Players[0].UserID
+	if winnerID == absentID {
+		winnerID = duel.Players[1].UserID
+	}
+
+	log.Printf("大会の対戦 %s でプレイヤー %s が現れないため不戦敗にします", duelID, absentID)
+	if _, err := s.duels.ForceEnd(duelID, winnerID, true); err != nil {
+		log.Printf("不戦敗の処理エラー (対戦: %s): %v", duelID, err)
+	}
+}
+
+// notifyRound は開始した最新の回戦を通知します
+func (s *Service) notifyRound(t *Tournament) {
+	if s.onRound == nil || len(t.Rounds) == 0 {
+		return
+	}
+	s.onRound(t, &t.Rounds[len(t.Rounds)-1])
+}
+
+// notifyPairing は回戦のうち ref の卓の組み合わせだけを通知します (再試合など)
+func (s *Service) notifyPairing(t *Tournament, ref pairingRef) {
+	if s.onRound == nil {
+		return
+	}
+	round := t.Rounds[ref.round]
+	round.Pairings = []Pairing{round.Pairings[ref.table]}
+	s.onRound(t, &round)
+}
+
+// cleanupLoop は終了した大会と開始されない大会を定期的に削除します
+func (s *Service) cleanupLoop() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		s.CleanupFinished(now.Add(-finishedRetention))
+		s.CleanupAbandoned(now.Add(-abandonedRetention))
+	}
+}
+
+// CleanupAbandoned は before より前に作成され、参加受付のまま開始されない大会をメモリから削除します
+func (s *Service) CleanupAbandoned(before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.tournaments {
+		if t.Status != StatusRegistration || !t.CreatedAt.Before(before) {
+			continue
+		}
+		delete(s.tournaments, id)
+		log.Printf("開始されなかった大会 %s をメモリから削除しました (主催者: %s)", id, t.OrganizerID)
+	}
+}
+
+// CleanupFinished は before より前に終了した大会をメモリから削除します
+func (s *Service) CleanupFinished(before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.tournaments {
+		if t.Status != StatusFinished || t.FinishedAt == nil || !t.FinishedAt.Before(before) {
+			continue
+		}
+		delete(s.tournaments, id)
+		for duelID, ref := range s.duelToTable {
+			if ref.tournamentID == id {
+				delete(s.duelToTable, duelID)
+				s.stopNoShowTimerLocked(duelID)
+			}
+		}
+		log.Printf("終了した大会 %s をメモリから削除しました", id)
+	}
+}
+
+// seed は参加者をレーティングの高い順 (同じなら登録順) に並べてシードを付けます
+func (s *Service) seed(ctx context.Context, entrants []Entrant) []Entrant {
+	if s.ratingOf != nil {
+		for i := range entrants {
+			lookupCtx, cancel := context.WithTimeout(ctx, ratingLookupTimeout)
+			r, err := s.ratingOf(lookupCtx, entrants[i].UserID)
+			cancel()
+			if err != nil {
+				log.Printf("シード決定のレーティング取得エラー (ユーザー: %s): %v", entrants[i].UserID, err)
+				continue
+			}
+			entrants[i].Rating = r
+		}
+	}
+	sort.SliceStable(entrants, func(i, j int) bool {
+		if entrants[i].Rating != entrants[j].Rating {
+			return entrants[i].Rating > entrants[j].Rating
+		}
+		return entrants[i].RegisteredAt.Before(entrants[j].RegisteredAt)
+	})
+	for i := range entrants {
+		entrants[i].Seed = i + 1
+	}
+	return entrants
+}
+
+// plannedRounds は大会の回戦数を決めます
+//
+// 勝ち抜き戦はブラケットの大きさで決まり、スイス式は指定がなければ
+// 全勝者が1人に絞られる log2(参加者数) 回戦とします (参加者数-1 が上限)。
+func plannedRounds(format string, players, requested int) int {
+	rounds := 0
+	for size := 1; size < players; size *= 2 {
+		rounds++
+	}
+	if format == FormatSingleElimination {
+		return rounds
+	}
+	if requested > 0 {
+		rounds = requested
+	}
+	if rounds > players-1 {
+		rounds = players - 1
+	}
+	return rounds
+}
+
+// complete は回戦の全卓の結果が出ているかを返します
+func (r *Round) complete() bool {
+	for _, pairing := range r.Pairings {
+		if pairing.Result == ResultPending {
+			return false
+		}
+	}
+	return true
+}
+
+// isLastRound は最新の回戦が最終回戦かを返します
+func (t *Tournament) isLastRound() bool {
+	if t.Format == FormatSingleElimination {
+		last := t.Rounds[len(t.Rounds)-1]
+		return len(last.Pairings) == 1
+	}
+	return len(t.Rounds) >= t.TotalRounds
+}
+
+// finish は大会を終了状態にし、優勝者を決めます
+func (t *Tournament) finish(now time.Time) {
+	t.Status = StatusFinished
+	t.FinishedAt = &now
+	if table := standings(t); len(table) > 0 {
+		t.WinnerID = table[0].UserID
+	}
+}
+
+// entrantIndex は参加者の添字を返します。参加していない場合は -1 を返します
+func (t *Tournament) entrantIndex(userID string) int {
+	for i, entrant := range t.Entrants {
+		if entrant.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// clone は大会のコピーを返します
+func (t *Tournament) clone() *Tournament {
+	copied := *t
+	copied.Entrants = append([]Entrant(nil), t.Entrants...)
+	copied.Rounds = make([]Round, len(t.Rounds))
+	for i, round := range t.Rounds {
+		round.Pairings = append([]Pairing(nil), round.Pairings...)
+		copied.Rounds[i] = round
+	}
+	return &copied
+}
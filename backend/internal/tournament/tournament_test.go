@@ -0,0 +1,150 @@
+// backend/internal/tournament/tournament_test.go
+package tournament
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+var testCards = []game.Card{{ID: 1, Name: "テスト", AttackPts: 1, DefensePts: 1}}
+
+// startTournament は参加者を登録して大会を開始します
+func startTournament(t *testing.T, s *Service, format string, players ...string) *Tournament {
+	t.Helper()
+	created, err := s.Create(players[0], "テスト大会", format, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range players {
+		if _, err := s.Register(created.ID, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	started, err := s.Start(context.Background(), created.ID, players[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return started
+}
+
+func TestReplayIsNotified(t *testing.T) {
+	duels := game.NewDuelService(testCards)
+	s := NewService(duels)
+	notified := make(chan Round, 4)
+	s.SetRoundCallback(func(_ *Tournament, round *Round) { notified <- *round })
+
+	started := startTournament(t, s, FormatSingleElimination, "a", "b")
+	<-notified
+	first := started.Rounds[0].Pairings[0]
+
+	// 勝ち抜き戦の引き分けは再試合になり、その卓だけが通知される
+	if _, err := duels.ForceEnd(first.DuelID, "", false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case round := <-notified:
+		if len(round.Pairings) != 1 {
+			t.Fatalf("再試合の通知の卓数 = %d, want 1", len(round.Pairings))
+		}
+		replay := round.Pairings[0]
+		if replay.DuelID == first.DuelID || replay.Games != 2 {
+			t.Errorf("再試合の組み合わせ = %+v, want 新しい対戦ID・2戦目", replay)
+		}
+		if _, err := duels.GetDuel(replay.DuelID); err != nil {
+			t.Errorf("再試合の対戦が作成されていません: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("再試合が通知されませんでした")
+	}
+}
+
+func TestNoShowForfeits(t *testing.T) {
+	duels := game.NewDuelService(testCards)
+	s := NewService(duels)
+	s.SetNoShowTimeout(20 * time.Millisecond)
+	finished := make(chan *Tournament, 1)
+	s.SetFinishCallback(func(t *Tournament) { finished <- t })
+
+	started := startTournament(t, s, FormatSingleElimination, "a", "b")
+	pairing := started.Rounds[0].Pairings[0]
+
+	// 先手が一度もアクションを送らないため、先手の不戦敗になる
+	select {
+	case result := <-finished:
+		if result.WinnerID != pairing.Player2ID {
+			t.Errorf("優勝 = %s, want %s (後手)", result.WinnerID, pairing.Player2ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("不戦敗になりませんでした")
+	}
+	duel, err := duels.GetDuel(pairing.DuelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !duel.Unrated {
+		t.Error("不戦敗の対戦がレーティング対象になっています")
+	}
+}
+
+func TestCleanupFinished(t *testing.T) {
+	duels := game.NewDuelService(testCards)
+	s := NewService(duels)
+	started := startTournament(t, s, FormatSingleElimination, "a", "b")
+	running := startTournament(t, s, FormatSingleElimination, "c", "d")
+
+	pairing := started.Rounds[0].Pairings[0]
+	if _, err := duels.ForceEnd(pairing.DuelID, pairing.Player1ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	s.CleanupFinished(time.Now().Add(-time.Hour))
+	if _, err := s.Get(started.ID); err != nil {
+		t.Fatalf("保持期間内の大会が削除されました: %v", err)
+	}
+
+	s.CleanupFinished(time.Now().Add(time.Second))
+	if _, err := s.Get(started.ID); err != ErrTournamentNotFound {
+		t.Errorf("終了した大会の取得 = %v, want ErrTournamentNotFound", err)
+	}
+	if _, err := s.Get(running.ID); err != nil {
+		t.Errorf("進行中の大会が削除されました: %v", err)
+	}
+}
+
+func TestCreateRejectsInvalidRounds(t *testing.T) {
+	s := NewService(game.NewDuelService(testCards))
+	for _, rounds := range []int{-1, maxSwissRounds + 1} {
+		if _, err := s.Create("a", "テスト大会", FormatSwiss, rounds); err != ErrInvalidRounds {
+			t.Errorf("Create(rounds=%d) = %v, want ErrInvalidRounds", rounds, err)
+		}
+	}
+	if _, err := s.Create("a", "テスト大会", FormatSwiss, maxSwissRounds); err != nil {
+		t.Errorf("Create(rounds=%d) = %v, want nil", maxSwissRounds, err)
+	}
+}
+
+func TestCleanupAbandoned(t *testing.T) {
+	s := NewService(game.NewDuelService(testCards))
+	abandoned, _ := s.Create("a", "開始されない大会", FormatSwiss, 0)
+	running := startTournament(t, s, FormatSingleElimination, "b", "c")
+
+	s.CleanupAbandoned(time.Now().Add(-time.Hour))
+	if _, err := s.Get(abandoned.ID); err != nil {
+		t.Fatalf("保持期間内の大会が削除されました: %v", err)
+	}
+
+	s.CleanupAbandoned(time.Now().Add(time.Second))
+	if _, err := s.Get(abandoned.ID); err != ErrTournamentNotFound {
+		t.Errorf("開始されない大会の取得 = %v, want ErrTournamentNotFound", err)
+	}
+	if _, err := s.Get(running.ID); err != nil {
+		t.Errorf("進行中の大会が削除されました: %v", err)
+	}
+	// 削除した大会は主催者の上限に数えない
+	if got := s.openCount("a"); got != 0 {
+		t.Errorf("削除後の openCount = %d, want 0", got)
+	}
+}
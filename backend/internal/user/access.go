@@ -0,0 +1,87 @@
+// backend/internal/user/access.go
+package user
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+)
+
+const (
+	// accessCacheTTL は権限・制裁の状態をキャッシュする時間です
+	// (他のインスタンスで変更した権限・制裁はこの時間内に反映されます)
+	accessCacheTTL = 30 * time.Second
+
+	// accessCachePruneSize はキャッシュの期限切れを掃除し始める件数です
+	accessCachePruneSize = 10000
+)
+
+// AccessCache はリクエストごとに確認するユーザーの権限と制裁の状態をキャッシュします
+//
+// 期限付きの制裁はキャッシュ中でも期限の時刻に解除されます。
+type AccessCache struct {
+	users   *db.UserRepository
+	mu      sync.Mutex
+	entries map[string]accessEntry
+}
+
+type accessEntry struct {
+	access  *db.UserAccess
+	expires time.Time
+}
+
+// NewAccessCache は新しいAccessCacheを作成します
+func NewAccessCache(users *db.UserRepository) *AccessCache {
+	return &AccessCache{users: users, entries: make(map[string]accessEntry)}
+}
+
+// Lookup はユーザーの権限と有効な制裁を返します。ユーザーが存在しない場合は nil を返します
+func (a *AccessCache) Lookup(ctx context.Context, userID string) (*db.UserAccess, error) {
+	now := time.Now()
+	a.mu.Lock()
+	entry, ok := a.entries[userID]
+	a.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.access, nil
+	}
+
+	access, err := a.users.GetAccess(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	if len(a.entries) >= accessCachePruneSize {
+		for id, e := range a.entries {
+			if now.After(e.expires) {
+				delete(a.entries, id)
+			}
+		}
+	}
+	a.entries[userID] = accessEntry{access: access, expires: cacheExpiry(access, now)}
+	a.mu.Unlock()
+	return access, nil
+}
+
+// Invalidate はユーザーのキャッシュを消し、次のリクエストでDBから読み直すようにします
+func (a *AccessCache) Invalidate(userID string) {
+	a.mu.Lock()
+	delete(a.entries, userID)
+	a.mu.Unlock()
+}
+
+// cacheExpiry はキャッシュの期限を返します。期限付きの制裁が先に切れる場合はその時刻です
+func cacheExpiry(access *db.UserAccess, now time.Time) time.Time {
+	expires := now.Add(accessCacheTTL)
+	if access == nil {
+		return expires
+	}
+	for _, s := range []*db.Sanction{access.Block, access.Mute} {
+		if s != nil && s.ExpiresAt != nil && s.ExpiresAt.Before(expires) {
+			expires = *s.ExpiresAt
+		}
+	}
+	return expires
+}
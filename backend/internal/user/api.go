@@ -0,0 +1,152 @@
+// backend/internal/user/api.go
+package user
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// API はログイン中のユーザーのプロフィールをRESTで公開します
+type API struct {
+	Users       *db.UserRepository
+	Provisioner *Provisioner
+	Access      *AccessCache
+	// InMatch はユーザーがマッチング中・シリーズや大会への参加中かを返します (その間はアカウントを削除できない)
+	InMatch func(userID string) (bool, error)
+	// Forfeit はユーザーの進行中の対戦を相手の勝ちとして終了させます (アカウントの削除後に呼び出す)
+	Forfeit func(userID string) error
+}
+
+// NewAPI はAPIを作成します
+func NewAPI(users *db.UserRepository, provisioner *Provisioner, access *AccessCache, inMatch func(userID string) (bool, error), forfeit func(userID string) error) *API {
+	return &API{Users: users, Provisioner: provisioner, Access: access, InMatch: inMatch, Forfeit: forfeit}
+}
+
+// updateRequest はプロフィール変更のリクエストです
+type updateRequest struct {
+	Username *string `json:"username"`
+}
+
+// exportArchive はユーザー本人に開示する個人データのアーカイブです
+type exportArchive struct {
+	ExportedAt time.Time `json:"exportedAt"`
+	*db.UserData
+	// Decks は保存しているデッキです。デッキは対戦ごとにカードプールから組むため保存しておらず、常に空です
+	Decks []struct{} `json:"decks"`
+}
+
+// GetMe はログイン中のユーザーを返します
+// GET /api/users/me
+func (api *API) GetMe(c echo.Context) error {
+	uid := c.Get("uid").(string)
+	user, err := api.Users.GetByID(c.Request().Context(), uid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "ユーザー情報の取得に失敗しました")
+	}
+	if user == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "ユーザーが見つかりません")
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+// UpdateMe はログイン中のユーザーのユーザー名を変更します
+// PATCH /api/users/me
+func (api *API) UpdateMe(c echo.Context) error {
+	uid := c.Get("uid").(string)
+
+	var req updateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+	if req.Username == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "usernameが必要です")
+	}
+
+	username := NormalizeUsername(*req.Username)
+	if err := ValidateChosenUsername(username); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	err := api.Users.UpdateUsername(ctx, uid, username)
+	if errors.Is(err, db.ErrUsernameTaken) {
+		return echo.NewHTTPError(http.StatusConflict, "ユーザー名は既に使われています")
+	}
+	if err != nil {
+		log.Printf("ユーザー名変更エラー (ユーザー: %s): %v", uid, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "ユーザー名の変更に失敗しました")
+	}
+
+	return api.GetMe(c)
+}
+
+// ExportMe はログイン中のユーザーの個人データ (プロフィール・所持カード・デッキ・対戦履歴) を
+// JSONのアーカイブとして返します
+// GET /api/users/me/export
+func (api *API) ExportMe(c echo.Context) error {
+	uid := c.Get("uid").(string)
+	data, err := api.Users.ExportData(c.Request().Context(), uid)
+	if err != nil {
+		log.Printf("個人データの書き出しエラー (ユーザー: %s): %v", uid, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "個人データの書き出しに失敗しました")
+	}
+	if data == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "ユーザーが見つかりません")
+	}
+
+	archive := exportArchive{ExportedAt: time.Now(), UserData: data, Decks: []struct{}{}}
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="go-card-export.json"`)
+	return c.JSON(http.StatusOK, archive)
+}
+
+// DeleteMe はログイン中のユーザーのアカウントを削除します
+//
+// ユーザー名・所持カード・ログイン情報などの個人データを削除し、対戦履歴と制裁の記録は
+// 匿名のユーザーに付け替えて残します。Firebaseのアカウント自体は削除しないため、
+// 同じアカウントで再びログインすると新しいユーザーとして作成されます。
+// そのため制裁期間中は (作り直して制裁を逃れられないよう) 削除できません。
+// DELETE /api/users/me
+func (api *API) DeleteMe(c echo.Context) error {
+	uid := c.Get("uid").(string)
+
+	// マッチングのキューやシリーズ・大会はこのユーザーIDで動いているため、終わるまで待ってもらう
+	if api.InMatch != nil {
+		inMatch, err := api.InMatch(uid)
+		if err != nil {
+			log.Printf("参加状況の確認エラー (ユーザー: %s): %v", uid, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "アカウントの削除に失敗しました")
+		}
+		if inMatch {
+			return echo.NewHTTPError(http.StatusConflict, "マッチング中・シリーズや大会への参加中はアカウントを削除できません")
+		}
+	}
+
+	err := api.Users.Delete(c.Request().Context(), uid, deletedIDPrefix+uuid.New().String())
+	if errors.Is(err, db.ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "ユーザーが見つかりません")
+	}
+	if errors.Is(err, db.ErrUnderSanction) {
+		return echo.NewHTTPError(http.StatusConflict, "制裁期間中はアカウントを削除できません")
+	}
+	if err != nil {
+		log.Printf("アカウント削除エラー (ユーザー: %s): %v", uid, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "アカウントの削除に失敗しました")
+	}
+	api.Provisioner.Forget(uid)
+	api.Access.Invalidate(uid)
+	// 進行中の対戦は削除したユーザーの負けとして終わらせる
+	if api.Forfeit != nil {
+		if err := api.Forfeit(uid); err != nil {
+			log.Printf("進行中の対戦の終了エラー (ユーザー: %s): %v", uid, err)
+		}
+	}
+
+	log.Printf("ユーザー %s がアカウントを削除しました", uid)
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,164 @@
+// backend/internal/user/profanity.go
+package user
+
+import (
+	"strings"
+	"unicode"
+)
+
+// profaneEnglishWords はユーザー名に使えない英語の語です (単語単位で判定)
+//
+// 普通の語や名前の一部に含まれることがあるため ("Scunthorpe", "Nazir", "retardant" など)、
+// 単語がこの語そのもの、または englishSuffixes を付けた形の場合だけ一致とします。
+var profaneEnglishWords = []string{
+	"fuck",
+	"shit",
+	"cunt",
+	"bitch",
+	"whore",
+	"slut",
+	"nigger",
+	"nigga",
+	"faggot",
+	"retard",
+	"penis",
+	"vagina",
+	"nazi",
+	"hitler",
+}
+
+// englishSuffixes は profaneEnglishWords の活用形として一致とする語尾です
+var englishSuffixes = []string{"", "s", "es", "er", "ers", "ed", "ing", "y", "ty"}
+
+// profaneJapaneseWords はユーザー名に使えない日本語の語です (正規化後の部分一致で判定)
+//
+// 日本語は単語の区切りがないため、名前のどこに含まれていても一致とします。
+var profaneJapaneseWords = []string{
+	"ちんこ",
+	"ちんぽ",
+	"まんこ",
+	"セックス",
+	"死ね",
+	"殺す",
+	"きちがい",
+	"キチガイ",
+	"ガイジ",
+	"がいじ",
+}
+
+// leetReplacer は記号や数字で置き換えた表記を元の文字に戻します
+var leetReplacer = strings.NewReplacer(
+	"0", "o",
+	"1", "i",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"7", "t",
+	"@", "a",
+	"$", "s",
+	"!", "i",
+)
+
+// ContainsProfanity は name に不適切な語が含まれているかを返します
+//
+// 大文字小文字・数字や記号による言い換え・区切り文字を無視して判定します。
+// 英語の語は単語単位で判定し、1文字ずつ区切った表記 ("f.u.c.k") は1語として扱います。
+func ContainsProfanity(name string) bool {
+	replaced := leetReplacer.Replace(name)
+
+	normalized := normalizeForProfanity(replaced)
+	for _, word := range profaneJapaneseWords {
+		if strings.Contains(normalized, word) {
+			return true
+		}
+	}
+
+	for _, word := range englishWords(replaced) {
+		if isProfaneEnglish(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// isProfaneEnglish は英単語が profaneEnglishWords またはその活用形かを返します
+func isProfaneEnglish(word string) bool {
+	for _, profane := range profaneEnglishWords {
+		if !strings.HasPrefix(word, profane) {
+			continue
+		}
+		suffix := word[len(profane):]
+		for _, allowed := range englishSuffixes {
+			if suffix == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// englishWords は name を英字以外で区切った単語を小文字で返します
+//
+// キャメルケース ("xXFuckXx") を大文字の前で区切った単語と、1文字の単語が続く部分
+// ("f.u.c.k") をつなげた単語も返します。
+func englishWords(name string) []string {
+	var words []string
+	var letters strings.Builder // 続いている1文字の単語
+	endLetters := func() {
+		if letters.Len() > 1 {
+			words = append(words, letters.String())
+		}
+		letters.Reset()
+	}
+
+	tokens := strings.FieldsFunc(name, func(r rune) bool {
+		return r > unicode.MaxASCII || !unicode.IsLetter(r)
+	})
+	for _, token := range tokens {
+		lower := strings.ToLower(token)
+		words = append(words, lower)
+		words = append(words, splitCamelCase(token)...)
+		if len(token) == 1 {
+			letters.WriteString(lower)
+			continue
+		}
+		endLetters()
+	}
+	endLetters()
+	return words
+}
+
+// splitCamelCase は英字の単語を大文字で始まる部分の前で区切ります。区切れない場合は nil を返します
+//
+// 大文字が続く場合は、小文字が続く前の大文字から次の部分とします ("XFuck" は "X" と "Fuck")。
+func splitCamelCase(token string) []string {
+	var parts []string
+	start := 0
+	for i := 1; i < len(token); i++ {
+		upper, prevUpper := isUpperASCII(token[i]), isUpperASCII(token[i-1])
+		nextLower := i+1 < len(token) && !isUpperASCII(token[i+1])
+		if upper && (!prevUpper || nextLower) {
+			parts = append(parts, strings.ToLower(token[start:i]))
+			start = i
+		}
+	}
+	if start == 0 {
+		return nil
+	}
+	return append(parts, strings.ToLower(token[start:]))
+}
+
+// isUpperASCII は英字の大文字かを返します
+func isUpperASCII(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+// normalizeForProfanity は判定用に小文字化し、文字以外を取り除きます
+func normalizeForProfanity(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, strings.ToLower(name))
+}
@@ -0,0 +1,37 @@
+// backend/internal/user/profanity_test.go
+package user
+
+import "testing"
+
+func TestContainsProfanity(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"alice", false},
+		{"fuck", true},
+		{"FUCK you", true},
+		{"fuckers", true},
+		{"sh1t", true},
+		{"f.u.c.k", true},
+		{"f u c k", true},
+		{"xXFuckXx", true},
+		{"BigShitHead", true},
+		{"n4z1", true},
+		{"nazis", true},
+		{"死ねばいい", true},
+		{"セックス王", true},
+		// 普通の語や名前の一部に含まれるだけなら使える
+		{"Scunthorpe", false},
+		{"Nazir", false},
+		{"retardant", false},
+		{"Shitake", false},
+		{"a b c", false},
+		{"penistone", false},
+	}
+	for _, tt := range tests {
+		if got := ContainsProfanity(tt.name); got != tt.want {
+			t.Errorf("ContainsProfanity(%q) = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
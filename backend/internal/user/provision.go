@@ -0,0 +1,151 @@
+// backend/internal/user/provision.go
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxHandleAttempts は生成したユーザー名が重複したときに作り直す回数です
+	maxHandleAttempts = 5
+
+	// guestIDPrefix はゲストアカウントのユーザーIDの接頭辞です
+	guestIDPrefix = "guest-"
+
+	// knownTTL は users 行があると確認した記録を使う期間です
+	//
+	// 他のインスタンスで削除されたユーザーは Forget の通知で記録を消しますが、
+	// 通知が届かなかった場合もこの期間が過ぎれば確認し直します。
+	knownTTL = 10 * time.Minute
+
+	// methodForget は他のインスタンスに確認済みの記録を消させる処理です
+	methodForget = "provisioner.forget"
+)
+
+// Cluster は他のインスタンスに Forget を伝えるための連携です (ws.Hub が実装します)
+type Cluster interface {
+	Handle(method string, handler func(args json.RawMessage) (interface{}, error))
+	Gather(method string, args interface{}) ([]json.RawMessage, error)
+}
+
+// Provisioner は認証できたユーザーの users 行を初回だけ作成します
+//
+// Firebaseなど外部で認証されたユーザーは users に行がないと対戦の外部キーを満たせないため、
+// 認証ミドルウェアから Ensure を呼び出して最初のリクエストで作成します。
+type Provisioner struct {
+	users   *db.UserRepository
+	known   sync.Map // users 行があると確認済みのユーザーID (ゲストを除く) -> 確認した時刻
+	cluster Cluster
+}
+
+// NewProvisioner は新しいProvisionerを作成します
+func NewProvisioner(users *db.UserRepository) *Provisioner {
+	return &Provisioner{users: users}
+}
+
+// SetCluster は他のインスタンスとの連携を設定し、他のインスタンスからの Forget を受け付けます
+//
+// 設定しない場合は Forget はこのインスタンスの記録だけを消します。
+func (p *Provisioner) SetCluster(cluster Cluster) {
+	p.cluster = cluster
+	cluster.Handle(methodForget, func(args json.RawMessage) (interface{}, error) {
+		var userID string
+		if err := json.Unmarshal(args, &userID); err != nil {
+			return nil, err
+		}
+		p.known.Delete(userID)
+		return nil, nil
+	})
+}
+
+// Ensure はユーザーの users 行がなければ作成します
+//
+// name (トークンの表示名) が使えればそれを、使えない・重複している場合は生成した名前を使います。
+// ゲストの行は CreateGuest で作るため、見つからない場合は作成せずに db.ErrNotGuest を返します。
+// ゲストの行は他のインスタンスでの連携で削除されることがあるため、ゲストは毎回確認します。
+func (p *Provisioner) Ensure(ctx context.Context, userID, name string, guest bool) error {
+	if checkedAt, ok := p.known.Load(userID); ok && !guest && time.Since(checkedAt.(time.Time)) < knownTTL {
+		return nil
+	}
+
+	existing, err := p.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if guest {
+		if existing == nil || !existing.IsGuest {
+			return db.ErrNotGuest
+		}
+		return nil
+	}
+	if existing == nil {
+		if err := p.create(ctx, userID, name, false); err != nil {
+			return err
+		}
+	}
+
+	p.known.Store(userID, time.Now())
+	return nil
+}
+
+// Forget は確認済みの記録をすべてのインスタンスで消し、次の Ensure で users 行を確認し直すようにします
+//
+// 他のインスタンスに伝えられなかった場合も、その記録は knownTTL が過ぎると使われなくなります。
+func (p *Provisioner) Forget(userID string) {
+	p.known.Delete(userID)
+	if p.cluster == nil {
+		return
+	}
+	if _, err := p.cluster.Gather(methodForget, userID); err != nil {
+		log.Printf("他のインスタンスへの確認済みの記録の削除エラー (ユーザー: %s): %v", userID, err)
+	}
+}
+
+// CreateGuest は新しいゲストアカウントを作成し、ユーザーIDを返します
+func (p *Provisioner) CreateGuest(ctx context.Context) (string, error) {
+	userID := guestIDPrefix + uuid.New().String()
+	if err := p.create(ctx, userID, "", true); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// create はユーザー名を決めて users 行を作成します
+func (p *Provisioner) create(ctx context.Context, userID, name string, guest bool) error {
+	candidates := make([]string, 0, maxHandleAttempts+1)
+	if name = NormalizeUsername(name); ValidateChosenUsername(name) == nil {
+		candidates = append(candidates, name)
+	}
+	for i := 0; i < maxHandleAttempts; i++ {
+		handle, err := GenerateHandle()
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, handle)
+	}
+
+	for _, username := range candidates {
+		err := p.users.Create(ctx, &db.User{ID: userID, Username: username, IsGuest: guest})
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, db.ErrUserExists):
+			// 同じユーザーの別のリクエストが先に作成した
+			return nil
+		case errors.Is(err, db.ErrUsernameTaken):
+			continue
+		default:
+			return err
+		}
+	}
+	return fmt.Errorf("ユーザー名を決められませんでした (ユーザー: %s)", userID)
+}
@@ -0,0 +1,46 @@
+// backend/internal/user/provision_test.go
+package user
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// testCluster は Provisioner 同士で Forget を伝える Cluster のテスト用の実装です
+type testCluster struct {
+	handlers []func(args json.RawMessage) (interface{}, error)
+}
+
+func (c *testCluster) Handle(method string, handler func(args json.RawMessage) (interface{}, error)) {
+	c.handlers = append(c.handlers, handler)
+}
+
+func (c *testCluster) Gather(method string, args interface{}) ([]json.RawMessage, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	for _, handler := range c.handlers {
+		if _, err := handler(data); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func TestForgetAcrossInstances(t *testing.T) {
+	cluster := &testCluster{}
+	a, b := NewProvisioner(nil), NewProvisioner(nil)
+	a.SetCluster(cluster)
+	b.SetCluster(cluster)
+	a.known.Store("alice", time.Now())
+	b.known.Store("alice", time.Now())
+
+	a.Forget("alice")
+	for name, p := range map[string]*Provisioner{"a": a, "b": b} {
+		if _, ok := p.known.Load("alice"); ok {
+			t.Errorf("Forget 後の %s の確認済みの記録 = あり; want なし", name)
+		}
+	}
+}
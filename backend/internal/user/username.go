@@ -0,0 +1,77 @@
+// backend/internal/user/username.go
+package user
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MinUsernameLength はユーザー名の最小文字数です
+	MinUsernameLength = 3
+	// MaxUsernameLength はユーザー名の最大文字数です
+	MaxUsernameLength = 20
+
+	// handlePrefix は自動生成するユーザー名の接頭辞です
+	handlePrefix = "player-"
+	// deletedIDPrefix は削除したユーザーの対戦履歴に残す匿名のユーザーのID・ユーザー名の接頭辞です
+	deletedIDPrefix = "deleted-"
+)
+
+var (
+	// ErrInvalidUsername はユーザー名の長さや使える文字が条件を満たさないことを表します
+	ErrInvalidUsername = fmt.Errorf("ユーザー名は%d〜%d文字の文字・数字・空白と _ - . で指定してください", MinUsernameLength, MaxUsernameLength)
+	// ErrProfaneUsername はユーザー名に不適切な語が含まれていることを表します
+	ErrProfaneUsername = errors.New("ユーザー名に使用できない語が含まれています")
+	// ErrReservedUsername は自動生成用の名前など、ユーザーが指定できない名前であることを表します
+	ErrReservedUsername = errors.New("このユーザー名は使用できません")
+)
+
+// NormalizeUsername は前後の空白を除き、連続する空白を1つにまとめます
+func NormalizeUsername(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// ValidateUsername は正規化済みのユーザー名が使えるかを検証します
+//
+// 文字 (日本語を含む)・数字・空白と _ - . が使えます。
+func ValidateUsername(name string) error {
+	if n := utf8.RuneCountInString(name); n < MinUsernameLength || n > MaxUsernameLength {
+		return ErrInvalidUsername
+	}
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || r == '_' || r == '-' || r == '.' {
+			continue
+		}
+		return ErrInvalidUsername
+	}
+	if ContainsProfanity(name) {
+		return ErrProfaneUsername
+	}
+	return nil
+}
+
+// ValidateChosenUsername はユーザーが指定したユーザー名を検証します
+//
+// ValidateUsername に加え、自動生成する名前や削除したユーザーの名前と紛らわしいものを拒否します。
+func ValidateChosenUsername(name string) error {
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, handlePrefix) || strings.HasPrefix(lower, deletedIDPrefix) {
+		return ErrReservedUsername
+	}
+	return ValidateUsername(name)
+}
+
+// GenerateHandle はユーザー名が決まっていないユーザーの名前を生成します ("player-1a2b3c" など)
+func GenerateHandle() (string, error) {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ユーザー名生成エラー: %w", err)
+	}
+	return handlePrefix + hex.EncodeToString(buf), nil
+}
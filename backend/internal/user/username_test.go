@@ -0,0 +1,82 @@
+// backend/internal/user/username_test.go
+package user
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"alice", "alice"},
+		{"  alice  ", "alice"},
+		{"alice   the\tgreat", "alice the great"},
+		{"   ", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeUsername(tt.name); got != tt.want {
+			t.Errorf("NormalizeUsername(%q) = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		name string
+		want error
+	}{
+		{"alice", nil},
+		{"たろう", nil},
+		{"a_b-c.d 1", nil},
+		{"ab", ErrInvalidUsername},
+		{strings.Repeat("a", MaxUsernameLength), nil},
+		{strings.Repeat("a", MaxUsernameLength+1), ErrInvalidUsername},
+		{strings.Repeat("あ", MaxUsernameLength), nil},
+		{"alice!", ErrInvalidUsername},
+		{"<script>", ErrInvalidUsername},
+		{"fuck you", ErrProfaneUsername},
+		{"Scunthorpe", nil},
+		// 自動生成する名前と同じ形は ValidateUsername では拒否しない
+		{"player-1a2b3c", nil},
+	}
+	for _, tt := range tests {
+		if got := ValidateUsername(tt.name); got != tt.want {
+			t.Errorf("ValidateUsername(%q) = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateChosenUsername(t *testing.T) {
+	tests := []struct {
+		name string
+		want error
+	}{
+		{"alice", nil},
+		{"player-1a2b3c", ErrReservedUsername},
+		{"Player-alice", ErrReservedUsername},
+		{"deleted-1234", ErrReservedUsername},
+		{"player alice", nil},
+		{"ab", ErrInvalidUsername},
+		{"shit", ErrProfaneUsername},
+	}
+	for _, tt := range tests {
+		if got := ValidateChosenUsername(tt.name); got != tt.want {
+			t.Errorf("ValidateChosenUsername(%q) = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateHandleIsValid(t *testing.T) {
+	handle, err := GenerateHandle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(handle, handlePrefix) || ValidateUsername(handle) != nil {
+		t.Errorf("GenerateHandle = %q; want %s で始まる使える名前", handle, handlePrefix)
+	}
+	if ValidateChosenUsername(handle) != ErrReservedUsername {
+		t.Errorf("生成した名前 %q をユーザーが指定できます", handle)
+	}
+}
@@ -0,0 +1,94 @@
+// backend/internal/ws/account.go
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// アカウントの削除・連携で他のインスタンスを呼び出す処理
+const (
+	methodInMatch = "user.inMatch"
+	methodForfeit = "user.forfeit"
+)
+
+// handleAccountCalls はアカウントの削除・連携の確認と後始末を他のインスタンスから受け付けます
+func (h *Hub) handleAccountCalls() {
+	h.Handle(methodInMatch, func(args json.RawMessage) (interface{}, error) {
+		var userID string
+		if err := json.Unmarshal(args, &userID); err != nil {
+			return nil, err
+		}
+		return h.localInMatch(userID), nil
+	})
+	h.Handle(methodForfeit, func(args json.RawMessage) (interface{}, error) {
+		var userID string
+		if err := json.Unmarshal(args, &userID); err != nil {
+			return nil, err
+		}
+		h.duelService.Forfeit(userID)
+		return nil, nil
+	})
+}
+
+// AddInMatchCheck は InMatch で参加中とみなす処理を追加します (大会など Hub の外で管理しているもの)
+//
+// 各インスタンスは自分が保持している分だけを確認します。
+func (h *Hub) AddInMatchCheck(check func(userID string) bool) {
+	h.callMu.Lock()
+	defer h.callMu.Unlock()
+	h.inMatchChecks = append(h.inMatchChecks, check)
+}
+
+// InMatch はユーザーがいずれかのインスタンスでマッチング中・シリーズや大会への参加中かを返します
+//
+// 進行中の対戦は時間切れがなく待っても終わるとは限らないため含めません (Forfeit で終了させます)。
+func (h *Hub) InMatch(userID string) (bool, error) {
+	if h.localInMatch(userID) {
+		return true, nil
+	}
+
+	replies, err := h.Gather(methodInMatch, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, reply := range replies {
+		var inMatch bool
+		if err := json.Unmarshal(reply, &inMatch); err != nil {
+			return false, fmt.Errorf("参加状況の変換エラー: %w", err)
+		}
+		if inMatch {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// localInMatch はユーザーがこのインスタンスでマッチング中・シリーズや大会への参加中かを返します
+func (h *Hub) localInMatch(userID string) bool {
+	if _, err := h.matchmakingService.GetUserRoom(userID); err == nil {
+		return true
+	}
+	if h.duelService.RematchPending(userID) || h.matchService.InSeries(userID) {
+		return true
+	}
+
+	h.callMu.RLock()
+	checks := h.inMatchChecks
+	h.callMu.RUnlock()
+	for _, check := range checks {
+		if check(userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Forfeit はユーザーがすべてのインスタンスで参加している進行中の対戦を、相手の勝ちとして終了させます
+func (h *Hub) Forfeit(userID string) error {
+	h.duelService.Forfeit(userID)
+	if _, err := h.Gather(methodForfeit, userID); err != nil {
+		return fmt.Errorf("他のインスタンスの対戦の終了エラー: %w", err)
+	}
+	return nil
+}
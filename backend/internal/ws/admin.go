@@ -0,0 +1,91 @@
+// backend/internal/ws/admin.go
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+// 管理画面の操作で他のインスタンスを呼び出す処理
+const (
+	methodLiveDuels  = "duel.live"
+	methodGetDuel    = "duel.get"
+	methodUpdateCard = "card.update"
+)
+
+// handleAdminCalls は管理画面の操作を他のインスタンスから受け付けます
+func (h *Hub) handleAdminCalls() {
+	h.Handle(methodLiveDuels, func(json.RawMessage) (interface{}, error) {
+		return h.duelService.LiveDuels(), nil
+	})
+	h.Handle(methodGetDuel, func(args json.RawMessage) (interface{}, error) {
+		var duelID string
+		if err := json.Unmarshal(args, &duelID); err != nil {
+			return nil, err
+		}
+		return h.duelService.GetDuel(duelID)
+	})
+	h.Handle(methodUpdateCard, func(args json.RawMessage) (interface{}, error) {
+		var card game.Card
+		if err := json.Unmarshal(args, &card); err != nil {
+			return nil, err
+		}
+		h.duelService.UpdateCard(card)
+		return nil, nil
+	})
+}
+
+// LiveDuels はすべてのインスタンスで進行中の対戦をID順に返します
+func (h *Hub) LiveDuels() ([]*game.Duel, error) {
+	duels := h.duelService.LiveDuels()
+	replies, err := h.Gather(methodLiveDuels, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		var remote []*game.Duel
+		if err := json.Unmarshal(reply, &remote); err != nil {
+			return nil, fmt.Errorf("進行中の対戦の変換エラー: %w", err)
+		}
+		duels = append(duels, remote...)
+	}
+
+	sort.Slice(duels, func(i, j int) bool { return duels[i].ID < duels[j].ID })
+	return duels, nil
+}
+
+// GetDuel は対戦の状態 (両プレイヤーの手札を含む) を返します
+//
+// 対戦が別インスタンスにある場合は担当インスタンスから取得します。
+func (h *Hub) GetDuel(duelID string) (*game.Duel, error) {
+	if duel, err := h.duelService.GetDuel(duelID); err == nil {
+		return duel, nil
+	}
+
+	owner, err := h.owner(duelKey(duelID))
+	if err != nil {
+		return nil, err
+	}
+	if owner == "" || owner == h.instanceID {
+		return nil, game.ErrDuelNotFound
+	}
+	var duel game.Duel
+	if err := h.Call(owner, methodGetDuel, duelID, &duel); err != nil {
+		return nil, err
+	}
+	return &duel, nil
+}
+
+// UpdateCard はすべてのインスタンスのカードプールのカードを置き換えます
+//
+// 変更後に作成される対戦から反映されます。
+func (h *Hub) UpdateCard(card game.Card) error {
+	h.duelService.UpdateCard(card)
+	if _, err := h.Gather(methodUpdateCard, card); err != nil {
+		return fmt.Errorf("他のインスタンスへのカードの反映エラー: %w", err)
+	}
+	return nil
+}
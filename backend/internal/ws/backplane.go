@@ -0,0 +1,199 @@
+// backend/internal/ws/backplane.go
+package ws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var errBackplaneClosed = errors.New("backplane is closed")
+
+// Backplane は複数のHubインスタンス間でメッセージを中継するpub/sub基盤です
+//
+// ユーザーの接続先やDuelを保持しているインスタンスは「所有者」として
+// キーごとに記録し、メッセージはその所有者のチャネルへ送信されます。
+type Backplane interface {
+	// Publish はチャネルにペイロードを送信し、受信した購読者の数を返します
+	Publish(ctx context.Context, channel string, payload []byte) (int, error)
+
+	// Subscribe はチャネルを購読し、受信したペイロードごとにhandlerを呼び出します
+	Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (unsubscribe func(), err error)
+
+	// SetOwner はキーの所有者をttl付きで記録します
+	SetOwner(ctx context.Context, key, owner string, ttl time.Duration) error
+
+	// SetOwnerIfAbsent は所有者がいない場合のみキーの所有者をttl付きで記録し、記録できたかを返します
+	SetOwnerIfAbsent(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+
+	// Owner はキーの所有者を返します。所有者がいない場合は空文字を返します
+	Owner(ctx context.Context, key string) (string, error)
+
+	// ReleaseOwner はownerが現在の所有者である場合のみキーを削除します
+	ReleaseOwner(ctx context.Context, key, owner string) error
+
+	// Close は購読と接続をすべて終了します
+	Close() error
+}
+
+// MemoryBackplane はプロセス内で完結するBackplaneの実装です
+//
+// 単一インスタンス運用や、同じプロセス内で複数のHubを動かすテストで使用します。
+type MemoryBackplane struct {
+	mu     sync.RWMutex
+	subs   map[string]map[int]*memorySubscriber
+	owners map[string]memoryOwner
+	nextID int
+	closed bool
+}
+
+type memoryOwner struct {
+	owner   string
+	expires time.Time
+}
+
+// memorySubscriber は購読者ごとの配送キューを持ち、受信順序を保証します
+type memorySubscriber struct {
+	queue chan []byte
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewMemoryBackplane は新しいMemoryBackplaneを作成します
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{
+		subs:   make(map[string]map[int]*memorySubscriber),
+		owners: make(map[string]memoryOwner),
+	}
+}
+
+// Publish はチャネルの全購読者にペイロードを配送します
+func (b *MemoryBackplane) Publish(ctx context.Context, channel string, payload []byte) (int, error) {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return 0, errBackplaneClosed
+	}
+	subs := make([]*memorySubscriber, 0, len(b.subs[channel]))
+	for _, sub := range b.subs[channel] {
+		subs = append(subs, sub)
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.queue <- payload:
+		case <-sub.done:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	return len(subs), nil
+}
+
+// Subscribe はチャネルの購読を開始します
+func (b *MemoryBackplane) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, errBackplaneClosed
+	}
+
+	sub := &memorySubscriber{
+		queue: make(chan []byte, 256),
+		done:  make(chan struct{}),
+	}
+	id := b.nextID
+	b.nextID++
+	if b.subs[channel] == nil {
+		b.subs[channel] = make(map[int]*memorySubscriber)
+	}
+	b.subs[channel][id] = sub
+
+	go func() {
+		for {
+			select {
+			case payload := <-sub.queue:
+				handler(payload)
+			case <-sub.done:
+				return
+			}
+		}
+	}()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		delete(b.subs[channel], id)
+		if len(b.subs[channel]) == 0 {
+			delete(b.subs, channel)
+		}
+		b.mu.Unlock()
+		sub.once.Do(func() { close(sub.done) })
+	}
+	return unsubscribe, nil
+}
+
+// SetOwner はキーの所有者を記録します
+func (b *MemoryBackplane) SetOwner(ctx context.Context, key, owner string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.owners[key] = memoryOwner{owner: owner, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// SetOwnerIfAbsent は所有者がいない (期限切れを含む) 場合のみキーの所有者を記録します
+func (b *MemoryBackplane) SetOwnerIfAbsent(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := b.owners[key]; ok && !now.After(entry.expires) {
+		return false, nil
+	}
+	b.owners[key] = memoryOwner{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Owner はキーの所有者を返します
+func (b *MemoryBackplane) Owner(ctx context.Context, key string) (string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	entry, ok := b.owners[key]
+	if !ok || time.Now().After(entry.expires) {
+		return "", nil
+	}
+	return entry.owner, nil
+}
+
+// ReleaseOwner はownerが所有者である場合のみキーを削除します
+func (b *MemoryBackplane) ReleaseOwner(ctx context.Context, key, owner string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if entry, ok := b.owners[key]; ok && entry.owner == owner {
+		delete(b.owners, key)
+	}
+	return nil
+}
+
+// Close はすべての購読を終了します
+func (b *MemoryBackplane) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for _, subs := range b.subs {
+		for _, sub := range subs {
+			sub.once.Do(func() { close(sub.done) })
+		}
+	}
+	b.subs = make(map[string]map[int]*memorySubscriber)
+	return nil
+}
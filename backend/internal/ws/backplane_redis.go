@@ -0,0 +1,104 @@
+// backend/internal/ws/backplane_redis.go
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// releaseOwnerScript は所有者が一致する場合のみキーを削除します
+var releaseOwnerScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisBackplane はRedisのPub/Subを使ったBackplaneの実装です
+//
+// Redisプロトコルを話すサーバー (Redis, Memorystore, miniredis など) で動作します。
+type RedisBackplane struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisBackplane はredis:// 形式のURLからRedisBackplaneを作成します
+func NewRedisBackplane(url string) (*RedisBackplane, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("Redis URL解析エラー: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("Redis接続エラー: %w", err)
+	}
+
+	return &RedisBackplane{client: client, prefix: "gocard:"}, nil
+}
+
+// Publish はチャネルにペイロードを送信し、受信した購読者の数を返します
+func (b *RedisBackplane) Publish(ctx context.Context, channel string, payload []byte) (int, error) {
+	receivers, err := b.client.Publish(ctx, b.prefix+channel, payload).Result()
+	return int(receivers), err
+}
+
+// Subscribe はチャネルを購読します
+func (b *RedisBackplane) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (func(), error) {
+	ps := b.client.Subscribe(ctx, b.prefix+channel)
+	// 購読が確立するまで待つ
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, fmt.Errorf("Redis購読エラー: %w", err)
+	}
+
+	go func() {
+		for msg := range ps.Channel() {
+			handler([]byte(msg.Payload))
+		}
+	}()
+
+	unsubscribe := func() {
+		if err := ps.Close(); err != nil {
+			log.Printf("Redis購読解除エラー (チャネル: %s): %v", channel, err)
+		}
+	}
+	return unsubscribe, nil
+}
+
+// SetOwner はキーの所有者をttl付きで記録します
+func (b *RedisBackplane) SetOwner(ctx context.Context, key, owner string, ttl time.Duration) error {
+	return b.client.Set(ctx, b.prefix+key, owner, ttl).Err()
+}
+
+// SetOwnerIfAbsent は所有者がいない場合のみキーの所有者を記録します (SET NX)
+func (b *RedisBackplane) SetOwnerIfAbsent(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return b.client.SetNX(ctx, b.prefix+key, owner, ttl).Result()
+}
+
+// Owner はキーの所有者を返します
+func (b *RedisBackplane) Owner(ctx context.Context, key string) (string, error) {
+	owner, err := b.client.Get(ctx, b.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return owner, err
+}
+
+// ReleaseOwner はownerが所有者である場合のみキーを削除します
+func (b *RedisBackplane) ReleaseOwner(ctx context.Context, key, owner string) error {
+	return releaseOwnerScript.Run(ctx, b.client, []string{b.prefix + key}, owner).Err()
+}
+
+// Close はRedis接続を閉じます
+func (b *RedisBackplane) Close() error {
+	return b.client.Close()
+}
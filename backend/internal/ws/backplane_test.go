@@ -0,0 +1,146 @@
+// backend/internal/ws/backplane_test.go
+package ws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// backplanes はテスト対象のBackplaneを実装ごとに作成します
+func backplanes(t *testing.T) map[string]func(t *testing.T) (Backplane, func(d time.Duration)) {
+	return map[string]func(t *testing.T) (Backplane, func(d time.Duration)){
+		"memory": func(t *testing.T) (Backplane, func(d time.Duration)) {
+			return NewMemoryBackplane(), time.Sleep
+		},
+		"redis": func(t *testing.T) (Backplane, func(d time.Duration)) {
+			mr := miniredis.RunT(t)
+			bp, err := NewRedisBackplane("redis://" + mr.Addr())
+			if err != nil {
+				t.Fatalf("NewRedisBackplane: %v", err)
+			}
+			return bp, mr.FastForward
+		},
+	}
+}
+
+func TestBackplaneOwner(t *testing.T) {
+	for name, newBackplane := range backplanes(t) {
+		t.Run(name, func(t *testing.T) {
+			bp, advance := newBackplane(t)
+			defer bp.Close()
+			ctx := context.Background()
+
+			if owner, err := bp.Owner(ctx, "user:u1"); err != nil || owner != "" {
+				t.Fatalf("未登録のキーの所有者 = %q, %v; want \"\"", owner, err)
+			}
+
+			if err := bp.SetOwner(ctx, "user:u1", "a", time.Minute); err != nil {
+				t.Fatalf("SetOwner: %v", err)
+			}
+			// 所有者でないインスタンスは解放できない (再接続先のインスタンスの登録を消さない)
+			if err := bp.ReleaseOwner(ctx, "user:u1", "b"); err != nil {
+				t.Fatalf("ReleaseOwner: %v", err)
+			}
+			if owner, _ := bp.Owner(ctx, "user:u1"); owner != "a" {
+				t.Fatalf("他のインスタンスの解放後の所有者 = %q; want \"a\"", owner)
+			}
+			if err := bp.ReleaseOwner(ctx, "user:u1", "a"); err != nil {
+				t.Fatalf("ReleaseOwner: %v", err)
+			}
+			if owner, _ := bp.Owner(ctx, "user:u1"); owner != "" {
+				t.Fatalf("解放後の所有者 = %q; want \"\"", owner)
+			}
+
+			// 延長されなかったキーは期限切れで消える
+			if err := bp.SetOwner(ctx, "duel:d1", "a", 50*time.Millisecond); err != nil {
+				t.Fatalf("SetOwner: %v", err)
+			}
+			advance(100 * time.Millisecond)
+			if owner, _ := bp.Owner(ctx, "duel:d1"); owner != "" {
+				t.Fatalf("期限切れ後の所有者 = %q; want \"\"", owner)
+			}
+		})
+	}
+}
+
+func TestBackplaneSetOwnerIfAbsent(t *testing.T) {
+	for name, newBackplane := range backplanes(t) {
+		t.Run(name, func(t *testing.T) {
+			bp, advance := newBackplane(t)
+			defer bp.Close()
+			ctx := context.Background()
+
+			if ok, err := bp.SetOwnerIfAbsent(ctx, "roomCode:ABC", "a", 50*time.Millisecond); err != nil || !ok {
+				t.Fatalf("空いているキーの登録 = %t, %v; want true", ok, err)
+			}
+			// 他のインスタンスが登録済みのキーは奪えない
+			if ok, err := bp.SetOwnerIfAbsent(ctx, "roomCode:ABC", "b", time.Minute); err != nil || ok {
+				t.Fatalf("登録済みのキーの登録 = %t, %v; want false", ok, err)
+			}
+			if owner, _ := bp.Owner(ctx, "roomCode:ABC"); owner != "a" {
+				t.Fatalf("所有者 = %q; want \"a\"", owner)
+			}
+
+			// 期限切れのキーは登録できる
+			advance(100 * time.Millisecond)
+			if ok, err := bp.SetOwnerIfAbsent(ctx, "roomCode:ABC", "b", time.Minute); err != nil || !ok {
+				t.Fatalf("期限切れのキーの登録 = %t, %v; want true", ok, err)
+			}
+			if owner, _ := bp.Owner(ctx, "roomCode:ABC"); owner != "b" {
+				t.Fatalf("所有者 = %q; want \"b\"", owner)
+			}
+		})
+	}
+}
+
+func TestBackplanePublishSubscribe(t *testing.T) {
+	for name, newBackplane := range backplanes(t) {
+		t.Run(name, func(t *testing.T) {
+			bp, _ := newBackplane(t)
+			defer bp.Close()
+			ctx := context.Background()
+
+			received := make(chan string, 3)
+			unsubscribe, err := bp.Subscribe(ctx, "instance:a", func(payload []byte) {
+				received <- string(payload)
+			})
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+
+			for _, payload := range []string{"1", "2", "3"} {
+				receivers, err := bp.Publish(ctx, "instance:a", []byte(payload))
+				if err != nil {
+					t.Fatalf("Publish: %v", err)
+				}
+				if receivers != 1 {
+					t.Fatalf("受信した購読者の数 = %d; want 1", receivers)
+				}
+			}
+			// 同じチャネルのメッセージは送信順に届く
+			for _, want := range []string{"1", "2", "3"} {
+				select {
+				case got := <-received:
+					if got != want {
+						t.Fatalf("受信 = %q; want %q", got, want)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("%q を受信できませんでした", want)
+				}
+			}
+
+			unsubscribe()
+			if _, err := bp.Publish(ctx, "instance:a", []byte("4")); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			select {
+			case got := <-received:
+				t.Fatalf("購読解除後に %q を受信しました", got)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// backend/internal/ws/chat.go
+package ws
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxChatLength はチャットメッセージの最大文字数です
+const maxChatLength = 200
+
+// handleChat はロビーのチャットメッセージを全インスタンスのクライアントに送信します
+func (c *Client) handleChat(msg *Message) {
+	if c.rejectMuted() {
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := decodeContent(msg.Content, &req); err != nil {
+		c.sendError("textが必要です")
+		return
+	}
+	text := strings.TrimSpace(req.Text)
+	if text == "" || utf8.RuneCountInString(text) > maxChatLength {
+		c.sendError(fmt.Sprintf("textは1〜%d文字で指定してください", maxChatLength))
+		return
+	}
+
+	c.hub.BroadcastMessage(&Message{
+		Type:    "chat",
+		UserID:  c.userID,
+		Content: map[string]interface{}{"text": text, "sentAt": time.Now()},
+	})
+}
+
+// rejectMuted はチャット禁止中のユーザーにエラーを送り、送信を止める場合に true を返します
+//
+// チャット禁止を確認できない場合も送信を止めます。
+func (c *Client) rejectMuted() bool {
+	mute, err := c.hub.activeMute(c.userID)
+	if err != nil {
+		log.Printf("制裁の確認エラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError("チャットを送信できませんでした。しばらくしてから再度お試しください")
+		return true
+	}
+	if mute == nil {
+		return false
+	}
+
+	message := "チャットは禁止されています"
+	if mute.ExpiresAt != nil {
+		message = fmt.Sprintf("チャットは %s まで禁止されています", mute.ExpiresAt.Format("2006-01-02 15:04 MST"))
+	}
+	c.sendError(message)
+	return true
+}
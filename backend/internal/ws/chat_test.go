@@ -0,0 +1,64 @@
+// backend/internal/ws/chat_test.go
+package ws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/db"
+)
+
+func TestChatStopsWhenMuteCannotBeChecked(t *testing.T) {
+	var mu sync.Mutex
+	var lookupErr error
+	var mute *db.Sanction
+	lookup := func(ctx context.Context, userID string) (*db.UserAccess, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		return &db.UserAccess{Mute: mute}, nil
+	}
+	setAccess := func(err error, sanction *db.Sanction) {
+		mu.Lock()
+		defer mu.Unlock()
+		lookupErr, mute = err, sanction
+	}
+
+	hub := NewHub(nil, WithBackplane(NewMemoryBackplane()), WithAccessLookup(lookup))
+	go hub.Run()
+	sender := connectTestClient(t, hub, "u1")
+	listener := connectTestClient(t, hub, "u2")
+	chat := func(text string) {
+		sender.handleMessage(&Message{Type: "chat", Content: map[string]interface{}{"text": text}})
+	}
+
+	// 制裁を確認できない場合・チャット禁止中は送信されない
+	setAccess(errors.New("DBに接続できません"), nil)
+	chat("確認できない")
+	waitForMessage(t, sender, "error")
+
+	expires := time.Now().Add(time.Hour)
+	setAccess(nil, &db.Sanction{Kind: db.SanctionMute, ExpiresAt: &expires})
+	chat("禁止中")
+	waitForMessage(t, sender, "error")
+
+	setAccess(nil, nil)
+	chat("送信できる")
+	var texts []string
+	waitFor(t, "chat の受信", func() bool {
+		for _, msg := range listener.send.drain() {
+			if msg.Type == "chat" {
+				texts = append(texts, msg.Content.(map[string]interface{})["text"].(string))
+			}
+		}
+		return len(texts) > 0
+	})
+	if len(texts) != 1 || texts[0] != "送信できる" {
+		t.Errorf("届いたチャット = %v; want [送信できる]", texts)
+	}
+}
@@ -2,8 +2,10 @@
 package ws
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
-	"sync"
 	"time"
 
 	"github.com/KOU050223/go-card/internal/game"
@@ -19,38 +21,33 @@ const (
 
 	// Send pings to peer with this period. Must be less than pongWait.
 	pingPeriod = 30 * time.Second
-
-	// Maximum message size allowed from peer.
-	maxMessageSize = 512
 )
 
 // Client はWebSocketクライアント接続を表します
 type Client struct {
 	hub                *Hub
 	conn               *websocket.Conn
-	send               chan *Message
+	send               *outbox
+	codec              Codec
 	userID             string
-	closed             bool
-	mu                 sync.Mutex
 	matchmakingService *game.MatchmakingService
 	duelService        *game.DuelService
+	limiter            *rateLimiter
+
+	// 対戦ごとに最後に送信した状態 (writePumpのgoroutineのみが使用)
+	duelStates map[string]interface{}
 }
 
-// closeSend はsendチャネルを安全に閉じます
+// closeSend は送信キューを閉じます。未送信のメッセージは送り切ってから切断します
 func (c *Client) closeSend() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if !c.closed {
-		close(c.send)
-		c.closed = true
-	}
+	c.send.close()
 }
 
-// isClosed はクライアントが閉じられているかチェックします
-func (c *Client) isClosed() bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.closed
+// enqueue はメッセージを送信キューに追加します。閉じた接続に対してもpanicしません
+func (c *Client) enqueue(msg *Message) {
+	if err := c.send.push(msg); err != nil && err != errOutboxClosed {
+		log.Printf("送信キュー追加エラー (ユーザー: %s): %v", c.userID, err)
+	}
 }
 
 // readPump はクライアントからのメッセージを読み取り、処理します
@@ -60,7 +57,7 @@ func (c *Client) readPump() {
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadLimit(c.hub.limits.readLimit())
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -68,8 +65,7 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		var msg Message
-		err := c.conn.ReadJSON(&msg)
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket読み取りエラー (ユーザー: %s): %v", c.userID, err)
@@ -79,8 +75,40 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// メッセージログ
-		log.Printf("メッセージ受信 (ユーザー: %s): タイプ=%s", c.userID, msg.Type)
+		var msg Message
+		if err := c.codec.Decode(data, &msg); err != nil {
+			// 解析できないメッセージも違反として数え、続く場合は抑制・切断する
+			switch c.limiter.malformed(time.Now()) {
+			case verdictThrottle:
+				time.Sleep(throttleDelay)
+				continue
+			case verdictDisconnect:
+				c.closeRateLimited()
+				return
+			}
+			log.Printf("メッセージ解析エラー (ユーザー: %s): %v", c.userID, err)
+			c.sendError("メッセージの形式が不正です")
+			continue
+		}
+
+		// レート制限
+		switch c.limiter.check(msg.Type, time.Now()) {
+		case verdictReject:
+			c.sendError("メッセージの送信頻度が高すぎます")
+			continue
+		case verdictThrottle:
+			time.Sleep(throttleDelay)
+			continue
+		case verdictDisconnect:
+			c.closeRateLimited()
+			return
+		}
+
+		// タイプごとのサイズ上限
+		if len(data) > c.hub.limits.limitFor(msg.Type) {
+			c.sendError("メッセージが大きすぎます")
+			continue
+		}
 
 		// 送信者IDを設定
 		msg.UserID = c.userID
@@ -90,6 +118,14 @@ func (c *Client) readPump() {
 	}
 }
 
+// closeRateLimited は違反が続いた接続にクローズ理由を送ります。接続はreadPumpの終了時に閉じます
+func (c *Client) closeRateLimited() {
+	log.Printf("レート制限超過のため切断します (ユーザー: %s)", c.userID)
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, rateLimitCloseReason),
+		time.Now().Add(writeWait))
+}
+
 // writePump はクライアントへのメッセージを送信し、pingを定期的に送ります
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -100,18 +136,18 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// Hubがチャネルを閉じた
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+		case <-c.send.notify:
+			if !c.writeQueued() {
 				return
 			}
 
-			// JSONエンコード
-			err := c.conn.WriteJSON(message)
-			if err != nil {
-				log.Printf("WebSocket書き込みエラー: %v", err)
+			if closed, code, reason := c.send.isClosed(); closed {
+				// 閉じる直前に積まれたメッセージも送ってから切断
+				if !c.writeQueued() {
+					return
+				}
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
 				return
 			}
 
@@ -124,30 +160,119 @@ func (c *Client) writePump() {
 	}
 }
 
+// writeQueued は送信キューに溜まったメッセージを書き込みます
+func (c *Client) writeQueued() bool {
+	for _, message := range c.send.drain() {
+		message = c.prepareDuelState(message)
+		if message == nil {
+			continue
+		}
+
+		data, err := c.codec.Encode(message)
+		if err != nil {
+			log.Printf("メッセージエンコードエラー (タイプ: %s): %v", message.Type, err)
+			continue
+		}
+
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.conn.WriteMessage(c.codec.FrameType(), data); err != nil {
+			log.Printf("WebSocket書き込みエラー: %v", err)
+			return false
+		}
+	}
+	return true
+}
+
+// prepareDuelState は対戦状態のメッセージを前回送信分からの差分に変換します
+//
+// 最初の送信と再同期要求後は duelData として全体を送り、以降は duelPatch を送ります。
+// 既に送信済みのバージョン以下の更新はnilを返して送信を省略します。
+func (c *Client) prepareDuelState(message *Message) *Message {
+	if message.Type != "duelData" && message.Type != "duelUpdate" {
+		return message
+	}
+
+	duelID := duelIDOf(message.Content)
+	doc, err := toDocument(message.Content)
+	if duelID == "" || err != nil {
+		return message
+	}
+	if c.duelStates == nil {
+		c.duelStates = make(map[string]interface{})
+	}
+
+	base, known := c.duelStates[duelID]
+	if message.Type == "duelData" || !known {
+		c.duelStates[duelID] = doc
+		return &Message{Type: "duelData", UserID: message.UserID, Content: doc}
+	}
+
+	baseVersion, version := documentVersion(base), documentVersion(doc)
+	if version <= baseVersion {
+		return nil
+	}
+
+	c.duelStates[duelID] = doc
+	return &Message{
+		Type:   "duelPatch",
+		UserID: message.UserID,
+		Content: &DuelPatch{
+			DuelID:      duelID,
+			BaseVersion: baseVersion,
+			Version:     version,
+			Ops:         diffDocuments(base, doc),
+		},
+	}
+}
+
 // handleMessage はメッセージタイプに応じた処理を行います
 func (c *Client) handleMessage(msg *Message) {
 	switch msg.Type {
 	case "test":
 		log.Printf("テストメッセージ受信 (ユーザー: %s): %v", c.userID, msg.Content)
 		// エコーバック
-		c.send <- &Message{
+		c.enqueue(&Message{
 			Type:    "testResponse",
 			UserID:  c.userID,
 			Content: "テストメッセージを受信しました",
-		}
+		})
 	case "findMatch":
 		c.handleFindMatch(msg)
 	case "cancelMatch":
 		c.handleCancelMatch(msg)
+	case "acceptMatch":
+		c.handleAcceptMatch(msg)
+	case "declineMatch":
+		c.handleDeclineMatch(msg)
+	case "createRoom":
+		c.handleCreateRoom(msg)
+	case "joinRoom":
+		c.handleJoinRoom(msg)
+	case "setReady":
+		c.handleSetReady(msg)
+	case "leaveRoom":
+		c.handleLeaveRoom(msg)
 	case "gameAction":
 		c.handleGameAction(msg)
+	case "resync":
+		c.handleResync(msg)
+	case "offerRematch", "acceptRematch":
+		// 申し込みと承諾は同じ操作で、両者が申し込むと再戦が成立する
+		c.handleRematch(msg, game.ActionRematch)
+	case "declineRematch":
+		c.handleRematch(msg, game.ActionDeclineRematch)
+	case "chooseFirst":
+		c.handleChooseFirst(msg)
+	case "chat":
+		c.handleChat(msg)
 	case "ping":
 		// ping応答
-		c.send <- &Message{Type: "pong", UserID: c.userID}
+		c.enqueue(&Message{Type: "pong", UserID: c.userID})
 	default:
-		log.Printf("未知のメッセージタイプ: %s", msg.Type)
-		// デフォルトはブロードキャスト
-		c.hub.broadcast <- msg
+		// 送信者や内容を偽った任意のメッセージを全インスタンスに流さないよう、未知のタイプは拒否する
+		// (ロビーへの送信は chat を使う)
+		log.Printf("未知のメッセージタイプ (ユーザー: %s): %q", c.userID, msg.Type)
+		c.sendError(fmt.Sprintf("未知のメッセージタイプです: %s", msg.Type))
 	}
 }
 
@@ -158,7 +283,7 @@ func (c *Client) handleFindMatch(msg *Message) {
 		return
 	}
 
-	room, err := c.matchmakingService.FindMatch(c.userID)
+	room, err := c.matchmakingService.FindMatch(context.Background(), c.userID)
 	if err != nil {
 		log.Printf("マッチメイキングエラー (ユーザー: %s): %v", c.userID, err)
 		c.sendError(err.Error())
@@ -166,10 +291,17 @@ func (c *Client) handleFindMatch(msg *Message) {
 	}
 
 	// ルーム情報を送信
-	c.send <- &Message{
+	c.enqueue(&Message{
 		Type:    "roomJoined",
 		UserID:  c.userID,
 		Content: room,
+	})
+
+	// 待機中は以降 queueStatus が定期的に送信される
+	if room.Status == "waiting" {
+		if info, err := c.matchmakingService.QueueInfo(context.Background(), c.userID); err == nil && info != nil {
+			c.enqueue(&Message{Type: "queueStatus", UserID: c.userID, Content: info})
+		}
 	}
 
 	// マッチングが完了した場合（2人揃った場合）は対戦準備
@@ -186,16 +318,135 @@ func (c *Client) handleCancelMatch(msg *Message) {
 		return
 	}
 
-	err := c.matchmakingService.CancelMatch(c.userID)
+	err := c.matchmakingService.CancelMatch(context.Background(), c.userID)
 	if err != nil {
 		log.Printf("マッチキャンセルエラー (ユーザー: %s): %v", c.userID, err)
 		c.sendError(err.Error())
 		return
 	}
 
-	c.send <- &Message{
+	c.enqueue(&Message{
 		Type:   "matchCancelled",
 		UserID: c.userID,
+	})
+}
+
+// handleAcceptMatch は承認待ちのマッチを承認します
+func (c *Client) handleAcceptMatch(msg *Message) {
+	if c.matchmakingService == nil {
+		c.sendError("マッチメイキングサービスが利用できません")
+		return
+	}
+
+	// 両者が承認すると gameStart が送信される (マッチが別インスタンスにある場合も同じ)
+	if _, err := c.hub.AcceptMatch(context.Background(), c.userID); err != nil {
+		log.Printf("マッチ承認エラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+	}
+}
+
+// handleDeclineMatch は承認待ちのマッチを辞退します
+func (c *Client) handleDeclineMatch(msg *Message) {
+	if c.matchmakingService == nil {
+		c.sendError("マッチメイキングサービスが利用できません")
+		return
+	}
+
+	// 辞退した本人には matchDeclined が送信される
+	if _, err := c.hub.DeclineMatch(context.Background(), c.userID); err != nil {
+		log.Printf("マッチ辞退エラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+	}
+}
+
+// handleCreateRoom は招待コード付きのプライベートルームを作成します
+func (c *Client) handleCreateRoom(msg *Message) {
+	if c.matchmakingService == nil {
+		c.sendError("マッチメイキングサービスが利用できません")
+		return
+	}
+
+	var req struct {
+		BestOf int `json:"bestOf"` // 省略時は1試合
+	}
+	if msg.Content != nil {
+		if err := decodeContent(msg.Content, &req); err != nil {
+			c.sendError("ルーム作成リクエストが不正です")
+			return
+		}
+	}
+
+	room, err := c.hub.CreatePrivateRoom(c.userID, req.BestOf)
+	if err != nil {
+		log.Printf("ルーム作成エラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+		return
+	}
+	c.enqueue(&Message{Type: "roomJoined", UserID: c.userID, Content: room})
+}
+
+// handleJoinRoom は招待コードでプライベートルームに参加します
+func (c *Client) handleJoinRoom(msg *Message) {
+	if c.matchmakingService == nil {
+		c.sendError("マッチメイキングサービスが利用できません")
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := decodeContent(msg.Content, &req); err != nil || req.Code == "" {
+		c.sendError("codeが必要です")
+		return
+	}
+
+	// 参加者全員には roomUpdate で通知される (ルームが別インスタンスにある場合も同じ)
+	if _, err := c.hub.JoinPrivateRoom(c.userID, req.Code); err != nil {
+		log.Printf("ルーム参加エラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+	}
+}
+
+// handleSetReady はプライベートルームでの準備状態を切り替えます
+func (c *Client) handleSetReady(msg *Message) {
+	if c.matchmakingService == nil {
+		c.sendError("マッチメイキングサービスが利用できません")
+		return
+	}
+
+	req := struct {
+		Ready bool `json:"ready"`
+	}{Ready: true}
+	if msg.Content != nil {
+		if err := decodeContent(msg.Content, &req); err != nil {
+			c.sendError("不正なリクエストです")
+			return
+		}
+	}
+
+	// 両者が準備完了すると gameStart が送信される
+	if _, err := c.hub.SetReady(c.userID, req.Ready); err != nil {
+		log.Printf("準備状態の更新エラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+	}
+}
+
+// handleLeaveRoom はプライベートルームから退出します
+func (c *Client) handleLeaveRoom(msg *Message) {
+	if c.matchmakingService == nil {
+		c.sendError("マッチメイキングサービスが利用できません")
+		return
+	}
+
+	forwarded, err := c.hub.LeavePrivateRoom(c.userID)
+	if err != nil {
+		log.Printf("ルーム退出エラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+		return
+	}
+	// 別インスタンスに転送した場合は転送先から roomLeft が送信される
+	if !forwarded {
+		c.enqueue(&Message{Type: "roomLeft", UserID: c.userID})
 	}
 }
 
@@ -206,20 +457,93 @@ func (c *Client) handleGameAction(msg *Message) {
 		return
 	}
 
-	// ゲームアクションの詳細な処理は後で実装
-	log.Printf("ゲームアクション受信 (ユーザー: %s): %v", c.userID, msg.Content)
+	var action game.GameAction
+	if err := decodeContent(msg.Content, &action); err != nil {
+		c.sendError("アクションの形式が不正です")
+		return
+	}
+	if action.DuelID == "" {
+		c.sendError("duelIdが必要です")
+		return
+	}
+	// なりすまし防止のため送信者を接続ユーザーに固定
+	action.PlayerID = c.userID
 
-	// 今は単純にブロードキャスト
-	c.hub.broadcast <- msg
+	// 対戦を保持しているインスタンスへ転送
+	if err := c.hub.SubmitAction(action); err != nil {
+		log.Printf("ゲームアクションエラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+	}
+}
+
+// handleRematch は終了した対戦の再戦の申し込み・承諾・辞退を処理します
+func (c *Client) handleRematch(msg *Message, actionType string) {
+	var req struct {
+		DuelID string `json:"duelId"`
+	}
+	if err := decodeContent(msg.Content, &req); err != nil || req.DuelID == "" {
+		c.sendError("duelIdが必要です")
+		return
+	}
+
+	// 終了した対戦を保持しているインスタンスで処理し、結果は rematch メッセージで通知される
+	action := game.GameAction{DuelID: req.DuelID, PlayerID: c.userID, ActionType: actionType}
+	if err := c.hub.SubmitAction(action); err != nil {
+		log.Printf("再戦の申し込みエラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+	}
+}
+
+// handleChooseFirst はシリーズの次の試合で先手か後手かを選択します
+func (c *Client) handleChooseFirst(msg *Message) {
+	var req struct {
+		MatchID string `json:"matchId"`
+		GoFirst bool   `json:"goFirst"`
+	}
+	if err := decodeContent(msg.Content, &req); err != nil || req.MatchID == "" {
+		c.sendError("matchIdが必要です")
+		return
+	}
+
+	// 次の試合の開始は matchUpdate メッセージで通知される
+	if err := c.hub.ChooseFirst(req.MatchID, c.userID, req.GoFirst); err != nil {
+		log.Printf("先手・後手の選択エラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+	}
+}
+
+// handleResync はクライアントのバージョン不一致時に対戦状態の全体を再送します
+func (c *Client) handleResync(msg *Message) {
+	var req struct {
+		DuelID string `json:"duelId"`
+	}
+	if err := decodeContent(msg.Content, &req); err != nil || req.DuelID == "" {
+		c.sendError("duelIdが必要です")
+		return
+	}
+
+	if err := c.hub.RequestDuelData(req.DuelID, c.userID); err != nil {
+		log.Printf("再同期エラー (ユーザー: %s): %v", c.userID, err)
+		c.sendError(err.Error())
+	}
+}
+
+// decodeContent はメッセージのContentを指定された構造体に変換します
+func decodeContent(content interface{}, v interface{}) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("コンテンツ変換エラー: %w", err)
+	}
+	return json.Unmarshal(data, v)
 }
 
 // sendError はエラーメッセージを送信します
 func (c *Client) sendError(message string) {
-	c.send <- &Message{
+	c.enqueue(&Message{
 		Type:    "error",
 		UserID:  c.userID,
 		Content: map[string]string{"message": message},
-	}
+	})
 }
 
 // notifyGameReady はゲーム準備完了を通知します
@@ -0,0 +1,100 @@
+// backend/internal/ws/codec.go
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// 対応するWebSocketサブプロトコル名
+const (
+	SubprotocolJSON    = "gocard.json"
+	SubprotocolMsgpack = "gocard.msgpack"
+)
+
+// Codec はWebSocketメッセージのエンコード方式です
+type Codec interface {
+	// Subprotocol はネゴシエーションに使うサブプロトコル名を返します
+	Subprotocol() string
+
+	// FrameType はWebSocketのフレーム種別 (テキスト/バイナリ) を返します
+	FrameType() int
+
+	// Encode はメッセージをバイト列に変換します
+	Encode(msg *Message) ([]byte, error)
+
+	// Decode はバイト列をメッセージに変換します
+	Decode(data []byte, msg *Message) error
+}
+
+// codecs はサーバーが対応するコーデックです (優先順)
+var codecs = []Codec{msgpackCodec{}, jsonCodec{}}
+
+// subprotocols はアップグレード時にサーバーが提示するサブプロトコルです
+func subprotocols() []string {
+	names := make([]string, 0, len(codecs))
+	for _, codec := range codecs {
+		names = append(names, codec.Subprotocol())
+	}
+	return names
+}
+
+// codecFor はネゴシエーション結果のサブプロトコルに対応するコーデックを返します
+//
+// サブプロトコルを指定しない既存クライアントにはJSONを使用します。
+func codecFor(subprotocol string) Codec {
+	for _, codec := range codecs {
+		if codec.Subprotocol() == subprotocol {
+			return codec
+		}
+	}
+	return jsonCodec{}
+}
+
+// jsonCodec はJSONテキストフレームのコーデックです
+type jsonCodec struct{}
+
+func (jsonCodec) Subprotocol() string { return SubprotocolJSON }
+
+func (jsonCodec) FrameType() int { return websocket.TextMessage }
+
+func (jsonCodec) Encode(msg *Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (jsonCodec) Decode(data []byte, msg *Message) error {
+	return json.Unmarshal(data, msg)
+}
+
+// msgpackCodec はMessagePackバイナリフレームのコーデックです
+//
+// フィールド名はJSONと揃えるため json タグを使用します。
+type msgpackCodec struct{}
+
+func (msgpackCodec) Subprotocol() string { return SubprotocolMsgpack }
+
+func (msgpackCodec) FrameType() int { return websocket.BinaryMessage }
+
+func (msgpackCodec) Encode(msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(msg); err != nil {
+		return nil, fmt.Errorf("MessagePackエンコードエラー: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Decode(data []byte, msg *Message) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	if err := dec.Decode(msg); err != nil {
+		return fmt.Errorf("MessagePackデコードエラー: %w", err)
+	}
+	return nil
+}
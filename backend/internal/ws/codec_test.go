@@ -0,0 +1,86 @@
+// backend/internal/ws/codec_test.go
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/game"
+	"github.com/gorilla/websocket"
+)
+
+func TestCodecFor(t *testing.T) {
+	tests := []struct {
+		subprotocol string
+		want        string
+	}{
+		{SubprotocolJSON, SubprotocolJSON},
+		{SubprotocolMsgpack, SubprotocolMsgpack},
+		// サブプロトコルを指定しない既存クライアントはJSON
+		{"", SubprotocolJSON},
+		{"unknown", SubprotocolJSON},
+	}
+	for _, tt := range tests {
+		if got := codecFor(tt.subprotocol).Subprotocol(); got != tt.want {
+			t.Errorf("codecFor(%q) = %q; want %q", tt.subprotocol, got, tt.want)
+		}
+	}
+
+	if codecFor(SubprotocolMsgpack).FrameType() != websocket.BinaryMessage {
+		t.Error("MessagePackはバイナリフレームで送る必要があります")
+	}
+	if codecFor(SubprotocolJSON).FrameType() != websocket.TextMessage {
+		t.Error("JSONはテキストフレームで送る必要があります")
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	duel := &game.Duel{
+		ID:        "d1",
+		TurnCount: 3,
+		Status:    "active",
+		StartedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Version:   7,
+	}
+
+	for _, codec := range codecs {
+		t.Run(codec.Subprotocol(), func(t *testing.T) {
+			data, err := codec.Encode(&Message{Type: "duelUpdate", UserID: "u1", Content: duel})
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			var msg Message
+			if err := codec.Decode(data, &msg); err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if msg.Type != "duelUpdate" || msg.UserID != "u1" {
+				t.Errorf("Type, UserID = %q, %q; want \"duelUpdate\", \"u1\"", msg.Type, msg.UserID)
+			}
+
+			// 復元したContentはmapになり、json タグのフィールド名で参照できる
+			content, ok := msg.Content.(map[string]interface{})
+			if !ok {
+				t.Fatalf("Content の型 = %T; want map[string]interface{}", msg.Content)
+			}
+			if got := duelIDOf(content); got != "d1" {
+				t.Errorf("duelIDOf = %q; want \"d1\"", got)
+			}
+			if got := coalesceKey(&msg); got != "duelUpdate:d1" {
+				t.Errorf("coalesceKey = %q; want \"duelUpdate:d1\"", got)
+			}
+			if _, ok := content["turnCount"]; !ok {
+				t.Errorf("turnCount がありません: %v", content)
+			}
+		})
+	}
+}
+
+func TestCodecDecodeInvalid(t *testing.T) {
+	for _, codec := range codecs {
+		var msg Message
+		if err := codec.Decode([]byte{0xc1, 0xff}, &msg); err == nil {
+			t.Errorf("%s: 不正なデータのデコードがエラーになりませんでした", codec.Subprotocol())
+		}
+	}
+}
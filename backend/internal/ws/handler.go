@@ -2,35 +2,42 @@
 package ws
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
+	"github.com/KOU050223/go-card/internal/game"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // 本番環境では適切なオリジン検証を行うこと
-	},
+// newUpgrader はWebSocketのアップグレーダーを作成します
+//
+// checkOrigin が nil の場合は、Origin がリクエスト先のホストと一致する接続だけを受け付けます。
+func newUpgrader(checkOrigin func(r *http.Request) bool) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    subprotocols(),
+		CheckOrigin:     checkOrigin,
+	}
 }
 
 // ServeWS はHTTP接続をWebSocket接続にアップグレードします
 func ServeWS(c echo.Context, hub *Hub, userID string) error {
-	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	conn, err := hub.upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		log.Printf("WebSocketアップグレードエラー: %v", err)
 		return err
 	}
 
 	client := &Client{
-		hub:    hub,
-		conn:   conn,
-		send:   make(chan *Message, 256),
-		userID: userID,
-		closed: false,
+		hub:     hub,
+		conn:    conn,
+		send:    newOutbox(),
+		codec:   codecFor(conn.Subprotocol()),
+		userID:  userID,
+		limiter: newRateLimiter(),
 	}
 
 	// クライアント登録
@@ -64,7 +71,19 @@ func ServeDuelWS(c echo.Context, hub *Hub, userID string) error {
 		return c.String(http.StatusUnauthorized, "userIDが必要です (from ServeDuelWS)")
 	}
 
-	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	// 接続チケットは対戦IDを指定せずに発行できるため、接続時に対戦のプレイヤーかを確認する
+	switch err := hub.CheckParticipant(duelID, userID); {
+	case errors.Is(err, game.ErrDuelNotFound):
+		return c.String(http.StatusNotFound, "対戦が見つかりません")
+	case errors.Is(err, ErrNotParticipant):
+		log.Printf("[ServeDuelWS] 対戦の参加者ではありません (userID=%s, duelId=%s)", userID, duelID)
+		return c.String(http.StatusForbidden, err.Error())
+	case err != nil:
+		log.Printf("[ServeDuelWS] 参加者の確認エラー: %v (userID=%s, duelId=%s)", err, userID, duelID)
+		return c.String(http.StatusInternalServerError, "対戦の確認に失敗しました")
+	}
+
+	conn, err := hub.upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		log.Printf("[ServeDuelWS] WebSocketアップグレードエラー: %v (userID=%s, duelId=%s)", err, userID, duelID)
 		return err
@@ -73,35 +92,26 @@ func ServeDuelWS(c echo.Context, hub *Hub, userID string) error {
 	client := &Client{
 		hub:         hub,
 		conn:        conn,
-		send:        make(chan *Message, 256),
+		send:        newOutbox(),
+		codec:       codecFor(conn.Subprotocol()),
 		userID:      userID,
-		closed:      false,
 		duelService: hub.duelService,
+		limiter:     newRateLimiter(),
 	}
 
 	// クライアント登録
 	client.hub.register <- client
 
-	// 対戦データを取得して送信
-	duel, err := hub.duelService.GetDuel(duelID)
-	client.send <- &Message{
-		Type:    "duelData",
-		UserID:  userID,
-		Content: duel,
-	}
-	if err != nil {
-		log.Printf("[ServeDuelWS] duelデータ取得失敗: %v (userID=%s, duelId=%s)", err, userID, duelID)
-		client.send <- &Message{
-			Type:    "error",
-			UserID:  userID,
-			Content: map[string]string{"message": "対戦データが見つかりません"},
-		}
-	} else {
-		client.send <- &Message{
+	// 対戦データを取得して送信 (別インスタンスの対戦は担当インスタンスから届く)
+	if duel, err := hub.duelService.GetDuel(duelID); err == nil {
+		client.enqueue(&Message{
 			Type:    "duelData",
 			UserID:  userID,
 			Content: duel,
-		}
+		})
+	} else if err := hub.RequestDuelData(duelID, userID); err != nil {
+		log.Printf("[ServeDuelWS] duelデータ取得失敗: %v (userID=%s, duelId=%s)", err, userID, duelID)
+		client.sendError("対戦データが見つかりません")
 	}
 
 	// クライアントの読み書きgoroutineを起動
@@ -2,12 +2,42 @@
 package ws
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"sync"
 	"time"
 
+	"github.com/KOU050223/go-card/internal/bot"
+	"github.com/KOU050223/go-card/internal/db"
 	"github.com/KOU050223/go-card/internal/game"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// 所有者キー (ユーザー所在・Duel担当) の有効期限。cleanupタスクで定期的に延長します
+	ownerTTL = 2 * time.Minute
+
+	// Backplane操作のタイムアウト
+	backplaneTimeout = 3 * time.Second
+
+	// 待機者同士の再マッチング間隔
+	matchInterval = 5 * time.Second
+	// 対戦の通知のうち、他のインスタンスへの転送待ちにできるメッセージの数
+	remoteQueueSize = 256
+
+	// 待機中のクライアントにキュー状況を送信する間隔
+	queueStatusInterval = 3 * time.Second
+
+	// 全インスタンス共通のブロードキャストチャネル
+	broadcastChannel = "broadcast"
+
+	// BAN・利用停止で切断するときのクローズ理由
+	accountBlockedCloseReason = "account blocked"
 )
 
 // Hub はWebSocketクライアントを管理します
@@ -30,6 +60,193 @@ type Hub struct {
 	// ゲームサービス
 	matchmakingService *game.MatchmakingService
 	duelService        *game.DuelService
+	matchService       *game.MatchService // Best-of-N のシリーズ
+
+	// サーバー側のボット (このインスタンスが担当する対戦のボットを動かす)
+	bots *bot.Driver
+
+	// 受信メッセージのサイズ上限
+	limits MessageLimits
+
+	// マッチメイキングの待機キュー (nilならメモリ)
+	queueStore game.QueueStore
+
+	// シリーズの保存先 (nilならメモリのみ)
+	matchRepo *db.MatchRepository
+
+	// WebSocket接続のアップグレーダー (Originの検証を含む)
+	upgrader    *websocket.Upgrader
+	checkOrigin func(r *http.Request) bool
+
+	// ユーザーの制裁の確認 (nilなら確認しない)
+	lookupAccess func(ctx context.Context, userID string) (*db.UserAccess, error)
+
+	// インスタンス間中継
+	instanceID  string
+	backplane   Backplane
+	unsubscribe []func()
+
+	// 対戦の通知のうち、他のインスタンスに接続しているユーザー宛ての転送待ち
+	remote chan remoteMessage
+
+	// 他のインスタンスから呼び出せる処理と、応答待ちの呼び出し (rpc.go)
+	callMu   sync.RWMutex
+	handlers map[string]Handler
+	pending  map[string]chan *remoteReply
+
+	// InMatch で参加中とみなす Hub の外の処理 (account.go、callMu で保護)
+	inMatchChecks []func(userID string) bool
+
+	// Hubの外で所有者として登録したキー (定期的に延長する)
+	ownedMu   sync.Mutex
+	ownedKeys []func() []string
+}
+
+// remoteMessage は他のインスタンスに接続しているユーザー宛ての転送待ちのメッセージです
+type remoteMessage struct {
+	userID  string
+	message *Message
+}
+
+// Option はHubの生成オプションです
+type Option func(*Hub)
+
+// WithBackplane はインスタンス間中継に使うBackplaneを指定します
+func WithBackplane(bp Backplane) Option {
+	return func(h *Hub) {
+		h.backplane = bp
+	}
+}
+
+// WithInstanceID はこのHubのインスタンスIDを指定します
+func WithInstanceID(id string) Option {
+	return func(h *Hub) {
+		h.instanceID = id
+	}
+}
+
+// WithMessageLimits はクライアントから受信するメッセージのサイズ上限を指定します
+func WithMessageLimits(limits MessageLimits) Option {
+	return func(h *Hub) {
+		h.limits = limits
+	}
+}
+
+// WithMatchmakingStore はマッチメイキングの待機キューの保存先を指定します
+func WithMatchmakingStore(store game.QueueStore) Option {
+	return func(h *Hub) {
+		h.queueStore = store
+	}
+}
+
+// WithAllowedOrigins はWebSocket接続を受け付けるOriginの許可リストを指定します
+//
+// CORSの AllowOrigins と同じ形式で、"*" はすべてのOriginを、"https://*.example.com" は
+// サブドメインを許可します。許可されていないOriginからの接続は 403 で拒否してログに残します。
+// 指定しない場合は、リクエスト先と同じOriginからの接続だけを受け付けます。
+func WithAllowedOrigins(origins []string) Option {
+	return func(h *Hub) {
+		h.checkOrigin = newOriginChecker(origins).check
+	}
+}
+
+// WithAccessLookup はチャットの送信時にユーザーの制裁 (チャット禁止) を確認する関数を指定します
+func WithAccessLookup(lookup func(ctx context.Context, userID string) (*db.UserAccess, error)) Option {
+	return func(h *Hub) {
+		h.lookupAccess = lookup
+	}
+}
+
+// WithMatchRepository はBest-of-Nのシリーズの保存先を指定します
+func WithMatchRepository(repo *db.MatchRepository) Option {
+	return func(h *Hub) {
+		h.matchRepo = repo
+	}
+}
+
+// envelope はBackplane上を流れるメッセージです
+type envelope struct {
+	Kind    string           `json:"kind"` // "user", "broadcast", "action", "sync", "choose", "forceEnd", "disconnect", "room", "cooldown", "call", "reply"
+	Origin  string           `json:"origin"`
+	UserID  string           `json:"userId,omitempty"`
+	DuelID  string           `json:"duelId,omitempty"`
+	Message *Message         `json:"message,omitempty"`
+	Action  *game.GameAction `json:"action,omitempty"`
+	Choice  *firstChoice     `json:"choice,omitempty"`
+	End     *forceEnd        `json:"end,omitempty"`
+	Room    *roomOp          `json:"room,omitempty"`
+	Until   *time.Time       `json:"until,omitempty"`
+	Call    *remoteCall      `json:"call,omitempty"`
+	Reply   *remoteReply     `json:"reply,omitempty"`
+}
+
+// validate はエンベロープの種類ごとに必要な項目がそろっているかを確認します
+func (env *envelope) validate() error {
+	var missing string
+	switch env.Kind {
+	case "user", "disconnect":
+		if env.UserID == "" {
+			missing = "userId"
+		} else if env.Message == nil {
+			missing = "message"
+		}
+	case "broadcast":
+		if env.Message == nil {
+			missing = "message"
+		}
+	case "action":
+		if env.Action == nil {
+			missing = "action"
+		}
+	case "sync":
+		if env.DuelID == "" || env.UserID == "" {
+			missing = "duelId・userId"
+		}
+	case "choose":
+		if env.Choice == nil {
+			missing = "choice"
+		}
+	case "forceEnd":
+		if env.End == nil {
+			missing = "end"
+		}
+	case "room":
+		if env.Room == nil || env.Room.UserID == "" {
+			missing = "room"
+		}
+	case "cooldown":
+		if env.UserID == "" || env.Until == nil {
+			missing = "userId・until"
+		}
+	case "call":
+		if env.Call == nil || env.Call.ID == "" || env.Call.Method == "" {
+			missing = "call"
+		}
+	case "reply":
+		if env.Reply == nil || env.Reply.ID == "" {
+			missing = "reply"
+		}
+	default:
+		return errors.New("未知の種類です")
+	}
+	if missing != "" {
+		return fmt.Errorf("%sがありません", missing)
+	}
+	return nil
+}
+
+// forceEnd は管理者による対戦の強制終了です
+type forceEnd struct {
+	DuelID   string `json:"duelId"`
+	WinnerID string `json:"winnerId,omitempty"`
+	Unrated  bool   `json:"unrated,omitempty"`
+}
+
+// firstChoice はシリーズの次の試合の先手・後手の選択です
+type firstChoice struct {
+	MatchID string `json:"matchId"`
+	UserID  string `json:"userId"`
+	GoFirst bool   `json:"goFirst"`
 }
 
 // Message はクライアント間で送受信されるメッセージを表します
@@ -40,20 +257,92 @@ type Message struct {
 }
 
 // NewHub は新しいHub構造体を作成します
-func NewHub(cards []game.Card) *Hub {
+func NewHub(cards []game.Card, opts ...Option) *Hub {
 	hub := &Hub{
 		clients:    make(map[string]*Client),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan *Message),
+		remote:     make(chan remoteMessage, remoteQueueSize),
+		limits:     DefaultMessageLimits(),
+		handlers:   make(map[string]Handler),
+		pending:    make(map[string]chan *remoteReply),
+	}
+	for _, opt := range opts {
+		opt(hub)
+	}
+	if hub.instanceID == "" {
+		hub.instanceID = uuid.New().String()
+	}
+	if hub.backplane == nil {
+		hub.backplane = NewMemoryBackplane()
 	}
-	hub.matchmakingService = game.NewMatchmakingService()
+	hub.upgrader = newUpgrader(hub.checkOrigin)
+
+	hub.matchmakingService = game.NewMatchmakingService(hub.queueStore)
 	hub.duelService = game.NewDuelService(cards) // ★ここで渡す
 	hub.matchmakingService.SetMatchCallback(hub.onMatchFound)
+	hub.bots = bot.NewDriver(hub.duelService)
+	hub.duelService.SetCreateCallback(hub.onDuelCreated)
+	hub.duelService.SetUpdateCallback(hub.onDuelUpdated)
+	hub.duelService.AddFinishCallback(hub.onDuelFinished)
+	hub.duelService.SetRematchCallback(hub.onRematch)
+	hub.matchService = game.NewMatchService(hub.duelService, hub.matchRepo)
+	hub.matchService.SetUpdateCallback(hub.onMatchUpdated)
+	hub.matchmakingService.SetRoomUpdateCallback(hub.onRoomUpdated)
+	hub.matchmakingService.SetMatchAbortedCallback(hub.onMatchAborted)
+
+	hub.Handle(methodDuelPlayers, func(args json.RawMessage) (interface{}, error) {
+		var duelID string
+		if err := json.Unmarshal(args, &duelID); err != nil {
+			return nil, err
+		}
+		return hub.localDuelPlayers(duelID)
+	})
+	hub.handleAdminCalls()
+	hub.handleAccountCalls()
+
+	hub.subscribe(instanceChannel(hub.instanceID))
+	hub.subscribe(broadcastChannel)
+
 	go hub.startCleanupTask()
+	go hub.forwardRemote()
 	return hub
 }
 
+// subscribe はBackplaneのチャネルを購読します
+func (h *Hub) subscribe(channel string) {
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	unsubscribe, err := h.backplane.Subscribe(ctx, channel, h.handleEnvelope)
+	if err != nil {
+		log.Printf("Backplane購読エラー (チャネル: %s): %v", channel, err)
+		return
+	}
+	h.unsubscribe = append(h.unsubscribe, unsubscribe)
+}
+
+// instanceChannel はインスタンス宛てのチャネル名を返します
+func instanceChannel(instanceID string) string {
+	return "instance:" + instanceID
+}
+
+// userKey はユーザー所在の所有者キーを返します
+func userKey(userID string) string {
+	return "user:" + userID
+}
+
+// duelKey はDuel担当インスタンスの所有者キーを返します
+func duelKey(duelID string) string {
+	return "duel:" + duelID
+}
+
+// matchKey はシリーズ担当インスタンスの所有者キーを返します
+func matchKey(matchID string) string {
+	return "match:" + matchID
+}
+
 // Run はHubのメインループを開始します
 func (h *Hub) Run() {
 	for {
@@ -72,35 +361,43 @@ func (h *Hub) Run() {
 			client.duelService = h.duelService
 
 			h.clients[client.userID] = client
+			count := len(h.clients)
 			h.mu.Unlock()
-			log.Printf("ユーザー %s が接続しました。接続数: %d", client.userID, len(h.clients))
+			h.claim(userKey(client.userID))
+			log.Printf("ユーザー %s が接続しました。接続数: %d", client.userID, count)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, exists := h.clients[client.userID]; exists {
-				// マッチメイキングからも削除
-				if h.matchmakingService != nil {
-					h.matchmakingService.CancelMatch(client.userID)
-				}
-
+			current, exists := h.clients[client.userID]
+			removed := exists && current == client
+			if removed {
 				client.closeSend()
 				delete(h.clients, client.userID)
-				log.Printf("ユーザー %s が切断しました。接続数: %d", client.userID, len(h.clients))
 			}
+			count := len(h.clients)
 			h.mu.Unlock()
 
+			// DB・Backplaneへの問い合わせはロックの外で行う (他のユーザーへの送信を待たせない)。
+			// 登録と登録解除はこのgoroutineだけが処理するため、同じユーザーの再接続と入れ替わることはない
+			if removed {
+				// マッチメイキングからも削除
+				if h.matchmakingService != nil {
+					h.matchmakingService.CancelMatch(context.Background(), client.userID)
+				}
+				// このインスタンスの対戦への再戦の申し込みを取り消す
+				// (他のインスタンスの対戦への申し込みは期限で削除される)
+				h.duelService.CancelRematches(client.userID)
+				h.release(userKey(client.userID))
+				log.Printf("ユーザー %s が切断しました。接続数: %d", client.userID, count)
+			}
+
 		case message := <-h.broadcast:
 			log.Printf("ブロードキャスト: %v", message.Type)
 			h.mu.RLock()
 			for userID, client := range h.clients {
-				select {
-				case client.send <- message:
-					// メッセージが送信キューに追加された
-				default:
-					// クライアント送信バッファが一杯
-					client.closeSend()
-					delete(h.clients, userID)
-					log.Printf("ユーザー %s への送信に失敗しました", userID)
+				// 混雑しているクライアントには破棄される (ゲーム進行に影響しない)
+				if err := client.send.offer(message); err != nil && err != errOutboxClosed {
+					log.Printf("ユーザー %s への送信に失敗しました: %v", userID, err)
 				}
 			}
 			h.mu.RUnlock()
@@ -109,7 +406,68 @@ func (h *Hub) Run() {
 }
 
 // SendToUser は特定のユーザーにメッセージを送信します
+//
+// ユーザーが別インスタンスに接続している場合はBackplane経由で転送します。
 func (h *Hub) SendToUser(userID string, message *Message) error {
+	if h.hasLocalClient(userID) {
+		return h.sendToLocalUser(userID, message)
+	}
+
+	owner, err := h.owner(userKey(userID))
+	if err != nil {
+		return err
+	}
+	if owner == "" || owner == h.instanceID {
+		return fmt.Errorf("ユーザー %s は接続していません", userID)
+	}
+
+	return h.publish(instanceChannel(owner), &envelope{
+		Kind:    "user",
+		UserID:  userID,
+		Message: message,
+	})
+}
+
+// notifyUser は対戦の進行を処理するgoroutineからユーザーにメッセージを送信します
+//
+// 他のインスタンスへの転送はBackplaneの応答を待つため、遅い・応答しないBackplaneで
+// すべての対戦が止まらないよう forwardRemote のgoroutineに任せます。転送待ちが一杯の
+// 場合は破棄します (対戦状態はバージョンの欠落からクライアントが再同期を要求します)。
+func (h *Hub) notifyUser(userID string, message *Message) {
+	if h.hasLocalClient(userID) {
+		if err := h.sendToLocalUser(userID, message); err != nil {
+			log.Printf("通知エラー (ユーザー: %s, 種類: %s): %v", userID, message.Type, err)
+		}
+		return
+	}
+
+	select {
+	case h.remote <- remoteMessage{userID: userID, message: message}:
+	default:
+		log.Printf("転送待ちが一杯のため通知を破棄しました (ユーザー: %s, 種類: %s)", userID, message.Type)
+	}
+}
+
+// forwardRemote は notifyUser の転送待ちのメッセージを順に他のインスタンスへ転送します
+func (h *Hub) forwardRemote() {
+	for m := range h.remote {
+		if err := h.SendToUser(m.userID, m.message); err != nil {
+			log.Printf("通知の転送エラー (ユーザー: %s, 種類: %s): %v", m.userID, m.message.Type, err)
+		}
+	}
+}
+
+// hasLocalClient はユーザーがこのインスタンスに接続しているかを返します
+func (h *Hub) hasLocalClient(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	_, exists := h.clients[userID]
+	return exists
+}
+
+// sendToLocalUser はこのインスタンスに接続しているユーザーにメッセージを送信します
+func (h *Hub) sendToLocalUser(userID string, message *Message) error {
 	h.mu.RLock()
 	client, exists := h.clients[userID]
 	h.mu.RUnlock()
@@ -118,42 +476,541 @@ func (h *Hub) SendToUser(userID string, message *Message) error {
 		return fmt.Errorf("ユーザー %s は接続していません", userID)
 	}
 
-	select {
-	case client.send <- message:
-		return nil
-	default:
-		return fmt.Errorf("ユーザー %s への送信バッファが一杯です", userID)
+	// 送信キューが上限を超えた場合は接続が切断され、再接続時に状態を再送します
+	if err := client.send.push(message); err != nil {
+		return fmt.Errorf("ユーザー %s への送信に失敗しました: %w", userID, err)
 	}
+	return nil
 }
 
-// BroadcastMessage はすべてのクライアントにメッセージを送信します
+// BroadcastMessage はすべてのインスタンスのクライアントにメッセージを送信します
 func (h *Hub) BroadcastMessage(message *Message) {
 	h.broadcast <- message
+	if err := h.publish(broadcastChannel, &envelope{Kind: "broadcast", Message: message}); err != nil {
+		log.Printf("ブロードキャスト転送エラー: %v", err)
+	}
 }
 
-// onMatchFound はマッチング完了時に呼ばれるコールバック関数です
-func (h *Hub) onMatchFound(roomID string, players []game.MatchmakingRequest) {
-	log.Printf("マッチング完了コールバック: ルーム %s", roomID)
+// SubmitAction はゲームアクションを対戦を保持しているインスタンスへ届けます
+func (h *Hub) SubmitAction(action game.GameAction) error {
+	if h.duelService.HasDuel(action.DuelID) {
+		return h.duelService.SubmitAction(action)
+	}
+
+	owner, err := h.owner(duelKey(action.DuelID))
+	if err != nil {
+		return err
+	}
+	if owner == "" || owner == h.instanceID {
+		return fmt.Errorf("対戦 %s が見つかりません", action.DuelID)
+	}
+
+	return h.publish(instanceChannel(owner), &envelope{Kind: "action", Action: &action})
+}
+
+// ChooseFirst はシリーズの次の試合の先手・後手を選択します
+//
+// シリーズが別インスタンスにある場合は担当インスタンスに転送します。
+func (h *Hub) ChooseFirst(matchID, userID string, goFirst bool) error {
+	if h.matchService.HasMatch(matchID) {
+		_, err := h.matchService.ChooseFirst(matchID, userID, goFirst)
+		return err
+	}
+
+	owner, err := h.owner(matchKey(matchID))
+	if err != nil {
+		return err
+	}
+	if owner == "" || owner == h.instanceID {
+		return game.ErrMatchNotFound
+	}
+
+	choice := &firstChoice{MatchID: matchID, UserID: userID, GoFirst: goFirst}
+	return h.publish(instanceChannel(owner), &envelope{Kind: "choose", Choice: choice})
+}
+
+// ForceEndDuel は対戦を強制終了し、終了後の対戦を返します
+//
+// 対戦が別インスタンスにある場合は担当インスタンスに転送し、nil の対戦を返します。
+func (h *Hub) ForceEndDuel(duelID, winnerID string, unrated bool) (*game.Duel, error) {
+	if h.duelService.HasDuel(duelID) {
+		return h.duelService.ForceEnd(duelID, winnerID, unrated)
+	}
 
-	// 対戦を作成
-	duelID, err := h.duelService.CreateDuel(players[0].UserID, players[1].UserID)
+	owner, err := h.owner(duelKey(duelID))
 	if err != nil {
-		log.Printf("対戦作成エラー: %v", err)
+		return nil, err
+	}
+	if owner == "" || owner == h.instanceID {
+		return nil, game.ErrDuelNotFound
+	}
+
+	end := &forceEnd{DuelID: duelID, WinnerID: winnerID, Unrated: unrated}
+	return nil, h.publish(instanceChannel(owner), &envelope{Kind: "forceEnd", End: end})
+}
+
+// DisconnectUser はユーザーの接続に reason を送ってから切断します (BAN・利用停止時に使います)
+//
+// ユーザーが別インスタンスに接続している場合は担当インスタンスに転送します。
+// 接続していない場合は何もしません。
+func (h *Hub) DisconnectUser(userID, reason string) error {
+	message := &Message{Type: "accountBlocked", UserID: userID, Content: map[string]string{"message": reason}}
+	if h.hasLocalClient(userID) {
+		h.disconnectLocalUser(userID, message)
+		return nil
+	}
+
+	owner, err := h.owner(userKey(userID))
+	if err != nil {
+		return err
+	}
+	if owner == "" || owner == h.instanceID {
+		return nil
+	}
+	return h.publish(instanceChannel(owner), &envelope{Kind: "disconnect", UserID: userID, Message: message})
+}
+
+// disconnectLocalUser はこのインスタンスに接続しているユーザーに message を送ってから切断します
+func (h *Hub) disconnectLocalUser(userID string, message *Message) {
+	h.mu.RLock()
+	client, exists := h.clients[userID]
+	h.mu.RUnlock()
+	if !exists {
 		return
 	}
 
-	// マッチしたプレイヤーにゲーム開始を通知
-	gameStartMessage := &Message{
-		Type: "gameStart",
+	client.enqueue(message)
+	client.send.closeWith(websocket.ClosePolicyViolation, accountBlockedCloseReason)
+	log.Printf("ユーザー %s の接続を切断しました (利用停止)", userID)
+}
+
+// activeMute はユーザーの有効なチャット禁止を返します。禁止されていない場合は nil を返します
+//
+// 確認に失敗した場合はエラーを返します。呼び出し側はチャット禁止中のユーザーに送信させないよう、
+// 送信を止めてください。
+func (h *Hub) activeMute(userID string) (*db.Sanction, error) {
+	if h.lookupAccess == nil {
+		return nil, nil
+	}
+	access, err := h.lookupAccess(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if access == nil {
+		return nil, nil
+	}
+	return access.Mute, nil
+}
+
+// methodDuelPlayers は対戦の担当インスタンスにプレイヤーを問い合わせる処理です
+const methodDuelPlayers = "duel.players"
+
+// ErrNotParticipant は対戦のプレイヤーでないユーザーが対戦に接続・対戦データを要求したことを表します
+var ErrNotParticipant = errors.New("この対戦の参加者ではありません")
+
+// CheckParticipant は userID が対戦 duelID のプレイヤーかを確認します
+//
+// 別インスタンスの対戦は担当インスタンスに問い合わせます。対戦がどのインスタンスにもない場合は
+// game.ErrDuelNotFound、プレイヤーでない場合は ErrNotParticipant を返します。
+func (h *Hub) CheckParticipant(duelID, userID string) error {
+	players, err := h.localDuelPlayers(duelID)
+	if errors.Is(err, game.ErrDuelNotFound) {
+		owner, ownerErr := h.owner(duelKey(duelID))
+		if ownerErr != nil {
+			return ownerErr
+		}
+		if owner == "" || owner == h.instanceID {
+			return game.ErrDuelNotFound
+		}
+		err = h.Call(owner, methodDuelPlayers, duelID, &players)
+	}
+	if err != nil {
+		return err
+	}
+	if userID != players[0] && userID != players[1] {
+		return ErrNotParticipant
+	}
+	return nil
+}
+
+// localDuelPlayers はこのインスタンスの対戦のプレイヤーのユーザーIDを返します
+func (h *Hub) localDuelPlayers(duelID string) ([2]string, error) {
+	duel, err := h.duelService.GetDuel(duelID)
+	if err != nil {
+		return [2]string{}, err
+	}
+	return [2]string{duel.Players[0].UserID, duel.Players[1].UserID}, nil
+}
+
+// RequestDuelData は対戦データをユーザーへ送信します
+//
+// 対戦が別インスタンスにある場合は担当インスタンスに送信を依頼します。
+func (h *Hub) RequestDuelData(duelID, userID string) error {
+	if duel, err := h.duelService.GetDuel(duelID); err == nil {
+		// 対戦データには両者の手札が含まれるため、プレイヤー以外には送らない
+		if userID != duel.Players[0].UserID && userID != duel.Players[1].UserID {
+			return ErrNotParticipant
+		}
+		return h.SendToUser(userID, &Message{Type: "duelData", UserID: userID, Content: duel})
+	}
+
+	owner, err := h.owner(duelKey(duelID))
+	if err != nil {
+		return err
+	}
+	if owner == "" || owner == h.instanceID {
+		return fmt.Errorf("対戦 %s が見つかりません", duelID)
+	}
+
+	return h.publish(instanceChannel(owner), &envelope{Kind: "sync", DuelID: duelID, UserID: userID})
+}
+
+// handleEnvelope はBackplaneから受信したメッセージを処理します
+func (h *Hub) handleEnvelope(payload []byte) {
+	var env envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		log.Printf("Backplaneメッセージ解析エラー: %v", err)
+		return
+	}
+	// 不正な・バージョンの異なるインスタンスからのメッセージでHubを止めないよう、処理する前に検証する
+	if err := env.validate(); err != nil {
+		log.Printf("不正なBackplaneメッセージを破棄しました (種類: %q, 送信元: %s): %v", env.Kind, env.Origin, err)
+		return
+	}
+
+	switch env.Kind {
+	case "user":
+		if err := h.sendToLocalUser(env.UserID, env.Message); err != nil {
+			log.Printf("転送メッセージ配送エラー (ユーザー: %s): %v", env.UserID, err)
+		}
+	case "broadcast":
+		if env.Origin != h.instanceID {
+			h.broadcast <- env.Message
+		}
+	case "action":
+		if err := h.duelService.SubmitAction(*env.Action); err != nil {
+			log.Printf("転送アクション処理エラー: %v", err)
+		}
+	case "sync":
+		if err := h.RequestDuelData(env.DuelID, env.UserID); err != nil {
+			log.Printf("対戦データ転送エラー (ユーザー: %s): %v", env.UserID, err)
+		}
+	case "choose":
+		if _, err := h.matchService.ChooseFirst(env.Choice.MatchID, env.Choice.UserID, env.Choice.GoFirst); err != nil {
+			log.Printf("転送された先手・後手の選択の処理エラー (シリーズ: %s): %v", env.Choice.MatchID, err)
+		}
+	case "disconnect":
+		h.disconnectLocalUser(env.UserID, env.Message)
+	case "forceEnd":
+		if _, err := h.duelService.ForceEnd(env.End.DuelID, env.End.WinnerID, env.End.Unrated); err != nil {
+			log.Printf("転送された強制終了の処理エラー (対戦: %s): %v", env.End.DuelID, err)
+		}
+	case "room":
+		h.handleRoomOp(env.Room)
+	case "cooldown":
+		if env.Origin != h.instanceID {
+			h.matchmakingService.SetCooldown(env.UserID, *env.Until)
+		}
+	case "call":
+		// Gather は broadcast で送るため、自分の呼び出しは処理しない (呼び出し元で直接処理する)
+		// 処理の中で他のインスタンスを呼び出せるよう、受信を止めずに別のgoroutineで実行する
+		if env.Origin != h.instanceID {
+			go h.answer(env.Origin, env.Call)
+		}
+	case "reply":
+		h.deliverReply(env.Reply)
+	}
+}
+
+// publish はエンベロープをBackplaneに送信します
+func (h *Hub) publish(channel string, env *envelope) error {
+	_, err := h.publishCount(channel, env)
+	return err
+}
+
+// publishCount はエンベロープをBackplaneに送信し、受信したインスタンスの数を返します
+func (h *Hub) publishCount(channel string, env *envelope) (int, error) {
+	env.Origin = h.instanceID
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return 0, fmt.Errorf("Backplaneメッセージ変換エラー: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	return h.backplane.Publish(ctx, channel, payload)
+}
+
+// owner はキーの所有者インスタンスを取得します
+func (h *Hub) owner(key string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	return h.backplane.Owner(ctx, key)
+}
+
+// claim はキーの所有者としてこのインスタンスを登録します
+func (h *Hub) claim(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	if err := h.backplane.SetOwner(ctx, key, h.instanceID, ownerTTL); err != nil {
+		log.Printf("所有者登録エラー (キー: %s): %v", key, err)
+	}
+}
+
+// claimIfAbsent は所有者がいない場合のみこのインスタンスをキーの所有者として登録し、登録できたかを返します
+func (h *Hub) claimIfAbsent(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	return h.backplane.SetOwnerIfAbsent(ctx, key, h.instanceID, ownerTTL)
+}
+
+// release はこのインスタンスが所有するキーを解放します
+func (h *Hub) release(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+	if err := h.backplane.ReleaseOwner(ctx, key, h.instanceID); err != nil {
+		log.Printf("所有者解放エラー (キー: %s): %v", key, err)
+	}
+}
+
+// Owner はキーの所有者インスタンスを返します。所有者がいない場合は空文字を返します
+func (h *Hub) Owner(key string) (string, error) {
+	return h.owner(key)
+}
+
+// Claim はキーの所有者としてこのインスタンスを登録します
+//
+// 所有者キーは ownerTTL で期限が切れるため、保持し続けるキーは Own で登録して延長します。
+func (h *Hub) Claim(key string) {
+	h.claim(key)
+}
+
+// Own は keys が返す所有者キーを、このインスタンスのキーとして定期的に延長します
+//
+// Hubの外 (大会など) で担当しているものを他のインスタンスから Call で転送できるようにします。
+func (h *Hub) Own(keys func() []string) {
+	h.ownedMu.Lock()
+	defer h.ownedMu.Unlock()
+	h.ownedKeys = append(h.ownedKeys, keys)
+}
+
+// externalKeys は Own で登録された所有者キーを返します
+func (h *Hub) externalKeys() []string {
+	h.ownedMu.Lock()
+	sources := append([]func() []string(nil), h.ownedKeys...)
+	h.ownedMu.Unlock()
+
+	var keys []string
+	for _, source := range sources {
+		keys = append(keys, source()...)
+	}
+	return keys
+}
+
+// onDuelCreated は対戦作成時にこのインスタンスを担当として登録します
+func (h *Hub) onDuelCreated(duel *game.Duel) {
+	h.claim(duelKey(duel.ID))
+	h.bots.OnDuelUpdate(duel)
+}
+
+// onDuelUpdated はアクション処理後の対戦状態を参加プレイヤーに送信します
+func (h *Hub) onDuelUpdated(duel *game.Duel) {
+	h.bots.OnDuelUpdate(duel)
+	for _, player := range duel.Players {
+		if bot.IsBot(player.UserID) {
+			continue
+		}
+		h.notifyUser(player.UserID, &Message{
+			Type:    "duelUpdate",
+			Content: duel,
+		})
+	}
+}
+
+// onRoomUpdated はプライベートルーム・承認待ちルームの状態変化を参加者に送信します
+func (h *Hub) onRoomUpdated(userID string, room *game.Room, closed bool) {
+	msgType := "roomUpdate"
+	switch {
+	case closed:
+		msgType = "roomClosed"
+		if room.Private {
+			h.releaseRoom(room)
+		}
+	case room.Status == game.RoomAccepting:
+		// 承認待ちの間は承認状況が変わるたびに送信し、クライアントは承認/辞退を返す
+		// 承認/辞退はどのインスタンスで受けても、承認期限のタイマーを持つこのインスタンスに転送される
+		// (承認期限は ownerTTL より短いため、所有者キーの延長は不要)
+		msgType = "matchFound"
+		h.claim(roomMemberKey(userID))
+	}
+	if err := h.SendToUser(userID, &Message{Type: msgType, Content: room}); err != nil {
+		log.Printf("ルーム状態通知エラー (ユーザー: %s): %v", userID, err)
+	}
+}
+
+// onMatchAborted は承認待ちのマッチが不成立になったことをプレイヤーに送信します
+func (h *Hub) onMatchAborted(userID string, room *game.Room, requeued bool, cooldown time.Duration) {
+	h.release(roomMemberKey(userID))
+	if !requeued {
+		// キュー参加の禁止は、どのインスタンスで参加しようとしても効くよう全インスタンスに共有する
+		until := time.Now().Add(cooldown)
+		if err := h.publish(broadcastChannel, &envelope{Kind: "cooldown", UserID: userID, Until: &until}); err != nil {
+			log.Printf("キュー参加禁止の共有エラー (ユーザー: %s): %v", userID, err)
+		}
+	}
+
+	msg := &Message{
+		Type: "matchRequeued",
 		Content: map[string]interface{}{
-			"roomId":  roomID,
-			"duelId":  duelID,
-			"players": players,
-			"message": "ゲームが開始されました",
+			"roomId":  room.ID,
+			"message": "相手が承認しなかったため、キューの先頭に戻りました",
 		},
 	}
+	if !requeued {
+		msg = &Message{
+			Type: "matchDeclined",
+			Content: map[string]interface{}{
+				"roomId":          room.ID,
+				"cooldownSeconds": int(cooldown.Seconds()),
+				"message":         "マッチを承認しなかったため、しばらくキューに参加できません",
+			},
+		}
+	}
+	if err := h.SendToUser(userID, msg); err != nil {
+		log.Printf("マッチ不成立通知エラー (ユーザー: %s): %v", userID, err)
+	}
+}
+
+// onRematch は再戦の申し込み状況を両プレイヤーに送信します
+func (h *Hub) onRematch(event *game.RematchEvent) {
+	h.bots.OnRematch(event)
+	for _, userID := range event.Players {
+		if bot.IsBot(userID) {
+			continue
+		}
+		h.notifyUser(userID, &Message{Type: "rematch", Content: event})
+	}
+}
+
+// onMatchUpdated はシリーズのスコア・状態の変化を両プレイヤーに送信します
+//
+// 次の試合が始まった場合は CurrentDuelID に新しい対戦IDが入っています。
+func (h *Hub) onMatchUpdated(match *game.Match) {
+	if match.Status == game.MatchFinished {
+		h.release(matchKey(match.ID))
+		// ルームの対戦IDはシリーズの1試合目
+		h.matchmakingService.EndGame(match.DuelIDs[0])
+	}
+	for _, userID := range match.Players {
+		if bot.IsBot(userID) {
+			continue
+		}
+		h.notifyUser(userID, &Message{Type: "matchUpdate", Content: match})
+	}
+}
+
+// onDuelFinished は終了した対戦のルームを片付けます
+//
+// シリーズの試合の場合、ルームは勝敗が決まるまで残し、シリーズの終了時に片付けます (onMatchUpdated)。
+func (h *Hub) onDuelFinished(duel *game.Duel) {
+	if duel.MatchID != "" {
+		return
+	}
+	h.matchmakingService.EndGame(duel.ID)
+}
+
+// refreshOwnership は保持しているユーザー・対戦・ルームと Own で登録された所有者キーを延長します
+func (h *Hub) refreshOwnership() {
+	for _, userID := range h.localUserIDs() {
+		h.claim(userKey(userID))
+	}
+	for _, duelID := range h.duelService.DuelIDs() {
+		h.claim(duelKey(duelID))
+	}
+	for _, matchID := range h.matchService.MatchIDs() {
+		h.claim(matchKey(matchID))
+	}
+	for _, room := range h.matchmakingService.PrivateLobbies() {
+		h.claimRoom(room)
+	}
+	for _, key := range h.externalKeys() {
+		h.claim(key)
+	}
+}
+
+// localUserIDs はこのインスタンスに接続しているユーザーIDを返します
+func (h *Hub) localUserIDs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	userIDs := make([]string, 0, len(h.clients))
+	for userID := range h.clients {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
+
+// Close はBackplaneの購読を終了し、所有者キーを解放します
+func (h *Hub) Close() error {
+	for _, unsubscribe := range h.unsubscribe {
+		unsubscribe()
+	}
+
+	for _, userID := range h.localUserIDs() {
+		h.release(userKey(userID))
+	}
+	for _, duelID := range h.duelService.DuelIDs() {
+		h.release(duelKey(duelID))
+	}
+	for _, matchID := range h.matchService.MatchIDs() {
+		h.release(matchKey(matchID))
+	}
+	for _, room := range h.matchmakingService.PrivateLobbies() {
+		h.releaseRoom(room)
+	}
+	for _, key := range h.externalKeys() {
+		h.release(key)
+	}
+
+	return h.backplane.Close()
+}
+
+// onMatchFound はマッチング完了時に呼ばれるコールバック関数です
+func (h *Hub) onMatchFound(room *game.Room) error {
+	log.Printf("マッチング完了コールバック: ルーム %s", room.ID)
+
+	// マッチングで決まったIDで対戦を作成
+	// ボットとの対戦はレーティングに反映しない
+	players := room.Players
+	content := map[string]interface{}{
+		"roomId":  room.ID,
+		"duelId":  room.DuelID,
+		"players": players,
+		"message": "ゲームが開始されました",
+	}
+	if room.BestOf > 1 {
+		// Best-of-N のシリーズは1試合目としてルームの対戦IDを使う
+		match, err := h.matchService.StartMatch(room.DuelID, players[0].UserID, players[1].UserID, room.BestOf)
+		if err != nil {
+			return err
+		}
+		h.claim(matchKey(match.ID))
+		content["matchId"] = match.ID
+		content["bestOf"] = match.BestOf
+	} else {
+		opts := game.DuelOptions{Unrated: room.Bot}
+		if err := h.duelService.CreateDuelWithOptions(room.DuelID, players[0].UserID, players[1].UserID, opts); err != nil {
+			return err
+		}
+	}
+
+	// マッチしたプレイヤーにゲーム開始を通知
+	gameStartMessage := &Message{Type: "gameStart", Content: content}
 
 	for _, player := range players {
+		if bot.IsBot(player.UserID) {
+			continue
+		}
 		err := h.SendToUser(player.UserID, gameStartMessage)
 		if err != nil {
 			log.Printf("ゲーム開始通知エラー (ユーザー: %s): %v", player.UserID, err)
@@ -161,10 +1018,18 @@ func (h *Hub) onMatchFound(roomID string, players []game.MatchmakingRequest) {
 	}
 
 	// ルームのゲームを開始状態にする
-	err = h.matchmakingService.StartGame(roomID)
-	if err != nil {
+	if err := h.matchmakingService.StartGame(room.ID); err != nil {
 		log.Printf("ルームゲーム開始エラー: %v", err)
 	}
+	// 対戦が始まったルームには参加・準備・承認の操作が届かなくなる
+	if room.Private {
+		h.releaseRoom(room)
+	} else if len(room.Ready) > 0 { // 両者の承認を経たランダムマッチ
+		for _, player := range players {
+			h.release(roomMemberKey(player.UserID))
+		}
+	}
+	return nil
 }
 
 // startCleanupTask は定期的なクリーンアップタスクを開始します
@@ -172,16 +1037,69 @@ func (h *Hub) startCleanupTask() {
 	ticker := time.NewTicker(30 * time.Second) // 30秒ごとにクリーンアップ
 	defer ticker.Stop()
 
+	// 待ち時間とともにレーティングの許容差が広がるため、再マッチングは短い間隔で行う
+	matchTicker := time.NewTicker(matchInterval)
+	defer matchTicker.Stop()
+
+	queueTicker := time.NewTicker(queueStatusInterval)
+	defer queueTicker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
 			if h.matchmakingService != nil {
 				h.matchmakingService.CleanupExpiredRooms(5 * time.Minute) // 5分以上古いルームを削除
+				h.matchmakingService.CleanupExpiredPrivateRooms()         // プライベートルームは個別の期限で削除
+			}
+			h.matchService.CleanupFinishedMatches()
+			h.refreshOwnership()
+		case <-matchTicker.C:
+			if h.matchmakingService != nil {
+				h.matchmakingService.MatchWaiting(context.Background())
 			}
+		case <-queueTicker.C:
+			h.pushQueueStatus()
+		}
+	}
+}
+
+// pushQueueStatus はこのインスタンスに接続している待機中のクライアントにキュー状況を送信します
+//
+// 他のインスタンスに接続しているユーザーにはそのインスタンスが送信します。
+func (h *Hub) pushQueueStatus() {
+	if h.matchmakingService == nil {
+		return
+	}
+	infos, err := h.matchmakingService.QueueInfos(context.Background())
+	if err != nil {
+		log.Printf("キュー状況取得エラー: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for userID, info := range infos {
+		if client, ok := h.clients[userID]; ok {
+			client.send.offer(&Message{Type: "queueStatus", Content: info})
 		}
 	}
 }
 
+// GetBotDriver はこのインスタンスのボットのドライバーを返します
+func (h *Hub) GetBotDriver() *bot.Driver {
+	return h.bots
+}
+
+// GetMatchmakingService returns the matchmaking service managed by the hub.
+func (h *Hub) GetMatchmakingService() *game.MatchmakingService {
+	return h.matchmakingService
+}
+
+// GetMatchService はBest-of-Nのシリーズを管理するサービスを返します
+func (h *Hub) GetMatchService() *game.MatchService {
+	return h.matchService
+}
+
 // GetDuelService returns the duel service managed by the hub.
 func (h *Hub) GetDuelService() *game.DuelService {
 	return h.duelService
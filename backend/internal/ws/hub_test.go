@@ -0,0 +1,458 @@
+// backend/internal/ws/hub_test.go
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+// newTestHub はBackplaneを共有するHubを作成して起動します
+func newTestHub(t *testing.T, bp Backplane, instanceID string) *Hub {
+	t.Helper()
+	hub := NewHub(nil, WithBackplane(bp), WithInstanceID(instanceID))
+	go hub.Run()
+	return hub
+}
+
+// connectTestClient はWebSocket接続なしでクライアントをHubに登録します
+func connectTestClient(t *testing.T, hub *Hub, userID string) *Client {
+	t.Helper()
+	client := &Client{hub: hub, send: newOutbox(), codec: jsonCodec{}, userID: userID, limiter: newRateLimiter()}
+	hub.register <- client
+	waitFor(t, "所有者の登録", func() bool {
+		owner, _ := hub.owner(userKey(userID))
+		return owner == hub.instanceID
+	})
+	return client
+}
+
+// waitFor は cond が満たされるまで待ちます
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s を待てませんでした", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitForMessage はクライアントの送信キューに msgType のメッセージが届くまで待ちます
+func waitForMessage(t *testing.T, client *Client, msgType string) *Message {
+	t.Helper()
+	var found *Message
+	waitFor(t, msgType+" の受信", func() bool {
+		for _, msg := range client.send.drain() {
+			if msg.Type == msgType {
+				found = msg
+			}
+		}
+		return found != nil
+	})
+	return found
+}
+
+// roomContent はメッセージの内容をルームとして読み取ります (転送されたメッセージはJSONのまま届く)
+func roomContent(t *testing.T, msg *Message) *game.Room {
+	t.Helper()
+	var room game.Room
+	if err := decodeContent(msg.Content, &room); err != nil {
+		t.Fatalf("ルームの読み取りエラー: %v", err)
+	}
+	return &room
+}
+
+func TestSendToUserAcrossInstances(t *testing.T) {
+	bp := NewMemoryBackplane()
+	hubA := newTestHub(t, bp, "a")
+	hubB := newTestHub(t, bp, "b")
+	client := connectTestClient(t, hubB, "u1")
+
+	// u1 は B に接続しているため、A からの送信は B へ転送される
+	if err := hubA.SendToUser("u1", &Message{Type: "hello", Content: "from a"}); err != nil {
+		t.Fatalf("SendToUser: %v", err)
+	}
+	msg := waitForMessage(t, client, "hello")
+	if msg.Content != "from a" {
+		t.Errorf("Content = %v; want \"from a\"", msg.Content)
+	}
+
+	if err := hubA.SendToUser("nobody", &Message{Type: "hello"}); err == nil {
+		t.Error("接続していないユーザーへの送信がエラーになりませんでした")
+	}
+}
+
+func TestUnregisterReleasesOwner(t *testing.T) {
+	bp := NewMemoryBackplane()
+	hubA := newTestHub(t, bp, "a")
+	hubB := newTestHub(t, bp, "b")
+	client := connectTestClient(t, hubB, "u1")
+
+	hubB.unregister <- client
+	waitFor(t, "所有者の解放", func() bool {
+		owner, _ := hubB.owner(userKey("u1"))
+		return owner == ""
+	})
+	if err := hubA.SendToUser("u1", &Message{Type: "hello"}); err == nil {
+		t.Error("切断したユーザーへの送信がエラーになりませんでした")
+	}
+
+	// 別のインスタンスに再接続した後に古いインスタンスが解放しても、新しい登録は消えない
+	connectTestClient(t, hubA, "u1")
+	hubB.release(userKey("u1"))
+	if owner, _ := hubA.owner(userKey("u1")); owner != "a" {
+		t.Errorf("再接続後の所有者 = %q; want \"a\"", owner)
+	}
+}
+
+func TestPrivateRoomAcrossInstances(t *testing.T) {
+	bp := NewMemoryBackplane()
+	hubA := newTestHub(t, bp, "a")
+	hubB := newTestHub(t, bp, "b")
+	host := connectTestClient(t, hubA, "u1")
+	guest := connectTestClient(t, hubB, "u2")
+
+	room, err := hubA.CreatePrivateRoom("u1", 1)
+	if err != nil {
+		t.Fatalf("CreatePrivateRoom: %v", err)
+	}
+
+	// ルームは A にあるため、B での参加は A へ転送される
+	joined, err := hubB.JoinPrivateRoom("u2", room.Code)
+	if err != nil {
+		t.Fatalf("JoinPrivateRoom: %v", err)
+	}
+	if joined != nil {
+		t.Errorf("転送した参加のルーム = %+v; want nil", joined)
+	}
+	update := roomContent(t, waitForMessage(t, guest, "roomUpdate"))
+	if len(update.Players) != 2 || update.ID != room.ID {
+		t.Errorf("参加後のルーム = %+v; want %s の2人", update, room.ID)
+	}
+	waitForMessage(t, host, "roomUpdate")
+
+	// 準備状態・退出も参加しているルームのインスタンスへ転送される
+	if _, err := hubB.SetReady("u2", true); err != nil {
+		t.Fatalf("SetReady: %v", err)
+	}
+	update = roomContent(t, waitForMessage(t, host, "roomUpdate"))
+	if len(update.Ready) != 1 || update.Ready[0] != "u2" {
+		t.Errorf("準備完了したユーザー = %v; want [u2]", update.Ready)
+	}
+
+	forwarded, err := hubB.LeavePrivateRoom("u2")
+	if err != nil || !forwarded {
+		t.Fatalf("LeavePrivateRoom = %v, %v; want 転送", forwarded, err)
+	}
+	waitForMessage(t, guest, "roomLeft")
+	if _, err := hubB.SetReady("u2", true); err != game.ErrRoomNotFound {
+		t.Errorf("退出後の SetReady = %v; want ErrRoomNotFound", err)
+	}
+
+	// 存在しないコードは転送せずにエラーにする
+	if _, err := hubB.JoinPrivateRoom("u2", "ZZZZZZ"); err != game.ErrRoomNotFound {
+		t.Errorf("存在しないコードの JoinPrivateRoom = %v; want ErrRoomNotFound", err)
+	}
+}
+
+func TestAcceptMatchAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	bp := NewMemoryBackplane()
+	hubA := newTestHub(t, bp, "a")
+	hubB := newTestHub(t, bp, "b")
+	first := connectTestClient(t, hubA, "u1")
+	second := connectTestClient(t, hubB, "u2")
+
+	// 2人とも A で参加したため、承認待ちのマッチと承認期限のタイマーは A にある
+	if _, err := hubA.GetMatchmakingService().FindMatch(ctx, "u1"); err != nil {
+		t.Fatalf("FindMatch: %v", err)
+	}
+	room, err := hubA.GetMatchmakingService().FindMatch(ctx, "u2")
+	if err != nil {
+		t.Fatalf("FindMatch: %v", err)
+	}
+	if room.Status != game.RoomAccepting {
+		t.Fatalf("マッチ成立後のルーム = %q; want %q", room.Status, game.RoomAccepting)
+	}
+	waitForMessage(t, second, "matchFound")
+
+	// B での承認は A へ転送される
+	accepted, err := hubB.AcceptMatch(ctx, "u2")
+	if err != nil {
+		t.Fatalf("AcceptMatch: %v", err)
+	}
+	if accepted != nil {
+		t.Errorf("転送した承認のルーム = %+v; want nil", accepted)
+	}
+	update := roomContent(t, waitForMessage(t, second, "matchFound"))
+	if len(update.Ready) != 1 || update.Ready[0] != "u2" {
+		t.Errorf("承認したユーザー = %v; want [u2]", update.Ready)
+	}
+
+	// B での辞退も A へ転送され、キュー参加の禁止は B にも共有される
+	forwarded, err := hubB.DeclineMatch(ctx, "u1")
+	if err != nil || !forwarded {
+		t.Fatalf("DeclineMatch = %v, %v; want 転送", forwarded, err)
+	}
+	waitForMessage(t, first, "matchDeclined")
+	waitForMessage(t, second, "matchRequeued")
+	waitFor(t, "キュー参加禁止の共有", func() bool {
+		status, err := hubB.GetMatchmakingService().Status(ctx, "u1")
+		return err == nil && status.Status == "cooldown"
+	})
+	if _, err := hubB.GetMatchmakingService().FindMatch(ctx, "u1"); !errors.Is(err, game.ErrQueueCooldown) {
+		t.Errorf("辞退後の B での FindMatch = %v; want ErrQueueCooldown", err)
+	}
+
+	if _, err := hubB.AcceptMatch(ctx, "u1"); !errors.Is(err, game.ErrNoPendingMatch) {
+		t.Errorf("不成立後の AcceptMatch = %v; want ErrNoPendingMatch", err)
+	}
+}
+
+func TestHandleEnvelopeDropsMalformed(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBackplane(), "a")
+	client := connectTestClient(t, hub, "u1")
+
+	// 必要な項目のないメッセージでHubのgoroutineがpanicしないこと
+	for _, payload := range []string{
+		`not json`,
+		`{"kind":"action"}`,
+		`{"kind":"choose"}`,
+		`{"kind":"forceEnd"}`,
+		`{"kind":"sync","userId":"u1"}`,
+		`{"kind":"user","userId":"u1"}`,
+		`{"kind":"disconnect","userId":"u1"}`,
+		`{"kind":"broadcast"}`,
+		`{"kind":"room"}`,
+		`{"kind":"cooldown","userId":"u1"}`,
+		`{"kind":"call","call":{"id":"c1"}}`,
+		`{"kind":"reply"}`,
+		`{"kind":"unknown"}`,
+	} {
+		hub.handleEnvelope([]byte(payload))
+	}
+
+	// 正しいメッセージは引き続き配送される
+	hub.handleEnvelope([]byte(`{"kind":"user","userId":"u1","message":{"type":"hello"}}`))
+	waitForMessage(t, client, "hello")
+}
+
+// errTestNotFound は呼び出し先で返す番兵エラーです
+var errTestNotFound = errors.New("見つかりません")
+
+// handleEcho は引数をそのまま返し、空の場合は errTestNotFound を返す処理を登録します
+func handleEcho(hub *Hub) {
+	hub.Handle("echo", func(args json.RawMessage) (interface{}, error) {
+		var text string
+		if err := json.Unmarshal(args, &text); err != nil {
+			return nil, err
+		}
+		if text == "" {
+			return nil, errTestNotFound
+		}
+		return hub.instanceID + ":" + text, nil
+	})
+}
+
+func TestCallAcrossInstances(t *testing.T) {
+	bp := NewMemoryBackplane()
+	hubA := newTestHub(t, bp, "a")
+	hubB := newTestHub(t, bp, "b")
+	handleEcho(hubA)
+	handleEcho(hubB)
+
+	var got string
+	if err := hubA.Call("b", "echo", "hi", &got); err != nil || got != "b:hi" {
+		t.Fatalf("B の呼び出し = %q, %v; want \"b:hi\"", got, err)
+	}
+	// 自分のインスタンスはBackplaneを経由せずに実行する
+	if err := hubA.Call("a", "echo", "hi", &got); err != nil || got != "a:hi" {
+		t.Fatalf("A の呼び出し = %q, %v; want \"a:hi\"", got, err)
+	}
+	// 呼び出し先の番兵エラーは errors.Is で判定できる
+	if err := hubA.Call("b", "echo", "", &got); !errors.Is(err, errTestNotFound) {
+		t.Errorf("エラーになる呼び出し = %v; want errTestNotFound", err)
+	}
+	if err := hubA.Call("b", "missing", "hi", &got); err == nil {
+		t.Error("未登録の処理の呼び出しが成功しました")
+	}
+}
+
+func TestGatherAcrossInstances(t *testing.T) {
+	bp := NewMemoryBackplane()
+	hubA := newTestHub(t, bp, "a")
+	for _, id := range []string{"b", "c"} {
+		handleEcho(newTestHub(t, bp, id))
+	}
+
+	results, err := hubA.Gather("echo", "hi")
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	// 呼び出したインスタンス自身は含まない
+	var got []string
+	for _, raw := range results {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, text)
+	}
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "b:hi" || got[1] != "c:hi" {
+		t.Errorf("Gather = %v; want [b:hi c:hi]", got)
+	}
+
+	if _, err := hubA.Gather("echo", ""); !errors.Is(err, errTestNotFound) {
+		t.Errorf("エラーになる Gather = %v; want errTestNotFound", err)
+	}
+}
+
+func TestUnknownMessageTypeIsRejected(t *testing.T) {
+	bp := NewMemoryBackplane()
+	hubA := newTestHub(t, bp, "a")
+	hubB := newTestHub(t, bp, "b")
+	sender := connectTestClient(t, hubA, "u1")
+	other := connectTestClient(t, hubB, "u2")
+
+	// 送信者を偽った未知のタイプのメッセージは他のクライアントに届かず、送信者にエラーが返る
+	sender.handleMessage(&Message{Type: "announcement", UserID: "admin", Content: "偽のお知らせ"})
+	waitForMessage(t, sender, "error")
+
+	sender.handleMessage(&Message{Type: "ping"})
+	waitForMessage(t, sender, "pong")
+	for _, msg := range other.send.drain() {
+		if msg.Type == "announcement" {
+			t.Fatalf("未知のタイプのメッセージが他のクライアントに届きました: %+v", msg)
+		}
+	}
+}
+
+func TestCheckParticipantAcrossInstances(t *testing.T) {
+	bp := NewMemoryBackplane()
+	hubA := newTestHub(t, bp, "a")
+	hubB := newTestHub(t, bp, "b")
+	duelID, err := hubA.duelService.CreateDuel("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 別インスタンスの対戦は担当インスタンスに問い合わせる
+	for _, hub := range []*Hub{hubA, hubB} {
+		if err := hub.CheckParticipant(duelID, "alice"); err != nil {
+			t.Errorf("%s: プレイヤーの確認 = %v; want nil", hub.instanceID, err)
+		}
+		if err := hub.CheckParticipant(duelID, "mallory"); !errors.Is(err, ErrNotParticipant) {
+			t.Errorf("%s: プレイヤー以外の確認 = %v; want ErrNotParticipant", hub.instanceID, err)
+		}
+		if err := hub.CheckParticipant("missing", "alice"); !errors.Is(err, game.ErrDuelNotFound) {
+			t.Errorf("%s: 存在しない対戦の確認 = %v; want ErrDuelNotFound", hub.instanceID, err)
+		}
+	}
+
+	// 両者の手札を含む対戦データはプレイヤー以外には送らない
+	if err := hubA.RequestDuelData(duelID, "mallory"); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("プレイヤー以外の RequestDuelData = %v; want ErrNotParticipant", err)
+	}
+}
+
+func TestAdminCallsAcrossInstances(t *testing.T) {
+	bp := NewMemoryBackplane()
+	cards := []game.Card{{ID: 1, Name: "変更前", AttackPts: 1, DefensePts: 1}}
+	hubA := NewHub(cards, WithBackplane(bp), WithInstanceID("a"))
+	hubB := NewHub(cards, WithBackplane(bp), WithInstanceID("b"))
+	go hubA.Run()
+	go hubB.Run()
+	duelA, err := hubA.duelService.CreateDuel("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	duelB, err := hubB.duelService.CreateDuel("carol", "dave")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 一覧はすべてのインスタンスの対戦を含む
+	duels, err := hubA.LiveDuels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{}
+	for _, duel := range duels {
+		ids[duel.ID] = true
+	}
+	if len(duels) != 2 || !ids[duelA] || !ids[duelB] {
+		t.Errorf("LiveDuels = %d 件; want %s と %s", len(duels), duelA, duelB)
+	}
+
+	// 別インスタンスの対戦は担当インスタンスから取得する
+	duel, err := hubA.GetDuel(duelB)
+	if err != nil || duel.Players[0].UserID != "carol" || len(duel.Players[0].Hand) == 0 {
+		t.Errorf("GetDuel(%s) = %+v, %v; want 手札を含む carol の対戦", duelB, duel, err)
+	}
+	if _, err := hubA.GetDuel("missing"); !errors.Is(err, game.ErrDuelNotFound) {
+		t.Errorf("存在しない対戦の GetDuel = %v; want ErrDuelNotFound", err)
+	}
+
+	// カードの変更はすべてのインスタンスに反映される
+	card := game.Card{ID: 1, Name: "変更後", AttackPts: 9, DefensePts: 9}
+	if err := hubA.UpdateCard(card); err != nil {
+		t.Fatal(err)
+	}
+	for _, hub := range []*Hub{hubA, hubB} {
+		duelID, err := hub.duelService.CreateDuel("erin", "frank")
+		if err != nil {
+			t.Fatal(err)
+		}
+		created, _ := hub.duelService.GetDuel(duelID)
+		if hand := created.Players[0].Hand; len(hand) == 0 || hand[0] != card {
+			t.Errorf("%s の新しい対戦の手札 = %+v; want %+v", hub.instanceID, hand, card)
+		}
+	}
+}
+
+func TestAccountCallsAcrossInstances(t *testing.T) {
+	bp := NewMemoryBackplane()
+	cards := []game.Card{{ID: 1, Name: "テスト", AttackPts: 1, DefensePts: 1}}
+	hubA := NewHub(cards, WithBackplane(bp), WithInstanceID("a"))
+	hubB := NewHub(cards, WithBackplane(bp), WithInstanceID("b"))
+	go hubA.Run()
+	go hubB.Run()
+	hubB.AddInMatchCheck(func(userID string) bool { return userID == "entrant" })
+
+	// 別インスタンスで管理している参加状況も確認する
+	for _, tc := range []struct {
+		userID string
+		want   bool
+	}{
+		{"entrant", true},
+		{"alice", false},
+	} {
+		if got, err := hubA.InMatch(tc.userID); err != nil || got != tc.want {
+			t.Errorf("InMatch(%s) = %v, %v; want %v", tc.userID, got, err, tc.want)
+		}
+	}
+
+	// 別インスタンスの進行中の対戦も終了させる
+	duelID, err := hubB.duelService.CreateDuel("alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hubA.Forfeit("alice"); err != nil {
+		t.Fatal(err)
+	}
+	duel, err := hubB.duelService.GetDuel(duelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duel.Status != "finished" || duel.WinnerID != "bob" {
+		t.Errorf("Forfeit 後の対戦 = %s (勝者 %s); want bob の勝ちで終了", duel.Status, duel.WinnerID)
+	}
+}
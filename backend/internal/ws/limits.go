@@ -0,0 +1,82 @@
+// backend/internal/ws/limits.go
+package ws
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// デフォルトの最大メッセージサイズ (バイト)
+const defaultMaxMessageSize = 4096
+
+// MessageLimits はクライアントから受信するメッセージの最大サイズ (バイト) です
+type MessageLimits struct {
+	// Default はPerTypeにないタイプに適用されます
+	Default int
+	// PerType はメッセージタイプごとの上限です
+	PerType map[string]int
+}
+
+// DefaultMessageLimits は標準のメッセージサイズ上限を返します
+func DefaultMessageLimits() MessageLimits {
+	return MessageLimits{
+		Default: defaultMaxMessageSize,
+		PerType: map[string]int{
+			"ping":           256,
+			"chat":           1024,
+			"findMatch":      512,
+			"cancelMatch":    512,
+			"acceptMatch":    512,
+			"declineMatch":   512,
+			"offerRematch":   512,
+			"acceptRematch":  512,
+			"declineRematch": 512,
+			"chooseFirst":    512,
+			"createRoom":     512,
+			"joinRoom":       512,
+			"setReady":       512,
+			"leaveRoom":      512,
+		},
+	}
+}
+
+// limitFor はメッセージタイプの上限を返します
+func (l MessageLimits) limitFor(msgType string) int {
+	if limit, ok := l.PerType[msgType]; ok {
+		return limit
+	}
+	return l.Default
+}
+
+// readLimit は接続に設定する読み取り上限 (全タイプの最大値) を返します
+func (l MessageLimits) readLimit() int64 {
+	max := l.Default
+	for _, limit := range l.PerType {
+		if limit > max {
+			max = limit
+		}
+	}
+	return int64(max)
+}
+
+// ParseMessageLimits は "gameAction=2048,chat=1024" 形式の設定を解析します
+func ParseMessageLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		msgType, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("メッセージサイズ設定の形式が不正です: %q", entry)
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("メッセージサイズが不正です: %q", entry)
+		}
+		limits[strings.TrimSpace(msgType)] = size
+	}
+	return limits, nil
+}
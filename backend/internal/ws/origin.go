@@ -0,0 +1,77 @@
+// backend/internal/ws/origin.go
+package ws
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// originChecker はWebSocket接続要求の Origin ヘッダーを許可リストで検証します
+//
+// 許可リストはCORSの AllowOrigins と同じ形式で、"*" はすべてのOriginを、
+// "https://*.example.com" は example.com のサブドメイン (example.com 自体は含まない) を許可します。
+type originChecker struct {
+	allowAll  bool
+	exact     map[string]bool
+	wildcards []wildcardOrigin
+}
+
+// wildcardOrigin は "https://*.example.com" 形式の許可するOriginです
+type wildcardOrigin struct {
+	scheme string
+	suffix string // ".example.com" (ポートがあれば ".example.com:8443")
+}
+
+// newOriginChecker は許可リストからoriginCheckerを作成します
+func newOriginChecker(allowed []string) *originChecker {
+	oc := &originChecker{exact: make(map[string]bool)}
+	for _, origin := range allowed {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "":
+		case origin == "*":
+			oc.allowAll = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://")
+			oc.wildcards = append(oc.wildcards, wildcardOrigin{scheme: scheme, suffix: strings.TrimPrefix(host, "*")})
+		default:
+			oc.exact[origin] = true
+		}
+	}
+	return oc
+}
+
+// check は接続要求を受け付けるかを返し、拒否した場合はログに残します
+//
+// Origin ヘッダーのない要求はブラウザ以外のクライアントからの接続として受け付けます
+// (他サイトのページから開かれた接続には必ず Origin が付きます)。
+func (oc *originChecker) check(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || oc.allows(origin) {
+		return true
+	}
+	log.Printf("許可されていないOriginからのWebSocket接続を拒否しました: %q (接続元: %s)", origin, r.RemoteAddr)
+	return false
+}
+
+// allows は origin が許可リストに含まれるかを返します
+func (oc *originChecker) allows(origin string) bool {
+	if oc.allowAll {
+		return true
+	}
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	if oc.exact[u.Scheme+"://"+u.Host] {
+		return true
+	}
+	for _, w := range oc.wildcards {
+		if u.Scheme == w.scheme && strings.HasSuffix(u.Host, w.suffix) && len(u.Host) > len(w.suffix) {
+			return true
+		}
+	}
+	return false
+}
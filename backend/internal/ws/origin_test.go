@@ -0,0 +1,81 @@
+// backend/internal/ws/origin_test.go
+package ws
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginCheckerAllows(t *testing.T) {
+	oc := newOriginChecker([]string{
+		"http://localhost:3000",
+		" https://App.Example.org/ ",
+		"https://*.example.com",
+		"https://*.example.net:8443",
+	})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"http://localhost:3000", true},
+		{"http://localhost:3001", false},
+		{"https://localhost:3000", false},
+		// 大文字小文字は区別しない
+		{"HTTP://LOCALHOST:3000", true},
+		// 許可リストの空白・末尾のスラッシュは無視する
+		{"https://app.example.org", true},
+		// サブドメインのワイルドカード
+		{"https://a.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"http://a.example.com", false},
+		{"https://evilexample.com", false},
+		{"https://a.example.com.evil.com", false},
+		{"https://a.example.com:8443", false},
+		// ポート付きのワイルドカードはポートも一致する必要がある
+		{"https://a.example.net:8443", true},
+		{"https://a.example.net", false},
+		{"https://a.example.net:9443", false},
+		// 解析できない・ホストのないOrigin
+		{"null", false},
+		{"://a.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := oc.allows(tt.origin); got != tt.want {
+			t.Errorf("allows(%q) = %v; want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestOriginCheckerAllowAll(t *testing.T) {
+	oc := newOriginChecker([]string{"*"})
+	for _, origin := range []string{"https://evil.example", "null"} {
+		if !oc.allows(origin) {
+			t.Errorf("* の許可リストで allows(%q) = false; want true", origin)
+		}
+	}
+}
+
+func TestOriginCheckerCheck(t *testing.T) {
+	oc := newOriginChecker([]string{"http://localhost:3000"})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		// Origin のない要求はブラウザ以外のクライアントとして受け付ける
+		{"", true},
+		{"http://localhost:3000", true},
+		{"https://evil.example", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := oc.check(r); got != tt.want {
+			t.Errorf("check(Origin: %q) = %v; want %v", tt.origin, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// backend/internal/ws/outbox.go
+package ws
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+
+	"github.com/KOU050223/go-card/internal/game"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// 破棄可能なメッセージを受け付けるキュー長の上限
+	outboxSoftLimit = 64
+
+	// キュー長がこれを超えたら低速クライアントとして切断します
+	outboxHardLimit = 512
+
+	// 低速クライアント切断時のクローズ理由
+	slowConsumerReason = "slow consumer"
+)
+
+var (
+	errOutboxClosed = errors.New("送信キューは閉じられています")
+	errSlowConsumer = errors.New("送信キューが上限を超えたため切断しました")
+)
+
+// outboxMetrics は送信キューで破棄・統合・切断した件数を集計します
+var outboxMetrics = expvar.NewMap("ws_outbox")
+
+// droppableTypes は混雑時に破棄してもよいメッセージタイプです
+//
+// ここにないタイプはゲーム進行に必要なため破棄せず、送れない場合は切断して
+// 再接続時の duelData で状態を復元させます。
+var droppableTypes = map[string]bool{
+	"pong":         true,
+	"testResponse": true,
+	"queueStatus":  true,
+}
+
+// outbox はクライアントごとの送信キューです
+//
+// 同じ対戦の duelUpdate のように後から届いたもので置き換えられるメッセージは
+// キュー内で統合し、最新の状態だけを送ります。
+type outbox struct {
+	mu          sync.Mutex
+	queue       []*Message
+	notify      chan struct{}
+	closed      bool
+	closeCode   int
+	closeReason string
+}
+
+// newOutbox は新しい送信キューを作成します
+func newOutbox() *outbox {
+	return &outbox{
+		notify:    make(chan struct{}, 1),
+		closeCode: websocket.CloseNormalClosure,
+	}
+}
+
+// push はメッセージをキューに追加します。閉じたキューに対してもpanicしません
+func (o *outbox) push(msg *Message) error {
+	return o.enqueue(msg, isDroppable(msg))
+}
+
+// offer はブロードキャストなど取りこぼしても問題ないメッセージを追加します
+func (o *outbox) offer(msg *Message) error {
+	return o.enqueue(msg, true)
+}
+
+// enqueue はメッセージをキューに追加します
+func (o *outbox) enqueue(msg *Message, droppable bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return errOutboxClosed
+	}
+
+	// 置き換え可能なメッセージは古いものを取り除いて最新のものを末尾に追加する
+	// (古いものの位置に入れると、その後に積まれたメッセージより先に届いて順序が入れ替わる)
+	if key := coalesceKey(msg); key != "" {
+		for i, queued := range o.queue {
+			if coalesceKey(queued) == key {
+				o.queue = append(o.queue[:i], o.queue[i+1:]...)
+				o.queue = append(o.queue, msg)
+				outboxMetrics.Add("coalesced", 1)
+				o.signal()
+				return nil
+			}
+		}
+	}
+
+	if len(o.queue) >= outboxSoftLimit && droppable {
+		outboxMetrics.Add("dropped", 1)
+		return nil
+	}
+
+	if len(o.queue) >= outboxHardLimit {
+		outboxMetrics.Add("slow_disconnects", 1)
+		o.closeLocked(websocket.CloseTryAgainLater, slowConsumerReason)
+		o.queue = nil
+		return errSlowConsumer
+	}
+
+	o.queue = append(o.queue, msg)
+	o.signal()
+	return nil
+}
+
+// drain はキュー内のメッセージをすべて取り出します
+func (o *outbox) drain() []*Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	msgs := o.queue
+	o.queue = nil
+	return msgs
+}
+
+// close はキューを閉じます。送信済みでないメッセージは書き込みgoroutineが送り切ります
+func (o *outbox) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closeLocked(websocket.CloseNormalClosure, "")
+}
+
+// closeWith はクローズコードと理由を指定してキューを閉じます
+func (o *outbox) closeWith(code int, reason string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closeLocked(code, reason)
+}
+
+// closeLocked はロック取得済みの状態でキューを閉じます
+func (o *outbox) closeLocked(code int, reason string) {
+	if o.closed {
+		return
+	}
+	o.closed = true
+	o.closeCode = code
+	o.closeReason = reason
+	o.signal()
+}
+
+// isClosed はキューが閉じられているかと、クローズコード・理由を返します
+func (o *outbox) isClosed() (bool, int, string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.closed, o.closeCode, o.closeReason
+}
+
+// signal は書き込みgoroutineを起こします
+func (o *outbox) signal() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// isDroppable は混雑時に破棄してよいメッセージかを返します
+func isDroppable(msg *Message) bool {
+	return droppableTypes[msg.Type]
+}
+
+// coalesceKey は後続のメッセージで置き換え可能な場合にそのキーを返します
+func coalesceKey(msg *Message) string {
+	switch msg.Type {
+	case "duelUpdate":
+		if duelID := duelIDOf(msg.Content); duelID != "" {
+			return msg.Type + ":" + duelID
+		}
+	case "queueStatus":
+		// 最新のキュー状況だけを送れば十分
+		return msg.Type
+	}
+	return ""
+}
+
+// duelIDOf はメッセージのContentから対戦IDを取り出します
+//
+// Backplane経由のメッセージはContentがmapとして復元されるため両方に対応します。
+func duelIDOf(content interface{}) string {
+	switch v := content.(type) {
+	case *game.Duel:
+		return v.ID
+	case map[string]interface{}:
+		if id, ok := v["id"].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
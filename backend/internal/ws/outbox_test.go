@@ -0,0 +1,124 @@
+// backend/internal/ws/outbox_test.go
+package ws
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KOU050223/go-card/internal/game"
+	"github.com/gorilla/websocket"
+)
+
+func TestOutboxCoalescesDuelUpdates(t *testing.T) {
+	o := newOutbox()
+	o.push(&Message{Type: "duelUpdate", Content: &game.Duel{ID: "d1", Version: 1}})
+	o.push(&Message{Type: "duelUpdate", Content: &game.Duel{ID: "d2", Version: 1}})
+	o.push(&Message{Type: "duelEvent", Content: "e1"})
+	// Backplane経由で届いたmap形式も同じ対戦として統合する
+	o.push(&Message{Type: "duelUpdate", Content: map[string]interface{}{"id": "d1", "version": 2}})
+	o.push(&Message{Type: "queueStatus", Content: 1})
+	o.push(&Message{Type: "queueStatus", Content: 2})
+
+	msgs := o.drain()
+	if len(msgs) != 4 {
+		t.Fatalf("キュー長 = %d; want 4", len(msgs))
+	}
+	// 統合されたメッセージは古いものが取り除かれ、後から積まれたメッセージより後に届く
+	if got := duelIDOf(msgs[0].Content); got != "d2" {
+		t.Errorf("1番目の対戦ID = %q; want \"d2\"", got)
+	}
+	if msgs[1].Type != "duelEvent" {
+		t.Errorf("2番目のタイプ = %q; want \"duelEvent\"", msgs[1].Type)
+	}
+	if got := msgs[2].Content.(map[string]interface{})["version"]; got != 2 {
+		t.Errorf("d1 の version = %v; want 2", got)
+	}
+	if msgs[3].Content != 2 {
+		t.Errorf("queueStatus = %v; want 2", msgs[3].Content)
+	}
+}
+
+func TestOutboxDropsDroppableOverSoftLimit(t *testing.T) {
+	o := newOutbox()
+	for i := 0; i < outboxSoftLimit; i++ {
+		if err := o.push(&Message{Type: "duelEvent", Content: i}); err != nil {
+			t.Fatalf("push: %v", err)
+		}
+	}
+
+	// ソフトリミットを超えた破棄可能なメッセージは黙って捨てる
+	if err := o.push(&Message{Type: "pong"}); err != nil {
+		t.Fatalf("push pong: %v", err)
+	}
+	if err := o.offer(&Message{Type: "chat"}); err != nil {
+		t.Fatalf("offer: %v", err)
+	}
+	// ゲーム進行に必要なメッセージは捨てない
+	if err := o.push(&Message{Type: "duelEvent", Content: "kept"}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	msgs := o.drain()
+	if len(msgs) != outboxSoftLimit+1 {
+		t.Fatalf("キュー長 = %d; want %d", len(msgs), outboxSoftLimit+1)
+	}
+	if last := msgs[len(msgs)-1]; last.Content != "kept" {
+		t.Errorf("最後のメッセージ = %v; want \"kept\"", last.Content)
+	}
+}
+
+func TestOutboxClosesOverHardLimit(t *testing.T) {
+	o := newOutbox()
+	for i := 0; i < outboxHardLimit; i++ {
+		if err := o.push(&Message{Type: "duelEvent", Content: i}); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+
+	if err := o.push(&Message{Type: "duelEvent"}); !errors.Is(err, errSlowConsumer) {
+		t.Fatalf("ハードリミット超過時のエラー = %v; want errSlowConsumer", err)
+	}
+	closed, code, reason := o.isClosed()
+	if !closed || code != websocket.CloseTryAgainLater || reason != slowConsumerReason {
+		t.Errorf("isClosed = %v, %d, %q; want true, %d, %q", closed, code, reason, websocket.CloseTryAgainLater, slowConsumerReason)
+	}
+	if msgs := o.drain(); len(msgs) != 0 {
+		t.Errorf("切断後のキュー長 = %d; want 0", len(msgs))
+	}
+	if err := o.push(&Message{Type: "duelEvent"}); !errors.Is(err, errOutboxClosed) {
+		t.Errorf("切断後の push のエラー = %v; want errOutboxClosed", err)
+	}
+}
+
+func TestOutboxCloseKeepsQueued(t *testing.T) {
+	o := newOutbox()
+	o.push(&Message{Type: "duelEnd"})
+	o.close()
+
+	// 閉じる前に積んだメッセージは書き込みgoroutineが送り切る
+	if msgs := o.drain(); len(msgs) != 1 {
+		t.Errorf("キュー長 = %d; want 1", len(msgs))
+	}
+	if closed, code, _ := o.isClosed(); !closed || code != websocket.CloseNormalClosure {
+		t.Errorf("isClosed = %v, %d; want true, %d", closed, code, websocket.CloseNormalClosure)
+	}
+}
+
+func TestCoalesceKey(t *testing.T) {
+	tests := []struct {
+		msg  *Message
+		want string
+	}{
+		{&Message{Type: "duelUpdate", Content: &game.Duel{ID: "d1"}}, "duelUpdate:d1"},
+		{&Message{Type: "duelUpdate", Content: map[string]interface{}{"id": "d1"}}, "duelUpdate:d1"},
+		{&Message{Type: "queueStatus"}, "queueStatus"},
+		// 対戦IDが取り出せないものは統合しない
+		{&Message{Type: "duelUpdate", Content: "no id"}, ""},
+		{&Message{Type: "duelEvent", Content: &game.Duel{ID: "d1"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := coalesceKey(tt.msg); got != tt.want {
+			t.Errorf("coalesceKey(%s, %v) = %q; want %q", tt.msg.Type, tt.msg.Content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// backend/internal/ws/patch.go
+package ws
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PatchOp はJSON Patch (RFC 6902) の1操作です
+//
+// サーバーが生成するのは add / remove / replace のみです。
+type PatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON は remove 操作では value を出力しません
+func (p PatchOp) MarshalJSON() ([]byte, error) {
+	if p.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{p.Op, p.Path})
+	}
+	type plain PatchOp
+	return json.Marshal(plain(p))
+}
+
+// DuelPatch は前回送信した状態からの差分です
+type DuelPatch struct {
+	DuelID      string    `json:"duelId"`
+	BaseVersion int       `json:"baseVersion"`
+	Version     int       `json:"version"`
+	Ops         []PatchOp `json:"ops"`
+}
+
+// toDocument は任意の値をJSONと同じ汎用表現 (map / slice / float64 など) に変換します
+func toDocument(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// diffDocuments は from を to に変換するPatch操作を生成します
+func diffDocuments(from, to interface{}) []PatchOp {
+	return appendDiff(nil, "", from, to)
+}
+
+// appendDiff はpath以下の差分をopsに追加します
+func appendDiff(ops []PatchOp, path string, from, to interface{}) []PatchOp {
+	switch a := from.(type) {
+	case map[string]interface{}:
+		b, ok := to.(map[string]interface{})
+		if !ok {
+			return append(ops, PatchOp{Op: "replace", Path: path, Value: to})
+		}
+
+		// 生成される操作の順序を安定させる
+		keys := make([]string, 0, len(a)+len(b))
+		for k := range a {
+			keys = append(keys, k)
+		}
+		for k := range b {
+			if _, exists := a[k]; !exists {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			childPath := path + "/" + escapePointer(k)
+			av, inA := a[k]
+			bv, inB := b[k]
+			switch {
+			case inA && !inB:
+				ops = append(ops, PatchOp{Op: "remove", Path: childPath})
+			case !inA && inB:
+				ops = append(ops, PatchOp{Op: "add", Path: childPath, Value: bv})
+			default:
+				ops = appendDiff(ops, childPath, av, bv)
+			}
+		}
+		return ops
+
+	case []interface{}:
+		b, ok := to.([]interface{})
+		// 長さが変わった配列は要素の移動を追わずに丸ごと置き換える
+		if !ok || len(a) != len(b) {
+			return append(ops, PatchOp{Op: "replace", Path: path, Value: to})
+		}
+		for i := range a {
+			ops = appendDiff(ops, path+"/"+strconv.Itoa(i), a[i], b[i])
+		}
+		return ops
+
+	default:
+		if !reflect.DeepEqual(from, to) {
+			ops = append(ops, PatchOp{Op: "replace", Path: path, Value: to})
+		}
+		return ops
+	}
+}
+
+// escapePointer はJSON Pointerのキーをエスケープします
+func escapePointer(key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	return strings.ReplaceAll(key, "/", "~1")
+}
+
+// documentVersion は対戦状態ドキュメントのバージョンを返します
+func documentVersion(doc interface{}) int {
+	if m, ok := doc.(map[string]interface{}); ok {
+		if v, ok := m["version"].(float64); ok {
+			return int(v)
+		}
+	}
+	return 0
+}
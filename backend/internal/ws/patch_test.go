@@ -0,0 +1,186 @@
+// backend/internal/ws/patch_test.go
+package ws
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+// applyPatch はテスト用に、クライアントと同じ方法で doc に ops を適用します
+func applyPatch(t *testing.T, doc interface{}, ops []PatchOp) interface{} {
+	t.Helper()
+	doc, err := toDocument(doc) // 元のドキュメントを書き換えないようにコピーする
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range ops {
+		value, err := toDocument(op.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if op.Path == "" {
+			doc = value
+			continue
+		}
+
+		keys := strings.Split(op.Path[1:], "/")
+		for i, key := range keys {
+			keys[i] = strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")
+		}
+		parent := doc
+		for _, key := range keys[:len(keys)-1] {
+			switch p := parent.(type) {
+			case map[string]interface{}:
+				parent = p[key]
+			case []interface{}:
+				i, _ := strconv.Atoi(key)
+				parent = p[i]
+			}
+		}
+
+		last := keys[len(keys)-1]
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if op.Op == "remove" {
+				delete(p, last)
+			} else {
+				p[last] = value
+			}
+		case []interface{}:
+			// 配列の長さが変わる操作は生成しない (配列ごと置き換える)
+			i, _ := strconv.Atoi(last)
+			p[i] = value
+		default:
+			t.Fatalf("%s の親がオブジェクト・配列ではありません", op.Path)
+		}
+	}
+	return doc
+}
+
+func TestDiffDocuments(t *testing.T) {
+	from := map[string]interface{}{
+		"version": 1.0,
+		"status":  "active",
+		"hand":    []interface{}{1.0, 2.0, 3.0},
+		"hp":      []interface{}{30.0, 30.0},
+		"old":     true,
+		"a/b":     "x",
+	}
+	to := map[string]interface{}{
+		"version": 2.0,
+		"status":  "active",
+		"hand":    []interface{}{1.0, 3.0},
+		"hp":      []interface{}{30.0, 25.0},
+		"new":     "y",
+		"a/b":     "z",
+	}
+
+	want := []PatchOp{
+		{Op: "replace", Path: "/a~1b", Value: "z"},
+		{Op: "replace", Path: "/hand", Value: []interface{}{1.0, 3.0}},
+		{Op: "replace", Path: "/hp/1", Value: 25.0},
+		{Op: "add", Path: "/new", Value: "y"},
+		{Op: "remove", Path: "/old"},
+		{Op: "replace", Path: "/version", Value: 2.0},
+	}
+	if got := diffDocuments(from, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffDocuments() =\n%v\nwant\n%v", got, want)
+	}
+	if got := diffDocuments(from, from); len(got) != 0 {
+		t.Errorf("同じドキュメントの差分 = %v; want なし", got)
+	}
+}
+
+func TestDiffDocumentsRoundTrip(t *testing.T) {
+	before := &game.Duel{
+		ID:      "d1",
+		Status:  "active",
+		Version: 3,
+		Players: [2]game.Player{
+			{UserID: "alice", HP: 30, Hand: []game.Card{{ID: 1}, {ID: 2}}},
+			{UserID: "bob", HP: 30, Hand: []game.Card{{ID: 3}}},
+		},
+	}
+	after := before.Clone()
+	after.Version = 4
+	after.ActiveIdx = 1
+	after.Players[0].Hand = after.Players[0].Hand[1:]
+	after.Players[0].PlayArea = []game.Card{{ID: 1}}
+	after.Players[1].HP = 24
+	after.Status = "finished"
+
+	from, err := toDocument(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := toDocument(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := diffDocuments(from, to)
+	if got := applyPatch(t, from, ops); !reflect.DeepEqual(got, to) {
+		t.Errorf("差分を適用した状態が一致しません\n got: %v\nwant: %v", got, to)
+	}
+}
+
+func TestPatchOpMarshalJSON(t *testing.T) {
+	data, err := json.Marshal([]PatchOp{
+		{Op: "remove", Path: "/a"},
+		{Op: "replace", Path: "/b", Value: nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// remove には value を付けず、replace の null は value として残す
+	want := `[{"op":"remove","path":"/a"},{"op":"replace","path":"/b","value":null}]`
+	if string(data) != want {
+		t.Errorf("json.Marshal = %s; want %s", data, want)
+	}
+}
+
+func TestPrepareDuelState(t *testing.T) {
+	c := &Client{userID: "alice"}
+	duel := &game.Duel{ID: "d1", Status: "active", Version: 1}
+
+	// 最初の更新は全体を送る
+	msg := c.prepareDuelState(&Message{Type: "duelUpdate", Content: duel})
+	if msg == nil || msg.Type != "duelData" {
+		t.Fatalf("最初の更新 = %+v; want duelData", msg)
+	}
+
+	// 以降は前回送信分からの差分を送る
+	next := duel.Clone()
+	next.Version = 2
+	next.TurnCount = 2
+	msg = c.prepareDuelState(&Message{Type: "duelUpdate", Content: next})
+	if msg == nil || msg.Type != "duelPatch" {
+		t.Fatalf("2回目の更新 = %+v; want duelPatch", msg)
+	}
+	patch := msg.Content.(*DuelPatch)
+	if patch.DuelID != "d1" || patch.BaseVersion != 1 || patch.Version != 2 {
+		t.Errorf("差分 = %+v; want d1 の 1 -> 2", patch)
+	}
+
+	// 送信済みのバージョン以下の更新は送らない
+	if msg := c.prepareDuelState(&Message{Type: "duelUpdate", Content: duel}); msg != nil {
+		t.Errorf("古いバージョンの更新 = %+v; want nil", msg)
+	}
+
+	// 再同期要求後の duelData は全体を送る
+	msg = c.prepareDuelState(&Message{Type: "duelData", Content: next})
+	if msg == nil || msg.Type != "duelData" {
+		t.Errorf("再同期 = %+v; want duelData", msg)
+	}
+
+	// 対戦以外のメッセージはそのまま送る
+	chat := &Message{Type: "chat", Content: "hi"}
+	if msg := c.prepareDuelState(chat); msg != chat {
+		t.Errorf("チャット = %+v; want そのまま", msg)
+	}
+}
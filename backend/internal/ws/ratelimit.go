@@ -0,0 +1,196 @@
+// backend/internal/ws/ratelimit.go
+package ws
+
+import (
+	"expvar"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// 違反回数を数える期間。この期間を過ぎると違反回数はリセットされます
+	violationWindow = 10 * time.Second
+
+	// この回数までの違反はエラー応答のみ
+	warnViolations = 3
+
+	// この回数までの違反は読み取りを遅延させて抑制し、超えたら切断します
+	throttleViolations = 10
+
+	// 抑制時に読み取りを止める時間
+	throttleDelay = 500 * time.Millisecond
+
+	// 違反が続いた場合に送るクローズ理由
+	rateLimitCloseReason = "rate limit exceeded"
+)
+
+// rateRule はトークンバケットの補充レートとバースト数です
+type rateRule struct {
+	limit rate.Limit
+	burst int
+}
+
+// connectionRule は接続全体に適用される上限です
+var connectionRule = rateRule{limit: 20, burst: 40}
+
+// messageRules はメッセージタイプごとの上限です
+var messageRules = map[string]rateRule{
+	"findMatch":      {limit: rate.Every(time.Second), burst: 3},
+	"cancelMatch":    {limit: rate.Every(time.Second), burst: 3},
+	"gameAction":     {limit: 10, burst: 20},
+	"resync":         {limit: rate.Every(time.Second), burst: 3},
+	"acceptMatch":    {limit: rate.Every(time.Second), burst: 3},
+	"declineMatch":   {limit: rate.Every(time.Second), burst: 3},
+	"offerRematch":   {limit: rate.Every(time.Second), burst: 3},
+	"acceptRematch":  {limit: rate.Every(time.Second), burst: 3},
+	"declineRematch": {limit: rate.Every(time.Second), burst: 3},
+	"chooseFirst":    {limit: rate.Every(time.Second), burst: 3},
+	"createRoom":     {limit: rate.Every(time.Second), burst: 3},
+	"joinRoom":       {limit: rate.Every(time.Second), burst: 3},
+	"setReady":       {limit: 2, burst: 5},
+	"leaveRoom":      {limit: rate.Every(time.Second), burst: 3},
+	"chat":           {limit: 1, burst: 5},
+	"ping":           {limit: 2, burst: 5},
+	"test":           {limit: 2, burst: 5},
+}
+
+// defaultRuleKey は messageRules にないタイプ (ブロードキャスト等) に使うキーです
+const defaultRuleKey = "other"
+
+var defaultMessageRule = rateRule{limit: 2, burst: 5}
+
+// malformedMetricKey は解析できなかったメッセージのメトリクスのキーです
+const malformedMetricKey = "malformed"
+
+// rateLimitMetrics は拒否したメッセージ数を "<タイプ>.<対応>" ごとに集計します
+var rateLimitMetrics = expvar.NewMap("ws_rate_limited")
+
+// limitVerdict はレート制限の判定結果です
+type limitVerdict int
+
+const (
+	verdictAllow limitVerdict = iota
+	verdictReject
+	verdictThrottle
+	verdictDisconnect
+)
+
+// String はメトリクス用の判定名を返します
+func (v limitVerdict) String() string {
+	switch v {
+	case verdictReject:
+		return "rejected"
+	case verdictThrottle:
+		return "throttled"
+	case verdictDisconnect:
+		return "disconnected"
+	default:
+		return "allowed"
+	}
+}
+
+// rateLimiter は1接続分のレート制限状態を保持します
+//
+// readPumpのgoroutineからのみ使用するためロックは持ちません。
+type rateLimiter struct {
+	conn        *rate.Limiter
+	perType     map[string]*rate.Limiter
+	violations  int
+	windowStart time.Time
+}
+
+// newRateLimiter は新しいrateLimiterを作成します
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		conn:    rate.NewLimiter(connectionRule.limit, connectionRule.burst),
+		perType: make(map[string]*rate.Limiter),
+	}
+}
+
+// ruleKey はメッセージタイプに対応するルールのキーを返します
+func ruleKey(msgType string) string {
+	if _, ok := messageRules[msgType]; ok {
+		return msgType
+	}
+	return defaultRuleKey
+}
+
+// check はメッセージを処理してよいか判定し、違反が続く場合は段階的に厳しい判定を返します
+func (l *rateLimiter) check(msgType string, now time.Time) limitVerdict {
+	key := ruleKey(msgType)
+	limiter, ok := l.perType[key]
+	if !ok {
+		rule, found := messageRules[key]
+		if !found {
+			rule = defaultMessageRule
+		}
+		limiter = rate.NewLimiter(rule.limit, rule.burst)
+		l.perType[key] = limiter
+	}
+
+	// 接続全体とタイプ別の両方のトークンが必要
+	// タイプ別を先に判定し、拒否したメッセージで接続全体のトークンを消費しない
+	if l.allow(limiter, now) {
+		return verdictAllow
+	}
+	return l.violate(key, now)
+}
+
+// malformed は解析できなかったメッセージを違反として数え、続く場合は段階的に厳しい判定を返します
+//
+// 解析できないメッセージにはタイプがないため、トークンではなく違反回数だけで制限します。
+func (l *rateLimiter) malformed(now time.Time) limitVerdict {
+	return l.violate(malformedMetricKey, now)
+}
+
+// violate は違反を記録し、期間内の違反回数に応じた判定を返します
+func (l *rateLimiter) violate(key string, now time.Time) limitVerdict {
+	if now.Sub(l.windowStart) > violationWindow {
+		l.windowStart = now
+		l.violations = 0
+	}
+	l.violations++
+
+	var verdict limitVerdict
+	switch {
+	case l.violations <= warnViolations:
+		verdict = verdictReject
+	case l.violations <= throttleViolations:
+		verdict = verdictThrottle
+	default:
+		verdict = verdictDisconnect
+	}
+
+	rateLimitMetrics.Add(key+"."+verdict.String(), 1)
+	return verdict
+}
+
+// allow はタイプ別と接続全体のトークンを取得します
+//
+// タイプ別で拒否した場合は接続全体のトークンを消費せず、接続全体で拒否した
+// 場合はタイプ別のトークンを返却します。
+func (l *rateLimiter) allow(limiter *rate.Limiter, now time.Time) bool {
+	perType := reserve(limiter, now)
+	if perType == nil {
+		return false
+	}
+	if reserve(l.conn, now) == nil {
+		perType.CancelAt(now)
+		return false
+	}
+	return true
+}
+
+// reserve はトークンをすぐに取得できる場合だけ予約を返します
+func reserve(limiter *rate.Limiter, now time.Time) *rate.Reservation {
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return nil
+	}
+	if reservation.DelayFrom(now) > 0 {
+		reservation.CancelAt(now)
+		return nil
+	}
+	return reservation
+}
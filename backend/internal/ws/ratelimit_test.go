@@ -0,0 +1,73 @@
+// backend/internal/ws/ratelimit_test.go
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterTypeRejectKeepsConnectionTokens(t *testing.T) {
+	l := newRateLimiter()
+	now := time.Now()
+
+	// findMatch のバーストを使い切った後の拒否で、接続全体のトークンを消費しない
+	rule := messageRules["findMatch"]
+	for i := 0; i < rule.burst; i++ {
+		if v := l.check("findMatch", now); v != verdictAllow {
+			t.Fatalf("%d回目の findMatch = %v; want allowed", i+1, v)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if v := l.check("findMatch", now); v == verdictAllow {
+			t.Fatal("バーストを超えた findMatch が許可されました")
+		}
+	}
+
+	if got, want := l.conn.TokensAt(now), float64(connectionRule.burst-rule.burst); got != want {
+		t.Errorf("接続全体の残りトークン = %v; want %v", got, want)
+	}
+}
+
+func TestRateLimiterConnectionRejectRefundsTypeToken(t *testing.T) {
+	l := newRateLimiter()
+	now := time.Now()
+
+	// 接続全体のトークンを使い切る
+	if !l.conn.AllowN(now, connectionRule.burst) {
+		t.Fatal("接続全体のトークンを取得できませんでした")
+	}
+
+	if v := l.check("ping", now); v == verdictAllow {
+		t.Fatal("接続全体の上限を超えた ping が許可されました")
+	}
+	if got, want := l.perType["ping"].TokensAt(now), float64(messageRules["ping"].burst); got != want {
+		t.Errorf("ping の残りトークン = %v; want %v", got, want)
+	}
+}
+
+func TestRateLimiterMalformedEscalates(t *testing.T) {
+	l := newRateLimiter()
+	now := time.Now()
+
+	// 解析できないメッセージはトークンが残っていても違反として数える
+	for i := 1; i <= throttleViolations+1; i++ {
+		v := l.malformed(now)
+		var want limitVerdict
+		switch {
+		case i <= warnViolations:
+			want = verdictReject
+		case i <= throttleViolations:
+			want = verdictThrottle
+		default:
+			want = verdictDisconnect
+		}
+		if v != want {
+			t.Fatalf("%d回目の不正なメッセージ = %v; want %v", i, v, want)
+		}
+	}
+
+	// 期間を過ぎると違反回数はリセットされる
+	if v := l.malformed(now.Add(violationWindow + time.Second)); v != verdictReject {
+		t.Errorf("期間経過後の不正なメッセージ = %v; want %v", v, verdictReject)
+	}
+}
@@ -0,0 +1,267 @@
+// backend/internal/ws/rooms.go
+package ws
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/KOU050223/go-card/internal/game"
+)
+
+// roomOp は担当インスタンスに転送するプライベートルーム・承認待ちのマッチの操作です
+type roomOp struct {
+	Op     string `json:"op"` // "join", "ready", "leave", "accept", "decline"
+	UserID string `json:"userId"`
+	Code   string `json:"code,omitempty"`
+	Ready  bool   `json:"ready,omitempty"`
+}
+
+// roomCodeKey は招待コードのルームを保持しているインスタンスの所有者キーを返します
+func roomCodeKey(code string) string {
+	return "roomCode:" + code
+}
+
+// roomMemberKey はユーザーが参加しているルーム (プライベートルーム・承認待ちのマッチ) を
+// 保持しているインスタンスの所有者キーを返します
+func roomMemberKey(userID string) string {
+	return "roomMember:" + userID
+}
+
+// AcceptMatch は承認待ちのマッチを承認します
+//
+// マッチが別インスタンスにある場合は承認期限のタイマーを持つ担当インスタンスに転送し、nil のルームを返します。
+func (h *Hub) AcceptMatch(ctx context.Context, userID string) (*game.Room, error) {
+	owner, err := h.roomOwner(userID)
+	if errors.Is(err, game.ErrRoomNotFound) {
+		return nil, game.ErrNoPendingMatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	if owner == h.instanceID {
+		return h.matchmakingService.AcceptMatch(ctx, userID)
+	}
+
+	op := &roomOp{Op: "accept", UserID: userID}
+	return nil, h.publish(instanceChannel(owner), &envelope{Kind: "room", Room: op})
+}
+
+// DeclineMatch は承認待ちのマッチを辞退します
+//
+// マッチが別インスタンスにある場合は担当インスタンスに転送して true を返します。
+func (h *Hub) DeclineMatch(ctx context.Context, userID string) (bool, error) {
+	owner, err := h.roomOwner(userID)
+	if errors.Is(err, game.ErrRoomNotFound) {
+		return false, game.ErrNoPendingMatch
+	}
+	if err != nil {
+		return false, err
+	}
+	if owner == h.instanceID {
+		return false, h.matchmakingService.DeclineMatch(ctx, userID)
+	}
+
+	op := &roomOp{Op: "decline", UserID: userID}
+	return true, h.publish(instanceChannel(owner), &envelope{Kind: "room", Room: op})
+}
+
+// CreatePrivateRoom はプライベートルームを作成し、このインスタンスをルームの担当として登録します
+//
+// 別インスタンスのルームに参加している場合は game.ErrAlreadyInRoom を返します。
+func (h *Hub) CreatePrivateRoom(userID string, bestOf int) (*game.Room, error) {
+	if err := h.checkRoomMember(userID, ""); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		room, err := h.matchmakingService.CreatePrivateRoom(userID, bestOf)
+		if err != nil {
+			return nil, err
+		}
+
+		// 招待コードはインスタンスごとに生成するため、別インスタンスで使用中なら作り直す
+		// (確認と登録の間に他のインスタンスが同じコードを登録しないよう、空いている場合だけ登録する)
+		claimed, err := h.claimIfAbsent(roomCodeKey(room.Code))
+		if err == nil && claimed {
+			h.claimRoom(room)
+			return room, nil
+		}
+		if leaveErr := h.matchmakingService.LeavePrivateRoom(userID); leaveErr != nil {
+			log.Printf("重複した招待コードのルーム削除エラー (ルーム: %s): %v", room.ID, leaveErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, errors.New("招待コードの空きがありません")
+}
+
+// JoinPrivateRoom は招待コードでプライベートルームに参加します
+//
+// ルームが別インスタンスにある場合は担当インスタンスに転送し、nil のルームを返します。
+func (h *Hub) JoinPrivateRoom(userID, code string) (*game.Room, error) {
+	if h.matchmakingService.HasRoomCode(code) {
+		return h.joinLocalRoom(userID, code)
+	}
+
+	owner, err := h.owner(roomCodeKey(game.NormalizeRoomCode(code)))
+	if err != nil {
+		return nil, err
+	}
+	if owner == "" || owner == h.instanceID {
+		return nil, game.ErrRoomNotFound
+	}
+	if err := h.checkRoomMember(userID, owner); err != nil {
+		return nil, err
+	}
+
+	op := &roomOp{Op: "join", UserID: userID, Code: code}
+	return nil, h.publish(instanceChannel(owner), &envelope{Kind: "room", Room: op})
+}
+
+// SetReady はプライベートルームでの準備状態を設定します
+//
+// ルームが別インスタンスにある場合は担当インスタンスに転送し、nil のルームを返します。
+func (h *Hub) SetReady(userID string, ready bool) (*game.Room, error) {
+	owner, err := h.roomOwner(userID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == h.instanceID {
+		return h.matchmakingService.SetReady(userID, ready)
+	}
+
+	op := &roomOp{Op: "ready", UserID: userID, Ready: ready}
+	return nil, h.publish(instanceChannel(owner), &envelope{Kind: "room", Room: op})
+}
+
+// LeavePrivateRoom は対戦開始前のプライベートルームから退出します
+//
+// ルームが別インスタンスにある場合は担当インスタンスに転送して true を返します。
+// 転送先での退出後、本人には roomLeft が送信されます。
+func (h *Hub) LeavePrivateRoom(userID string) (bool, error) {
+	owner, err := h.roomOwner(userID)
+	if err != nil {
+		return false, err
+	}
+	if owner == h.instanceID {
+		return false, h.leaveLocalRoom(userID)
+	}
+
+	op := &roomOp{Op: "leave", UserID: userID}
+	return true, h.publish(instanceChannel(owner), &envelope{Kind: "room", Room: op})
+}
+
+// handleRoomOp は別インスタンスから転送されたルームの操作を処理します
+//
+// 結果は参加者への roomUpdate・matchFound などで通知され、失敗した場合は操作したユーザーに error を送信します。
+func (h *Hub) handleRoomOp(op *roomOp) {
+	var err error
+	switch op.Op {
+	case "join":
+		_, err = h.joinLocalRoom(op.UserID, op.Code)
+	case "ready":
+		_, err = h.matchmakingService.SetReady(op.UserID, op.Ready)
+	case "leave":
+		if err = h.leaveLocalRoom(op.UserID); err == nil {
+			err = h.SendToUser(op.UserID, &Message{Type: "roomLeft", UserID: op.UserID})
+		}
+	case "accept":
+		_, err = h.matchmakingService.AcceptMatch(context.Background(), op.UserID)
+	case "decline":
+		err = h.matchmakingService.DeclineMatch(context.Background(), op.UserID)
+	default:
+		log.Printf("未知のルーム操作を破棄しました: %q", op.Op)
+		return
+	}
+	if err == nil {
+		return
+	}
+
+	log.Printf("転送されたルーム操作の処理エラー (操作: %s, ユーザー: %s): %v", op.Op, op.UserID, err)
+	message := &Message{Type: "error", UserID: op.UserID, Content: map[string]string{"message": err.Error()}}
+	if err := h.SendToUser(op.UserID, message); err != nil {
+		log.Printf("ルーム操作エラー通知エラー (ユーザー: %s): %v", op.UserID, err)
+	}
+}
+
+// joinLocalRoom はこのインスタンスのプライベートルームに参加し、参加者の担当として登録します
+func (h *Hub) joinLocalRoom(userID, code string) (*game.Room, error) {
+	room, err := h.matchmakingService.JoinPrivateRoom(userID, code)
+	if err != nil {
+		return nil, err
+	}
+	h.claim(roomMemberKey(userID))
+	return room, nil
+}
+
+// leaveLocalRoom はこのインスタンスのプライベートルームから退出し、担当の登録を解放します
+//
+// ルームが削除された場合、残っていた参加者の登録は onRoomUpdated で解放します。
+func (h *Hub) leaveLocalRoom(userID string) error {
+	room, err := h.matchmakingService.GetUserRoom(userID)
+	if err != nil {
+		return game.ErrRoomNotFound
+	}
+	code := room.Code
+	if err := h.matchmakingService.LeavePrivateRoom(userID); err != nil {
+		return err
+	}
+
+	h.release(roomMemberKey(userID))
+	if code != "" && !h.matchmakingService.HasRoomCode(code) {
+		h.release(roomCodeKey(code))
+	}
+	return nil
+}
+
+// roomOwner はユーザーが参加しているルームを保持しているインスタンスを返します
+//
+// どのインスタンスのルームにも参加していない場合は game.ErrRoomNotFound を返します。
+func (h *Hub) roomOwner(userID string) (string, error) {
+	if _, err := h.matchmakingService.GetUserRoom(userID); err == nil {
+		return h.instanceID, nil
+	}
+
+	owner, err := h.owner(roomMemberKey(userID))
+	if err != nil {
+		return "", err
+	}
+	if owner == "" || owner == h.instanceID {
+		return "", game.ErrRoomNotFound
+	}
+	return owner, nil
+}
+
+// checkRoomMember はユーザーが owner 以外のインスタンスのルームに参加していないかを確認します
+func (h *Hub) checkRoomMember(userID, owner string) error {
+	if _, err := h.matchmakingService.GetUserRoom(userID); err == nil && owner != "" {
+		return game.ErrAlreadyInRoom
+	}
+
+	current, err := h.owner(roomMemberKey(userID))
+	if err != nil {
+		return err
+	}
+	if current != "" && current != h.instanceID && current != owner {
+		return game.ErrAlreadyInRoom
+	}
+	return nil
+}
+
+// claimRoom はプライベートルームの招待コードと参加者の担当としてこのインスタンスを登録します
+func (h *Hub) claimRoom(room *game.Room) {
+	h.claim(roomCodeKey(room.Code))
+	for _, player := range room.Players {
+		h.claim(roomMemberKey(player.UserID))
+	}
+}
+
+// releaseRoom はプライベートルームの招待コードと参加者の担当の登録を解放します
+func (h *Hub) releaseRoom(room *game.Room) {
+	h.release(roomCodeKey(room.Code))
+	for _, player := range room.Players {
+		h.release(roomMemberKey(player.UserID))
+	}
+}
@@ -0,0 +1,204 @@
+// backend/internal/ws/rpc.go
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxGatherReplies は Gather で待つことができる応答の数 (インスタンス数) の上限です
+const maxGatherReplies = 64
+
+// errCallTimeout は呼び出したインスタンスから期限内に応答がなかったことを表します
+var errCallTimeout = errors.New("他のインスタンスからの応答がありません")
+
+// Handler は他のインスタンスから呼び出せる処理です
+//
+// 戻り値はJSONに変換して呼び出し元へ返します。他のパッケージが ws に依存せずに
+// 同じシグネチャの関数を渡せるよう、型の別名として定義しています。
+type Handler = func(args json.RawMessage) (interface{}, error)
+
+// RemoteError は他のインスタンスで処理がエラーになったことを表します
+//
+// errors.Is はメッセージが同じエラーと一致するため、呼び出し側は番兵エラーを
+// このインスタンスで処理した場合と同じように判定できます。
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// Is はメッセージが同じエラーを同じエラーとして扱います
+func (e *RemoteError) Is(target error) bool {
+	return target != nil && target.Error() == e.Message
+}
+
+// remoteCall は他のインスタンスで実行する処理の呼び出しです
+type remoteCall struct {
+	ID     string          `json:"id"`
+	Method string          `json:"method"`
+	Args   json.RawMessage `json:"args,omitempty"`
+}
+
+// remoteReply は remoteCall の結果です
+type remoteReply struct {
+	ID     string          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Handle は他のインスタンスから Call・Gather で呼び出せる処理を登録します
+func (h *Hub) Handle(method string, handler Handler) {
+	h.callMu.Lock()
+	defer h.callMu.Unlock()
+	h.handlers[method] = handler
+}
+
+// Call は instanceID のインスタンスで method を実行し、結果を result に読み込みます
+//
+// このインスタンスを指定した場合はBackplaneを経由せずに実行します。
+// 実行先のエラーは *RemoteError として返します。
+func (h *Hub) Call(instanceID, method string, args, result interface{}) error {
+	call, err := newRemoteCall(method, args)
+	if err != nil {
+		return err
+	}
+
+	var res json.RawMessage
+	if instanceID == h.instanceID {
+		if res, err = h.invoke(call); err != nil {
+			return err
+		}
+	} else {
+		replies, done := h.await(call.ID)
+		defer done()
+		if err := h.publish(instanceChannel(instanceID), &envelope{Kind: "call", Call: call}); err != nil {
+			return err
+		}
+		select {
+		case reply := <-replies:
+			if reply.Error != "" {
+				return &RemoteError{Message: reply.Error}
+			}
+			res = reply.Result
+		case <-time.After(backplaneTimeout):
+			return fmt.Errorf("%w (%s, インスタンス: %s)", errCallTimeout, method, instanceID)
+		}
+	}
+
+	if result == nil || len(res) == 0 {
+		return nil
+	}
+	return json.Unmarshal(res, result)
+}
+
+// Gather は他のすべてのインスタンスで method を実行し、各インスタンスの結果を返します
+//
+// Backplaneが配送したインスタンスの数だけ応答を待ちます。いずれかのインスタンスで
+// エラーになった場合や、期限内に応答がそろわない場合はエラーを返します。
+func (h *Hub) Gather(method string, args interface{}) ([]json.RawMessage, error) {
+	call, err := newRemoteCall(method, args)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, done := h.await(call.ID)
+	defer done()
+	receivers, err := h.publishCount(broadcastChannel, &envelope{Kind: "call", Call: call})
+	if err != nil {
+		return nil, err
+	}
+
+	// このインスタンスも broadcast を購読しているため、配送先から除く
+	expected := receivers - 1
+	results := make([]json.RawMessage, 0, expected)
+	timeout := time.NewTimer(backplaneTimeout)
+	defer timeout.Stop()
+	for len(results) < expected {
+		select {
+		case reply := <-replies:
+			if reply.Error != "" {
+				return nil, &RemoteError{Message: reply.Error}
+			}
+			results = append(results, reply.Result)
+		case <-timeout.C:
+			return nil, fmt.Errorf("%w (%s, 応答: %d/%d)", errCallTimeout, method, len(results), expected)
+		}
+	}
+	return results, nil
+}
+
+// newRemoteCall は引数をJSONに変換して呼び出しを作成します
+func newRemoteCall(method string, args interface{}) (*remoteCall, error) {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("呼び出しの引数の変換エラー (%s): %w", method, err)
+	}
+	return &remoteCall{ID: uuid.New().String(), Method: method, Args: raw}, nil
+}
+
+// await は呼び出しへの応答の受け取りを登録し、受け取りを終える関数とともに返します
+func (h *Hub) await(callID string) (<-chan *remoteReply, func()) {
+	replies := make(chan *remoteReply, maxGatherReplies)
+	h.callMu.Lock()
+	h.pending[callID] = replies
+	h.callMu.Unlock()
+
+	return replies, func() {
+		h.callMu.Lock()
+		delete(h.pending, callID)
+		h.callMu.Unlock()
+	}
+}
+
+// invoke は登録された処理を実行し、結果をJSONで返します
+func (h *Hub) invoke(call *remoteCall) (json.RawMessage, error) {
+	h.callMu.RLock()
+	handler, ok := h.handlers[call.Method]
+	h.callMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未登録の処理です: %s", call.Method)
+	}
+
+	result, err := handler(call.Args)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// answer は他のインスタンスからの呼び出しを実行し、結果を呼び出し元へ返します
+func (h *Hub) answer(origin string, call *remoteCall) {
+	reply := &remoteReply{ID: call.ID}
+	result, err := h.invoke(call)
+	if err != nil {
+		reply.Error = err.Error()
+	} else {
+		reply.Result = result
+	}
+	if err := h.publish(instanceChannel(origin), &envelope{Kind: "reply", Reply: reply}); err != nil {
+		log.Printf("呼び出しの応答エラー (%s, 呼び出し元: %s): %v", call.Method, origin, err)
+	}
+}
+
+// deliverReply は呼び出しへの応答を待っている呼び出し元に渡します
+func (h *Hub) deliverReply(reply *remoteReply) {
+	h.callMu.RLock()
+	replies, ok := h.pending[reply.ID]
+	h.callMu.RUnlock()
+	if !ok {
+		// 期限切れ後に届いた応答
+		return
+	}
+	select {
+	case replies <- reply:
+	default:
+	}
+}
@@ -0,0 +1,220 @@
+// backend/internal/ws/ticket.go
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultTicketTTL は接続チケットの既定の有効期間です
+//
+// チケットは発行直後にWebSocket接続で使う前提のため、短く設定しています。
+const DefaultTicketTTL = 30 * time.Second
+
+// ErrInvalidTicket はチケットが存在しない・期限切れ・使用済みであることを表します
+var ErrInvalidTicket = errors.New("無効な接続チケットです")
+
+// TicketStore は接続チケットを保存する場所です
+//
+// 複数インスタンスで動かす場合、チケットを発行したインスタンスと接続を受ける
+// インスタンスが異なることがあるため、共有ストア (Redis) を使います。
+type TicketStore interface {
+	// Put はチケットに値をttl付きで保存します
+	Put(ctx context.Context, ticket string, value []byte, ttl time.Duration) error
+
+	// Take はチケットの値を取り出して削除します。存在しない場合は nil を返します
+	Take(ctx context.Context, ticket string) ([]byte, error)
+}
+
+// MemoryTicketStore はプロセス内で完結するTicketStoreの実装です
+type MemoryTicketStore struct {
+	mu      sync.Mutex
+	tickets map[string]memoryTicket
+}
+
+type memoryTicket struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryTicketStore は新しいMemoryTicketStoreを作成します
+func NewMemoryTicketStore() *MemoryTicketStore {
+	return &MemoryTicketStore{tickets: make(map[string]memoryTicket)}
+}
+
+// Put はチケットを保存し、期限切れのチケットを削除します
+func (s *MemoryTicketStore) Put(ctx context.Context, ticket string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, t := range s.tickets {
+		if now.After(t.expires) {
+			delete(s.tickets, key)
+		}
+	}
+	s.tickets[ticket] = memoryTicket{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+// Take はチケットを取り出して削除します
+func (s *MemoryTicketStore) Take(ctx context.Context, ticket string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tickets[ticket]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.tickets, ticket)
+	if time.Now().After(t.expires) {
+		return nil, nil
+	}
+	return t.value, nil
+}
+
+// redisTicketStore はRedisBackplaneの接続を共有するTicketStoreの実装です
+type redisTicketStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// TicketStore はBackplaneと同じRedis接続を使うTicketStoreを返します
+func (b *RedisBackplane) TicketStore() TicketStore {
+	return &redisTicketStore{client: b.client, prefix: b.prefix + "ticket:"}
+}
+
+// Put はチケットをttl付きで保存します
+func (s *redisTicketStore) Put(ctx context.Context, ticket string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+ticket, value, ttl).Err()
+}
+
+// Take はGETDELでチケットを取り出して削除します (同じチケットを二度使えないようにする)
+func (s *redisTicketStore) Take(ctx context.Context, ticket string) ([]byte, error) {
+	value, err := s.client.GetDel(ctx, s.prefix+ticket).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// Ticket は接続チケットに紐づく情報です
+type Ticket struct {
+	UserID string `json:"userId"`
+	// DuelID が空でない場合、そのDuelへの接続にのみ使えます
+	DuelID string `json:"duelId,omitempty"`
+}
+
+// Tickets はWebSocket接続用の一回限りのチケットを発行・検証します
+//
+// IDトークンをクエリパラメータに載せるとアクセスログに残るため、WebSocket接続では
+// 認証済みのHTTPリクエストで発行した短命のチケットを代わりに使います。
+type Tickets struct {
+	store TicketStore
+	ttl   time.Duration
+}
+
+// NewTickets はTicketsを作成します。ttlが0以下の場合は DefaultTicketTTL を使います
+func NewTickets(store TicketStore, ttl time.Duration) *Tickets {
+	if ttl <= 0 {
+		ttl = DefaultTicketTTL
+	}
+	return &Tickets{store: store, ttl: ttl}
+}
+
+// Issue はユーザー (とDuel) に紐づくチケットを発行します
+func (t *Tickets) Issue(ctx context.Context, userID, duelID string) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("チケット生成エラー: %w", err)
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(buf)
+
+	value, err := json.Marshal(Ticket{UserID: userID, DuelID: duelID})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("チケットのシリアライズエラー: %w", err)
+	}
+	expiresAt := time.Now().Add(t.ttl)
+	if err := t.store.Put(ctx, ticket, value, t.ttl); err != nil {
+		return "", time.Time{}, fmt.Errorf("チケット保存エラー: %w", err)
+	}
+	return ticket, expiresAt, nil
+}
+
+// Redeem はチケットを使用済みにし、紐づく情報を返します
+func (t *Tickets) Redeem(ctx context.Context, ticket string) (*Ticket, error) {
+	if ticket == "" {
+		return nil, ErrInvalidTicket
+	}
+	value, err := t.store.Take(ctx, ticket)
+	if err != nil {
+		return nil, fmt.Errorf("チケット取得エラー: %w", err)
+	}
+	if value == nil {
+		return nil, ErrInvalidTicket
+	}
+
+	var info Ticket
+	if err := json.Unmarshal(value, &info); err != nil || info.UserID == "" {
+		return nil, ErrInvalidTicket
+	}
+	return &info, nil
+}
+
+// RedeemFor はチケットを使用済みにし、duelIDへの接続に使えればユーザーIDを返します
+//
+// Duelに紐づくチケットは、そのDuel以外への接続には使えません。
+func (t *Tickets) RedeemFor(ctx context.Context, ticket, duelID string) (string, error) {
+	info, err := t.Redeem(ctx, ticket)
+	if err != nil {
+		return "", err
+	}
+	if info.DuelID != "" && info.DuelID != duelID {
+		return "", ErrInvalidTicket
+	}
+	return info.UserID, nil
+}
+
+// TicketAPI は接続チケットを発行するREST APIです
+type TicketAPI struct {
+	Tickets *Tickets
+}
+
+// NewTicketAPI はTicketAPIを作成します
+func NewTicketAPI(tickets *Tickets) *TicketAPI {
+	return &TicketAPI{Tickets: tickets}
+}
+
+type ticketRequest struct {
+	DuelID string `json:"duelId"`
+}
+
+// Issue は接続チケットを発行します
+// POST /api/ws-ticket
+func (a *TicketAPI) Issue(c echo.Context) error {
+	uid := c.Get("uid").(string)
+
+	var req ticketRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "リクエストが不正です")
+	}
+
+	ticket, expiresAt, err := a.Tickets.Issue(c.Request().Context(), uid, req.DuelID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "チケットの発行に失敗しました")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"ticket":    ticket,
+		"expiresAt": expiresAt,
+	})
+}
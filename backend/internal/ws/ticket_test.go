@@ -0,0 +1,79 @@
+// backend/internal/ws/ticket_test.go
+package ws
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTicketsRedeemOnce(t *testing.T) {
+	tickets := NewTickets(NewMemoryTicketStore(), time.Minute)
+	ctx := context.Background()
+
+	ticket, _, err := tickets.Issue(ctx, "u1", "")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	info, err := tickets.Redeem(ctx, ticket)
+	if err != nil {
+		t.Fatalf("Redeem: %v", err)
+	}
+	if info.UserID != "u1" {
+		t.Errorf("チケットのユーザー = %q; want u1", info.UserID)
+	}
+
+	// 使用済みのチケットは二度使えない
+	if _, err := tickets.Redeem(ctx, ticket); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("2回目の Redeem のエラー = %v; want ErrInvalidTicket", err)
+	}
+	if _, err := tickets.Redeem(ctx, ""); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("空のチケットのエラー = %v; want ErrInvalidTicket", err)
+	}
+}
+
+func TestTicketsRedeemExpired(t *testing.T) {
+	tickets := NewTickets(NewMemoryTicketStore(), 20*time.Millisecond)
+	ctx := context.Background()
+
+	ticket, _, err := tickets.Issue(ctx, "u1", "")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := tickets.Redeem(ctx, ticket); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("期限切れのチケットのエラー = %v; want ErrInvalidTicket", err)
+	}
+}
+
+func TestTicketsRedeemForDuel(t *testing.T) {
+	tickets := NewTickets(NewMemoryTicketStore(), time.Minute)
+	ctx := context.Background()
+
+	// Duelに紐づくチケットは他のDuelへの接続に使えない
+	ticket, _, err := tickets.Issue(ctx, "u1", "d1")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if _, err := tickets.RedeemFor(ctx, ticket, "d2"); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("別のDuelへの RedeemFor のエラー = %v; want ErrInvalidTicket", err)
+	}
+
+	ticket, _, err = tickets.Issue(ctx, "u1", "d1")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if userID, err := tickets.RedeemFor(ctx, ticket, "d1"); err != nil || userID != "u1" {
+		t.Errorf("紐づいたDuelへの RedeemFor = %q, %v; want u1", userID, err)
+	}
+
+	// Duelに紐づかないチケットはどのDuelへの接続にも使える
+	ticket, _, err = tickets.Issue(ctx, "u1", "")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if userID, err := tickets.RedeemFor(ctx, ticket, "d2"); err != nil || userID != "u1" {
+		t.Errorf("Duelに紐づかないチケットの RedeemFor = %q, %v; want u1", userID, err)
+	}
+}
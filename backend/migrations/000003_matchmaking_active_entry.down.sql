@@ -0,0 +1,6 @@
+-- backend/migrations/000003_matchmaking_active_entry.down.sql
+ALTER TABLE matchmaking
+  DROP INDEX idx_matchmaking_user,
+  DROP INDEX idx_matchmaking_status_created,
+  DROP INDEX uq_matchmaking_active_user,
+  DROP COLUMN active_user_id;
@@ -0,0 +1,19 @@
+-- backend/migrations/000003_matchmaking_active_entry.up.sql
+-- 同じユーザーの重複した待機エントリは最新以外をキャンセル
+UPDATE matchmaking m
+JOIN (
+  SELECT user_id, MAX(id) AS keep_id
+  FROM matchmaking
+  WHERE status = 'waiting'
+  GROUP BY user_id
+) latest ON latest.user_id = m.user_id
+SET m.status = 'cancelled'
+WHERE m.status = 'waiting' AND m.id <> latest.keep_id;
+
+-- 待機中 (waiting) のエントリはユーザーごとに1件まで
+ALTER TABLE matchmaking
+  ADD COLUMN active_user_id VARCHAR(128)
+    GENERATED ALWAYS AS (IF(status = 'waiting', user_id, NULL)) STORED,
+  ADD UNIQUE KEY uq_matchmaking_active_user (active_user_id),
+  ADD INDEX idx_matchmaking_status_created (status, created_at),
+  ADD INDEX idx_matchmaking_user (user_id);
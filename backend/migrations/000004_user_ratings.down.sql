@@ -0,0 +1,12 @@
+-- backend/migrations/000004_user_ratings.down.sql
+ALTER TABLE matchmaking
+  DROP COLUMN rating;
+
+-- レーティング導入後に作成したユーザーは退避した値がないため現在の points のまま
+UPDATE users SET points = legacy_points WHERE legacy_points IS NOT NULL;
+
+ALTER TABLE users
+  DROP COLUMN legacy_points,
+  DROP COLUMN rating_volatility,
+  DROP COLUMN rating_rd,
+  DROP COLUMN rating;
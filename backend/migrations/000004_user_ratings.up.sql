@@ -0,0 +1,14 @@
+-- backend/migrations/000004_user_ratings.up.sql
+-- Glicko-2 レーティング (points は表示用に rating を丸めた値を保持)
+ALTER TABLE users
+  ADD COLUMN rating DOUBLE NOT NULL DEFAULT 1500,
+  ADD COLUMN rating_rd DOUBLE NOT NULL DEFAULT 350,
+  ADD COLUMN rating_volatility DOUBLE NOT NULL DEFAULT 0.06,
+  ADD COLUMN legacy_points INT NULL COMMENT 'レーティング導入前の points (down で復元)';
+
+-- 導入前の points を退避してから初期レーティングに揃える
+UPDATE users SET legacy_points = points, points = 1500;
+
+-- マッチング時のレーティング差の判定に使用
+ALTER TABLE matchmaking
+  ADD COLUMN rating DOUBLE NOT NULL DEFAULT 1500;
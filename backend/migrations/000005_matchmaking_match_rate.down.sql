@@ -0,0 +1,3 @@
+-- backend/migrations/000005_matchmaking_match_rate.down.sql
+ALTER TABLE matchmaking
+  DROP INDEX idx_matchmaking_status_updated;
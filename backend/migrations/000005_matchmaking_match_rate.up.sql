@@ -0,0 +1,4 @@
+-- backend/migrations/000005_matchmaking_match_rate.up.sql
+-- 待ち時間の見積もりに使う直近のマッチ数の集計用
+ALTER TABLE matchmaking
+  ADD INDEX idx_matchmaking_status_updated (status, updated_at);
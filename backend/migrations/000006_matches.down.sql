@@ -0,0 +1,9 @@
+-- backend/migrations/000006_matches.down.sql
+ALTER TABLE duels
+  DROP FOREIGN KEY fk_duels_match,
+  DROP INDEX idx_duels_match,
+  DROP COLUMN updated_at,
+  DROP COLUMN game_number,
+  DROP COLUMN match_id;
+
+DROP TABLE IF EXISTS matches;
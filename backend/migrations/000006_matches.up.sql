@@ -0,0 +1,27 @@
+-- backend/migrations/000006_matches.up.sql
+-- Best-of-N のシリーズ。各試合は duels に match_id と game_number 付きで保存する
+CREATE TABLE IF NOT EXISTS matches (
+  id VARCHAR(36) PRIMARY KEY,
+  player1_id VARCHAR(128) NOT NULL,
+  player2_id VARCHAR(128) NOT NULL,
+  best_of TINYINT NOT NULL,
+  player1_wins TINYINT NOT NULL DEFAULT 0,
+  player2_wins TINYINT NOT NULL DEFAULT 0,
+  draws TINYINT NOT NULL DEFAULT 0,
+  status ENUM('active', 'choosing', 'finished') NOT NULL DEFAULT 'active',
+  winner_id VARCHAR(128),
+  finished_at TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (player1_id) REFERENCES users(id),
+  FOREIGN KEY (player2_id) REFERENCES users(id),
+  FOREIGN KEY (winner_id) REFERENCES users(id)
+);
+
+-- DuelEntry.UpdatedAt に合わせて updated_at も追加
+ALTER TABLE duels
+  ADD COLUMN match_id VARCHAR(36) NULL,
+  ADD COLUMN game_number TINYINT NULL,
+  ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  ADD INDEX idx_duels_match (match_id, game_number),
+  ADD CONSTRAINT fk_duels_match FOREIGN KEY (match_id) REFERENCES matches(id);
@@ -0,0 +1,2 @@
+-- backend/migrations/000007_user_credentials.down.sql
+DROP TABLE IF EXISTS user_credentials;
@@ -0,0 +1,11 @@
+-- backend/migrations/000007_user_credentials.up.sql
+-- 自前のJWT認証 (AUTH_MODE=jwt) で使うログイン名とbcryptのパスワードハッシュ
+CREATE TABLE IF NOT EXISTS user_credentials (
+  user_id VARCHAR(128) PRIMARY KEY,
+  login_name VARCHAR(64) NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uq_user_credentials_login_name (login_name),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- backend/migrations/000008_unique_usernames.down.sql
+ALTER TABLE users
+  DROP INDEX uq_users_username;
@@ -0,0 +1,13 @@
+-- backend/migrations/000008_unique_usernames.up.sql
+-- ユーザー名を一意にする (既存の重複は2件目以降にユーザーIDを付けて解消)
+UPDATE users u
+  JOIN (
+    SELECT username, MIN(id) AS keep_id
+    FROM users
+    GROUP BY username
+    HAVING COUNT(*) > 1
+  ) d ON u.username = d.username AND u.id <> d.keep_id
+SET u.username = LEFT(CONCAT(u.username, '-', u.id), 255);
+
+ALTER TABLE users
+  ADD UNIQUE KEY uq_users_username (username);
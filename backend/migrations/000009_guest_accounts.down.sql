@@ -0,0 +1,3 @@
+-- backend/migrations/000009_guest_accounts.down.sql
+ALTER TABLE users
+  DROP COLUMN is_guest;
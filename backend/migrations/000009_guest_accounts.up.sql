@@ -0,0 +1,4 @@
+-- backend/migrations/000009_guest_accounts.up.sql
+-- サーバーが発行したゲストアカウント (Firebase・自前のアカウントに連携すると FALSE になる)
+ALTER TABLE users
+  ADD COLUMN is_guest BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+-- backend/migrations/000010_roles_and_bans.down.sql
+DROP TABLE IF EXISTS user_bans;
+
+ALTER TABLE users
+  DROP COLUMN role;
@@ -0,0 +1,17 @@
+-- backend/migrations/000010_roles_and_bans.up.sql
+-- ユーザーの権限 (player: 一般, moderator: 対戦の監視・BAN, admin: すべての管理操作)
+ALTER TABLE users
+  ADD COLUMN role ENUM('player', 'moderator', 'admin') NOT NULL DEFAULT 'player';
+
+-- BANの記録 (解除しても行は残す)
+CREATE TABLE IF NOT EXISTS user_bans (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  user_id VARCHAR(128) NOT NULL,
+  reason VARCHAR(255) NOT NULL DEFAULT '',
+  banned_by VARCHAR(128) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  revoked_at TIMESTAMP NULL,
+  revoked_by VARCHAR(128),
+  INDEX idx_user_bans_user (user_id, revoked_at),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
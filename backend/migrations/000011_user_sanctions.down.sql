@@ -0,0 +1,13 @@
+-- backend/migrations/000011_user_sanctions.down.sql
+DROP TABLE IF EXISTS moderation_audit_log;
+
+-- BAN以外の制裁は元の表では表せないため削除する
+DELETE FROM user_sanctions WHERE kind <> 'ban';
+
+ALTER TABLE user_sanctions
+  DROP COLUMN kind,
+  DROP COLUMN expires_at,
+  CHANGE COLUMN created_by banned_by VARCHAR(128) NOT NULL,
+  RENAME INDEX idx_user_sanctions_user TO idx_user_bans_user;
+
+RENAME TABLE user_sanctions TO user_bans;
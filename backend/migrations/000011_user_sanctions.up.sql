@@ -0,0 +1,22 @@
+-- backend/migrations/000011_user_sanctions.up.sql
+-- BANの記録を、期限付きの利用停止とチャット禁止を含む制裁の記録に拡張する
+RENAME TABLE user_bans TO user_sanctions;
+
+ALTER TABLE user_sanctions
+  ADD COLUMN kind ENUM('ban', 'suspend', 'mute') NOT NULL DEFAULT 'ban' AFTER user_id,
+  ADD COLUMN expires_at TIMESTAMP NULL AFTER created_at,
+  CHANGE COLUMN banned_by created_by VARCHAR(128) NOT NULL,
+  RENAME INDEX idx_user_bans_user TO idx_user_sanctions_user;
+
+-- 管理操作の記録 (誰がいつ何をしたか)
+CREATE TABLE IF NOT EXISTS moderation_audit_log (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  actor_id VARCHAR(128) NOT NULL,
+  action VARCHAR(64) NOT NULL,
+  target_type VARCHAR(32) NOT NULL,
+  target_id VARCHAR(128) NOT NULL,
+  details VARCHAR(512) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_moderation_audit_log_target (target_type, target_id, created_at),
+  INDEX idx_moderation_audit_log_actor (actor_id, created_at)
+);
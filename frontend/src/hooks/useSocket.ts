@@ -18,6 +18,58 @@ interface SocketMessage {
   [key: string]: any;
 }
 
+/** JSON Patch (RFC 6902) の1操作 (サーバーが生成するのは add / remove / replace のみ) */
+interface PatchOp {
+  op: 'add' | 'remove' | 'replace';
+  path: string;
+  value?: any;
+}
+
+/** サーバーが前回送信した対戦状態からの差分 (duelPatch) */
+interface DuelPatch {
+  duelId: string;
+  baseVersion: number;
+  version: number;
+  ops: PatchOp[];
+}
+
+/**
+ * JSON Pointer をキーの配列に分解する
+ */
+const parsePointer = (path: string): string[] =>
+  path === ''
+    ? []
+    : path.slice(1).split('/').map((key) => key.replace(/~1/g, '/').replace(/~0/g, '~'));
+
+/**
+ * 対戦状態に差分を適用した新しい状態を返す (適用できない場合は null)
+ */
+const applyDuelPatch = (doc: any, ops: PatchOp[]): any | null => {
+  let root = structuredClone(doc);
+  for (const { op, path, value } of ops) {
+    const keys = parsePointer(path);
+    if (keys.length === 0) {
+      if (op === 'remove') return null;
+      root = structuredClone(value);
+      continue;
+    }
+    let parent = root;
+    for (const key of keys.slice(0, -1)) {
+      parent = parent?.[key];
+    }
+    if (parent === null || typeof parent !== 'object') return null;
+
+    const last = keys[keys.length - 1];
+    if (op === 'remove') {
+      if (Array.isArray(parent)) parent.splice(Number(last), 1);
+      else delete parent[last];
+    } else {
+      parent[last] = structuredClone(value);
+    }
+  }
+  return root;
+};
+
 /**
  * Custom hook for WebSocket connection management
  * @param options - Configuration options for the WebSocket connection
@@ -54,6 +106,10 @@ export const useSocket = (options: UseSocketOptions = {}) => {
   const pingIntervalRef = useRef<NodeJS.Timeout | null>(null);
   const reconnectAttemptsRef = useRef(0);
   const isManualCloseRef = useRef(false);
+  // 最後に受け取った対戦状態の全体 (duelPatch の適用元)
+  const duelStateRef = useRef<any | null>(null);
+  // 再同期を要求して duelData を待っている間は、届いた差分を捨てる
+  const resyncRequestedRef = useRef(false);
 
   /**
    * Calculate exponential backoff delay
@@ -85,6 +141,52 @@ export const useSocket = (options: UseSocketOptions = {}) => {
     }
   }, []);
 
+  /**
+   * Apply a full duel state (duelData, or the result of a duelPatch) to the store
+   */
+  const applyDuelState = useCallback((content: any) => {
+    // --- 受信データの分割 ---
+    const { players, status, turnCount, activeIdx, ...rest } =
+      content || {};
+
+    // --- プレイヤー／相手の判定関数（キー名の揺れを網羅） ---
+    const isMe = (p: any) =>
+      p.uid === user?.uid ||
+      p.userId === user?.uid ||
+      p.UserID === user?.uid ||
+      p.user_id === user?.uid;
+
+    if (players && user) {
+      const currentPlayer = players.find(isMe);
+      const rivalPlayer   = players.find((p: any) => !isMe(p));
+
+      // 自分の情報を store へ
+      if (currentPlayer) {
+        setPlayer(currentPlayer);
+
+        // 手札・ターン情報も反映（プロパティ名の大小両対応）
+        const hand     = currentPlayer.hand ?? currentPlayer.Hand;
+        const isMyTurn = currentPlayer.isMyTurn ?? currentPlayer.IsMyTurn;
+
+        if (hand)            setHand(hand);
+        if (isMyTurn !== undefined) setIsMyTurn(isMyTurn);
+      }
+
+      // 相手情報を store へ
+      if (rivalPlayer) {
+        setOpponent(rivalPlayer);
+      }
+    }
+
+    // --- ゲーム全体のステータスを反映 ---
+    if (status) {
+      setGameStatus(status);   // 例: 'waiting' | 'playing' | 'finished'
+    }
+
+    // 必要なら追加情報（ターン数など）もここでセット
+    // e.g. setTurnCount(turnCount);
+  }, [user, setPlayer, setOpponent, setHand, setIsMyTurn, setGameStatus]);
+
   /**
    * Handle incoming WebSocket messages
    */
@@ -171,60 +273,46 @@ export const useSocket = (options: UseSocketOptions = {}) => {
           setConnectionStatus(false, message.message || message.content?.message);
           break;
           
-        case 'duelData': {
+        case 'duelData':
           console.log('Duel data received:', message.content);
+          duelStateRef.current = message.content ?? null;
+          resyncRequestedRef.current = false;
+          applyDuelState(message.content);
+          break;
 
-          // --- 受信データの分割 ---
-          const { players, status, turnCount, activeIdx, ...rest } =
-            message.content || {};
-
-          // --- プレイヤー／相手の判定関数（キー名の揺れを網羅） ---
-          const isMe = (p: any) =>
-            p.uid === user?.uid ||
-            p.userId === user?.uid ||
-            p.UserID === user?.uid ||
-            p.user_id === user?.uid;
-
-          if (players && user) {
-            const currentPlayer = players.find(isMe);
-            const rivalPlayer   = players.find((p: any) => !isMe(p));
-
-            // 自分の情報を store へ
-            if (currentPlayer) {
-              setPlayer(currentPlayer);
-
-              // 手札・ターン情報も反映（プロパティ名の大小両対応）
-              const hand     = currentPlayer.hand ?? currentPlayer.Hand;
-              const isMyTurn = currentPlayer.isMyTurn ?? currentPlayer.IsMyTurn;
-
-              if (hand)            setHand(hand);
-              if (isMyTurn !== undefined) setIsMyTurn(isMyTurn);
-            }
-
-            // 相手情報を store へ
-            if (rivalPlayer) {
-              setOpponent(rivalPlayer);
-            }
+        case 'duelPatch': {
+          const patch: DuelPatch = message.content;
+          const base = duelStateRef.current;
+          // 既に反映済みのバージョンは無視する
+          if (base && base.id === patch.duelId && patch.version <= base.version) {
+            break;
           }
-
-          // --- ゲーム全体のステータスを反映 ---
-          if (status) {
-            setGameStatus(status);   // 例: 'waiting' | 'playing' | 'finished'
+          // 手元の状態が差分の適用元と違う (取りこぼした) 場合は全体を送り直してもらう
+          const next =
+            base && base.id === patch.duelId && base.version === patch.baseVersion
+              ? applyDuelPatch(base, patch.ops)
+              : null;
+          if (!next) {
+            duelStateRef.current = null;
+            if (!resyncRequestedRef.current) {
+              console.warn('Duel patch gap, requesting resync:', patch.baseVersion, '->', patch.version);
+              resyncRequestedRef.current = true;
+              sendMessage({ type: 'resync', content: { duelId: patch.duelId } });
+            }
+            break;
           }
-
-          // 必要なら追加情報（ターン数など）もここでセット
-          // e.g. setTurnCount(turnCount);
-
+          duelStateRef.current = next;
+          applyDuelState(next);
           break;
         }
-          
+
         default:
           console.log('Unknown message type:', message.type);
       }
     } catch (error) {
       console.error('Error parsing WebSocket message:', error);
     }
-  }, [setPlayer, setOpponent, setHand, setIsMyTurn, setGameStatus, setWinner, updatePlayerHp, updateOpponentHp, setConnectionStatus, setCurrentRoom, setSearchingMatch, setMatchmakingError, setDuelId]);
+  }, [setPlayer, setOpponent, setHand, setIsMyTurn, setGameStatus, setWinner, updatePlayerHp, updateOpponentHp, setConnectionStatus, setCurrentRoom, setSearchingMatch, setMatchmakingError, setDuelId, sendMessage, applyDuelState]);
 
   /**
    * Start ping interval to keep connection alive
@@ -274,16 +362,36 @@ export const useSocket = (options: UseSocketOptions = {}) => {
     try {
       const token = user ? await user.getIdToken() : '';
       const uid = user ? user.uid : '';
-      
+
+      // IDトークンはクエリパラメータに載せず、一回限りの接続チケットと交換する
+      let ticket = '';
+      if (token) {
+        const res = await fetch('/api/ws-ticket', {
+          method: 'POST',
+          credentials: 'include',
+          headers: {
+            'Authorization': `Bearer ${token}`,
+            'Content-Type': 'application/json',
+          },
+          body: JSON.stringify(duelId ? { duelId } : {}),
+        });
+        if (res.ok) {
+          const data = await res.json();
+          ticket = data.ticket;
+        } else {
+          console.error('Failed to issue WebSocket ticket:', res.status);
+        }
+      }
+
       // デバッグ: duelIdの値を出力
       console.log('connect: duelId', duelId);
-      // UIDとトークンの両方をクエリパラメータで送信
+      // UIDとチケットをクエリパラメータで送信
       const params = new URLSearchParams();
-      if (token) params.append('token', token);
+      if (ticket) params.append('ticket', ticket);
       if (uid) params.append('uid', uid);
       if (duelId) params.append('duelId', duelId); // duelIdがある場合のみ追加
       const wsUrl = params.toString() ? `${url}?${params.toString()}` : url;
-      console.log('Connecting to WebSocket:', wsUrl.replace(/token=[^&]+/, 'token=***'));
+      console.log('Connecting to WebSocket:', wsUrl.replace(/ticket=[^&]+/, 'ticket=***'));
       
       wsRef.current = new WebSocket(wsUrl);
 